@@ -3,6 +3,9 @@ module github.com/EmirShimshir/marketplace-repository
 go 1.22.2
 
 require (
+	// The repositories use domain types (StockMovement, Review, Category,
+	// PromoCode, ImportReport and others) that this core version does not have
+	// yet. Bump it with go get to the core commit that adds them, go.sum follows.
 	github.com/EmirShimshir/marketplace-core v0.0.0-20240521182806-fd8f70de647a
 	github.com/golang-migrate/migrate v3.5.4+incompatible
 	github.com/google/uuid v1.6.0
@@ -0,0 +1,294 @@
+// Package bulkimport reads the rows of a bulk product import. Sellers send
+// one product with its stock per CSV record or NDJSON line, both backends
+// stream the rows through Read in batches and write every batch with a few
+// statements instead of a transaction per product.
+//
+// A CSV import starts with a header naming its columns, an NDJSON line is an
+// object with the same keys. product_id, name, price, category_id and
+// quantity are required, description, currency, photo_url and
+// reorder_threshold may be left out. Rows that can not be parsed or
+// validated without the database are reported with their line and skipped.
+package bulkimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// BatchSize is the number of rows written by a single statement.
+const BatchSize = 500
+
+const maxNameLength = 255
+
+var (
+	columns         = []string{"product_id", "name", "description", "price", "currency", "category_id", "photo_url", "quantity", "reorder_threshold"}
+	requiredColumns = []string{"product_id", "name", "price", "category_id", "quantity"}
+)
+
+// BatchFunc receives the valid rows of a batch together with the errors of
+// the rows skipped since the previous batch.
+type BatchFunc func(rows []domain.ImportRow, rowErrors []domain.ImportRowError) error
+
+// Read parses data in the format and calls fn for every batchSize rows and
+// once more for the rest. A product listed twice is imported from its first
+// row only. A malformed header or a failed read stops the import with
+// ErrNotAllowed, an error returned by fn stops it as is.
+func Read(data io.Reader, format domain.ImportFormat, batchSize int, fn BatchFunc) error {
+	b := batcher{size: batchSize, fn: fn, seen: make(map[domain.ID]int64)}
+	var err error
+	switch format {
+	case domain.ImportFormatCSV:
+		err = readCSV(data, &b)
+	case domain.ImportFormatNDJSON:
+		err = readNDJSON(data, &b)
+	default:
+		return errors.Wrap(domain.ErrNotAllowed, "unknown import format")
+	}
+	if err != nil {
+		return err
+	}
+	return b.flush()
+}
+
+// batcher collects the rows until a batch is full.
+type batcher struct {
+	size      int
+	fn        BatchFunc
+	rows      []domain.ImportRow
+	rowErrors []domain.ImportRowError
+	seen      map[domain.ID]int64
+}
+
+func (b *batcher) add(line int64, values map[string]string) error {
+	row, err := newRow(line, values)
+	if err == nil {
+		if first, ok := b.seen[row.Product.ID]; ok {
+			err = fmt.Errorf("product is already listed on line %d", first)
+		} else {
+			b.seen[row.Product.ID] = line
+		}
+	}
+	if err != nil {
+		b.reject(line, err)
+		return nil
+	}
+
+	b.rows = append(b.rows, row)
+	if len(b.rows) >= b.size {
+		return b.flush()
+	}
+	return nil
+}
+
+func (b *batcher) reject(line int64, err error) {
+	b.rowErrors = append(b.rowErrors, domain.ImportRowError{Line: line, Error: err.Error()})
+}
+
+func (b *batcher) flush() error {
+	if len(b.rows) == 0 && len(b.rowErrors) == 0 {
+		return nil
+	}
+	err := b.fn(b.rows, b.rowErrors)
+	b.rows, b.rowErrors = nil, nil
+	return err
+}
+
+func readCSV(data io.Reader, b *batcher) error {
+	reader := csv.NewReader(data)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return errors.Wrap(domain.ErrNotAllowed, "unable to read the CSV header: "+err.Error())
+	}
+	if err = checkColumns(header); err != nil {
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				b.reject(int64(parseErr.StartLine), parseErr.Err)
+				continue
+			}
+			return errors.Wrap(domain.ErrNotAllowed, err.Error())
+		}
+
+		line, _ := reader.FieldPos(0)
+		values := make(map[string]string, len(header))
+		for i, column := range header {
+			values[column] = record[i]
+		}
+		if err = b.add(int64(line), values); err != nil {
+			return err
+		}
+	}
+}
+
+func checkColumns(header []string) error {
+	present := make(map[string]bool, len(header))
+	for _, column := range header {
+		if !knownColumn(column) {
+			return errors.Wrap(domain.ErrNotAllowed, "unknown column "+column)
+		}
+		if present[column] {
+			return errors.Wrap(domain.ErrNotAllowed, "duplicate column "+column)
+		}
+		present[column] = true
+	}
+	for _, column := range requiredColumns {
+		if !present[column] {
+			return errors.Wrap(domain.ErrNotAllowed, "missing column "+column)
+		}
+	}
+	return nil
+}
+
+func knownColumn(column string) bool {
+	for _, name := range columns {
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
+
+func readNDJSON(data io.Reader, b *batcher) error {
+	scanner := bufio.NewScanner(data)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var line int64
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(text, &object); err != nil {
+			b.reject(line, err)
+			continue
+		}
+		values, err := jsonValues(object)
+		if err != nil {
+			b.reject(line, err)
+			continue
+		}
+		if err = b.add(line, values); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(domain.ErrNotAllowed, err.Error())
+	}
+	return nil
+}
+
+// jsonValues turns the strings and the numbers of an NDJSON object into the
+// values of the matching CSV columns.
+func jsonValues(object map[string]json.RawMessage) (map[string]string, error) {
+	values := make(map[string]string, len(object))
+	for key, raw := range object {
+		if !knownColumn(key) {
+			return nil, errors.New("unknown key " + key)
+		}
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case json.Number:
+			values[key] = v.String()
+		case nil:
+		default:
+			return nil, errors.New(key + " must be a string or a number")
+		}
+	}
+	return values, nil
+}
+
+// newRow validates the values of a row that do not need the database, the
+// category and the currency are checked by the repository.
+func newRow(line int64, values map[string]string) (domain.ImportRow, error) {
+	for _, column := range requiredColumns {
+		if strings.TrimSpace(values[column]) == "" {
+			return domain.ImportRow{}, errors.New(column + " is required")
+		}
+	}
+
+	productID, err := uuid.Parse(values["product_id"])
+	if err != nil {
+		return domain.ImportRow{}, errors.New("product_id is not a UUID")
+	}
+	categoryID, err := uuid.Parse(values["category_id"])
+	if err != nil {
+		return domain.ImportRow{}, errors.New("category_id is not a UUID")
+	}
+	if utf8.RuneCountInString(values["name"]) > maxNameLength {
+		return domain.ImportRow{}, fmt.Errorf("name is longer than %d characters", maxNameLength)
+	}
+	price, err := parseInt(values, "price")
+	if err != nil {
+		return domain.ImportRow{}, err
+	}
+	if price <= 0 {
+		return domain.ImportRow{}, errors.New("price must be positive")
+	}
+	quantity, err := parseInt(values, "quantity")
+	if err != nil {
+		return domain.ImportRow{}, err
+	}
+	reorderThreshold, err := parseInt(values, "reorder_threshold")
+	if err != nil {
+		return domain.ImportRow{}, err
+	}
+	if quantity < 0 || reorderThreshold < 0 {
+		return domain.ImportRow{}, errors.New("quantity and reorder_threshold can not be negative")
+	}
+
+	return domain.ImportRow{
+		Line: line,
+		Product: domain.Product{
+			ID:          domain.ID(productID.String()),
+			Name:        values["name"],
+			Description: values["description"],
+			Price:       price,
+			Currency:    values["currency"],
+			CategoryID:  domain.ID(categoryID.String()),
+			PhotoUrl:    values["photo_url"],
+		},
+		Quantity:         quantity,
+		ReorderThreshold: reorderThreshold,
+	}, nil
+}
+
+// parseInt parses the integer in the column, an empty optional column is 0.
+func parseInt(values map[string]string, column string) (int64, error) {
+	value := strings.TrimSpace(values[column])
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.New(column + " is not an integer")
+	}
+	return number, nil
+}
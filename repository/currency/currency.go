@@ -0,0 +1,13 @@
+// Package currency checks the ISO 4217 currency codes that both backends
+// store with every amount.
+package currency
+
+import "regexp"
+
+var codeRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Valid reports whether the code looks like an ISO 4217 alphabetic currency
+// code, the same check the postgres schema applies to currency columns.
+func Valid(code string) bool {
+	return codeRegexp.MatchString(code)
+}
@@ -0,0 +1,254 @@
+// Package encryption encrypts personal data before it is stored and decrypts
+// it when it is read back. The entity mappers of both backends call Encrypt
+// and Decrypt and return their errors, so repositories keep working with
+// plaintext domain values.
+//
+// An encrypted value looks like "enc:<key id>:<base64 nonce and ciphertext>".
+// The key ID lets old values be read after the current key is rotated, new
+// writes always use the current key. Values without the prefix are returned
+// as is, so rows written before encryption was enabled stay readable until
+// ReencryptPII of the backend encrypts them.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/guregu/null"
+	"github.com/pkg/errors"
+	"strings"
+	"sync"
+)
+
+const prefix = "enc:"
+
+var (
+	ErrUnknownKey    = errors.New("unknown encryption key")
+	ErrInvalidKey    = errors.New("invalid encryption key")
+	ErrNoKeyProvider = errors.New("no key provider")
+	ErrMalformed     = errors.New("malformed encrypted value")
+)
+
+// KeyProvider supplies the keys used to encrypt personal data at rest.
+type KeyProvider interface {
+	// CurrentKeyID returns the ID of the key new values are encrypted with.
+	CurrentKeyID() string
+	// Key returns the 32 byte AES-256 key with the given ID.
+	Key(keyID string) ([]byte, error)
+	// BlindIndexKey returns the HMAC key of the blind indexes. It must not
+	// change on rotation, otherwise the stored indexes stop matching.
+	BlindIndexKey() []byte
+}
+
+var (
+	mu       sync.RWMutex
+	provider KeyProvider
+)
+
+// SetKeyProvider enables encryption with the keys of p, a nil p disables it.
+// The current key is checked up front so that Encrypt does not fail later.
+func SetKeyProvider(p KeyProvider) error {
+	if p != nil {
+		key, err := p.Key(p.CurrentKeyID())
+		if err != nil {
+			return errors.Wrap(ErrUnknownKey, err.Error())
+		}
+		if _, err = newAEAD(key); err != nil {
+			return err
+		}
+		if strings.Contains(p.CurrentKeyID(), ":") {
+			return errors.Wrap(ErrInvalidKey, "key id must not contain ':'")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	provider = p
+	return nil
+}
+
+func currentProvider() KeyProvider {
+	mu.RLock()
+	defer mu.RUnlock()
+	return provider
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.Wrapf(ErrInvalidKey, "key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidKey, err.Error())
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt encrypts value with the current key. Empty values and values
+// written without a key provider are returned as is.
+func Encrypt(value string) (string, error) {
+	p := currentProvider()
+	if p == nil || value == "" {
+		return value, nil
+	}
+
+	keyID := p.CurrentKeyID()
+	key, err := p.Key(keyID)
+	if err != nil {
+		return "", errors.Wrap(ErrUnknownKey, err.Error())
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(keyID))
+	return prefix + keyID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt with the key the value was encrypted with.
+func Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+	p := currentProvider()
+	if p == nil {
+		return "", ErrNoKeyProvider
+	}
+
+	keyID, encoded, found := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !found {
+		return "", ErrMalformed
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errors.Wrap(ErrMalformed, err.Error())
+	}
+	key, err := p.Key(keyID)
+	if err != nil {
+		return "", errors.Wrap(ErrUnknownKey, err.Error())
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrMalformed
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(keyID))
+	if err != nil {
+		return "", errors.Wrap(ErrMalformed, err.Error())
+	}
+	return string(plain), nil
+}
+
+// KeyID returns the ID of the key value was encrypted with, or "" for a
+// plaintext value. It lets callers find values that still need rotation.
+func KeyID(value string) string {
+	if !strings.HasPrefix(value, prefix) {
+		return ""
+	}
+	keyID, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	return keyID
+}
+
+// EncryptNull is Encrypt for nullable values, a null value stays null.
+func EncryptNull(value null.String) (null.String, error) {
+	if !value.Valid {
+		return value, nil
+	}
+	encrypted, err := Encrypt(value.String)
+	if err != nil {
+		return null.String{}, err
+	}
+	return null.StringFrom(encrypted), nil
+}
+
+// DecryptNull is Decrypt for nullable values, a null value stays null.
+func DecryptNull(value null.String) (null.String, error) {
+	if !value.Valid {
+		return value, nil
+	}
+	plain, err := Decrypt(value.String)
+	if err != nil {
+		return null.String{}, err
+	}
+	return null.StringFrom(plain), nil
+}
+
+// CurrentPrefix returns the prefix of the values encrypted with the current
+// key, or "" without a key provider. Values stored without it still have to
+// be encrypted with the current key.
+func CurrentPrefix() string {
+	p := currentProvider()
+	if p == nil {
+		return ""
+	}
+	return prefix + p.CurrentKeyID() + ":"
+}
+
+// BlindIndex returns a deterministic keyed hash of value, so encrypted
+// columns can still be looked up by equality. Without a key provider it is
+// the plain SHA-256 of the value.
+func BlindIndex(value string) string {
+	var sum []byte
+	if p := currentProvider(); p != nil {
+		mac := hmac.New(sha256.New, p.BlindIndexKey())
+		mac.Write([]byte(value))
+		sum = mac.Sum(nil)
+	} else {
+		hash := sha256.Sum256([]byte(value))
+		sum = hash[:]
+	}
+	return hex.EncodeToString(sum)
+}
+
+// HashToken returns the hex encoded SHA-256 of a session or a user token,
+// only the hash of a token is ever stored. Unlike BlindIndex it is not keyed,
+// the tokens are random, so their hashes can not be guessed and stay valid
+// whatever key provider is set.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// StaticKeyProvider is a KeyProvider over keys known up front, for example
+// loaded from the service config. Rotation means adding a new key and
+// making it current, the old keys stay to read older values.
+type StaticKeyProvider struct {
+	currentKeyID  string
+	keys          map[string][]byte
+	blindIndexKey []byte
+}
+
+func NewStaticKeyProvider(currentKeyID string, keys map[string][]byte, blindIndexKey []byte) *StaticKeyProvider {
+	return &StaticKeyProvider{
+		currentKeyID:  currentKeyID,
+		keys:          keys,
+		blindIndexKey: blindIndexKey,
+	}
+}
+
+func (s *StaticKeyProvider) CurrentKeyID() string {
+	return s.currentKeyID
+}
+
+func (s *StaticKeyProvider) Key(keyID string) ([]byte, error) {
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found", keyID)
+	}
+	return key, nil
+}
+
+func (s *StaticKeyProvider) BlindIndexKey() []byte {
+	return s.blindIndexKey
+}
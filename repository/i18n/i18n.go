@@ -0,0 +1,25 @@
+// Package i18n holds the locale rules both backends apply to product
+// translations.
+package i18n
+
+import (
+	"regexp"
+	"strings"
+)
+
+var localeRegexp = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+// ValidLocale reports whether the locale is a language code optionally
+// followed by a region, such as "ru" or "en-US".
+func ValidLocale(locale string) bool {
+	return localeRegexp.MatchString(locale)
+}
+
+// LocaleChain lists the locales to try for the preferred one, most specific
+// first, so "en-US" falls back to "en".
+func LocaleChain(locale string) []string {
+	if language, _, found := strings.Cut(locale, "-"); found {
+		return []string{locale, language}
+	}
+	return []string{locale}
+}
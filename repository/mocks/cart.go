@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	time "time"
 
 	"github.com/EmirShimshir/marketplace-core/domain"
 	mock "github.com/stretchr/testify/mock"
@@ -60,6 +61,34 @@ func (_m *CartRepository) CreateCartItem(ctx context.Context, cartItem domain.Ca
 	return r0, r1
 }
 
+// CreateGuestCart provides a mock function with given fields: ctx, cart
+func (_m *CartRepository) CreateGuestCart(ctx context.Context, cart domain.Cart) (domain.Cart, error) {
+	ret := _m.Called(ctx, cart)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateGuestCart")
+	}
+
+	var r0 domain.Cart
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Cart) (domain.Cart, error)); ok {
+		return rf(ctx, cart)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.Cart) domain.Cart); ok {
+		r0 = rf(ctx, cart)
+	} else {
+		r0 = ret.Get(0).(domain.Cart)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.Cart) error); ok {
+		r1 = rf(ctx, cart)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteCartItem provides a mock function with given fields: ctx, cartItemID
 func (_m *CartRepository) DeleteCartItem(ctx context.Context, cartItemID domain.ID) error {
 	ret := _m.Called(ctx, cartItemID)
@@ -78,6 +107,64 @@ func (_m *CartRepository) DeleteCartItem(ctx context.Context, cartItemID domain.
 	return r0
 }
 
+// FindAbandonedCarts provides a mock function with given fields: ctx, olderThan, minValue
+func (_m *CartRepository) FindAbandonedCarts(ctx context.Context, olderThan time.Time, minValue int64) ([]domain.Cart, error) {
+	ret := _m.Called(ctx, olderThan, minValue)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindAbandonedCarts")
+	}
+
+	var r0 []domain.Cart
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int64) ([]domain.Cart, error)); ok {
+		return rf(ctx, olderThan, minValue)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int64) []domain.Cart); ok {
+		r0 = rf(ctx, olderThan, minValue)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Cart)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int64) error); ok {
+		r1 = rf(ctx, olderThan, minValue)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetCartByGuestToken provides a mock function with given fields: ctx, token
+func (_m *CartRepository) GetCartByGuestToken(ctx context.Context, token string) (domain.Cart, error) {
+	ret := _m.Called(ctx, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCartByGuestToken")
+	}
+
+	var r0 domain.Cart
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (domain.Cart, error)); ok {
+		return rf(ctx, token)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) domain.Cart); ok {
+		r0 = rf(ctx, token)
+	} else {
+		r0 = ret.Get(0).(domain.Cart)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCartByID provides a mock function with given fields: ctx, cartID
 func (_m *CartRepository) GetCartByID(ctx context.Context, cartID domain.ID) (domain.Cart, error) {
 	ret := _m.Called(ctx, cartID)
@@ -134,6 +221,118 @@ func (_m *CartRepository) GetCartItemByID(ctx context.Context, cartItemID domain
 	return r0, r1
 }
 
+// GetCartTotal provides a mock function with given fields: ctx, cartID
+func (_m *CartRepository) GetCartTotal(ctx context.Context, cartID domain.ID) (int64, error) {
+	ret := _m.Called(ctx, cartID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCartTotal")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) (int64, error)); ok {
+		return rf(ctx, cartID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) int64); ok {
+		r0 = rf(ctx, cartID)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, cartID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MergeCarts provides a mock function with given fields: ctx, guestCartID, userCartID
+func (_m *CartRepository) MergeCarts(ctx context.Context, guestCartID domain.ID, userCartID domain.ID) (domain.Cart, error) {
+	ret := _m.Called(ctx, guestCartID, userCartID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MergeCarts")
+	}
+
+	var r0 domain.Cart
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, domain.ID) (domain.Cart, error)); ok {
+		return rf(ctx, guestCartID, userCartID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, domain.ID) domain.Cart); ok {
+		r0 = rf(ctx, guestCartID, userCartID)
+	} else {
+		r0 = ret.Get(0).(domain.Cart)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID, domain.ID) error); ok {
+		r1 = rf(ctx, guestCartID, userCartID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PurgeStaleCartItems provides a mock function with given fields: ctx, olderThan
+func (_m *CartRepository) PurgeStaleCartItems(ctx context.Context, olderThan time.Time) (int64, error) {
+	ret := _m.Called(ctx, olderThan)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PurgeStaleCartItems")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int64, error)); ok {
+		return rf(ctx, olderThan)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int64); ok {
+		r0 = rf(ctx, olderThan)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, olderThan)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecalculateCart provides a mock function with given fields: ctx, cartID
+func (_m *CartRepository) RecalculateCart(ctx context.Context, cartID domain.ID) (domain.Cart, error) {
+	ret := _m.Called(ctx, cartID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecalculateCart")
+	}
+
+	var r0 domain.Cart
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) (domain.Cart, error)); ok {
+		return rf(ctx, cartID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) domain.Cart); ok {
+		r0 = rf(ctx, cartID)
+	} else {
+		r0 = ret.Get(0).(domain.Cart)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, cartID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateCart provides a mock function with given fields: ctx, cart
 func (_m *CartRepository) UpdateCart(ctx context.Context, cart domain.Cart) (domain.Cart, error) {
 	ret := _m.Called(ctx, cart)
@@ -14,6 +14,34 @@ type ProductRepository struct {
 	mock.Mock
 }
 
+// AddImage provides a mock function with given fields: ctx, image
+func (_m *ProductRepository) AddImage(ctx context.Context, image domain.ProductImage) (domain.ProductImage, error) {
+	ret := _m.Called(ctx, image)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AddImage")
+	}
+
+	var r0 domain.ProductImage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductImage) (domain.ProductImage, error)); ok {
+		return rf(ctx, image)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductImage) domain.ProductImage); ok {
+		r0 = rf(ctx, image)
+	} else {
+		r0 = ret.Get(0).(domain.ProductImage)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ProductImage) error); ok {
+		r1 = rf(ctx, image)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: ctx, product
 func (_m *ProductRepository) Create(ctx context.Context, product domain.Product) (domain.Product, error) {
 	ret := _m.Called(ctx, product)
@@ -60,6 +88,42 @@ func (_m *ProductRepository) Delete(ctx context.Context, productID domain.ID) er
 	return r0
 }
 
+// DeleteImage provides a mock function with given fields: ctx, imageID
+func (_m *ProductRepository) DeleteImage(ctx context.Context, imageID domain.ID) error {
+	ret := _m.Called(ctx, imageID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteImage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) error); ok {
+		r0 = rf(ctx, imageID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteTranslation provides a mock function with given fields: ctx, productID, locale
+func (_m *ProductRepository) DeleteTranslation(ctx context.Context, productID domain.ID, locale string) error {
+	ret := _m.Called(ctx, productID, locale)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteTranslation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, string) error); ok {
+		r0 = rf(ctx, productID, locale)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Get provides a mock function with given fields: ctx, limit, offset
 func (_m *ProductRepository) Get(ctx context.Context, limit int64, offset int64) ([]domain.Product, error) {
 	ret := _m.Called(ctx, limit, offset)
@@ -90,6 +154,126 @@ func (_m *ProductRepository) Get(ctx context.Context, limit int64, offset int64)
 	return r0, r1
 }
 
+// GetByAttributes provides a mock function with given fields: ctx, filters, limit, offset
+func (_m *ProductRepository) GetByAttributes(ctx context.Context, filters []domain.AttributeFilter, limit int64, offset int64) ([]domain.Product, error) {
+	ret := _m.Called(ctx, filters, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByAttributes")
+	}
+
+	var r0 []domain.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.AttributeFilter, int64, int64) ([]domain.Product, error)); ok {
+		return rf(ctx, filters, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.AttributeFilter, int64, int64) []domain.Product); ok {
+		r0 = rf(ctx, filters, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []domain.AttributeFilter, int64, int64) error); ok {
+		r1 = rf(ctx, filters, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByAttributesInLocale provides a mock function with given fields: ctx, filters, locale, limit, offset
+func (_m *ProductRepository) GetByAttributesInLocale(ctx context.Context, filters []domain.AttributeFilter, locale string, limit int64, offset int64) ([]domain.Product, error) {
+	ret := _m.Called(ctx, filters, locale, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByAttributesInLocale")
+	}
+
+	var r0 []domain.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.AttributeFilter, string, int64, int64) ([]domain.Product, error)); ok {
+		return rf(ctx, filters, locale, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []domain.AttributeFilter, string, int64, int64) []domain.Product); ok {
+		r0 = rf(ctx, filters, locale, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []domain.AttributeFilter, string, int64, int64) error); ok {
+		r1 = rf(ctx, filters, locale, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByCategoryID provides a mock function with given fields: ctx, categoryID, limit, offset
+func (_m *ProductRepository) GetByCategoryID(ctx context.Context, categoryID domain.ID, limit int64, offset int64) ([]domain.Product, error) {
+	ret := _m.Called(ctx, categoryID, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByCategoryID")
+	}
+
+	var r0 []domain.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, int64, int64) ([]domain.Product, error)); ok {
+		return rf(ctx, categoryID, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, int64, int64) []domain.Product); ok {
+		r0 = rf(ctx, categoryID, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID, int64, int64) error); ok {
+		r1 = rf(ctx, categoryID, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByCategoryIDInLocale provides a mock function with given fields: ctx, categoryID, locale, limit, offset
+func (_m *ProductRepository) GetByCategoryIDInLocale(ctx context.Context, categoryID domain.ID, locale string, limit int64, offset int64) ([]domain.Product, error) {
+	ret := _m.Called(ctx, categoryID, locale, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByCategoryIDInLocale")
+	}
+
+	var r0 []domain.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, string, int64, int64) ([]domain.Product, error)); ok {
+		return rf(ctx, categoryID, locale, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, string, int64, int64) []domain.Product); ok {
+		r0 = rf(ctx, categoryID, locale, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID, string, int64, int64) error); ok {
+		r1 = rf(ctx, categoryID, locale, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetByID provides a mock function with given fields: ctx, productID
 func (_m *ProductRepository) GetByID(ctx context.Context, productID domain.ID) (domain.Product, error) {
 	ret := _m.Called(ctx, productID)
@@ -118,6 +302,170 @@ func (_m *ProductRepository) GetByID(ctx context.Context, productID domain.ID) (
 	return r0, r1
 }
 
+// GetByIDInLocale provides a mock function with given fields: ctx, productID, locale
+func (_m *ProductRepository) GetByIDInLocale(ctx context.Context, productID domain.ID, locale string) (domain.Product, error) {
+	ret := _m.Called(ctx, productID, locale)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByIDInLocale")
+	}
+
+	var r0 domain.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, string) (domain.Product, error)); ok {
+		return rf(ctx, productID, locale)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, string) domain.Product); ok {
+		r0 = rf(ctx, productID, locale)
+	} else {
+		r0 = ret.Get(0).(domain.Product)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID, string) error); ok {
+		r1 = rf(ctx, productID, locale)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetInLocale provides a mock function with given fields: ctx, locale, limit, offset
+func (_m *ProductRepository) GetInLocale(ctx context.Context, locale string, limit int64, offset int64) ([]domain.Product, error) {
+	ret := _m.Called(ctx, locale, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInLocale")
+	}
+
+	var r0 []domain.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int64) ([]domain.Product, error)); ok {
+		return rf(ctx, locale, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64, int64) []domain.Product); ok {
+		r0 = rf(ctx, locale, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int64, int64) error); ok {
+		r1 = rf(ctx, locale, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTranslations provides a mock function with given fields: ctx, productID
+func (_m *ProductRepository) GetTranslations(ctx context.Context, productID domain.ID) ([]domain.ProductTranslation, error) {
+	ret := _m.Called(ctx, productID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTranslations")
+	}
+
+	var r0 []domain.ProductTranslation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) ([]domain.ProductTranslation, error)); ok {
+		return rf(ctx, productID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) []domain.ProductTranslation); ok {
+		r0 = rf(ctx, productID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.ProductTranslation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, productID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReorderImages provides a mock function with given fields: ctx, productID, imageIDs
+func (_m *ProductRepository) ReorderImages(ctx context.Context, productID domain.ID, imageIDs []domain.ID) error {
+	ret := _m.Called(ctx, productID, imageIDs)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReorderImages")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, []domain.ID) error); ok {
+		r0 = rf(ctx, productID, imageIDs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Search provides a mock function with given fields: ctx, locale, query, limit, offset
+func (_m *ProductRepository) Search(ctx context.Context, locale string, query string, limit int64, offset int64) ([]domain.Product, error) {
+	ret := _m.Called(ctx, locale, query, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Search")
+	}
+
+	var r0 []domain.Product
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, int64) ([]domain.Product, error)); ok {
+		return rf(ctx, locale, query, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64, int64) []domain.Product); ok {
+		r0 = rf(ctx, locale, query, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Product)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64, int64) error); ok {
+		r1 = rf(ctx, locale, query, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetTranslation provides a mock function with given fields: ctx, translation
+func (_m *ProductRepository) SetTranslation(ctx context.Context, translation domain.ProductTranslation) (domain.ProductTranslation, error) {
+	ret := _m.Called(ctx, translation)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetTranslation")
+	}
+
+	var r0 domain.ProductTranslation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductTranslation) (domain.ProductTranslation, error)); ok {
+		return rf(ctx, translation)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductTranslation) domain.ProductTranslation); ok {
+		r0 = rf(ctx, translation)
+	} else {
+		r0 = ret.Get(0).(domain.ProductTranslation)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ProductTranslation) error); ok {
+		r1 = rf(ctx, translation)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, product
 func (_m *ProductRepository) Update(ctx context.Context, product domain.Product) (domain.Product, error) {
 	ret := _m.Called(ctx, product)
@@ -4,6 +4,7 @@ package mocks
 
 import (
 	context "context"
+	io "io"
 
 	"github.com/EmirShimshir/marketplace-core/domain"
 	mock "github.com/stretchr/testify/mock"
@@ -70,6 +71,34 @@ func (_m *ShopRepository) CreateShopItem(ctx context.Context, shopItem domain.Sh
 	return r0, r1
 }
 
+// CreateVariant provides a mock function with given fields: ctx, variant
+func (_m *ShopRepository) CreateVariant(ctx context.Context, variant domain.ProductVariant) (domain.ProductVariant, error) {
+	ret := _m.Called(ctx, variant)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CreateVariant")
+	}
+
+	var r0 domain.ProductVariant
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductVariant) (domain.ProductVariant, error)); ok {
+		return rf(ctx, variant)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductVariant) domain.ProductVariant); ok {
+		r0 = rf(ctx, variant)
+	} else {
+		r0 = ret.Get(0).(domain.ProductVariant)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ProductVariant) error); ok {
+		r1 = rf(ctx, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // DeleteShop provides a mock function with given fields: ctx, shopID
 func (_m *ShopRepository) DeleteShop(ctx context.Context, shopID domain.ID) error {
 	ret := _m.Called(ctx, shopID)
@@ -106,6 +135,114 @@ func (_m *ShopRepository) DeleteShopItem(ctx context.Context, shopItemID domain.
 	return r0
 }
 
+// DeleteVariant provides a mock function with given fields: ctx, variantID
+func (_m *ShopRepository) DeleteVariant(ctx context.Context, variantID domain.ID) error {
+	ret := _m.Called(ctx, variantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteVariant")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) error); ok {
+		r0 = rf(ctx, variantID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetLowStockItems provides a mock function with given fields: ctx, shopID
+func (_m *ShopRepository) GetLowStockItems(ctx context.Context, shopID domain.ID) ([]domain.ShopItem, error) {
+	ret := _m.Called(ctx, shopID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLowStockItems")
+	}
+
+	var r0 []domain.ShopItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) ([]domain.ShopItem, error)); ok {
+		return rf(ctx, shopID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) []domain.ShopItem); ok {
+		r0 = rf(ctx, shopID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.ShopItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, shopID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetModerationHistory provides a mock function with given fields: ctx, shopID
+func (_m *ShopRepository) GetModerationHistory(ctx context.Context, shopID domain.ID) ([]domain.ModerationAction, error) {
+	ret := _m.Called(ctx, shopID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetModerationHistory")
+	}
+
+	var r0 []domain.ModerationAction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) ([]domain.ModerationAction, error)); ok {
+		return rf(ctx, shopID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) []domain.ModerationAction); ok {
+		r0 = rf(ctx, shopID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.ModerationAction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, shopID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOutOfStockProducts provides a mock function with given fields: ctx, limit, cursor
+func (_m *ShopRepository) GetOutOfStockProducts(ctx context.Context, limit int64, cursor domain.ID) ([]domain.ShopItem, error) {
+	ret := _m.Called(ctx, limit, cursor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOutOfStockProducts")
+	}
+
+	var r0 []domain.ShopItem
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, domain.ID) ([]domain.ShopItem, error)); ok {
+		return rf(ctx, limit, cursor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, domain.ID) []domain.ShopItem); ok {
+		r0 = rf(ctx, limit, cursor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.ShopItem)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, domain.ID) error); ok {
+		r1 = rf(ctx, limit, cursor)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetShopByID provides a mock function with given fields: ctx, shopID
 func (_m *ShopRepository) GetShopByID(ctx context.Context, shopID domain.ID) (domain.Shop, error) {
 	ret := _m.Called(ctx, shopID)
@@ -280,6 +417,208 @@ func (_m *ShopRepository) GetShops(ctx context.Context, limit int64, offset int6
 	return r0, r1
 }
 
+// GetShopsByModerationStatus provides a mock function with given fields: ctx, status, limit, offset
+func (_m *ShopRepository) GetShopsByModerationStatus(ctx context.Context, status domain.ShopModerationStatus, limit int64, offset int64) ([]domain.Shop, error) {
+	ret := _m.Called(ctx, status, limit, offset)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetShopsByModerationStatus")
+	}
+
+	var r0 []domain.Shop
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ShopModerationStatus, int64, int64) ([]domain.Shop, error)); ok {
+		return rf(ctx, status, limit, offset)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ShopModerationStatus, int64, int64) []domain.Shop); ok {
+		r0 = rf(ctx, status, limit, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Shop)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ShopModerationStatus, int64, int64) error); ok {
+		r1 = rf(ctx, status, limit, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStockHistory provides a mock function with given fields: ctx, shopItemID
+func (_m *ShopRepository) GetStockHistory(ctx context.Context, shopItemID domain.ID) ([]domain.StockMovement, error) {
+	ret := _m.Called(ctx, shopItemID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStockHistory")
+	}
+
+	var r0 []domain.StockMovement
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) ([]domain.StockMovement, error)); ok {
+		return rf(ctx, shopItemID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) []domain.StockMovement); ok {
+		r0 = rf(ctx, shopItemID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.StockMovement)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, shopItemID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVariantByID provides a mock function with given fields: ctx, variantID
+func (_m *ShopRepository) GetVariantByID(ctx context.Context, variantID domain.ID) (domain.ProductVariant, error) {
+	ret := _m.Called(ctx, variantID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVariantByID")
+	}
+
+	var r0 domain.ProductVariant
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) (domain.ProductVariant, error)); ok {
+		return rf(ctx, variantID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) domain.ProductVariant); ok {
+		r0 = rf(ctx, variantID)
+	} else {
+		r0 = ret.Get(0).(domain.ProductVariant)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, variantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVariantsByShopItemID provides a mock function with given fields: ctx, shopItemID
+func (_m *ShopRepository) GetVariantsByShopItemID(ctx context.Context, shopItemID domain.ID) ([]domain.ProductVariant, error) {
+	ret := _m.Called(ctx, shopItemID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVariantsByShopItemID")
+	}
+
+	var r0 []domain.ProductVariant
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) ([]domain.ProductVariant, error)); ok {
+		return rf(ctx, shopItemID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) []domain.ProductVariant); ok {
+		r0 = rf(ctx, shopItemID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.ProductVariant)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, shopItemID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ImportShopItems provides a mock function with given fields: ctx, shopID, format, data, dryRun
+func (_m *ShopRepository) ImportShopItems(ctx context.Context, shopID domain.ID, format domain.ImportFormat, data io.Reader, dryRun bool) (domain.ImportReport, error) {
+	ret := _m.Called(ctx, shopID, format, data, dryRun)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportShopItems")
+	}
+
+	var r0 domain.ImportReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, domain.ImportFormat, io.Reader, bool) (domain.ImportReport, error)); ok {
+		return rf(ctx, shopID, format, data, dryRun)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, domain.ImportFormat, io.Reader, bool) domain.ImportReport); ok {
+		r0 = rf(ctx, shopID, format, data, dryRun)
+	} else {
+		r0 = ret.Get(0).(domain.ImportReport)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID, domain.ImportFormat, io.Reader, bool) error); ok {
+		r1 = rf(ctx, shopID, format, data, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReconcileStock provides a mock function with given fields: ctx, shopItemID
+func (_m *ShopRepository) ReconcileStock(ctx context.Context, shopItemID domain.ID) (bool, error) {
+	ret := _m.Called(ctx, shopItemID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReconcileStock")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) (bool, error)); ok {
+		return rf(ctx, shopItemID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) bool); ok {
+		r0 = rf(ctx, shopItemID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, shopItemID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetModerationStatus provides a mock function with given fields: ctx, action
+func (_m *ShopRepository) SetModerationStatus(ctx context.Context, action domain.ModerationAction) (domain.Shop, error) {
+	ret := _m.Called(ctx, action)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetModerationStatus")
+	}
+
+	var r0 domain.Shop
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ModerationAction) (domain.Shop, error)); ok {
+		return rf(ctx, action)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ModerationAction) domain.Shop); ok {
+		r0 = rf(ctx, action)
+	} else {
+		r0 = ret.Get(0).(domain.Shop)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ModerationAction) error); ok {
+		r1 = rf(ctx, action)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UpdateShop provides a mock function with given fields: ctx, shop
 func (_m *ShopRepository) UpdateShop(ctx context.Context, shop domain.Shop) (domain.Shop, error) {
 	ret := _m.Called(ctx, shop)
@@ -336,6 +675,34 @@ func (_m *ShopRepository) UpdateShopItem(ctx context.Context, shopItem domain.Sh
 	return r0, r1
 }
 
+// UpdateVariant provides a mock function with given fields: ctx, variant
+func (_m *ShopRepository) UpdateVariant(ctx context.Context, variant domain.ProductVariant) (domain.ProductVariant, error) {
+	ret := _m.Called(ctx, variant)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateVariant")
+	}
+
+	var r0 domain.ProductVariant
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductVariant) (domain.ProductVariant, error)); ok {
+		return rf(ctx, variant)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ProductVariant) domain.ProductVariant); ok {
+		r0 = rf(ctx, variant)
+	} else {
+		r0 = ret.Get(0).(domain.ProductVariant)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ProductVariant) error); ok {
+		r1 = rf(ctx, variant)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewShopRepository creates a new instance of ShopRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewShopRepository(t interface {
@@ -14,6 +14,34 @@ type UserRepository struct {
 	mock.Mock
 }
 
+// BlockUser provides a mock function with given fields: ctx, action
+func (_m *UserRepository) BlockUser(ctx context.Context, action domain.ModerationAction) (domain.User, error) {
+	ret := _m.Called(ctx, action)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BlockUser")
+	}
+
+	var r0 domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ModerationAction) (domain.User, error)); ok {
+		return rf(ctx, action)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ModerationAction) domain.User); ok {
+		r0 = rf(ctx, action)
+	} else {
+		r0 = ret.Get(0).(domain.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ModerationAction) error); ok {
+		r1 = rf(ctx, action)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: ctx, user
 func (_m *UserRepository) Create(ctx context.Context, user domain.User) (domain.User, error) {
 	ret := _m.Called(ctx, user)
@@ -60,6 +88,54 @@ func (_m *UserRepository) Delete(ctx context.Context, userID domain.ID) error {
 	return r0
 }
 
+// EraseUser provides a mock function with given fields: ctx, userID
+func (_m *UserRepository) EraseUser(ctx context.Context, userID domain.ID) error {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EraseUser")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ExportUserData provides a mock function with given fields: ctx, userID
+func (_m *UserRepository) ExportUserData(ctx context.Context, userID domain.ID) ([]byte, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportUserData")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) ([]byte, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) []byte); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Get provides a mock function with given fields: Ctx, limit, offset
 func (_m *UserRepository) Get(Ctx context.Context, limit int64, offset int64) ([]domain.User, error) {
 	ret := _m.Called(Ctx, limit, offset)
@@ -146,6 +222,101 @@ func (_m *UserRepository) GetByID(ctx context.Context, userID domain.ID) (domain
 	return r0, r1
 }
 
+// GetModerationHistory provides a mock function with given fields: ctx, userID
+func (_m *UserRepository) GetModerationHistory(ctx context.Context, userID domain.ID) ([]domain.ModerationAction, error) {
+	ret := _m.Called(ctx, userID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetModerationHistory")
+	}
+
+	var r0 []domain.ModerationAction
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) ([]domain.ModerationAction, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID) []domain.ModerationAction); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.ModerationAction)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchUsers provides a mock function with given fields: ctx, query
+func (_m *UserRepository) SearchUsers(ctx context.Context, query domain.UserQuery) ([]domain.User, string, error) {
+	ret := _m.Called(ctx, query)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SearchUsers")
+	}
+
+	var r0 []domain.User
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.UserQuery) ([]domain.User, string, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.UserQuery) []domain.User); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.UserQuery) string); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, domain.UserQuery) error); ok {
+		r2 = rf(ctx, query)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UnblockUser provides a mock function with given fields: ctx, action
+func (_m *UserRepository) UnblockUser(ctx context.Context, action domain.ModerationAction) (domain.User, error) {
+	ret := _m.Called(ctx, action)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UnblockUser")
+	}
+
+	var r0 domain.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ModerationAction) (domain.User, error)); ok {
+		return rf(ctx, action)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ModerationAction) domain.User); ok {
+		r0 = rf(ctx, action)
+	} else {
+		r0 = ret.Get(0).(domain.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ModerationAction) error); ok {
+		r1 = rf(ctx, action)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, user
 func (_m *UserRepository) Update(ctx context.Context, user domain.User) (domain.User, error) {
 	ret := _m.Called(ctx, user)
@@ -148,6 +148,64 @@ func (_m *WithdrawRepository) GetByShopID(ctx context.Context, shopID domain.ID)
 	return r0, r1
 }
 
+// GetByStatus provides a mock function with given fields: ctx, status, limit, cursor
+func (_m *WithdrawRepository) GetByStatus(ctx context.Context, status domain.WithdrawStatus, limit int64, cursor domain.ID) ([]domain.Withdraw, error) {
+	ret := _m.Called(ctx, status, limit, cursor)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetByStatus")
+	}
+
+	var r0 []domain.Withdraw
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.WithdrawStatus, int64, domain.ID) ([]domain.Withdraw, error)); ok {
+		return rf(ctx, status, limit, cursor)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.WithdrawStatus, int64, domain.ID) []domain.Withdraw); ok {
+		r0 = rf(ctx, status, limit, cursor)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]domain.Withdraw)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.WithdrawStatus, int64, domain.ID) error); ok {
+		r1 = rf(ctx, status, limit, cursor)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// TransitionWithdraw provides a mock function with given fields: ctx, withdrawID, status, reviewerID
+func (_m *WithdrawRepository) TransitionWithdraw(ctx context.Context, withdrawID domain.ID, status domain.WithdrawStatus, reviewerID domain.ID) (domain.Withdraw, error) {
+	ret := _m.Called(ctx, withdrawID, status, reviewerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TransitionWithdraw")
+	}
+
+	var r0 domain.Withdraw
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, domain.WithdrawStatus, domain.ID) (domain.Withdraw, error)); ok {
+		return rf(ctx, withdrawID, status, reviewerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, domain.ID, domain.WithdrawStatus, domain.ID) domain.Withdraw); ok {
+		r0 = rf(ctx, withdrawID, status, reviewerID)
+	} else {
+		r0 = ret.Get(0).(domain.Withdraw)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, domain.ID, domain.WithdrawStatus, domain.ID) error); ok {
+		r1 = rf(ctx, withdrawID, status, reviewerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: ctx, withdraw
 func (_m *WithdrawRepository) Update(ctx context.Context, withdraw domain.Withdraw) (domain.Withdraw, error) {
 	ret := _m.Called(ctx, withdraw)
@@ -4,21 +4,27 @@ import (
 	"context"
 	"github.com/EmirShimshir/marketplace-core/domain"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/guregu/null"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
+	"time"
 )
 
-type MongoCartRepo struct{
+// CartItemTTL is how long a cart item is kept after its last update before
+// the TTL index removes it.
+const CartItemTTL = 30 * 24 * time.Hour
+
+type MongoCartRepo struct {
 	db *mongo.Collection
 }
 
 func NewCartRepo(db *mongo.Database) *MongoCartRepo {
 	collection := db.Collection(CartProductCollection)
 	indexModel := mongo.IndexModel{
-		Keys:    bson.D{{"cart_id", 1}, {"product_id", 1}},
+		Keys:    bson.D{{"cart_id", 1}, {"product_id", 1}, {"variant_id", 1}},
 		Options: options.Index().SetUnique(true),
 	}
 
@@ -27,6 +33,29 @@ func NewCartRepo(db *mongo.Database) *MongoCartRepo {
 		log.Fatalf("unable to create cart product collection index, %v", err)
 	}
 
+	ttlIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{"expires_at", 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	_, err = collection.Indexes().CreateOne(context.Background(), ttlIndexModel)
+	if err != nil {
+		log.Fatalf("unable to create cart product collection ttl index, %v", err)
+	}
+
+	cartIndexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{"updated_at", 1}},
+		},
+		{
+			Keys:    bson.D{{"guest_token", 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+	}
+	_, err = db.Collection(CartCollection).Indexes().CreateMany(context.Background(), cartIndexModels)
+	if err != nil {
+		log.Fatalf("unable to create cart collection indexes, %v", err)
+	}
+
 	return &MongoCartRepo{
 		db: db.Collection(CartCollection),
 	}
@@ -41,7 +70,7 @@ func (c *MongoCartRepo) GetCartByID(ctx context.Context, cartID domain.ID) (doma
 		return domain.Cart{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 	}
 
-	cursor, err := c.db.Database().Collection(CartProductCollection).Find(ctx, bson.M{"cart_id":cartID})
+	cursor, err := c.db.Database().Collection(CartProductCollection).Find(ctx, bson.M{"cart_id": cartID})
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return domain.Cart{}, errors.Wrap(domain.ErrNotExist, err.Error())
@@ -66,9 +95,167 @@ func (c *MongoCartRepo) GetCartByID(ctx context.Context, cartID domain.ID) (doma
 	return cart, nil
 }
 
-func (c *MongoCartRepo) UpdateCart(ctx context.Context, cart domain.Cart) (domain.Cart, error) {
+func (c *MongoCartRepo) GetCartByGuestToken(ctx context.Context, token string) (domain.Cart, error) {
+	var mgCart entity.MgCart
+	err := c.db.FindOne(ctx, bson.M{"guest_token": null.StringFrom(token)}).Decode(&mgCart)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.Cart{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.Cart{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return c.GetCartByID(ctx, domain.ID(mgCart.ID))
+}
+
+func (c *MongoCartRepo) CreateGuestCart(ctx context.Context, cart domain.Cart) (domain.Cart, error) {
+	if !cart.GuestToken.Valid || cart.GuestToken.String == "" {
+		return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "guest cart requires a token")
+	}
+
+	now := time.Now().UTC()
 	var mgCart = entity.NewMgCart(cart)
-	_, err := c.db.ReplaceOne(ctx, bson.M{"_id": mgCart.ID}, mgCart)
+	mgCart.CreatedAt = now
+	mgCart.UpdatedAt = now
+	_, err := c.db.InsertOne(ctx, mgCart)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.Cart{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Cart{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return c.GetCartByID(ctx, cart.ID)
+}
+
+// MergeCarts moves the guest cart items into the user cart, summing the
+// quantities of items present in both, and deletes the guest cart. Carts
+// holding items in different currencies are not merged.
+func (c *MongoCartRepo) MergeCarts(ctx context.Context, guestCartID, userCartID domain.ID) (domain.Cart, error) {
+	if guestCartID == userCartID {
+		return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "cart can not be merged into itself")
+	}
+
+	session, err := c.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.Cart{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		var guestCart, userCart entity.MgCart
+		if err := c.db.FindOne(sessionContext, bson.M{"_id": guestCartID}).Decode(&guestCart); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if !guestCart.GuestToken.Valid {
+			return nil, errors.Wrap(domain.ErrNotAllowed, "source cart is not a guest cart")
+		}
+		if err := c.db.FindOne(sessionContext, bson.M{"_id": userCartID}).Decode(&userCart); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if userCart.GuestToken.Valid {
+			return nil, errors.Wrap(domain.ErrNotAllowed, "target cart is a guest cart")
+		}
+
+		items := c.db.Database().Collection(CartProductCollection)
+		if guestCart.Currency != userCart.Currency {
+			guestItems, err := items.CountDocuments(sessionContext, bson.M{"cart_id": guestCartID})
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			userItems, err := items.CountDocuments(sessionContext, bson.M{"cart_id": userCartID})
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if guestItems != 0 && userItems != 0 {
+				return nil, errors.Wrap(domain.ErrNotAllowed, "carts hold items in different currencies")
+			}
+			if guestItems != 0 {
+				_, err = c.db.UpdateOne(sessionContext, bson.M{"_id": userCartID}, bson.M{"$set": bson.M{"currency": guestCart.Currency}})
+				if err != nil {
+					return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+				}
+			}
+		}
+
+		cursor, err := items.Find(sessionContext, bson.M{"cart_id": guestCartID})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		var mgCartItems []entity.MgCartItem
+		if err = cursor.All(sessionContext, &mgCartItems); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+
+		now := time.Now().UTC()
+		expiresAt := now.Add(CartItemTTL)
+		for _, mgCartItem := range mgCartItems {
+			filter := bson.M{"cart_id": userCartID, "product_id": mgCartItem.ProductID, "variant_id": bson.M{"$exists": false}}
+			if mgCartItem.VariantID != "" {
+				filter["variant_id"] = mgCartItem.VariantID
+			}
+			res, err := items.UpdateOne(sessionContext, filter, bson.M{
+				"$inc": bson.M{"quantity": mgCartItem.Quantity},
+				"$set": bson.M{"updated_at": now, "expires_at": expiresAt},
+			})
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+			}
+			if res.MatchedCount != 0 {
+				continue
+			}
+			_, err = items.UpdateOne(sessionContext, bson.M{"_id": mgCartItem.ID}, bson.M{
+				"$set": bson.M{"cart_id": userCartID, "updated_at": now, "expires_at": expiresAt},
+			})
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+			}
+		}
+
+		if err = touchCart(sessionContext, c.db.Database(), userCartID, now); err != nil {
+			return nil, err
+		}
+		if _, err = items.DeleteMany(sessionContext, bson.M{"cart_id": guestCartID}); err != nil {
+			return nil, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+		}
+		if _, err = c.db.DeleteOne(sessionContext, bson.M{"_id": guestCartID}); err != nil {
+			return nil, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	return c.GetCartByID(ctx, userCartID)
+}
+
+// UpdateCart stores the price of the cart, which must be the total of the
+// cart items at the current product and variant prices. The currency of the
+// cart follows its items and can not be changed here.
+func (c *MongoCartRepo) UpdateCart(ctx context.Context, cart domain.Cart) (domain.Cart, error) {
+	current, err := c.GetCartByID(ctx, cart.ID)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+	if cart.Currency != "" && current.Currency != cart.Currency {
+		return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "cart currency does not match")
+	}
+	total, err := c.getCartTotal(ctx, current)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+	if cart.Price != total {
+		return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "cart price does not match its items")
+	}
+
+	_, err = c.db.UpdateOne(ctx, bson.M{"_id": cart.ID}, bson.M{"$set": bson.M{"price": cart.Price}})
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return domain.Cart{}, errors.Wrap(domain.ErrNotExist, err.Error())
@@ -79,6 +266,129 @@ func (c *MongoCartRepo) UpdateCart(ctx context.Context, cart domain.Cart) (domai
 	return c.GetCartByID(ctx, cart.ID)
 }
 
+func (c *MongoCartRepo) GetCartTotal(ctx context.Context, cartID domain.ID) (int64, error) {
+	cart, err := c.GetCartByID(ctx, cartID)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.getCartTotal(ctx, cart)
+}
+
+func (c *MongoCartRepo) getCartTotal(ctx context.Context, cart domain.Cart) (int64, error) {
+	var total int64
+	for _, item := range cart.Items {
+		price, _, err := getItemPrice(ctx, c.db.Database(), item.ProductID.String(), item.VariantID.String())
+		if err != nil {
+			return 0, err
+		}
+		total += price * item.Quantity
+	}
+	return total, nil
+}
+
+// RecalculateCart stores the price computed from the cart items and the
+// current product and variant prices.
+func (c *MongoCartRepo) RecalculateCart(ctx context.Context, cartID domain.ID) (domain.Cart, error) {
+	total, err := c.GetCartTotal(ctx, cartID)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+
+	_, err = c.db.UpdateOne(ctx, bson.M{"_id": cartID}, bson.M{"$set": bson.M{"price": total}})
+	if err != nil {
+		return domain.Cart{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+
+	return c.GetCartByID(ctx, cartID)
+}
+
+// abandonedCart is a cart read by FindAbandonedCarts together with its items
+// and the products and variants they refer to.
+type abandonedCart struct {
+	entity.MgCart `bson:",inline"`
+	Items         []entity.MgCartItem       `bson:"items"`
+	Products      []entity.MgProduct        `bson:"products"`
+	Variants      []entity.MgProductVariant `bson:"variants"`
+}
+
+// FindAbandonedCarts returns the non empty carts not updated since olderThan
+// whose items are worth at least minValue at the current prices, the carts
+// and their prices are read with a single aggregation.
+func (c *MongoCartRepo) FindAbandonedCarts(ctx context.Context, olderThan time.Time, minValue int64) ([]domain.Cart, error) {
+	cursor, err := c.db.Aggregate(ctx, mongo.Pipeline{
+		{{"$match", bson.M{"updated_at": bson.M{"$lt": olderThan}}}},
+		{{"$sort", bson.D{{"updated_at", 1}, {"_id", 1}}}},
+		{{"$lookup", bson.M{
+			"from":         CartProductCollection,
+			"localField":   "_id",
+			"foreignField": "cart_id",
+			"as":           "items",
+		}}},
+		{{"$match", bson.M{"items.0": bson.M{"$exists": true}}}},
+		{{"$lookup", bson.M{
+			"from":         ProductCollection,
+			"localField":   "items.product_id",
+			"foreignField": "_id",
+			"as":           "products",
+		}}},
+		{{"$lookup", bson.M{
+			"from":         ProductVariantCollection,
+			"localField":   "items.variant_id",
+			"foreignField": "_id",
+			"as":           "variants",
+		}}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgCarts []abandonedCart
+	if err = cursor.All(ctx, &mgCarts); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	carts := make([]domain.Cart, 0)
+	for _, mgCart := range mgCarts {
+		prices := make(map[string]int64, len(mgCart.Products)+len(mgCart.Variants))
+		for _, product := range mgCart.Products {
+			prices[product.ID] = product.Price
+		}
+		for _, variant := range mgCart.Variants {
+			if variant.Price.Valid {
+				prices[variant.ID] = variant.Price.Int64
+			}
+		}
+
+		cart := mgCart.ToDomain()
+		cart.Items = make([]domain.CartItem, len(mgCart.Items))
+		var total int64
+		for i, item := range mgCart.Items {
+			price, ok := prices[item.VariantID]
+			if item.VariantID == "" || !ok {
+				price = prices[item.ProductID]
+			}
+			total += price * item.Quantity
+			cart.Items[i] = item.ToDomain()
+		}
+		if total >= minValue {
+			carts = append(carts, cart)
+		}
+	}
+
+	return carts, nil
+}
+
+// PurgeStaleCartItems removes cart items not updated since olderThan. Items
+// written by the repository also expire on their own through the TTL index.
+func (c *MongoCartRepo) PurgeStaleCartItems(ctx context.Context, olderThan time.Time) (int64, error) {
+	res, err := c.db.Database().Collection(CartProductCollection).DeleteMany(ctx, bson.M{"updated_at": bson.M{"$lt": olderThan}})
+	if err != nil {
+		return 0, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return res.DeletedCount, nil
+}
+
 func (c *MongoCartRepo) ClearCart(ctx context.Context, cartID domain.ID) error {
 	_, err := c.db.Database().Collection(CartProductCollection).DeleteMany(ctx, bson.M{"cart_id": cartID})
 	if err != nil {
@@ -100,29 +410,124 @@ func (c *MongoCartRepo) GetCartItemByID(ctx context.Context, cartItemID domain.I
 }
 
 func (c *MongoCartRepo) CreateCartItem(ctx context.Context, cartItem domain.CartItem) (domain.CartItem, error) {
-	var mgCartItem = entity.NewMgCartItem(cartItem)
-	_, err := c.db.Database().Collection(CartProductCollection).InsertOne(ctx, mgCartItem)
+	session, err := c.db.Database().Client().StartSession()
 	if err != nil {
-		if mongo.IsDuplicateKeyError(err) {
-			return domain.CartItem{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		return domain.CartItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(CartItemTTL)
+	var mgCartItem = entity.NewMgCartItem(cartItem)
+	mgCartItem.CreatedAt = now
+	mgCartItem.UpdatedAt = now
+	mgCartItem.ExpiresAt = &expiresAt
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		if err := setCartCurrency(sessionContext, c.db.Database(), cartItem); err != nil {
+			return nil, err
 		}
-		return domain.CartItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		_, err := c.db.Database().Collection(CartProductCollection).InsertOne(sessionContext, mgCartItem)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errors.Wrap(domain.ErrDuplicate, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		return nil, touchCart(sessionContext, c.db.Database(), cartItem.CartID, now)
+	})
+	if err != nil {
+		return domain.CartItem{}, err
 	}
 
 	return c.GetCartItemByID(ctx, cartItem.ID)
 }
 
 func (c *MongoCartRepo) UpdateCartItem(ctx context.Context, cartItem domain.CartItem) (domain.CartItem, error) {
+	current, err := c.GetCartItemByID(ctx, cartItem.ID)
+	if err != nil {
+		return domain.CartItem{}, err
+	}
+
+	session, err := c.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.CartItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(CartItemTTL)
 	var mgCartItem = entity.NewMgCartItem(cartItem)
-	_, err := c.db.Database().Collection(CartProductCollection).ReplaceOne(ctx, bson.M{"_id": mgCartItem.ID}, mgCartItem)
+	mgCartItem.CreatedAt = current.CreatedAt
+	mgCartItem.UpdatedAt = now
+	mgCartItem.ExpiresAt = &expiresAt
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		if err := setCartCurrency(sessionContext, c.db.Database(), cartItem); err != nil {
+			return nil, err
+		}
+		_, err := c.db.Database().Collection(CartProductCollection).ReplaceOne(sessionContext, bson.M{"_id": mgCartItem.ID}, mgCartItem)
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		return nil, touchCart(sessionContext, c.db.Database(), cartItem.CartID, now)
+	})
 	if err != nil {
+		return domain.CartItem{}, err
+	}
+
+	return c.GetCartItemByID(ctx, cartItem.ID)
+}
+
+// setCartCurrency prices the cart in the currency of the product written by
+// the cart item. A cart holding other items in another currency is rejected
+// with ErrNotAllowed.
+func setCartCurrency(ctx context.Context, db *mongo.Database, cartItem domain.CartItem) error {
+	var mgCart entity.MgCart
+	if err := db.Collection(CartCollection).FindOne(ctx, bson.M{"_id": cartItem.CartID}).Decode(&mgCart); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return domain.CartItem{}, errors.Wrap(domain.ErrNotExist, err.Error())
+			return errors.Wrap(domain.ErrNotExist, err.Error())
 		}
-		return domain.CartItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var mgProduct entity.MgProduct
+	if err := db.Collection(ProductCollection).FindOne(ctx, bson.M{"_id": cartItem.ProductID}).Decode(&mgProduct); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if mgCart.Currency == mgProduct.Currency {
+		return nil
 	}
 
-	return c.GetCartItemByID(ctx, cartItem.ID)
+	others, err := db.Collection(CartProductCollection).CountDocuments(ctx,
+		bson.M{"cart_id": cartItem.CartID, "_id": bson.M{"$ne": cartItem.ID}})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if others != 0 {
+		return errors.Wrap(domain.ErrNotAllowed, "cart holds items in another currency")
+	}
+
+	_, err = db.Collection(CartCollection).UpdateOne(ctx, bson.M{"_id": cartItem.CartID},
+		bson.M{"$set": bson.M{"currency": mgProduct.Currency}})
+	if err != nil {
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	return nil
+}
+
+// touchCart marks the cart as updated at now.
+func touchCart(ctx context.Context, db *mongo.Database, cartID domain.ID, now time.Time) error {
+	res, err := db.Collection(CartCollection).UpdateOne(ctx, bson.M{"_id": cartID}, bson.M{"$set": bson.M{"updated_at": now}})
+	if err != nil {
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if res.MatchedCount == 0 {
+		return errors.Wrap(domain.ErrNotExist, "cart not found")
+	}
+	return nil
 }
 
 func (c *MongoCartRepo) DeleteCartItem(ctx context.Context, cartItemID domain.ID) error {
@@ -131,4 +536,4 @@ func (c *MongoCartRepo) DeleteCartItem(ctx context.Context, cartItemID domain.ID
 		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
 	}
 	return nil
-}
\ No newline at end of file
+}
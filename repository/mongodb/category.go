@@ -0,0 +1,210 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+)
+
+type MongoCategoryRepo struct {
+	db *mongo.Collection
+}
+
+func NewCategoryRepo(db *mongo.Database) *MongoCategoryRepo {
+	collection := db.Collection(CategoryCollection)
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"slug", 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{"parent_id", 1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(context.Background(), indexModels)
+	if err != nil {
+		log.Fatalf("unable to create category collection index, %v", err)
+	}
+
+	return &MongoCategoryRepo{
+		db: collection,
+	}
+}
+
+func (c *MongoCategoryRepo) GetByID(ctx context.Context, categoryID domain.ID) (domain.Category, error) {
+	result := c.db.FindOne(ctx, bson.M{"_id": categoryID})
+
+	var mgCategory entity.MgCategory
+	if err := result.Decode(&mgCategory); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.Category{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.Category{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgCategory.ToDomain(), nil
+}
+
+func (c *MongoCategoryRepo) GetBySlug(ctx context.Context, slug string) (domain.Category, error) {
+	result := c.db.FindOne(ctx, bson.M{"slug": slug})
+
+	var mgCategory entity.MgCategory
+	if err := result.Decode(&mgCategory); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.Category{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.Category{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgCategory.ToDomain(), nil
+}
+
+// GetChildren returns direct subcategories of the parent, an empty parentID
+// returns root categories.
+func (c *MongoCategoryRepo) GetChildren(ctx context.Context, parentID domain.ID) ([]domain.Category, error) {
+	filter := bson.M{"parent_id": parentID}
+	if parentID == "" {
+		filter = bson.M{"parent_id": bson.M{"$exists": false}}
+	}
+	cursor, err := c.db.Find(ctx, filter, options.Find().SetSort(bson.D{{"slug", 1}}))
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgCategories []entity.MgCategory
+	err = cursor.All(ctx, &mgCategories)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]domain.Category, len(mgCategories))
+	for i, category := range mgCategories {
+		categories[i] = category.ToDomain()
+	}
+	return categories, nil
+}
+
+// GetDescendants returns all subcategories of the category at any depth.
+func (c *MongoCategoryRepo) GetDescendants(ctx context.Context, categoryID domain.ID) ([]domain.Category, error) {
+	mgCategories, err := getCategoryDescendants(ctx, c.db.Database(), categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := make([]domain.Category, len(mgCategories))
+	for i, category := range mgCategories {
+		categories[i] = category.ToDomain()
+	}
+	return categories, nil
+}
+
+func (c *MongoCategoryRepo) Create(ctx context.Context, category domain.Category) (domain.Category, error) {
+	if category.ParentID != "" {
+		if _, err := c.GetByID(ctx, category.ParentID); err != nil {
+			return domain.Category{}, err
+		}
+	}
+
+	var mgCategory = entity.NewMgCategory(category)
+	_, err := c.db.InsertOne(ctx, mgCategory)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.Category{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Category{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return c.GetByID(ctx, category.ID)
+}
+
+// Update rejects moving a category under itself or one of its descendants.
+func (c *MongoCategoryRepo) Update(ctx context.Context, category domain.Category) (domain.Category, error) {
+	if category.ParentID != "" {
+		if category.ParentID == category.ID {
+			return domain.Category{}, errors.Wrap(domain.ErrNotAllowed, "category can not be its own parent")
+		}
+		descendants, err := getCategoryDescendants(ctx, c.db.Database(), category.ID)
+		if err != nil {
+			return domain.Category{}, err
+		}
+		for _, descendant := range descendants {
+			if descendant.ID == category.ParentID.String() {
+				return domain.Category{}, errors.Wrap(domain.ErrNotAllowed, "category parent is its descendant")
+			}
+		}
+	}
+
+	var mgCategory = entity.NewMgCategory(category)
+	_, err := c.db.ReplaceOne(ctx, bson.M{"_id": mgCategory.ID}, mgCategory)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.Category{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Category{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return c.GetByID(ctx, category.ID)
+}
+
+// Delete refuses to remove a category that still has subcategories or
+// products, mirroring the foreign keys of the relational schema.
+func (c *MongoCategoryRepo) Delete(ctx context.Context, categoryID domain.ID) error {
+	children, err := c.db.CountDocuments(ctx, bson.M{"parent_id": categoryID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	products, err := c.db.Database().Collection(ProductCollection).CountDocuments(ctx, bson.M{"category_id": categoryID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	if children != 0 || products != 0 {
+		return errors.Wrap(domain.ErrDeleteFailed, "category is in use")
+	}
+
+	_, err = c.db.DeleteOne(ctx, bson.M{"_id": categoryID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+func getCategoryDescendants(ctx context.Context, db *mongo.Database, categoryID domain.ID) ([]entity.MgCategory, error) {
+	cursor, err := db.Collection(CategoryCollection).Aggregate(ctx, mongo.Pipeline{
+		{{"$match", bson.M{"_id": categoryID}}},
+		{{"$graphLookup", bson.M{
+			"from":             CategoryCollection,
+			"startWith":        "$_id",
+			"connectFromField": "_id",
+			"connectToField":   "parent_id",
+			"as":               "descendants",
+		}}},
+		{{"$unwind", "$descendants"}},
+		{{"$replaceRoot", bson.M{"newRoot": "$descendants"}}},
+		{{"$sort", bson.D{{"slug", 1}}}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgCategories []entity.MgCategory
+	err = cursor.All(ctx, &mgCategories)
+	if err != nil {
+		return nil, err
+	}
+	return mgCategories, nil
+}
+
+func checkCategoryExists(ctx context.Context, db *mongo.Database, categoryID domain.ID) error {
+	count, err := db.Collection(CategoryCollection).CountDocuments(ctx, bson.M{"_id": categoryID})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if count == 0 {
+		return errors.Wrap(domain.ErrNotExist, "category does not exist")
+	}
+	return nil
+}
@@ -1,14 +1,30 @@
 package mongodb
 
 const (
-	UserCollection             = "user"
-	CartCollection             = "cart"
-	CartProductCollection      = "cart_product"
-	ProductCollection          = "product"
-	ShopCollection             = "shop"
-	ShopProductCollection      = "shop_product"
-	OrderCustomerCollection    = "order_customer"
-	OrderShopCollection        = "order_shop"
-	OrderShopProductCollection = "order_shop_product"
-	WithdrawCollection         = "withdraw"
+	UserCollection               = "user"
+	CartCollection               = "cart"
+	CartProductCollection        = "cart_product"
+	ProductCollection            = "product"
+	ShopCollection               = "shop"
+	ShopProductCollection        = "shop_product"
+	OrderCustomerCollection      = "order_customer"
+	OrderShopCollection          = "order_shop"
+	OrderShopProductCollection   = "order_shop_product"
+	WithdrawCollection           = "withdraw"
+	StockMovementCollection      = "stock_movement"
+	ReviewCollection             = "review"
+	CategoryCollection           = "category"
+	ProductImageCollection       = "product_image"
+	ProductVariantCollection     = "product_variant"
+	PromoCodeCollection          = "promo_code"
+	WishlistCollection           = "wishlist"
+	WishlistProductCollection    = "wishlist_product"
+	UserAddressCollection        = "user_address"
+	ShipmentCollection           = "shipment"
+	ShipmentEventCollection      = "shipment_event"
+	SessionCollection            = "user_session"
+	UserTokenCollection          = "user_token"
+	ModerationCollection         = "moderation_action"
+	PayoutBatchCollection        = "payout_batch"
+	ProductTranslationCollection = "product_translation"
 )
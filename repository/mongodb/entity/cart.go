@@ -2,40 +2,62 @@ package entity
 
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/guregu/null"
+	"time"
 )
 
 type MgCart struct {
-	ID    string `bson:"_id"`
-	Price int64     `bson:"price"`
+	ID         string      `bson:"_id"`
+	Price      int64       `bson:"price"`
+	Currency   string      `bson:"currency,omitempty"`
+	GuestToken null.String `bson:"guest_token,omitempty"`
+	CreatedAt  time.Time   `bson:"created_at"`
+	UpdatedAt  time.Time   `bson:"updated_at"`
 }
 
 func (c *MgCart) ToDomain() domain.Cart {
 	return domain.Cart{
-		ID:    domain.ID(c.ID),
-		Price: c.Price,
+		ID:         domain.ID(c.ID),
+		Price:      c.Price,
+		Currency:   c.Currency,
+		GuestToken: c.GuestToken,
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
 	}
 }
 
 func NewMgCart(cart domain.Cart) MgCart {
 	return MgCart{
-		ID: string(cart.ID),
-		Price: cart.Price,
+		ID:         string(cart.ID),
+		Price:      cart.Price,
+		Currency:   cart.Currency,
+		GuestToken: cart.GuestToken,
+		CreatedAt:  cart.CreatedAt,
+		UpdatedAt:  cart.UpdatedAt,
 	}
 }
 
 type MgCartItem struct {
-	ID        string `bson:"_id"`
-	CartID    string `bson:"cart_id"`
-	ProductID string `bson:"product_id"`
+	ID        string    `bson:"_id"`
+	CartID    string    `bson:"cart_id"`
+	ProductID string    `bson:"product_id"`
+	VariantID string    `bson:"variant_id,omitempty"`
 	Quantity  int64     `bson:"quantity"`
+	CreatedAt time.Time `bson:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
+	// ExpiresAt is set by the repository writes and drives the TTL index.
+	ExpiresAt *time.Time `bson:"expires_at,omitempty"`
 }
 
 func (ci *MgCartItem) ToDomain() domain.CartItem {
 	return domain.CartItem{
-		ID: domain.ID(ci.ID),
+		ID:        domain.ID(ci.ID),
 		CartID:    domain.ID(ci.CartID),
 		ProductID: domain.ID(ci.ProductID),
+		VariantID: domain.ID(ci.VariantID),
 		Quantity:  ci.Quantity,
+		CreatedAt: ci.CreatedAt,
+		UpdatedAt: ci.UpdatedAt,
 	}
 }
 
@@ -44,6 +66,9 @@ func NewMgCartItem(cartItem domain.CartItem) MgCartItem {
 		ID:        string(cartItem.ID),
 		CartID:    string(cartItem.CartID),
 		ProductID: string(cartItem.ProductID),
+		VariantID: string(cartItem.VariantID),
 		Quantity:  cartItem.Quantity,
+		CreatedAt: cartItem.CreatedAt,
+		UpdatedAt: cartItem.UpdatedAt,
 	}
 }
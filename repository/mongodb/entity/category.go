@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+)
+
+type MgCategory struct {
+	ID       string `bson:"_id"`
+	ParentID string `bson:"parent_id,omitempty"`
+	Slug     string `bson:"slug"`
+	Name     string `bson:"name"`
+}
+
+func (c *MgCategory) ToDomain() domain.Category {
+	return domain.Category{
+		ID:       domain.ID(c.ID),
+		ParentID: domain.ID(c.ParentID),
+		Slug:     c.Slug,
+		Name:     c.Name,
+	}
+}
+
+func NewMgCategory(category domain.Category) MgCategory {
+	return MgCategory{
+		ID:       category.ID.String(),
+		ParentID: category.ParentID.String(),
+		Slug:     category.Slug,
+		Name:     category.Name,
+	}
+}
@@ -0,0 +1,82 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"time"
+)
+
+const (
+	MgModerationTargetUser = "User"
+	MgModerationTargetShop = "Shop"
+)
+
+const (
+	MgModerationBlock   = "Block"
+	MgModerationUnblock = "Unblock"
+	MgModerationPending = "Pending"
+	MgModerationApprove = "Approve"
+	MgModerationSuspend = "Suspend"
+)
+
+type MgModerationAction struct {
+	ID          string    `bson:"_id"`
+	TargetType  string    `bson:"target_type"`
+	TargetID    string    `bson:"target_id"`
+	ModeratorID string    `bson:"moderator_id,omitempty"`
+	Action      string    `bson:"action"`
+	Reason      string    `bson:"reason"`
+	CreatedAt   time.Time `bson:"created_at"`
+}
+
+func (m *MgModerationAction) ToDomain() domain.ModerationAction {
+	var action domain.ModerationActionType
+	switch m.Action {
+	case MgModerationBlock:
+		action = domain.ModerationActionBlock
+	case MgModerationUnblock:
+		action = domain.ModerationActionUnblock
+	case MgModerationPending:
+		action = domain.ModerationActionPending
+	case MgModerationApprove:
+		action = domain.ModerationActionApprove
+	case MgModerationSuspend:
+		action = domain.ModerationActionSuspend
+	}
+
+	return domain.ModerationAction{
+		ID:          domain.ID(m.ID),
+		TargetID:    domain.ID(m.TargetID),
+		ModeratorID: domain.ID(m.ModeratorID),
+		Action:      action,
+		Reason:      m.Reason,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+func NewMgModerationActionType(action domain.ModerationActionType) string {
+	switch action {
+	case domain.ModerationActionBlock:
+		return MgModerationBlock
+	case domain.ModerationActionUnblock:
+		return MgModerationUnblock
+	case domain.ModerationActionPending:
+		return MgModerationPending
+	case domain.ModerationActionApprove:
+		return MgModerationApprove
+	case domain.ModerationActionSuspend:
+		return MgModerationSuspend
+	}
+	return ""
+}
+
+func NewMgModerationAction(targetType string, action domain.ModerationAction) MgModerationAction {
+	return MgModerationAction{
+		ID:          action.ID.String(),
+		TargetType:  targetType,
+		TargetID:    action.TargetID.String(),
+		ModeratorID: action.ModeratorID.String(),
+		Action:      NewMgModerationActionType(action.Action),
+		Reason:      action.Reason,
+		CreatedAt:   action.CreatedAt,
+	}
+}
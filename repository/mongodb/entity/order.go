@@ -2,38 +2,71 @@ package entity
 
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
 	"time"
 )
 
 type MgOrderCustomer struct {
-	ID         string    `bson:"_id"`
-	CustomerID string    `bson:"customer_id"`
-	Address    string    `bson:"address"`
-	CreatedAt  time.Time `bson:"created_at"`
-	TotalPrice int64     `bson:"total_price"`
-	Payed      bool      `bson:"payed"`
+	ID          string    `bson:"_id"`
+	CustomerID  string    `bson:"customer_id"`
+	Address     string    `bson:"address"`
+	CreatedAt   time.Time `bson:"created_at"`
+	TotalPrice  int64     `bson:"total_price"`
+	Currency    string    `bson:"currency"`
+	Payed       bool      `bson:"payed"`
+	PromoCodeID string    `bson:"promo_code_id,omitempty"`
+	Discount    int64     `bson:"discount"`
+	AddressID   string    `bson:"address_id,omitempty"`
+	// ShippingAddress is the snapshot of the address taken at order creation.
+	ShippingAddress *MgOrderAddress `bson:"shipping_address,omitempty"`
 }
 
-func (oc *MgOrderCustomer) ToDomain() domain.OrderCustomer {
-	return domain.OrderCustomer{
-		ID:         domain.ID(oc.ID),
-		CustomerID: domain.ID(oc.CustomerID),
-		Address:    oc.Address,
-		CreatedAt:  oc.CreatedAt,
-		TotalPrice: oc.TotalPrice,
-		Payed:      oc.Payed,
+func (oc *MgOrderCustomer) ToDomain() (domain.OrderCustomer, error) {
+	address, err := encryption.Decrypt(oc.Address)
+	if err != nil {
+		return domain.OrderCustomer{}, err
+	}
+	shippingAddress, err := oc.ShippingAddress.ToDomain()
+	if err != nil {
+		return domain.OrderCustomer{}, err
 	}
+	return domain.OrderCustomer{
+		ID:              domain.ID(oc.ID),
+		CustomerID:      domain.ID(oc.CustomerID),
+		Address:         address,
+		CreatedAt:       oc.CreatedAt,
+		TotalPrice:      oc.TotalPrice,
+		Currency:        oc.Currency,
+		Payed:           oc.Payed,
+		PromoCodeID:     domain.ID(oc.PromoCodeID),
+		Discount:        oc.Discount,
+		AddressID:       domain.ID(oc.AddressID),
+		ShippingAddress: shippingAddress,
+	}, nil
 }
 
-func NewMgOrderCustomer(orderCustomer domain.OrderCustomer) MgOrderCustomer {
-	return MgOrderCustomer{
-		ID:         orderCustomer.ID.String(),
-		CustomerID: orderCustomer.CustomerID.String(),
-		Address:    orderCustomer.Address,
-		CreatedAt:  orderCustomer.CreatedAt,
-		TotalPrice: orderCustomer.TotalPrice,
-		Payed:      orderCustomer.Payed,
+func NewMgOrderCustomer(orderCustomer domain.OrderCustomer) (MgOrderCustomer, error) {
+	address, err := encryption.Encrypt(orderCustomer.Address)
+	if err != nil {
+		return MgOrderCustomer{}, err
+	}
+	shippingAddress, err := NewMgOrderAddress(orderCustomer.ShippingAddress)
+	if err != nil {
+		return MgOrderCustomer{}, err
 	}
+	return MgOrderCustomer{
+		ID:              orderCustomer.ID.String(),
+		CustomerID:      orderCustomer.CustomerID.String(),
+		Address:         address,
+		CreatedAt:       orderCustomer.CreatedAt,
+		TotalPrice:      orderCustomer.TotalPrice,
+		Currency:        orderCustomer.Currency,
+		Payed:           orderCustomer.Payed,
+		PromoCodeID:     orderCustomer.PromoCodeID.String(),
+		Discount:        orderCustomer.Discount,
+		AddressID:       orderCustomer.AddressID.String(),
+		ShippingAddress: shippingAddress,
+	}, nil
 }
 
 const (
@@ -94,6 +127,7 @@ type MgOrderShopItem struct {
 	ID          string `bson:"_id"`
 	OrderShopID string `bson:"order_shop_id"`
 	ProductID   string `bson:"product_id"`
+	VariantID   string `bson:"variant_id,omitempty"`
 	Quantity    int64  `bson:"quantity"`
 }
 
@@ -102,6 +136,7 @@ func (osi *MgOrderShopItem) ToDomain() domain.OrderShopItem {
 		ID:          domain.ID(osi.ID),
 		OrderShopID: domain.ID(osi.OrderShopID),
 		ProductID:   domain.ID(osi.ProductID),
+		VariantID:   domain.ID(osi.VariantID),
 		Quantity:    osi.Quantity,
 	}
 }
@@ -111,6 +146,7 @@ func NewMgOrderShopItem(orderShopItem domain.OrderShopItem) MgOrderShopItem {
 		ID:          orderShopItem.ID.String(),
 		OrderShopID: orderShopItem.OrderShopID.String(),
 		ProductID:   orderShopItem.ProductID.String(),
+		VariantID:   orderShopItem.VariantID.String(),
 		Quantity:    orderShopItem.Quantity,
 	}
 }
@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/guregu/null"
+	"time"
+)
+
+const (
+	MgPayoutBatchOpen    = "Open"
+	MgPayoutBatchSettled = "Settled"
+)
+
+type MgPayoutBatch struct {
+	ID        string     `bson:"_id"`
+	Total     int64      `bson:"total"`
+	Currency  string     `bson:"currency"`
+	Status    string     `bson:"status"`
+	CreatedAt time.Time  `bson:"created_at"`
+	SettledAt *time.Time `bson:"settled_at,omitempty"`
+}
+
+func (b *MgPayoutBatch) ToDomain() domain.PayoutBatch {
+	var status domain.PayoutBatchStatus
+	switch b.Status {
+	case MgPayoutBatchOpen:
+		status = domain.PayoutBatchOpen
+	case MgPayoutBatchSettled:
+		status = domain.PayoutBatchSettled
+	}
+
+	return domain.PayoutBatch{
+		ID:        domain.ID(b.ID),
+		Total:     b.Total,
+		Currency:  b.Currency,
+		Status:    status,
+		CreatedAt: b.CreatedAt,
+		SettledAt: null.TimeFromPtr(b.SettledAt),
+	}
+}
+
+func NewMgPayoutBatch(batch domain.PayoutBatch) MgPayoutBatch {
+	var status string
+	switch batch.Status {
+	case domain.PayoutBatchOpen:
+		status = MgPayoutBatchOpen
+	case domain.PayoutBatchSettled:
+		status = MgPayoutBatchSettled
+	}
+
+	return MgPayoutBatch{
+		ID:        batch.ID.String(),
+		Total:     batch.Total,
+		Currency:  batch.Currency,
+		Status:    status,
+		CreatedAt: batch.CreatedAt,
+		SettledAt: batch.SettledAt.Ptr(),
+	}
+}
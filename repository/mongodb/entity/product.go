@@ -2,74 +2,66 @@ package entity
 
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
-)
-
-const (
-	MgProductElectronic = "Electronic"
-	MgProductFashion    = "Fashion"
-	MgProductHome       = "Home"
-	MgProductHealth     = "Health"
-	MgProductSport      = "Sport"
-	MgProductBooks      = "Books"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 type MgProduct struct {
 	ID          string `bson:"_id"`
-	Name        string    `bson:"name"`
-	Description string    `bson:"description"`
-	Price       int64     `bson:"price"`
-	Category    string    `bson:"category"`
-	PhotoUrl    string    `bson:"photo_url"`
+	Name        string `bson:"name"`
+	Description string `bson:"description"`
+	Price       int64  `bson:"price"`
+	Currency    string `bson:"currency"`
+	CategoryID  string `bson:"category_id"`
+	PhotoUrl    string `bson:"photo_url"`
+	Attributes  bson.M `bson:"attributes,omitempty"`
 }
 
 func (u *MgProduct) ToDomain() domain.Product {
-	var productCategory domain.ProductCategory
-	switch u.Category {
-	case MgProductElectronic:
-		productCategory = domain.ElectronicCategory
-	case MgProductFashion:
-		productCategory = domain.FashionCategory
-	case MgProductHome:
-		productCategory = domain.HomeCategory
-	case MgProductHealth:
-		productCategory = domain.HealthCategory
-	case MgProductSport:
-		productCategory = domain.SportCategory
-	case MgProductBooks:
-		productCategory = domain.BooksCategory
-	}
 	return domain.Product{
 		ID:          domain.ID(u.ID),
 		Name:        u.Name,
 		Description: u.Description,
 		Price:       u.Price,
-		Category:    productCategory,
+		Currency:    u.Currency,
+		CategoryID:  domain.ID(u.CategoryID),
 		PhotoUrl:    u.PhotoUrl,
+		Attributes:  u.Attributes,
 	}
 }
 
 func NewMgProduct(product domain.Product) MgProduct {
-	var productCategory string
-	switch product.Category {
-	case domain.ElectronicCategory:
-		productCategory = MgProductElectronic
-	case domain.FashionCategory:
-		productCategory = MgProductFashion
-	case domain.HomeCategory:
-		productCategory = MgProductHome
-	case domain.HealthCategory:
-		productCategory = MgProductHealth
-	case domain.SportCategory:
-		productCategory = MgProductSport
-	case domain.BooksCategory:
-		productCategory = MgProductBooks
-	}
 	return MgProduct{
 		ID:          product.ID.String(),
 		Name:        product.Name,
 		Description: product.Description,
 		Price:       product.Price,
-		Category:    productCategory,
+		Currency:    product.Currency,
+		CategoryID:  product.CategoryID.String(),
 		PhotoUrl:    product.PhotoUrl,
+		Attributes:  newMgAttributes(product.Attributes),
+	}
+}
+
+// newMgAttributes stores every numeric attribute as a double so that values
+// read back have the same type regardless of how they were written.
+func newMgAttributes(attributes map[string]interface{}) bson.M {
+	if len(attributes) == 0 {
+		return nil
+	}
+	mgAttributes := make(bson.M, len(attributes))
+	for key, value := range attributes {
+		switch v := value.(type) {
+		case int:
+			mgAttributes[key] = float64(v)
+		case int32:
+			mgAttributes[key] = float64(v)
+		case int64:
+			mgAttributes[key] = float64(v)
+		case float32:
+			mgAttributes[key] = float64(v)
+		default:
+			mgAttributes[key] = v
+		}
 	}
+	return mgAttributes
 }
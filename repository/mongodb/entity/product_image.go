@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+)
+
+type MgProductImage struct {
+	ID        string `bson:"_id"`
+	ProductID string `bson:"product_id"`
+	Url       string `bson:"url"`
+	Position  int64  `bson:"position"`
+	AltText   string `bson:"alt_text"`
+	IsPrimary bool   `bson:"is_primary"`
+}
+
+func (i *MgProductImage) ToDomain() domain.ProductImage {
+	return domain.ProductImage{
+		ID:        domain.ID(i.ID),
+		ProductID: domain.ID(i.ProductID),
+		Url:       i.Url,
+		Position:  i.Position,
+		AltText:   i.AltText,
+		IsPrimary: i.IsPrimary,
+	}
+}
+
+func NewMgProductImage(image domain.ProductImage) MgProductImage {
+	return MgProductImage{
+		ID:        image.ID.String(),
+		ProductID: image.ProductID.String(),
+		Url:       image.Url,
+		Position:  image.Position,
+		AltText:   image.AltText,
+		IsPrimary: image.IsPrimary,
+	}
+}
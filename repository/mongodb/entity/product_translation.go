@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+)
+
+// MgProductTranslation is stored with the text search language of its
+// locale, which the text index reads to pick the stemmer.
+type MgProductTranslation struct {
+	ProductID   string `bson:"product_id"`
+	Locale      string `bson:"locale"`
+	Name        string `bson:"name"`
+	Description string `bson:"description"`
+	Language    string `bson:"language"`
+}
+
+func (t *MgProductTranslation) ToDomain() domain.ProductTranslation {
+	return domain.ProductTranslation{
+		ProductID:   domain.ID(t.ProductID),
+		Locale:      t.Locale,
+		Name:        t.Name,
+		Description: t.Description,
+	}
+}
+
+func NewMgProductTranslation(translation domain.ProductTranslation, language string) MgProductTranslation {
+	return MgProductTranslation{
+		ProductID:   translation.ProductID.String(),
+		Locale:      translation.Locale,
+		Name:        translation.Name,
+		Description: translation.Description,
+		Language:    language,
+	}
+}
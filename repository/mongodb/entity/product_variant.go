@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/guregu/null"
+)
+
+type MgProductVariant struct {
+	ID         string            `bson:"_id"`
+	ShopItemID string            `bson:"shop_product_id"`
+	SKU        string            `bson:"sku"`
+	Attributes map[string]string `bson:"attributes"`
+	Price      null.Int          `bson:"price,omitempty"`
+	Quantity   int64             `bson:"quantity"`
+}
+
+func (v *MgProductVariant) ToDomain() domain.ProductVariant {
+	return domain.ProductVariant{
+		ID:         domain.ID(v.ID),
+		ShopItemID: domain.ID(v.ShopItemID),
+		SKU:        v.SKU,
+		Attributes: v.Attributes,
+		Price:      v.Price,
+		Quantity:   v.Quantity,
+	}
+}
+
+func NewMgProductVariant(variant domain.ProductVariant) MgProductVariant {
+	return MgProductVariant{
+		ID:         variant.ID.String(),
+		ShopItemID: variant.ShopItemID.String(),
+		SKU:        variant.SKU,
+		Attributes: variant.Attributes,
+		Price:      variant.Price,
+		Quantity:   variant.Quantity,
+	}
+}
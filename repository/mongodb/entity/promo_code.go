@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"time"
+)
+
+const (
+	MgPromoCodePercentage = "Percentage"
+	MgPromoCodeFixed      = "Fixed"
+)
+
+type MgPromoCode struct {
+	ID             string    `bson:"_id"`
+	Code           string    `bson:"code"`
+	Type           string    `bson:"type"`
+	Value          int64     `bson:"value"`
+	Currency       string    `bson:"currency,omitempty"`
+	ShopID         string    `bson:"shop_id,omitempty"`
+	ValidFrom      time.Time `bson:"valid_from"`
+	ValidTo        time.Time `bson:"valid_to"`
+	MaxUses        int64     `bson:"max_uses"`
+	MaxUsesPerUser int64     `bson:"max_uses_per_user"`
+	UsedCount      int64     `bson:"used_count"`
+}
+
+func (p *MgPromoCode) ToDomain() domain.PromoCode {
+	var promoCodeType domain.PromoCodeType
+	switch p.Type {
+	case MgPromoCodePercentage:
+		promoCodeType = domain.PromoCodePercentage
+	case MgPromoCodeFixed:
+		promoCodeType = domain.PromoCodeFixed
+	}
+
+	return domain.PromoCode{
+		ID:             domain.ID(p.ID),
+		Code:           p.Code,
+		Type:           promoCodeType,
+		Value:          p.Value,
+		Currency:       p.Currency,
+		ShopID:         domain.ID(p.ShopID),
+		ValidFrom:      p.ValidFrom,
+		ValidTo:        p.ValidTo,
+		MaxUses:        p.MaxUses,
+		MaxUsesPerUser: p.MaxUsesPerUser,
+		UsedCount:      p.UsedCount,
+	}
+}
+
+func NewMgPromoCode(promoCode domain.PromoCode) MgPromoCode {
+	var promoCodeType string
+	switch promoCode.Type {
+	case domain.PromoCodePercentage:
+		promoCodeType = MgPromoCodePercentage
+	case domain.PromoCodeFixed:
+		promoCodeType = MgPromoCodeFixed
+	}
+
+	return MgPromoCode{
+		ID:             promoCode.ID.String(),
+		Code:           promoCode.Code,
+		Type:           promoCodeType,
+		Value:          promoCode.Value,
+		Currency:       promoCode.Currency,
+		ShopID:         promoCode.ShopID.String(),
+		ValidFrom:      promoCode.ValidFrom,
+		ValidTo:        promoCode.ValidTo,
+		MaxUses:        promoCode.MaxUses,
+		MaxUsesPerUser: promoCode.MaxUsesPerUser,
+		UsedCount:      promoCode.UsedCount,
+	}
+}
@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"time"
+)
+
+type MgReview struct {
+	ID        string    `bson:"_id"`
+	ProductID string    `bson:"product_id"`
+	UserID    string    `bson:"user_id"`
+	Rating    int64     `bson:"rating"`
+	Text      string    `bson:"text"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+func (r *MgReview) ToDomain() domain.Review {
+	return domain.Review{
+		ID:        domain.ID(r.ID),
+		ProductID: domain.ID(r.ProductID),
+		UserID:    domain.ID(r.UserID),
+		Rating:    r.Rating,
+		Text:      r.Text,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func NewMgReview(review domain.Review) MgReview {
+	return MgReview{
+		ID:        review.ID.String(),
+		ProductID: review.ProductID.String(),
+		UserID:    review.UserID.String(),
+		Rating:    review.Rating,
+		Text:      review.Text,
+		CreatedAt: review.CreatedAt,
+	}
+}
+
+type MgProductRating struct {
+	ProductID   string  `bson:"_id"`
+	Rating      float64 `bson:"rating"`
+	ReviewCount int64   `bson:"review_count"`
+}
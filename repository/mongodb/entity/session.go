@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/guregu/null"
+	"time"
+)
+
+type MgSession struct {
+	ID         string     `bson:"_id"`
+	UserID     string     `bson:"user_id"`
+	TokenHash  string     `bson:"token_hash"`
+	DeviceInfo string     `bson:"device_info"`
+	IP         string     `bson:"ip"`
+	CreatedAt  time.Time  `bson:"created_at"`
+	ExpiresAt  time.Time  `bson:"expires_at"`
+	RevokedAt  *time.Time `bson:"revoked_at,omitempty"`
+}
+
+func (s *MgSession) ToDomain() domain.Session {
+	return domain.Session{
+		ID:         domain.ID(s.ID),
+		UserID:     domain.ID(s.UserID),
+		TokenHash:  s.TokenHash,
+		DeviceInfo: s.DeviceInfo,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt,
+		ExpiresAt:  s.ExpiresAt,
+		RevokedAt:  null.TimeFromPtr(s.RevokedAt),
+	}
+}
+
+func NewMgSession(session domain.Session) MgSession {
+	return MgSession{
+		ID:         session.ID.String(),
+		UserID:     session.UserID.String(),
+		TokenHash:  session.TokenHash,
+		DeviceInfo: session.DeviceInfo,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt,
+		ExpiresAt:  session.ExpiresAt,
+		RevokedAt:  session.RevokedAt.Ptr(),
+	}
+}
@@ -0,0 +1,103 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/guregu/null"
+	"time"
+)
+
+type MgShipment struct {
+	ID             string     `bson:"_id"`
+	OrderShopID    string     `bson:"order_shop_id"`
+	Carrier        string     `bson:"carrier"`
+	TrackingNumber string     `bson:"tracking_number"`
+	ShippedAt      *time.Time `bson:"shipped_at,omitempty"`
+	DeliveredAt    *time.Time `bson:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `bson:"created_at"`
+}
+
+func (s *MgShipment) ToDomain() domain.Shipment {
+	return domain.Shipment{
+		ID:             domain.ID(s.ID),
+		OrderShopID:    domain.ID(s.OrderShopID),
+		Carrier:        s.Carrier,
+		TrackingNumber: s.TrackingNumber,
+		ShippedAt:      null.TimeFromPtr(s.ShippedAt),
+		DeliveredAt:    null.TimeFromPtr(s.DeliveredAt),
+		CreatedAt:      s.CreatedAt,
+	}
+}
+
+func NewMgShipment(shipment domain.Shipment) MgShipment {
+	return MgShipment{
+		ID:             shipment.ID.String(),
+		OrderShopID:    shipment.OrderShopID.String(),
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		ShippedAt:      shipment.ShippedAt.Ptr(),
+		DeliveredAt:    shipment.DeliveredAt.Ptr(),
+		CreatedAt:      shipment.CreatedAt,
+	}
+}
+
+const (
+	MgShipmentEventShipped   = "Shipped"
+	MgShipmentEventInTransit = "InTransit"
+	MgShipmentEventDelivered = "Delivered"
+	MgShipmentEventFailed    = "Failed"
+)
+
+type MgShipmentEvent struct {
+	ID          string    `bson:"_id"`
+	ShipmentID  string    `bson:"shipment_id"`
+	Type        string    `bson:"type"`
+	Location    string    `bson:"location"`
+	Description string    `bson:"description"`
+	OccurredAt  time.Time `bson:"occurred_at"`
+}
+
+func (e *MgShipmentEvent) ToDomain() domain.ShipmentEvent {
+	var eventType domain.ShipmentEventType
+	switch e.Type {
+	case MgShipmentEventShipped:
+		eventType = domain.ShipmentEventShipped
+	case MgShipmentEventInTransit:
+		eventType = domain.ShipmentEventInTransit
+	case MgShipmentEventDelivered:
+		eventType = domain.ShipmentEventDelivered
+	case MgShipmentEventFailed:
+		eventType = domain.ShipmentEventFailed
+	}
+
+	return domain.ShipmentEvent{
+		ID:          domain.ID(e.ID),
+		ShipmentID:  domain.ID(e.ShipmentID),
+		Type:        eventType,
+		Location:    e.Location,
+		Description: e.Description,
+		OccurredAt:  e.OccurredAt,
+	}
+}
+
+func NewMgShipmentEvent(event domain.ShipmentEvent) MgShipmentEvent {
+	var eventType string
+	switch event.Type {
+	case domain.ShipmentEventShipped:
+		eventType = MgShipmentEventShipped
+	case domain.ShipmentEventInTransit:
+		eventType = MgShipmentEventInTransit
+	case domain.ShipmentEventDelivered:
+		eventType = MgShipmentEventDelivered
+	case domain.ShipmentEventFailed:
+		eventType = MgShipmentEventFailed
+	}
+
+	return MgShipmentEvent{
+		ID:          event.ID.String(),
+		ShipmentID:  event.ShipmentID.String(),
+		Type:        eventType,
+		Location:    event.Location,
+		Description: event.Description,
+		OccurredAt:  event.OccurredAt,
+	}
+}
@@ -2,60 +2,104 @@ package entity
 
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+)
+
+const (
+	MgShopApproved  = "Approved"
+	MgShopPending   = "Pending"
+	MgShopSuspended = "Suspended"
 )
 
 type MgShop struct {
-	ID          string `bson:"_id"`
-	SellerID    string `bson:"seller_id"`
-	Name        string    `bson:"name"`
-	Description string    `bson:"description"`
-	Requisites  string    `bson:"requisites"`
-	Email       string    `bson:"email"`
+	ID               string `bson:"_id"`
+	SellerID         string `bson:"seller_id"`
+	Name             string `bson:"name"`
+	Description      string `bson:"description"`
+	Requisites       string `bson:"requisites"`
+	Email            string `bson:"email"`
+	ModerationStatus string `bson:"moderation_status"`
+	DefaultCurrency  string `bson:"default_currency"`
 }
 
-func (s *MgShop) ToDomain() domain.Shop {
-	return domain.Shop{
-		ID:          domain.ID(s.ID),
-		SellerID:    domain.ID(s.SellerID),
-		Name:        s.Name,
-		Description: s.Description,
-		Requisites:  s.Requisites,
-		Email:       s.Email,
+func (s *MgShop) ToDomain() (domain.Shop, error) {
+	requisites, err := encryption.Decrypt(s.Requisites)
+	if err != nil {
+		return domain.Shop{}, err
+	}
+	var moderationStatus domain.ShopModerationStatus
+	switch s.ModerationStatus {
+	case MgShopPending:
+		moderationStatus = domain.ShopModerationPending
+	case MgShopSuspended:
+		moderationStatus = domain.ShopModerationSuspended
+	default:
+		moderationStatus = domain.ShopModerationApproved
 	}
+	return domain.Shop{
+		ID:               domain.ID(s.ID),
+		SellerID:         domain.ID(s.SellerID),
+		Name:             s.Name,
+		Description:      s.Description,
+		Requisites:       requisites,
+		Email:            s.Email,
+		ModerationStatus: moderationStatus,
+		DefaultCurrency:  s.DefaultCurrency,
+	}, nil
 }
 
-func NewMgShop(shop domain.Shop) MgShop {
+func NewMgShop(shop domain.Shop) (MgShop, error) {
+	requisites, err := encryption.Encrypt(shop.Requisites)
+	if err != nil {
+		return MgShop{}, err
+	}
 	return MgShop{
-		ID:          shop.ID.String(),
-		SellerID:    shop.SellerID.String(),
-		Name:        shop.Name,
-		Description: shop.Description,
-		Requisites:  shop.Requisites,
-		Email:       shop.Email,
+		ID:               shop.ID.String(),
+		SellerID:         shop.SellerID.String(),
+		Name:             shop.Name,
+		Description:      shop.Description,
+		Requisites:       requisites,
+		Email:            shop.Email,
+		ModerationStatus: NewMgShopModerationStatus(shop.ModerationStatus),
+		DefaultCurrency:  shop.DefaultCurrency,
+	}, nil
+}
+
+func NewMgShopModerationStatus(status domain.ShopModerationStatus) string {
+	switch status {
+	case domain.ShopModerationPending:
+		return MgShopPending
+	case domain.ShopModerationSuspended:
+		return MgShopSuspended
+	default:
+		return MgShopApproved
 	}
 }
 
 type MgShopItem struct {
-	ID        string `bson:"_id"`
-	ShopID    string `bson:"shop_id"`
-	ProductID string `bson:"product_id"`
-	Quantity  int64     `bson:"quantity"`
+	ID               string `bson:"_id"`
+	ShopID           string `bson:"shop_id"`
+	ProductID        string `bson:"product_id"`
+	Quantity         int64  `bson:"quantity"`
+	ReorderThreshold int64  `bson:"reorder_threshold"`
 }
 
 func (si *MgShopItem) ToDomain() domain.ShopItem {
 	return domain.ShopItem{
-		ID:        domain.ID(si.ID),
-		ShopID:    domain.ID(si.ShopID),
-		ProductID: domain.ID(si.ProductID),
-		Quantity:  si.Quantity,
+		ID:               domain.ID(si.ID),
+		ShopID:           domain.ID(si.ShopID),
+		ProductID:        domain.ID(si.ProductID),
+		Quantity:         si.Quantity,
+		ReorderThreshold: si.ReorderThreshold,
 	}
 }
 
 func NewMgShopItem(shopItem domain.ShopItem) MgShopItem {
 	return MgShopItem{
-		ID:        shopItem.ID.String(),
-		ShopID:    shopItem.ShopID.String(),
-		ProductID: shopItem.ProductID.String(),
-		Quantity:  shopItem.Quantity,
+		ID:               shopItem.ID.String(),
+		ShopID:           shopItem.ShopID.String(),
+		ProductID:        shopItem.ProductID.String(),
+		Quantity:         shopItem.Quantity,
+		ReorderThreshold: shopItem.ReorderThreshold,
 	}
 }
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"time"
+)
+
+const (
+	MgStockMovementRestock    = "Restock"
+	MgStockMovementSale       = "Sale"
+	MgStockMovementCancel     = "Cancel"
+	MgStockMovementAdjustment = "Adjustment"
+)
+
+type MgStockMovement struct {
+	ID          string    `bson:"_id"`
+	ShopItemID  string    `bson:"shop_product_id"`
+	VariantID   string    `bson:"variant_id,omitempty"`
+	Delta       int64     `bson:"delta"`
+	Reason      string    `bson:"reason"`
+	ReferenceID string    `bson:"reference_id,omitempty"`
+	CreatedAt   time.Time `bson:"created_at"`
+}
+
+func (sm *MgStockMovement) ToDomain() domain.StockMovement {
+	var reason domain.StockMovementReason
+	switch sm.Reason {
+	case MgStockMovementRestock:
+		reason = domain.StockMovementRestock
+	case MgStockMovementSale:
+		reason = domain.StockMovementSale
+	case MgStockMovementCancel:
+		reason = domain.StockMovementCancel
+	case MgStockMovementAdjustment:
+		reason = domain.StockMovementAdjustment
+	}
+
+	return domain.StockMovement{
+		ID:          domain.ID(sm.ID),
+		ShopItemID:  domain.ID(sm.ShopItemID),
+		VariantID:   domain.ID(sm.VariantID),
+		Delta:       sm.Delta,
+		Reason:      reason,
+		ReferenceID: domain.ID(sm.ReferenceID),
+		CreatedAt:   sm.CreatedAt,
+	}
+}
+
+func NewMgStockMovement(movement domain.StockMovement) MgStockMovement {
+	var reason string
+	switch movement.Reason {
+	case domain.StockMovementRestock:
+		reason = MgStockMovementRestock
+	case domain.StockMovementSale:
+		reason = MgStockMovementSale
+	case domain.StockMovementCancel:
+		reason = MgStockMovementCancel
+	case domain.StockMovementAdjustment:
+		reason = MgStockMovementAdjustment
+	}
+
+	return MgStockMovement{
+		ID:          movement.ID.String(),
+		ShopItemID:  movement.ShopItemID.String(),
+		VariantID:   movement.VariantID.String(),
+		Delta:       movement.Delta,
+		Reason:      reason,
+		ReferenceID: movement.ReferenceID.String(),
+		CreatedAt:   movement.CreatedAt,
+	}
+}
@@ -2,8 +2,11 @@ package entity
 
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
 	"github.com/google/uuid"
 	"github.com/guregu/null"
+	"strings"
+	"time"
 )
 
 const (
@@ -13,17 +16,34 @@ const (
 )
 
 type MgUser struct {
-	ID       string   `bson:"_id"`
-	CartID   string   `bson:"cart_id"`
-	Name     string      `bson:"name"`
-	Surname  string      `bson:"surname"`
-	Phone    null.String `bson:"phone,omitempty"`
-	Email    string      `bson:"email"`
-	Password string      `bson:"password"`
-	Role     string      `bson:"role"`
+	ID      string `bson:"_id"`
+	CartID  string `bson:"cart_id"`
+	Name    string `bson:"name"`
+	Surname string `bson:"surname"`
+	// NameLower and SurnameLower back the case-insensitive prefix search,
+	// a case-insensitive regex can not use an index.
+	NameLower       string      `bson:"name_lower"`
+	SurnameLower    string      `bson:"surname_lower"`
+	Phone           null.String `bson:"phone,omitempty"`
+	PhoneHash       string      `bson:"phone_hash,omitempty"`
+	Email           string      `bson:"email"`
+	EmailHash       string      `bson:"email_hash"`
+	Password        string      `bson:"password"`
+	Role            string      `bson:"role"`
+	EmailVerifiedAt *time.Time  `bson:"email_verified_at,omitempty"`
+	BlockedAt       *time.Time  `bson:"blocked_at,omitempty"`
+	BlockedReason   string      `bson:"blocked_reason,omitempty"`
 }
 
-func (u *MgUser) ToDomain() domain.User {
+func (u *MgUser) ToDomain() (domain.User, error) {
+	phone, err := encryption.DecryptNull(u.Phone)
+	if err != nil {
+		return domain.User{}, err
+	}
+	email, err := encryption.Decrypt(u.Email)
+	if err != nil {
+		return domain.User{}, err
+	}
 	var userRole domain.UserRole
 	switch u.Role {
 	case MgUserCustomer:
@@ -34,37 +54,61 @@ func (u *MgUser) ToDomain() domain.User {
 		userRole = domain.UserModerator
 	}
 	return domain.User{
-		ID:       domain.ID(u.ID),
-		CartID:   domain.ID(u.CartID),
-		Name:     u.Name,
-		Surname:  u.Surname,
-		Phone:    u.Phone,
-		Email:    u.Email,
-		Password: u.Password,
-		Role:     userRole,
-	}
+		ID:              domain.ID(u.ID),
+		CartID:          domain.ID(u.CartID),
+		Name:            u.Name,
+		Surname:         u.Surname,
+		Phone:           phone,
+		Email:           email,
+		Password:        u.Password,
+		Role:            userRole,
+		EmailVerifiedAt: null.TimeFromPtr(u.EmailVerifiedAt),
+		BlockedAt:       null.TimeFromPtr(u.BlockedAt),
+		BlockedReason:   u.BlockedReason,
+	}, nil
 }
 
-func NewMgUser(user domain.User) MgUser {
+func NewMgUser(user domain.User) (MgUser, error) {
 	id, _ := uuid.Parse(user.ID.String())
 	cartID, _ := uuid.Parse(user.CartID.String())
-	var userRole string
-	switch user.Role {
-	case domain.UserCustomer:
-		userRole = MgUserCustomer
-	case domain.UserSeller:
-		userRole = MgUserSeller
-	case domain.UserModerator:
-		userRole = MgUserModerator
+	var phoneHash string
+	if user.Phone.Valid {
+		phoneHash = encryption.BlindIndex(user.Phone.String)
+	}
+	phone, err := encryption.EncryptNull(user.Phone)
+	if err != nil {
+		return MgUser{}, err
+	}
+	email, err := encryption.Encrypt(user.Email)
+	if err != nil {
+		return MgUser{}, err
 	}
 	return MgUser{
-		ID:       id.String(),
-		CartID:   cartID.String(),
-		Name:     user.Name,
-		Surname:  user.Surname,
-		Phone:    user.Phone,
-		Email:    user.Email,
-		Password: user.Password,
-		Role:     userRole,
+		ID:              id.String(),
+		CartID:          cartID.String(),
+		Name:            user.Name,
+		Surname:         user.Surname,
+		NameLower:       strings.ToLower(user.Name),
+		SurnameLower:    strings.ToLower(user.Surname),
+		Phone:           phone,
+		PhoneHash:       phoneHash,
+		Email:           email,
+		EmailHash:       encryption.BlindIndex(user.Email),
+		Password:        user.Password,
+		Role:            NewMgUserRole(user.Role),
+		EmailVerifiedAt: user.EmailVerifiedAt.Ptr(),
+		BlockedAt:       user.BlockedAt.Ptr(),
+		BlockedReason:   user.BlockedReason,
+	}, nil
+}
+
+func NewMgUserRole(role domain.UserRole) string {
+	switch role {
+	case domain.UserSeller:
+		return MgUserSeller
+	case domain.UserModerator:
+		return MgUserModerator
+	default:
+		return MgUserCustomer
 	}
 }
@@ -0,0 +1,130 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+)
+
+type MgUserAddress struct {
+	ID         string `bson:"_id"`
+	UserID     string `bson:"user_id"`
+	Recipient  string `bson:"recipient"`
+	Phone      string `bson:"phone"`
+	Country    string `bson:"country"`
+	City       string `bson:"city"`
+	Street     string `bson:"street"`
+	PostalCode string `bson:"postal_code"`
+	IsDefault  bool   `bson:"is_default"`
+}
+
+func (a *MgUserAddress) ToDomain() (domain.UserAddress, error) {
+	address := domain.UserAddress{
+		ID:         domain.ID(a.ID),
+		UserID:     domain.ID(a.UserID),
+		Recipient:  a.Recipient,
+		Phone:      a.Phone,
+		Country:    a.Country,
+		City:       a.City,
+		Street:     a.Street,
+		PostalCode: a.PostalCode,
+		IsDefault:  a.IsDefault,
+	}
+	err := decryptFields(&address.Recipient, &address.Phone, &address.Street, &address.PostalCode)
+	if err != nil {
+		return domain.UserAddress{}, err
+	}
+	return address, nil
+}
+
+func NewMgUserAddress(address domain.UserAddress) (MgUserAddress, error) {
+	mgAddress := MgUserAddress{
+		ID:         address.ID.String(),
+		UserID:     address.UserID.String(),
+		Recipient:  address.Recipient,
+		Phone:      address.Phone,
+		Country:    address.Country,
+		City:       address.City,
+		Street:     address.Street,
+		PostalCode: address.PostalCode,
+		IsDefault:  address.IsDefault,
+	}
+	err := encryptFields(&mgAddress.Recipient, &mgAddress.Phone, &mgAddress.Street, &mgAddress.PostalCode)
+	if err != nil {
+		return MgUserAddress{}, err
+	}
+	return mgAddress, nil
+}
+
+// MgOrderAddress is the address snapshot of an order. The recipient, the
+// phone, the street and the postal code are encrypted like in MgUserAddress.
+type MgOrderAddress struct {
+	Recipient  string `bson:"recipient"`
+	Phone      string `bson:"phone"`
+	Country    string `bson:"country"`
+	City       string `bson:"city"`
+	Street     string `bson:"street"`
+	PostalCode string `bson:"postal_code"`
+}
+
+func (a *MgOrderAddress) ToDomain() (domain.OrderAddress, error) {
+	if a == nil {
+		return domain.OrderAddress{}, nil
+	}
+	address := domain.OrderAddress{
+		Recipient:  a.Recipient,
+		Phone:      a.Phone,
+		Country:    a.Country,
+		City:       a.City,
+		Street:     a.Street,
+		PostalCode: a.PostalCode,
+	}
+	err := decryptFields(&address.Recipient, &address.Phone, &address.Street, &address.PostalCode)
+	if err != nil {
+		return domain.OrderAddress{}, err
+	}
+	return address, nil
+}
+
+// NewMgOrderAddress returns nil for an empty snapshot so it is not stored.
+func NewMgOrderAddress(address domain.OrderAddress) (*MgOrderAddress, error) {
+	if address == (domain.OrderAddress{}) {
+		return nil, nil
+	}
+	mgAddress := &MgOrderAddress{
+		Recipient:  address.Recipient,
+		Phone:      address.Phone,
+		Country:    address.Country,
+		City:       address.City,
+		Street:     address.Street,
+		PostalCode: address.PostalCode,
+	}
+	err := encryptFields(&mgAddress.Recipient, &mgAddress.Phone, &mgAddress.Street, &mgAddress.PostalCode)
+	if err != nil {
+		return nil, err
+	}
+	return mgAddress, nil
+}
+
+// encryptFields encrypts the given fields in place.
+func encryptFields(fields ...*string) error {
+	for _, field := range fields {
+		encrypted, err := encryption.Encrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = encrypted
+	}
+	return nil
+}
+
+// decryptFields decrypts the given fields in place.
+func decryptFields(fields ...*string) error {
+	for _, field := range fields {
+		decrypted, err := encryption.Decrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = decrypted
+	}
+	return nil
+}
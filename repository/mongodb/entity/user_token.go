@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/guregu/null"
+	"time"
+)
+
+const (
+	MgTokenEmailVerification = "EmailVerification"
+	MgTokenPasswordReset     = "PasswordReset"
+)
+
+type MgUserToken struct {
+	ID         string     `bson:"_id"`
+	UserID     string     `bson:"user_id"`
+	TokenHash  string     `bson:"token_hash"`
+	Purpose    string     `bson:"purpose"`
+	CreatedAt  time.Time  `bson:"created_at"`
+	ExpiresAt  time.Time  `bson:"expires_at"`
+	ConsumedAt *time.Time `bson:"consumed_at,omitempty"`
+}
+
+func (t *MgUserToken) ToDomain() domain.UserToken {
+	var purpose domain.TokenPurpose
+	switch t.Purpose {
+	case MgTokenEmailVerification:
+		purpose = domain.TokenPurposeEmailVerification
+	case MgTokenPasswordReset:
+		purpose = domain.TokenPurposePasswordReset
+	}
+
+	return domain.UserToken{
+		ID:         domain.ID(t.ID),
+		UserID:     domain.ID(t.UserID),
+		TokenHash:  t.TokenHash,
+		Purpose:    purpose,
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		ConsumedAt: null.TimeFromPtr(t.ConsumedAt),
+	}
+}
+
+func NewMgTokenPurpose(purpose domain.TokenPurpose) string {
+	switch purpose {
+	case domain.TokenPurposeEmailVerification:
+		return MgTokenEmailVerification
+	case domain.TokenPurposePasswordReset:
+		return MgTokenPasswordReset
+	}
+	return ""
+}
+
+func NewMgUserToken(token domain.UserToken) MgUserToken {
+	return MgUserToken{
+		ID:         token.ID.String(),
+		UserID:     token.UserID.String(),
+		TokenHash:  token.TokenHash,
+		Purpose:    NewMgTokenPurpose(token.Purpose),
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		ConsumedAt: token.ConsumedAt.Ptr(),
+	}
+}
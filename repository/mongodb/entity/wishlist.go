@@ -0,0 +1,59 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"time"
+)
+
+type MgWishlist struct {
+	ID        string    `bson:"_id"`
+	UserID    string    `bson:"user_id"`
+	Name      string    `bson:"name"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+func (w *MgWishlist) ToDomain() domain.Wishlist {
+	return domain.Wishlist{
+		ID:        domain.ID(w.ID),
+		UserID:    domain.ID(w.UserID),
+		Name:      w.Name,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func NewMgWishlist(wishlist domain.Wishlist) MgWishlist {
+	return MgWishlist{
+		ID:        wishlist.ID.String(),
+		UserID:    wishlist.UserID.String(),
+		Name:      wishlist.Name,
+		CreatedAt: wishlist.CreatedAt,
+	}
+}
+
+type MgWishlistItem struct {
+	ID         string    `bson:"_id"`
+	WishlistID string    `bson:"wishlist_id"`
+	ProductID  string    `bson:"product_id"`
+	VariantID  string    `bson:"variant_id,omitempty"`
+	AddedAt    time.Time `bson:"added_at"`
+}
+
+func (wi *MgWishlistItem) ToDomain() domain.WishlistItem {
+	return domain.WishlistItem{
+		ID:         domain.ID(wi.ID),
+		WishlistID: domain.ID(wi.WishlistID),
+		ProductID:  domain.ID(wi.ProductID),
+		VariantID:  domain.ID(wi.VariantID),
+		AddedAt:    wi.AddedAt,
+	}
+}
+
+func NewMgWishlistItem(wishlistItem domain.WishlistItem) MgWishlistItem {
+	return MgWishlistItem{
+		ID:         wishlistItem.ID.String(),
+		WishlistID: wishlistItem.WishlistID.String(),
+		ProductID:  wishlistItem.ProductID.String(),
+		VariantID:  wishlistItem.VariantID.String(),
+		AddedAt:    wishlistItem.AddedAt,
+	}
+}
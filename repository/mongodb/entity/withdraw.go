@@ -2,6 +2,8 @@ package entity
 
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/guregu/null"
+	"time"
 )
 
 const (
@@ -11,11 +13,17 @@ const (
 )
 
 type MgWithdraw struct {
-	ID      string `bson:"_id"`
-	ShopID  string `bson:"shop_id"`
-	Comment string `bson:"comment"`
-	Sum     int64  `bson:"sum"`
-	Status  string `bson:"status"`
+	ID          string     `bson:"_id"`
+	ShopID      string     `bson:"shop_id"`
+	Comment     string     `bson:"comment"`
+	Sum         int64      `bson:"sum"`
+	Currency    string     `bson:"currency"`
+	Status      string     `bson:"status"`
+	RequestedAt time.Time  `bson:"requested_at"`
+	ApprovedAt  *time.Time `bson:"approved_at,omitempty"`
+	PaidAt      *time.Time `bson:"paid_at,omitempty"`
+	ReviewerID  string     `bson:"reviewer_id,omitempty"`
+	BatchID     string     `bson:"payout_batch_id,omitempty"`
 }
 
 func (w *MgWithdraw) ToDomain() domain.Withdraw {
@@ -30,30 +38,43 @@ func (w *MgWithdraw) ToDomain() domain.Withdraw {
 	}
 
 	return domain.Withdraw{
-		ID:      domain.ID(w.ID),
-		ShopID:  domain.ID(w.ShopID),
-		Comment: w.Comment,
-		Sum:     w.Sum,
-		Status:  withdrawStatus,
+		ID:          domain.ID(w.ID),
+		ShopID:      domain.ID(w.ShopID),
+		Comment:     w.Comment,
+		Sum:         w.Sum,
+		Currency:    w.Currency,
+		Status:      withdrawStatus,
+		RequestedAt: w.RequestedAt,
+		ApprovedAt:  null.TimeFromPtr(w.ApprovedAt),
+		PaidAt:      null.TimeFromPtr(w.PaidAt),
+		ReviewerID:  domain.ID(w.ReviewerID),
+		BatchID:     domain.ID(w.BatchID),
 	}
 }
 
-func NewMgWithdraw(withdraw domain.Withdraw) MgWithdraw {
-	var withdrawStatus string
-	switch withdraw.Status {
-	case domain.WithdrawStatusStart:
-		withdrawStatus = MgWithdrawStart
+func NewMgWithdrawStatus(status domain.WithdrawStatus) string {
+	switch status {
 	case domain.WithdrawStatusReady:
-		withdrawStatus = MgWithdrawReady
+		return MgWithdrawReady
 	case domain.WithdrawStatusDone:
-		withdrawStatus = MgWithdrawDone
+		return MgWithdrawDone
+	default:
+		return MgWithdrawStart
 	}
+}
 
+func NewMgWithdraw(withdraw domain.Withdraw) MgWithdraw {
 	return MgWithdraw{
-		ID:      withdraw.ID.String(),
-		ShopID:  withdraw.ShopID.String(),
-		Comment: withdraw.Comment,
-		Sum:     withdraw.Sum,
-		Status:  withdrawStatus,
+		ID:          withdraw.ID.String(),
+		ShopID:      withdraw.ShopID.String(),
+		Comment:     withdraw.Comment,
+		Sum:         withdraw.Sum,
+		Currency:    withdraw.Currency,
+		Status:      NewMgWithdrawStatus(withdraw.Status),
+		RequestedAt: withdraw.RequestedAt,
+		ApprovedAt:  withdraw.ApprovedAt.Ptr(),
+		PaidAt:      withdraw.PaidAt.Ptr(),
+		ReviewerID:  withdraw.ReviewerID.String(),
+		BatchID:     withdraw.BatchID.String(),
 	}
 }
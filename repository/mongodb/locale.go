@@ -0,0 +1,17 @@
+package mongodb
+
+import "strings"
+
+// searchLanguage is the text search language used for the texts of a
+// locale, locales without a stemmer are indexed as is.
+func searchLanguage(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	switch language {
+	case "ru":
+		return "russian"
+	case "en":
+		return "english"
+	default:
+		return "none"
+	}
+}
@@ -0,0 +1,196 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+	"time"
+)
+
+// migrations bring the documents written before a schema change to the shape
+// the repositories expect, like the sql migrations do for postgres. Every
+// step only touches the documents it has not migrated yet, so they are safe
+// to run on every start.
+var migrations = []func(ctx context.Context, db *mongo.Database) error{
+	migrateOpeningStock,
+	migrateCartTimestamps,
+	migrateCurrency,
+	migrateUserSearchFields,
+	ReencryptPII,
+}
+
+// Migrate runs the migrations in order and stops at the first failed one.
+// Run it before creating the repositories, the indexes they create expect
+// migrated documents.
+func Migrate(ctx context.Context, db *mongo.Database) error {
+	for _, migration := range migrations {
+		if err := migration(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateOpeningStock records the opening balance of the shop items and the
+// variants that had stock before the stock_movement ledger, so ReconcileStock
+// matches them.
+func migrateOpeningStock(ctx context.Context, db *mongo.Database) error {
+	shopItemMovements := bson.M{"$and": bson.A{
+		bson.M{"$eq": bson.A{"$shop_product_id", "$$id"}},
+		bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$variant_id", nil}}, nil}},
+	}}
+	shopItems, err := findUnrecordedStock(ctx, db, ShopProductCollection, shopItemMovements)
+	if err != nil {
+		return err
+	}
+	variants, err := findUnrecordedStock(ctx, db, ProductVariantCollection,
+		bson.M{"$eq": bson.A{"$variant_id", "$$id"}})
+	if err != nil {
+		return err
+	}
+
+	var movements []interface{}
+	for _, shopItem := range shopItems {
+		movements = append(movements, entity.NewMgStockMovement(newStockMovement(domain.ID(shopItem.ID),
+			shopItem.Quantity, domain.StockMovementAdjustment, "")))
+	}
+	for _, variant := range variants {
+		movement := newStockMovement(domain.ID(variant.ShopItemID), variant.Quantity, domain.StockMovementAdjustment, "")
+		movement.VariantID = domain.ID(variant.ID)
+		movements = append(movements, entity.NewMgStockMovement(movement))
+	}
+	if len(movements) == 0 {
+		return nil
+	}
+	if _, err = db.Collection(StockMovementCollection).InsertMany(ctx, movements); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
+
+// unrecordedStock is a shop item or a variant missing from the ledger, the
+// shop item of a shop item is left empty.
+type unrecordedStock struct {
+	ID         string `bson:"_id"`
+	ShopItemID string `bson:"shop_product_id"`
+	Quantity   int64  `bson:"quantity"`
+}
+
+// findUnrecordedStock returns the documents of the collection with stock
+// that no stock movement matching the expression refers to, $$id is the id
+// of the document in the expression.
+func findUnrecordedStock(ctx context.Context, db *mongo.Database, collection string, movementExpr bson.M) ([]unrecordedStock, error) {
+	cursor, err := db.Collection(collection).Aggregate(ctx, mongo.Pipeline{
+		{{"$match", bson.M{"quantity": bson.M{"$ne": 0}}}},
+		{{"$lookup", bson.M{
+			"from":     StockMovementCollection,
+			"let":      bson.M{"id": "$_id"},
+			"pipeline": bson.A{bson.M{"$match": bson.M{"$expr": movementExpr}}, bson.M{"$limit": 1}},
+			"as":       "movements",
+		}}},
+		{{"$match", bson.M{"movements": bson.M{"$size": 0}}}},
+		{{"$project", bson.M{"_id": 1, "shop_product_id": 1, "quantity": 1}}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var documents []unrecordedStock
+	if err = cursor.All(ctx, &documents); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return documents, nil
+}
+
+// migrateCartTimestamps dates the carts and the cart items stored before they
+// had timestamps to the migration, so FindAbandonedCarts and the cart item
+// TTL index start counting for them from now on.
+func migrateCartTimestamps(ctx context.Context, db *mongo.Database) error {
+	now := time.Now().UTC()
+	_, err := db.Collection(CartCollection).UpdateMany(ctx,
+		bson.M{"updated_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"created_at": now, "updated_at": now}})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	_, err = db.Collection(CartProductCollection).UpdateMany(ctx,
+		bson.M{"updated_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"created_at": now, "updated_at": now, "expires_at": now.Add(CartItemTTL)}})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
+
+// defaultCurrency is the currency of the amounts stored before they had one,
+// everything was priced in roubles then.
+const defaultCurrency = "RUB"
+
+// migrateCurrency prices the documents stored before amounts had a currency
+// in defaultCurrency, like the sql migration does. Only fixed amount promo
+// codes have a currency.
+func migrateCurrency(ctx context.Context, db *mongo.Database) error {
+	fields := []struct {
+		collection string
+		field      string
+		filter     bson.M
+	}{
+		{ProductCollection, "currency", bson.M{}},
+		{ShopCollection, "default_currency", bson.M{}},
+		{CartCollection, "currency", bson.M{}},
+		{OrderCustomerCollection, "currency", bson.M{}},
+		{WithdrawCollection, "currency", bson.M{}},
+		{PayoutBatchCollection, "currency", bson.M{}},
+		{PromoCodeCollection, "currency", bson.M{"type": entity.MgPromoCodeFixed}},
+	}
+	for _, f := range fields {
+		f.filter[f.field] = bson.M{"$exists": false}
+		_, err := db.Collection(f.collection).UpdateMany(ctx, f.filter,
+			bson.M{"$set": bson.M{f.field: defaultCurrency}})
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return nil
+}
+
+// migrateUserSearchFields fills in the lowercased name and surname of the
+// users stored before SearchUsers matched prefixes on them. They are
+// lowercased here rather than with $toLower, which only handles ASCII.
+func migrateUserSearchFields(ctx context.Context, db *mongo.Database) error {
+	collection := db.Collection(UserCollection)
+	cursor, err := collection.Find(ctx, bson.M{"name_lower": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"name": 1, "surname": 1}))
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var user struct {
+			ID      string `bson:"_id"`
+			Name    string `bson:"name"`
+			Surname string `bson:"surname"`
+		}
+		if err = cursor.Decode(&user); err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": user.ID}, bson.M{"$set": bson.M{
+			"name_lower":    strings.ToLower(user.Name),
+			"surname_lower": strings.ToLower(user.Surname),
+		}})
+		if err != nil {
+			return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
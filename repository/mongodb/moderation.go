@@ -0,0 +1,57 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+)
+
+// createModerationIndex indexes the moderation history by target, it is
+// shared by the user and the shop repositories.
+func createModerationIndex(db *mongo.Database) {
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{"target_type", 1}, {"target_id", 1}, {"created_at", 1}},
+	}
+	_, err := db.Collection(ModerationCollection).Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create moderation collection index, %v", err)
+	}
+}
+
+// addModerationAction records the action in the moderation history of the
+// target.
+func addModerationAction(ctx context.Context, db *mongo.Database, targetType string, action domain.ModerationAction) error {
+	_, err := db.Collection(ModerationCollection).InsertOne(ctx, entity.NewMgModerationAction(targetType, action))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
+
+func getModerationHistory(ctx context.Context, db *mongo.Database, targetType string, targetID domain.ID) ([]domain.ModerationAction, error) {
+	opts := options.Find().SetSort(bson.D{{"created_at", 1}, {"_id", 1}})
+	cursor, err := db.Collection(ModerationCollection).Find(ctx,
+		bson.M{"target_type": targetType, "target_id": targetID}, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgActions []entity.MgModerationAction
+	if err = cursor.All(ctx, &mgActions); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	actions := make([]domain.ModerationAction, len(mgActions))
+	for i, mgAction := range mgActions {
+		actions[i] = mgAction.ToDomain()
+	}
+	return actions, nil
+}
@@ -3,12 +3,15 @@ package mongodb
 import (
 	"context"
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
+	"strings"
 )
 
 type MongoOrderRepo struct {
@@ -29,7 +32,7 @@ func NewOrderRepo(db *mongo.Database) *MongoOrderRepo {
 
 	collection = db.Collection(OrderShopProductCollection)
 	indexModel = mongo.IndexModel{
-		Keys:    bson.D{{"order_shop_id", 1}, {"product_id", 1}},
+		Keys:    bson.D{{"order_shop_id", 1}, {"product_id", 1}, {"variant_id", 1}},
 		Options: options.Index().SetUnique(true),
 	}
 
@@ -59,9 +62,8 @@ func (o *MongoOrderRepo) GetOrderCustomerByCustomerID(ctx context.Context, custo
 
 	orderCustomers := make([]domain.OrderCustomer, len(mgOrderCustomersArray))
 	for i := range orderCustomers {
-		orderCustomers[i] = mgOrderCustomersArray[i].ToDomain()
 		var err error
-		orderCustomers[i], err = o.GetOrderCustomerByID(ctx, orderCustomers[i].ID)
+		orderCustomers[i], err = o.GetOrderCustomerByID(ctx, domain.ID(mgOrderCustomersArray[i].ID))
 		if err != nil {
 			return nil, err
 		}
@@ -79,7 +81,10 @@ func (o *MongoOrderRepo) GetOrderCustomerByID(ctx context.Context, orderCustomer
 		}
 		return domain.OrderCustomer{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 	}
-	orderCustomer := mgOrderCustomer.ToDomain()
+	orderCustomer, err := mgOrderCustomer.ToDomain()
+	if err != nil {
+		return domain.OrderCustomer{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
 
 	cursor, err := o.db.Database().Collection(OrderShopCollection).Find(ctx, bson.M{"order_customer_id": orderCustomerID})
 	if err != nil {
@@ -179,8 +184,11 @@ func (o *MongoOrderRepo) GetNoNotifiedOrderShops(ctx context.Context) ([]domain.
 	return orderShops, nil
 }
 
-func (o *MongoOrderRepo) getMgEntities(orderCustomer domain.OrderCustomer) (entity.MgOrderCustomer, []entity.MgOrderShop, []entity.MgOrderShopItem) {
-	mgOrderCustomer := entity.NewMgOrderCustomer(orderCustomer)
+func (o *MongoOrderRepo) getMgEntities(orderCustomer domain.OrderCustomer) (entity.MgOrderCustomer, []entity.MgOrderShop, []entity.MgOrderShopItem, error) {
+	mgOrderCustomer, err := entity.NewMgOrderCustomer(orderCustomer)
+	if err != nil {
+		return entity.MgOrderCustomer{}, nil, nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
 	mgOrderShops := make([]entity.MgOrderShop, 0)
 	mgOrderShopItems := make([]entity.MgOrderShopItem, 0)
 	for _, orderShop := range orderCustomer.OrderShops {
@@ -189,42 +197,162 @@ func (o *MongoOrderRepo) getMgEntities(orderCustomer domain.OrderCustomer) (enti
 			mgOrderShopItems = append(mgOrderShopItems, entity.NewMgOrderShopItem(item))
 		}
 	}
-	return mgOrderCustomer, mgOrderShops, mgOrderShopItems
+	return mgOrderCustomer, mgOrderShops, mgOrderShopItems, nil
+}
+
+// getItemPrice returns the current unit price of the order item and its
+// currency, the variant price override takes precedence over the product
+// price and is in the currency of the product.
+func getItemPrice(ctx context.Context, db *mongo.Database, productID, variantID string) (int64, string, error) {
+	var mgProduct entity.MgProduct
+	err := db.Collection(ProductCollection).FindOne(ctx, bson.M{"_id": productID}).Decode(&mgProduct)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, "", errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return 0, "", errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	if variantID != "" {
+		var mgVariant entity.MgProductVariant
+		err := db.Collection(ProductVariantCollection).FindOne(ctx, bson.M{"_id": variantID}).Decode(&mgVariant)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return 0, "", errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+			return 0, "", errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if mgVariant.Price.Valid {
+			return mgVariant.Price.Int64, mgProduct.Currency, nil
+		}
+	}
+	return mgProduct.Price, mgProduct.Currency, nil
+}
+
+// checkOrderTotal rejects the order when its declared total price differs
+// from the sum of the current item prices or when the items are priced in
+// different currencies. An order without a currency takes the currency of
+// its items.
+func checkOrderTotal(ctx context.Context, db *mongo.Database, mgOrderCustomer *entity.MgOrderCustomer, mgOrderShopItems []entity.MgOrderShopItem) error {
+	var total int64
+	for _, mgOrderShopItem := range mgOrderShopItems {
+		price, currency, err := getItemPrice(ctx, db, mgOrderShopItem.ProductID, mgOrderShopItem.VariantID)
+		if err != nil {
+			return err
+		}
+		if mgOrderCustomer.Currency == "" {
+			mgOrderCustomer.Currency = currency
+		}
+		if currency != mgOrderCustomer.Currency {
+			return errors.Wrapf(domain.ErrNotAllowed, "order item is priced in %s, order is in %s", currency, mgOrderCustomer.Currency)
+		}
+		total += price * mgOrderShopItem.Quantity
+	}
+	if !currency.Valid(mgOrderCustomer.Currency) {
+		return errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+	if total != mgOrderCustomer.TotalPrice {
+		return errors.Wrapf(domain.ErrNotAllowed, "order total price %d differs from computed %d", mgOrderCustomer.TotalPrice, total)
+	}
+	return nil
+}
+
+// snapshotAddress copies the chosen address of the customer into the order,
+// so later edits of the address book do not change placed orders.
+func snapshotAddress(ctx context.Context, db *mongo.Database, mgOrderCustomer *entity.MgOrderCustomer) error {
+	var mgUserAddress entity.MgUserAddress
+	err := db.Collection(UserAddressCollection).FindOne(ctx, bson.M{"_id": mgOrderCustomer.AddressID}).Decode(&mgUserAddress)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if mgUserAddress.UserID != mgOrderCustomer.CustomerID {
+		return errors.Wrap(domain.ErrNotAllowed, "address does not belong to the customer")
+	}
+	userAddress, err := mgUserAddress.ToDomain()
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	mgOrderCustomer.ShippingAddress, err = entity.NewMgOrderAddress(domain.OrderAddress{
+		Recipient:  userAddress.Recipient,
+		Phone:      userAddress.Phone,
+		Country:    userAddress.Country,
+		City:       userAddress.City,
+		Street:     userAddress.Street,
+		PostalCode: userAddress.PostalCode,
+	})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if mgOrderCustomer.Address == "" {
+		address, err := encryption.Encrypt(strings.Join([]string{userAddress.PostalCode,
+			userAddress.Country, userAddress.City, userAddress.Street}, ", "))
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		mgOrderCustomer.Address = address
+	}
+	return nil
 }
 
 func (o *MongoOrderRepo) CreateOrderCustomer(ctx context.Context, orderCustomer domain.OrderCustomer) (domain.OrderCustomer, error) {
 	session, err := o.db.Database().Client().StartSession()
 	if err != nil {
-		return domain.OrderCustomer{}, nil
+		return domain.OrderCustomer{}, errors.Wrap(domain.ErrTransactionError, err.Error())
 	}
 	defer session.EndSession(ctx)
 
-	err = mongo.WithSession(ctx, session, func(sessionContext mongo.SessionContext) error {
-		mgOrderCustomer, mgOrderShops, mgOrderShopItems := o.getMgEntities(orderCustomer)
-		err = o.txInsertOrderCustomer(ctx, mgOrderCustomer)
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		mgOrderCustomer, mgOrderShops, mgOrderShopItems, err := o.getMgEntities(orderCustomer)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		err = checkOrderTotal(sessionContext, o.db.Database(), &mgOrderCustomer, mgOrderShopItems)
+		if err != nil {
+			return nil, err
+		}
+		orderCustomer.Currency = mgOrderCustomer.Currency
+		if mgOrderCustomer.AddressID != "" {
+			err = snapshotAddress(sessionContext, o.db.Database(), &mgOrderCustomer)
+			if err != nil {
+				return nil, err
+			}
+		}
+		mgOrderCustomer.Discount = 0
+		if orderCustomer.PromoCodeID != "" {
+			mgOrderCustomer.Discount, err = redeemPromoCode(sessionContext, o.db.Database(), orderCustomer)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = o.txInsertOrderCustomer(sessionContext, mgOrderCustomer)
+		if err != nil {
+			return nil, err
+		}
+		shopIDs := make(map[string]string, len(mgOrderShops))
 		for _, mgOrderShop := range mgOrderShops {
-			err = o.txInsertOrderShop(ctx, mgOrderShop)
+			err = o.txInsertOrderShop(sessionContext, mgOrderShop)
 			if err != nil {
-				return err
+				return nil, err
 			}
+			shopIDs[mgOrderShop.ID] = mgOrderShop.ShopID
 		}
 		for _, pgOrderShopItem := range mgOrderShopItems {
-			err = o.txUpdateShopItem(ctx, pgOrderShopItem)
+			err = o.txUpdateShopItem(sessionContext, pgOrderShopItem, shopIDs[pgOrderShopItem.OrderShopID])
 			if err != nil {
-				return err
+				return nil, err
 			}
-			err = o.txInsertOrderShopItem(ctx, pgOrderShopItem)
+			err = o.txInsertOrderShopItem(sessionContext, pgOrderShopItem)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
-		return nil
+		return nil, nil
 	})
 	if err != nil {
-		return domain.OrderCustomer{}, err
+		return domain.OrderCustomer{}, txError(err)
 	}
 
 	return o.GetOrderCustomerByID(ctx, orderCustomer.ID)
@@ -308,15 +436,56 @@ func (o *MongoOrderRepo) txInsertOrderShop(ctx context.Context, shop entity.MgOr
 	return nil
 }
 
-func (o *MongoOrderRepo) txUpdateShopItem(ctx context.Context, item entity.MgOrderShopItem) error {
-	_, err := o.db.Database().Collection(OrderShopProductCollection).ReplaceOne(ctx, bson.M{"_id": item.ID}, item)
+// txUpdateShopItem takes the ordered quantity from the stock of the product
+// in the shop of the order shop.
+func (o *MongoOrderRepo) txUpdateShopItem(ctx context.Context, item entity.MgOrderShopItem, shopID string) error {
+	if item.VariantID != "" {
+		return o.txUpdateVariant(ctx, item, shopID)
+	}
+
+	var mgShopItem entity.MgShopItem
+	err := o.db.Database().Collection(ShopProductCollection).FindOneAndUpdate(ctx,
+		bson.M{"product_id": item.ProductID, "shop_id": shopID, "quantity": bson.M{"$gte": item.Quantity}},
+		bson.M{"$inc": bson.M{"quantity": -item.Quantity}}).Decode(&mgShopItem)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return errors.Wrap(domain.ErrNotExist, err.Error())
 		}
+		return wrapTxError(domain.ErrPersistenceFailed, err)
+	}
+
+	movement := newStockMovement(domain.ID(mgShopItem.ID), -item.Quantity,
+		domain.StockMovementSale, domain.ID(item.OrderShopID))
+	return insertStockMovement(ctx, o.db.Database(), movement)
+}
+
+// txUpdateVariant takes the ordered quantity from the variant stock, the
+// variant must belong to the shop item of the ordered product in the shop.
+func (o *MongoOrderRepo) txUpdateVariant(ctx context.Context, item entity.MgOrderShopItem, shopID string) error {
+	var mgShopItem entity.MgShopItem
+	err := o.db.Database().Collection(ShopProductCollection).FindOne(ctx,
+		bson.M{"product_id": item.ProductID, "shop_id": shopID}).Decode(&mgShopItem)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.Wrap(domain.ErrNotExist, "product is not sold by the shop")
+		}
 		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 	}
-	return nil
+
+	var mgVariant entity.MgProductVariant
+	err = o.db.Database().Collection(ProductVariantCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": item.VariantID, "shop_product_id": mgShopItem.ID, "quantity": bson.M{"$gte": item.Quantity}},
+		bson.M{"$inc": bson.M{"quantity": -item.Quantity}}).Decode(&mgVariant)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.Wrap(domain.ErrNotExist, "variant is not available")
+		}
+		return wrapTxError(domain.ErrUpdateFailed, err)
+	}
+
+	movement := newVariantStockMovement(mgVariant.ToDomain(), -item.Quantity,
+		domain.StockMovementSale, domain.ID(item.OrderShopID))
+	return insertStockMovement(ctx, o.db.Database(), movement)
 }
 
 func (o *MongoOrderRepo) txInsertOrderShopItem(ctx context.Context, item entity.MgOrderShopItem) error {
@@ -0,0 +1,247 @@
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"strconv"
+	"time"
+)
+
+type MongoPayoutBatchRepo struct {
+	db *mongo.Collection
+}
+
+func NewPayoutBatchRepo(db *mongo.Database) *MongoPayoutBatchRepo {
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{"payout_batch_id", 1}},
+	}
+	_, err := db.Collection(WithdrawCollection).Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create withdraw collection index, %v", err)
+	}
+
+	return &MongoPayoutBatchRepo{
+		db: db.Collection(PayoutBatchCollection),
+	}
+}
+
+// payoutBatchCSVHeader is the header of the bank transfer list of a batch,
+// the amount is in the minor units the withdraw sum is stored in.
+var payoutBatchCSVHeader = []string{"withdraw_id", "shop_id", "shop_name", "requisites", "amount", "comment"}
+
+func (p *MongoPayoutBatchRepo) loadWithdraws(ctx context.Context, batch *domain.PayoutBatch) error {
+	cursor, err := p.db.Database().Collection(WithdrawCollection).Find(ctx, bson.M{"payout_batch_id": batch.ID},
+		options.Find().SetSort(bson.D{{"_id", 1}}))
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgWithdraws []entity.MgWithdraw
+	if err = cursor.All(ctx, &mgWithdraws); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	batch.Withdraws = make([]domain.Withdraw, len(mgWithdraws))
+	for i, mgWithdraw := range mgWithdraws {
+		batch.Withdraws[i] = mgWithdraw.ToDomain()
+	}
+	return nil
+}
+
+func (p *MongoPayoutBatchRepo) Get(ctx context.Context, limit, offset int64) ([]domain.PayoutBatch, error) {
+	opts := options.Find().SetSort(bson.D{{"created_at", 1}, {"_id", 1}}).SetSkip(offset).SetLimit(limit)
+	cursor, err := p.db.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgBatches []entity.MgPayoutBatch
+	if err = cursor.All(ctx, &mgBatches); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	batches := make([]domain.PayoutBatch, len(mgBatches))
+	for i, mgBatch := range mgBatches {
+		batches[i] = mgBatch.ToDomain()
+		if err = p.loadWithdraws(ctx, &batches[i]); err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
+func (p *MongoPayoutBatchRepo) GetByID(ctx context.Context, batchID domain.ID) (domain.PayoutBatch, error) {
+	var mgBatch entity.MgPayoutBatch
+	if err := p.db.FindOne(ctx, bson.M{"_id": batchID}).Decode(&mgBatch); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.PayoutBatch{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	batch := mgBatch.ToDomain()
+	if err := p.loadWithdraws(ctx, &batch); err != nil {
+		return domain.PayoutBatch{}, err
+	}
+	return batch, nil
+}
+
+// Create opens a new batch and attaches every Ready withdraw in the currency
+// of the batch that is not in a batch yet, the total is the sum of the
+// attached withdraws. Only the ID and the currency of the batch are taken
+// from the argument. A batch without withdraws is not created and
+// ErrNotAllowed is returned.
+func (p *MongoPayoutBatchRepo) Create(ctx context.Context, batch domain.PayoutBatch) (domain.PayoutBatch, error) {
+	if !currency.Valid(batch.Currency) {
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+
+	mgBatch := entity.NewMgPayoutBatch(domain.PayoutBatch{
+		ID:        batch.ID,
+		Currency:  batch.Currency,
+		Status:    domain.PayoutBatchOpen,
+		CreatedAt: time.Now().UTC(),
+	})
+
+	session, err := p.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		if _, err := p.db.InsertOne(sessionContext, mgBatch); err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errors.Wrap(domain.ErrDuplicate, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+
+		// each withdraw is attached by a single document update, so a
+		// concurrent batch can not take the same withdraw
+		withdrawCollection := p.db.Database().Collection(WithdrawCollection)
+		res, err := withdrawCollection.UpdateMany(sessionContext,
+			bson.M{"status": entity.MgWithdrawReady, "payout_batch_id": bson.M{"$exists": false}, "currency": batch.Currency},
+			bson.M{"$set": bson.M{"payout_batch_id": batch.ID}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		if res.ModifiedCount == 0 {
+			// aborting the transaction drops the inserted batch
+			return nil, errors.Wrap(domain.ErrNotAllowed, "no ready withdraws to pay")
+		}
+
+		attached := domain.PayoutBatch{ID: batch.ID}
+		if err = p.loadWithdraws(sessionContext, &attached); err != nil {
+			return nil, err
+		}
+		var total int64
+		for _, withdraw := range attached.Withdraws {
+			total += withdraw.Sum
+		}
+		_, err = p.db.UpdateOne(sessionContext, bson.M{"_id": batch.ID}, bson.M{"$set": bson.M{"total": total}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.PayoutBatch{}, err
+	}
+
+	return p.GetByID(ctx, batch.ID)
+}
+
+// Settle marks the batch as paid out and moves its withdraws to Done. A
+// batch can only be settled once.
+func (p *MongoPayoutBatchRepo) Settle(ctx context.Context, batchID domain.ID) (domain.PayoutBatch, error) {
+	session, err := p.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		now := time.Now().UTC()
+		res, err := p.db.UpdateOne(sessionContext,
+			bson.M{"_id": batchID, "status": entity.MgPayoutBatchOpen},
+			bson.M{"$set": bson.M{"status": entity.MgPayoutBatchSettled, "settled_at": now}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		if res.MatchedCount == 0 {
+			if _, err = p.GetByID(sessionContext, batchID); err != nil {
+				return nil, err
+			}
+			return nil, errors.Wrap(domain.ErrNotAllowed, "payout batch is already settled")
+		}
+
+		_, err = p.db.Database().Collection(WithdrawCollection).UpdateMany(sessionContext,
+			bson.M{"payout_batch_id": batchID, "status": entity.MgWithdrawReady},
+			bson.M{"$set": bson.M{"status": entity.MgWithdrawDone, "paid_at": now}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.PayoutBatch{}, err
+	}
+
+	return p.GetByID(ctx, batchID)
+}
+
+// ExportCSV returns the bank transfer list of the batch, one row per
+// withdraw with the requisites of the shop to pay.
+func (p *MongoPayoutBatchRepo) ExportCSV(ctx context.Context, batchID domain.ID) ([]byte, error) {
+	batch, err := p.GetByID(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err = w.Write(payoutBatchCSVHeader); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	shopRepo := &MongoShopRepo{db: p.db.Database().Collection(ShopCollection)}
+	shops := make(map[domain.ID]domain.Shop)
+	for _, withdraw := range batch.Withdraws {
+		shop, ok := shops[withdraw.ShopID]
+		if !ok {
+			shop, err = shopRepo.GetShopByID(ctx, withdraw.ShopID)
+			if err != nil {
+				return nil, err
+			}
+			shops[withdraw.ShopID] = shop
+		}
+
+		err = w.Write([]string{
+			withdraw.ID.String(),
+			shop.ID.String(),
+			shop.Name,
+			shop.Requisites,
+			strconv.FormatInt(withdraw.Sum, 10),
+			withdraw.Comment,
+		})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return buf.Bytes(), nil
+}
@@ -3,20 +3,85 @@ package mongodb
 import (
 	"context"
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
+	"github.com/EmirShimshir/marketplace-repository/repository/i18n"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"sort"
+	"strings"
 )
 
-type MongoProductRepo struct{
+type MongoProductRepo struct {
 	db *mongo.Collection
 }
 
 func NewProductRepo(db *mongo.Database) *MongoProductRepo {
+	collection := db.Collection(ProductCollection)
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{"category_id", 1}},
+	}
+
+	_, err := collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create product collection index, %v", err)
+	}
+
+	indexModel = mongo.IndexModel{
+		Keys: bson.D{{"attributes.$**", 1}},
+	}
+
+	_, err = collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create product attributes index, %v", err)
+	}
+
+	imageIndexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{"product_id", 1}, {"position", 1}},
+		},
+		{
+			Keys: bson.D{{"product_id", 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"is_primary": true}),
+		},
+	}
+	_, err = db.Collection(ProductImageCollection).Indexes().CreateMany(context.Background(), imageIndexModels)
+	if err != nil {
+		log.Fatalf("unable to create product_image collection index, %v", err)
+	}
+
+	indexModel = mongo.IndexModel{
+		Keys:    bson.D{{"name", "text"}, {"description", "text"}},
+		Options: options.Index().SetDefaultLanguage("none"),
+	}
+
+	_, err = collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create product text index, %v", err)
+	}
+
+	translationIndexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"product_id", 1}, {"locale", 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{"name", "text"}, {"description", "text"}},
+			Options: options.Index().SetDefaultLanguage("none").
+				SetLanguageOverride("language"),
+		},
+	}
+	_, err = db.Collection(ProductTranslationCollection).Indexes().CreateMany(context.Background(), translationIndexModels)
+	if err != nil {
+		log.Fatalf("unable to create product_translation collection index, %v", err)
+	}
+
 	return &MongoProductRepo{
-		db: db.Collection(ProductCollection),
+		db: collection,
 	}
 }
 
@@ -35,9 +100,23 @@ func (p *MongoProductRepo) Get(ctx context.Context, limit, offset int64) ([]doma
 		return nil, err
 	}
 
+	productIDs := make([]string, len(mgProductsArray))
+	for i, product := range mgProductsArray {
+		productIDs[i] = product.ID
+	}
+	ratings, err := p.getRatings(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	products := make([]domain.Product, len(mgProductsArray))
 	for i, product := range mgProductsArray {
 		products[i] = product.ToDomain()
+		products[i].Rating = ratings[product.ID].Rating
+		products[i].ReviewCount = ratings[product.ID].ReviewCount
+	}
+	if err = p.loadImages(ctx, products); err != nil {
+		return nil, err
 	}
 
 	return products, nil
@@ -53,10 +132,132 @@ func (p *MongoProductRepo) GetByID(ctx context.Context, productID domain.ID) (do
 		}
 		return domain.Product{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 	}
-	return mgProduct.ToDomain(), nil
+
+	ratings, err := p.getRatings(ctx, []string{mgProduct.ID})
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	products := []domain.Product{mgProduct.ToDomain()}
+	products[0].Rating = ratings[mgProduct.ID].Rating
+	products[0].ReviewCount = ratings[mgProduct.ID].ReviewCount
+	if err = p.loadImages(ctx, products); err != nil {
+		return domain.Product{}, err
+	}
+	return products[0], nil
+}
+
+// GetByCategoryID returns products of the category and all of its
+// subcategories.
+func (p *MongoProductRepo) GetByCategoryID(ctx context.Context, categoryID domain.ID, limit, offset int64) ([]domain.Product, error) {
+	descendants, err := getCategoryDescendants(ctx, p.db.Database(), categoryID)
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := []string{categoryID.String()}
+	for _, category := range descendants {
+		categoryIDs = append(categoryIDs, category.ID)
+	}
+
+	cursor, err := p.db.Find(ctx, bson.M{"category_id": bson.M{"$in": categoryIDs}},
+		options.Find().SetSort(bson.D{{"_id", 1}}).SetSkip(offset).SetLimit(limit))
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgProductsArray []entity.MgProduct
+	err = cursor.All(ctx, &mgProductsArray)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]string, len(mgProductsArray))
+	for i, product := range mgProductsArray {
+		productIDs[i] = product.ID
+	}
+	ratings, err := p.getRatings(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]domain.Product, len(mgProductsArray))
+	for i, product := range mgProductsArray {
+		products[i] = product.ToDomain()
+		products[i].Rating = ratings[product.ID].Rating
+		products[i].ReviewCount = ratings[product.ID].ReviewCount
+	}
+	if err = p.loadImages(ctx, products); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// GetByAttributes lists products matching all attribute filters, range
+// filters only match numeric attribute values.
+func (p *MongoProductRepo) GetByAttributes(ctx context.Context, filters []domain.AttributeFilter, limit, offset int64) ([]domain.Product, error) {
+	conditions := bson.A{}
+	for _, filter := range filters {
+		if filter.Key == "" || strings.ContainsAny(filter.Key, ".$") {
+			return nil, errors.Wrap(domain.ErrNotAllowed, "invalid attribute key "+filter.Key)
+		}
+		field := "attributes." + filter.Key
+		if filter.Value != nil {
+			conditions = append(conditions, bson.M{field: filter.Value})
+		}
+		if filter.Min.Valid {
+			conditions = append(conditions, bson.M{field: bson.M{"$gte": filter.Min.Float64}})
+		}
+		if filter.Max.Valid {
+			conditions = append(conditions, bson.M{field: bson.M{"$lte": filter.Max.Float64}})
+		}
+	}
+	query := bson.M{}
+	if len(conditions) != 0 {
+		query = bson.M{"$and": conditions}
+	}
+
+	cursor, err := p.db.Find(ctx, query, options.Find().SetSort(bson.D{{"_id", 1}}).SetSkip(offset).SetLimit(limit))
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgProductsArray []entity.MgProduct
+	err = cursor.All(ctx, &mgProductsArray)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]string, len(mgProductsArray))
+	for i, product := range mgProductsArray {
+		productIDs[i] = product.ID
+	}
+	ratings, err := p.getRatings(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]domain.Product, len(mgProductsArray))
+	for i, product := range mgProductsArray {
+		products[i] = product.ToDomain()
+		products[i].Rating = ratings[product.ID].Rating
+		products[i].ReviewCount = ratings[product.ID].ReviewCount
+	}
+	if err = p.loadImages(ctx, products); err != nil {
+		return nil, err
+	}
+
+	return products, nil
 }
 
 func (p *MongoProductRepo) Create(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if !currency.Valid(product.Currency) {
+		return domain.Product{}, errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+	if err := checkCategoryExists(ctx, p.db.Database(), product.CategoryID); err != nil {
+		return domain.Product{}, err
+	}
+
 	var mgProduct = entity.NewMgProduct(product)
 	_, err := p.db.InsertOne(ctx, mgProduct)
 	if err != nil {
@@ -69,14 +270,49 @@ func (p *MongoProductRepo) Create(ctx context.Context, product domain.Product) (
 	return p.GetByID(ctx, product.ID)
 }
 
+// Update rewrites the product. The currency of a product in carts priced in
+// its current currency can not change, the carts would mix currencies.
 func (p *MongoProductRepo) Update(ctx context.Context, product domain.Product) (domain.Product, error) {
-	var mgProduct = entity.NewMgProduct(product)
-	_, err := p.db.ReplaceOne(ctx, bson.M{"_id": mgProduct.ID}, mgProduct)
+	if !currency.Valid(product.Currency) {
+		return domain.Product{}, errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+	if err := checkCategoryExists(ctx, p.db.Database(), product.CategoryID); err != nil {
+		return domain.Product{}, err
+	}
+
+	session, err := p.db.Database().Client().StartSession()
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return domain.Product{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		return domain.Product{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		var current entity.MgProduct
+		if err := p.db.FindOne(sessionContext, bson.M{"_id": product.ID}).Decode(&current); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 		}
-		return domain.Product{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		if current.Currency != product.Currency {
+			count, err := p.db.Database().Collection(CartProductCollection).CountDocuments(sessionContext,
+				bson.M{"product_id": product.ID}, options.Count().SetLimit(1))
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if count != 0 {
+				return nil, errors.Wrap(domain.ErrNotAllowed, "product is in carts priced in its current currency")
+			}
+		}
+
+		var mgProduct = entity.NewMgProduct(product)
+		if _, err := p.db.ReplaceOne(sessionContext, bson.M{"_id": mgProduct.ID}, mgProduct); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.Product{}, err
 	}
 
 	return p.GetByID(ctx, product.ID)
@@ -87,5 +323,473 @@ func (p *MongoProductRepo) Delete(ctx context.Context, productID domain.ID) erro
 	if err != nil {
 		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
 	}
+	_, err = p.db.Database().Collection(ProductImageCollection).DeleteMany(ctx, bson.M{"product_id": productID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	_, err = p.db.Database().Collection(ProductTranslationCollection).DeleteMany(ctx, bson.M{"product_id": productID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// AddImage appends the image to the end of the product gallery. A primary
+// image replaces the previous primary one.
+func (p *MongoProductRepo) AddImage(ctx context.Context, image domain.ProductImage) (domain.ProductImage, error) {
+	images := p.db.Database().Collection(ProductImageCollection)
+
+	session, err := p.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.ProductImage{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		count, err := p.db.CountDocuments(sessionContext, bson.M{"_id": image.ProductID})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if count == 0 {
+			return nil, errors.Wrap(domain.ErrNotExist, "product does not exist")
+		}
+
+		var last entity.MgProductImage
+		err = images.FindOne(sessionContext, bson.M{"product_id": image.ProductID},
+			options.FindOne().SetSort(bson.D{{"position", -1}})).Decode(&last)
+		switch {
+		case err == mongo.ErrNoDocuments:
+			image.Position = 0
+		case err != nil:
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		default:
+			image.Position = last.Position + 1
+		}
+
+		if image.IsPrimary {
+			_, err = images.UpdateMany(sessionContext, bson.M{"product_id": image.ProductID},
+				bson.M{"$set": bson.M{"is_primary": false}})
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+		}
+
+		var mgImage = entity.NewMgProductImage(image)
+		_, err = images.InsertOne(sessionContext, mgImage)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errors.Wrap(domain.ErrDuplicate, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.ProductImage{}, err
+	}
+
+	var mgImage entity.MgProductImage
+	if err = images.FindOne(ctx, bson.M{"_id": image.ID}).Decode(&mgImage); err != nil {
+		return domain.ProductImage{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgImage.ToDomain(), nil
+}
+
+// ReorderImages sets gallery positions to the order of imageIDs, which must
+// list every image of the product exactly once.
+func (p *MongoProductRepo) ReorderImages(ctx context.Context, productID domain.ID, imageIDs []domain.ID) error {
+	seen := make(map[domain.ID]bool, len(imageIDs))
+	for _, imageID := range imageIDs {
+		if seen[imageID] {
+			return errors.Wrap(domain.ErrNotAllowed, "image is listed twice")
+		}
+		seen[imageID] = true
+	}
+
+	images := p.db.Database().Collection(ProductImageCollection)
+
+	session, err := p.db.Database().Client().StartSession()
+	if err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		count, err := images.CountDocuments(sessionContext, bson.M{"product_id": productID})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		if count != int64(len(imageIDs)) {
+			return nil, errors.Wrap(domain.ErrNotAllowed, "image list does not match product gallery")
+		}
+
+		for position, imageID := range imageIDs {
+			result, err := images.UpdateOne(sessionContext, bson.M{"_id": imageID, "product_id": productID},
+				bson.M{"$set": bson.M{"position": position}})
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+			}
+			if result.MatchedCount != 1 {
+				return nil, errors.Wrap(domain.ErrNotExist, "image does not belong to product")
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+func (p *MongoProductRepo) DeleteImage(ctx context.Context, imageID domain.ID) error {
+	_, err := p.db.Database().Collection(ProductImageCollection).DeleteOne(ctx, bson.M{"_id": imageID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// SetTranslation creates or replaces the name and the description of the
+// product in the locale of the translation.
+func (p *MongoProductRepo) SetTranslation(ctx context.Context, translation domain.ProductTranslation) (domain.ProductTranslation, error) {
+	if !i18n.ValidLocale(translation.Locale) {
+		return domain.ProductTranslation{}, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+
+	count, err := p.db.CountDocuments(ctx, bson.M{"_id": translation.ProductID})
+	if err != nil {
+		return domain.ProductTranslation{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if count == 0 {
+		return domain.ProductTranslation{}, errors.Wrap(domain.ErrNotExist, "product does not exist")
+	}
+
+	mgTranslation := entity.NewMgProductTranslation(translation, searchLanguage(translation.Locale))
+	_, err = p.db.Database().Collection(ProductTranslationCollection).ReplaceOne(ctx,
+		bson.M{"product_id": mgTranslation.ProductID, "locale": mgTranslation.Locale},
+		mgTranslation, options.Replace().SetUpsert(true))
+	if err != nil {
+		return domain.ProductTranslation{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgTranslation.ToDomain(), nil
+}
+
+// GetTranslations returns every translation of the product ordered by
+// locale.
+func (p *MongoProductRepo) GetTranslations(ctx context.Context, productID domain.ID) ([]domain.ProductTranslation, error) {
+	cursor, err := p.db.Database().Collection(ProductTranslationCollection).Find(ctx,
+		bson.M{"product_id": productID}, options.Find().SetSort(bson.D{{"locale", 1}}))
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgTranslations []entity.MgProductTranslation
+	if err = cursor.All(ctx, &mgTranslations); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	translations := make([]domain.ProductTranslation, len(mgTranslations))
+	for i, translation := range mgTranslations {
+		translations[i] = translation.ToDomain()
+	}
+	return translations, nil
+}
+
+func (p *MongoProductRepo) DeleteTranslation(ctx context.Context, productID domain.ID, locale string) error {
+	_, err := p.db.Database().Collection(ProductTranslationCollection).DeleteOne(ctx,
+		bson.M{"product_id": productID, "locale": locale})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// GetInLocale works like Get with the names and the descriptions in the
+// preferred locale. A product without a translation in the locale falls
+// back to its language and then to its own name and description.
+func (p *MongoProductRepo) GetInLocale(ctx context.Context, locale string, limit, offset int64) ([]domain.Product, error) {
+	if !i18n.ValidLocale(locale) {
+		return nil, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+	products, err := p.Get(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.localize(ctx, products, locale); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetByIDInLocale works like GetByID with the same fallback as GetInLocale.
+func (p *MongoProductRepo) GetByIDInLocale(ctx context.Context, productID domain.ID, locale string) (domain.Product, error) {
+	if !i18n.ValidLocale(locale) {
+		return domain.Product{}, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+	product, err := p.GetByID(ctx, productID)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	products := []domain.Product{product}
+	if err = p.localize(ctx, products, locale); err != nil {
+		return domain.Product{}, err
+	}
+	return products[0], nil
+}
+
+// GetByCategoryIDInLocale works like GetByCategoryID with the same fallback
+// as GetInLocale.
+func (p *MongoProductRepo) GetByCategoryIDInLocale(ctx context.Context, categoryID domain.ID, locale string, limit, offset int64) ([]domain.Product, error) {
+	if !i18n.ValidLocale(locale) {
+		return nil, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+	products, err := p.GetByCategoryID(ctx, categoryID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.localize(ctx, products, locale); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetByAttributesInLocale works like GetByAttributes with the same fallback
+// as GetInLocale.
+func (p *MongoProductRepo) GetByAttributesInLocale(ctx context.Context, filters []domain.AttributeFilter, locale string, limit, offset int64) ([]domain.Product, error) {
+	if !i18n.ValidLocale(locale) {
+		return nil, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+	products, err := p.GetByAttributes(ctx, filters, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.localize(ctx, products, locale); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// Search finds products by the words of the query. Translations in the
+// preferred locale are matched with the stemmer of its language, so any
+// word form matches, and the untranslated texts are matched as is. The
+// products are returned in the preferred locale, the best match first by
+// the best text score of their texts.
+func (p *MongoProductRepo) Search(ctx context.Context, locale, query string, limit, offset int64) ([]domain.Product, error) {
+	if !i18n.ValidLocale(locale) {
+		return nil, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+
+	if limit <= 0 {
+		return []domain.Product{}, nil
+	}
+
+	// a page of the union is among the best offset+limit matches of each
+	// collection, so neither is read past them
+	top := offset + limit
+	translationScores, err := searchScores(ctx, p.db.Database().Collection(ProductTranslationCollection), top,
+		bson.M{"locale": bson.M{"$in": i18n.LocaleChain(locale)}, "$text": bson.M{"$search": query, "$language": searchLanguage(locale)}},
+		"$product_id")
+	if err != nil {
+		return nil, err
+	}
+	productScores, err := searchScores(ctx, p.db, top, bson.M{"$text": bson.M{"$search": query}}, "$_id")
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64, len(translationScores)+len(productScores))
+	for _, score := range append(translationScores, productScores...) {
+		if current, ok := scores[score.ID]; !ok || score.Score > current {
+			scores[score.ID] = score.Score
+		}
+	}
+	rankedIDs := make([]string, 0, len(scores))
+	for id := range scores {
+		rankedIDs = append(rankedIDs, id)
+	}
+	sort.Slice(rankedIDs, func(i, j int) bool {
+		if scores[rankedIDs[i]] != scores[rankedIDs[j]] {
+			return scores[rankedIDs[i]] > scores[rankedIDs[j]]
+		}
+		return rankedIDs[i] < rankedIDs[j]
+	})
+	if offset >= int64(len(rankedIDs)) {
+		return []domain.Product{}, nil
+	}
+	rankedIDs = rankedIDs[offset:]
+	if int64(len(rankedIDs)) > limit {
+		rankedIDs = rankedIDs[:limit]
+	}
+
+	cursor, err := p.db.Find(ctx, bson.M{"_id": bson.M{"$in": rankedIDs}})
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgFound []entity.MgProduct
+	if err = cursor.All(ctx, &mgFound); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	found := make(map[string]entity.MgProduct, len(mgFound))
+	for _, product := range mgFound {
+		found[product.ID] = product
+	}
+	mgProductsArray := make([]entity.MgProduct, 0, len(rankedIDs))
+	for _, id := range rankedIDs {
+		if product, ok := found[id]; ok {
+			mgProductsArray = append(mgProductsArray, product)
+		}
+	}
+
+	productIDs := make([]string, len(mgProductsArray))
+	for i, product := range mgProductsArray {
+		productIDs[i] = product.ID
+	}
+	ratings, err := p.getRatings(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]domain.Product, len(mgProductsArray))
+	for i, product := range mgProductsArray {
+		products[i] = product.ToDomain()
+		products[i].Rating = ratings[product.ID].Rating
+		products[i].ReviewCount = ratings[product.ID].ReviewCount
+	}
+	if err = p.loadImages(ctx, products); err != nil {
+		return nil, err
+	}
+	if err = p.localize(ctx, products, locale); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// searchScore is the best text score of a product in a collection.
+type searchScore struct {
+	ID    string  `bson:"_id"`
+	Score float64 `bson:"score"`
+}
+
+// searchScores returns the best limit products matching the text search
+// filter on the collection by their best text score, productID is the
+// expression of the product id in the documents.
+func searchScores(ctx context.Context, collection *mongo.Collection, limit int64, filter bson.M, productID string) ([]searchScore, error) {
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{"$match", filter}},
+		{{"$addFields", bson.M{"score": bson.M{"$meta": "textScore"}}}},
+		{{"$group", bson.M{"_id": productID, "score": bson.M{"$max": "$score"}}}},
+		{{"$sort", bson.D{{"score", -1}, {"_id", 1}}}},
+		{{"$limit", limit}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var scores []searchScore
+	if err = cursor.All(ctx, &scores); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return scores, nil
+}
+
+// localize replaces the names and the descriptions of the products with
+// their translations to the locale with a single query, trying the locales
+// of i18n.LocaleChain in order.
+func (p *MongoProductRepo) localize(ctx context.Context, products []domain.Product, locale string) error {
+	if len(products) == 0 {
+		return nil
+	}
+	productIDs := make([]string, len(products))
+	for i, product := range products {
+		productIDs[i] = product.ID.String()
+	}
+
+	chain := i18n.LocaleChain(locale)
+	cursor, err := p.db.Database().Collection(ProductTranslationCollection).Find(ctx, bson.M{
+		"product_id": bson.M{"$in": productIDs},
+		"locale":     bson.M{"$in": chain},
+	})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgTranslations []entity.MgProductTranslation
+	if err = cursor.All(ctx, &mgTranslations); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	translations := make(map[domain.ID]map[string]domain.ProductTranslation)
+	for _, mgTranslation := range mgTranslations {
+		translation := mgTranslation.ToDomain()
+		if translations[translation.ProductID] == nil {
+			translations[translation.ProductID] = make(map[string]domain.ProductTranslation)
+		}
+		translations[translation.ProductID][translation.Locale] = translation
+	}
+	for i := range products {
+		for _, candidate := range chain {
+			if translation, ok := translations[products[i].ID][candidate]; ok {
+				products[i].Name = translation.Name
+				products[i].Description = translation.Description
+				break
+			}
+		}
+	}
 	return nil
 }
+
+// loadImages fills the ordered image galleries of the products with a
+// single query.
+func (p *MongoProductRepo) loadImages(ctx context.Context, products []domain.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	productIDs := make([]string, len(products))
+	for i, product := range products {
+		productIDs[i] = product.ID.String()
+	}
+
+	cursor, err := p.db.Database().Collection(ProductImageCollection).Find(ctx,
+		bson.M{"product_id": bson.M{"$in": productIDs}},
+		options.Find().SetSort(bson.D{{"position", 1}, {"_id", 1}}))
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgImages []entity.MgProductImage
+	if err = cursor.All(ctx, &mgImages); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	images := make(map[domain.ID][]domain.ProductImage)
+	for _, mgImage := range mgImages {
+		image := mgImage.ToDomain()
+		images[image.ProductID] = append(images[image.ProductID], image)
+	}
+	for i := range products {
+		products[i].Images = images[products[i].ID]
+	}
+	return nil
+}
+
+// getRatings aggregates average rating and review count for the given
+// products with a single query.
+func (p *MongoProductRepo) getRatings(ctx context.Context, productIDs []string) (map[string]entity.MgProductRating, error) {
+	cursor, err := p.db.Database().Collection(ReviewCollection).Aggregate(ctx, mongo.Pipeline{
+		{{"$match", bson.M{"product_id": bson.M{"$in": productIDs}}}},
+		{{"$group", bson.M{"_id": "$product_id", "rating": bson.M{"$avg": "$rating"}, "review_count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgRatings []entity.MgProductRating
+	err = cursor.All(ctx, &mgRatings)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings := make(map[string]entity.MgProductRating, len(mgRatings))
+	for _, rating := range mgRatings {
+		ratings[rating.ProductID] = rating
+	}
+	return ratings, nil
+}
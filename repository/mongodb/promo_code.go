@@ -0,0 +1,217 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+type MongoPromoCodeRepo struct {
+	db *mongo.Collection
+}
+
+func NewPromoCodeRepo(db *mongo.Database) *MongoPromoCodeRepo {
+	collection := db.Collection(PromoCodeCollection)
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{"code", 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create promo code collection index, %v", err)
+	}
+
+	indexModel = mongo.IndexModel{
+		Keys: bson.D{{"promo_code_id", 1}, {"customer_id", 1}},
+	}
+
+	_, err = db.Collection(OrderCustomerCollection).Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create OrderCustomerCollection promo code index, %v", err)
+	}
+
+	return &MongoPromoCodeRepo{
+		db: collection,
+	}
+}
+
+func (p *MongoPromoCodeRepo) GetByID(ctx context.Context, promoCodeID domain.ID) (domain.PromoCode, error) {
+	result := p.db.FindOne(ctx, bson.M{"_id": promoCodeID})
+
+	var mgPromoCode entity.MgPromoCode
+	if err := result.Decode(&mgPromoCode); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.PromoCode{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.PromoCode{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgPromoCode.ToDomain(), nil
+}
+
+func (p *MongoPromoCodeRepo) GetByCode(ctx context.Context, code string) (domain.PromoCode, error) {
+	result := p.db.FindOne(ctx, bson.M{"code": code})
+
+	var mgPromoCode entity.MgPromoCode
+	if err := result.Decode(&mgPromoCode); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.PromoCode{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.PromoCode{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgPromoCode.ToDomain(), nil
+}
+
+// checkPromoCodeCurrency requires a currency for fixed amount codes, the
+// currency of a percentage code is not stored.
+func checkPromoCodeCurrency(promoCode *domain.PromoCode) error {
+	if promoCode.Type != domain.PromoCodeFixed {
+		promoCode.Currency = ""
+		return nil
+	}
+	if !currency.Valid(promoCode.Currency) {
+		return errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+	return nil
+}
+
+func (p *MongoPromoCodeRepo) Create(ctx context.Context, promoCode domain.PromoCode) (domain.PromoCode, error) {
+	if err := checkPromoCodeCurrency(&promoCode); err != nil {
+		return domain.PromoCode{}, err
+	}
+
+	var mgPromoCode = entity.NewMgPromoCode(promoCode)
+	_, err := p.db.InsertOne(ctx, mgPromoCode)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.PromoCode{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.PromoCode{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return p.GetByID(ctx, promoCode.ID)
+}
+
+func (p *MongoPromoCodeRepo) Update(ctx context.Context, promoCode domain.PromoCode) (domain.PromoCode, error) {
+	if err := checkPromoCodeCurrency(&promoCode); err != nil {
+		return domain.PromoCode{}, err
+	}
+
+	var mgPromoCode = entity.NewMgPromoCode(promoCode)
+	_, err := p.db.ReplaceOne(ctx, bson.M{"_id": mgPromoCode.ID}, mgPromoCode)
+	if err != nil {
+		return domain.PromoCode{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+
+	return p.GetByID(ctx, promoCode.ID)
+}
+
+// Delete fails for codes that were already redeemed, orders keep a
+// reference to the code they used.
+func (p *MongoPromoCodeRepo) Delete(ctx context.Context, promoCodeID domain.ID) error {
+	count, err := p.db.Database().Collection(OrderCustomerCollection).CountDocuments(ctx, bson.M{"promo_code_id": promoCodeID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	if count != 0 {
+		return errors.Wrap(domain.ErrDeleteFailed, "promo code is used by orders")
+	}
+
+	_, err = p.db.DeleteOne(ctx, bson.M{"_id": promoCodeID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// redeemPromoCode validates the promo code of the order against its validity
+// window and usage limits, counts one use and returns the discount. The
+// discount is computed from current item prices, limited to the items of the
+// promo code shop when the code is shop scoped. A fixed amount code only
+// applies to orders in its currency. It runs in the transaction of the order,
+// a rejected order gives the use back.
+func redeemPromoCode(ctx context.Context, db *mongo.Database, orderCustomer domain.OrderCustomer) (int64, error) {
+	// the use is taken first, concurrent orders with the code write the same
+	// document and their transactions are retried one after another, so the
+	// per user count below sees the orders committed before. The write
+	// conflict is returned unwrapped to keep the label WithTransaction
+	// retries on.
+	var mgPromoCode entity.MgPromoCode
+	err := db.Collection(PromoCodeCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": orderCustomer.PromoCodeID, "$or": bson.A{
+			bson.M{"max_uses": 0},
+			bson.M{"$expr": bson.M{"$lt": bson.A{"$used_count", "$max_uses"}}},
+		}},
+		bson.M{"$inc": bson.M{"used_count": 1}}).Decode(&mgPromoCode)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			count, err := db.Collection(PromoCodeCollection).CountDocuments(ctx, bson.M{"_id": orderCustomer.PromoCodeID})
+			if err != nil {
+				return 0, wrapTxError(domain.ErrPersistenceFailed, err)
+			}
+			if count == 0 {
+				return 0, errors.Wrap(domain.ErrNotExist, "promo code does not exist")
+			}
+			return 0, errors.Wrap(domain.ErrNotAllowed, "promo code is exhausted")
+		}
+		return 0, wrapTxError(domain.ErrUpdateFailed, err)
+	}
+	promoCode := mgPromoCode.ToDomain()
+
+	now := time.Now().UTC()
+	if now.Before(promoCode.ValidFrom) || now.After(promoCode.ValidTo) {
+		return 0, errors.Wrap(domain.ErrNotAllowed, "promo code is not active")
+	}
+	if promoCode.Type == domain.PromoCodeFixed && promoCode.Currency != orderCustomer.Currency {
+		return 0, errors.Wrap(domain.ErrNotAllowed, "promo code is in another currency")
+	}
+	if promoCode.MaxUsesPerUser != 0 {
+		uses, err := db.Collection(OrderCustomerCollection).CountDocuments(ctx,
+			bson.M{"promo_code_id": promoCode.ID, "customer_id": orderCustomer.CustomerID})
+		if err != nil {
+			return 0, wrapTxError(domain.ErrPersistenceFailed, err)
+		}
+		if uses >= promoCode.MaxUsesPerUser {
+			return 0, errors.Wrap(domain.ErrNotAllowed, "promo code is exhausted for the user")
+		}
+	}
+
+	var amount int64
+	for _, orderShop := range orderCustomer.OrderShops {
+		if promoCode.ShopID != "" && promoCode.ShopID != orderShop.ShopID {
+			continue
+		}
+		for _, item := range orderShop.OrderShopItems {
+			price, _, err := getItemPrice(ctx, db, item.ProductID.String(), item.VariantID.String())
+			if err != nil {
+				return 0, err
+			}
+			amount += price * item.Quantity
+		}
+	}
+	if amount == 0 {
+		return 0, errors.Wrap(domain.ErrNotAllowed, "promo code does not apply to the order")
+	}
+
+	return promoCodeDiscount(promoCode, amount), nil
+}
+
+func promoCodeDiscount(promoCode domain.PromoCode, amount int64) int64 {
+	switch promoCode.Type {
+	case domain.PromoCodePercentage:
+		return amount * promoCode.Value / 100
+	case domain.PromoCodeFixed:
+		if promoCode.Value < amount {
+			return promoCode.Value
+		}
+		return amount
+	}
+	return 0
+}
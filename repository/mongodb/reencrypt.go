@@ -0,0 +1,202 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"regexp"
+)
+
+// ReencryptPII encrypts the personal data stored in plaintext or with an
+// older key with the current key and fills in the email and phone blind
+// indexes of the users stored before they existed. The personal data are the
+// email and phone of the users, the shop requisites, the user addresses and
+// the address and address snapshot of the orders. It runs with the
+// migrations, run it again after every key rotation. Without a key provider
+// it only fills in the missing blind indexes. It only touches the documents
+// not encrypted with the current key yet, so it is safe to run again.
+func ReencryptPII(ctx context.Context, db *mongo.Database) error {
+	prefix := encryption.CurrentPrefix()
+	if err := reencryptUsers(ctx, db, prefix); err != nil {
+		return err
+	}
+	if prefix == "" {
+		return nil
+	}
+	if err := reencryptShops(ctx, db, prefix); err != nil {
+		return err
+	}
+	if err := reencryptUserAddresses(ctx, db, prefix); err != nil {
+		return err
+	}
+	return reencryptOrderCustomers(ctx, db, prefix)
+}
+
+// notEncryptedFilter matches the documents with a non-empty field that is not
+// encrypted with the key of the prefix.
+func notEncryptedFilter(field, prefix string) bson.M {
+	return bson.M{field: bson.M{
+		"$gt":  "",
+		"$not": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefix)},
+	}}
+}
+
+func reencryptUsers(ctx context.Context, db *mongo.Database, prefix string) error {
+	filter := bson.A{
+		bson.M{"email_hash": bson.M{"$exists": false}},
+		bson.M{"phone": bson.M{"$exists": true}, "phone_hash": bson.M{"$exists": false}},
+	}
+	if prefix != "" {
+		filter = append(filter, notEncryptedFilter("email", prefix))
+	}
+	collection := db.Collection(UserCollection)
+	cursor, err := collection.Find(ctx, bson.M{"$or": filter})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var mgUser entity.MgUser
+		if err = cursor.Decode(&mgUser); err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		user, err := mgUser.ToDomain()
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if mgUser, err = entity.NewMgUser(user); err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		set := bson.M{"email": mgUser.Email, "email_hash": mgUser.EmailHash}
+		if user.Phone.Valid {
+			set["phone"] = mgUser.Phone
+			set["phone_hash"] = mgUser.PhoneHash
+		}
+		if _, err = collection.UpdateOne(ctx, bson.M{"_id": mgUser.ID}, bson.M{"$set": set}); err != nil {
+			return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
+
+func reencryptShops(ctx context.Context, db *mongo.Database, prefix string) error {
+	collection := db.Collection(ShopCollection)
+	cursor, err := collection.Find(ctx, notEncryptedFilter("requisites", prefix))
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var mgShop entity.MgShop
+		if err = cursor.Decode(&mgShop); err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		shop, err := mgShop.ToDomain()
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if mgShop, err = entity.NewMgShop(shop); err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": mgShop.ID}, bson.M{"$set": bson.M{"requisites": mgShop.Requisites}})
+		if err != nil {
+			return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
+
+func reencryptOrderCustomers(ctx context.Context, db *mongo.Database, prefix string) error {
+	collection := db.Collection(OrderCustomerCollection)
+	cursor, err := collection.Find(ctx, bson.M{"$or": bson.A{
+		notEncryptedFilter("address", prefix),
+		notEncryptedFilter("shipping_address.recipient", prefix),
+		notEncryptedFilter("shipping_address.phone", prefix),
+		notEncryptedFilter("shipping_address.street", prefix),
+		notEncryptedFilter("shipping_address.postal_code", prefix),
+	}})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var mgOrderCustomer entity.MgOrderCustomer
+		if err = cursor.Decode(&mgOrderCustomer); err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		orderCustomer, err := mgOrderCustomer.ToDomain()
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if mgOrderCustomer, err = entity.NewMgOrderCustomer(orderCustomer); err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		update := bson.M{"address": mgOrderCustomer.Address}
+		if mgOrderCustomer.ShippingAddress != nil {
+			update["shipping_address"] = mgOrderCustomer.ShippingAddress
+		}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": mgOrderCustomer.ID}, bson.M{"$set": update})
+		if err != nil {
+			return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
+
+func reencryptUserAddresses(ctx context.Context, db *mongo.Database, prefix string) error {
+	collection := db.Collection(UserAddressCollection)
+	cursor, err := collection.Find(ctx, bson.M{"$or": bson.A{
+		notEncryptedFilter("recipient", prefix),
+		notEncryptedFilter("phone", prefix),
+		notEncryptedFilter("street", prefix),
+		notEncryptedFilter("postal_code", prefix),
+	}})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var mgUserAddress entity.MgUserAddress
+		if err = cursor.Decode(&mgUserAddress); err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		address, err := mgUserAddress.ToDomain()
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if mgUserAddress, err = entity.NewMgUserAddress(address); err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": mgUserAddress.ID}, bson.M{"$set": bson.M{
+			"recipient":   mgUserAddress.Recipient,
+			"phone":       mgUserAddress.Phone,
+			"street":      mgUserAddress.Street,
+			"postal_code": mgUserAddress.PostalCode,
+		}})
+		if err != nil {
+			return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+	}
+	if err = cursor.Err(); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
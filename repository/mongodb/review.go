@@ -0,0 +1,146 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+)
+
+type MongoReviewRepo struct {
+	db *mongo.Collection
+}
+
+func NewReviewRepo(db *mongo.Database) *MongoReviewRepo {
+	collection := db.Collection(ReviewCollection)
+	indexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"product_id", 1}, {"user_id", 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{"product_id", 1}, {"created_at", -1}},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(context.Background(), indexModels)
+	if err != nil {
+		log.Fatalf("unable to create review collection index, %v", err)
+	}
+
+	return &MongoReviewRepo{
+		db: collection,
+	}
+}
+
+func (r *MongoReviewRepo) GetByID(ctx context.Context, reviewID domain.ID) (domain.Review, error) {
+	result := r.db.FindOne(ctx, bson.M{"_id": reviewID})
+
+	var mgReview entity.MgReview
+	if err := result.Decode(&mgReview); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.Review{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.Review{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgReview.ToDomain(), nil
+}
+
+func (r *MongoReviewRepo) GetByProductID(ctx context.Context, productID domain.ID, limit, offset int64) ([]domain.Review, error) {
+	opts := options.Find().SetSort(bson.D{{"created_at", -1}, {"_id", 1}}).SetSkip(offset).SetLimit(limit)
+	cursor, err := r.db.Find(ctx, bson.M{"product_id": productID}, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgReviews []entity.MgReview
+	err = cursor.All(ctx, &mgReviews)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews := make([]domain.Review, len(mgReviews))
+	for i, review := range mgReviews {
+		reviews[i] = review.ToDomain()
+	}
+	return reviews, nil
+}
+
+// Create stores the review only if the user has bought the product, i.e.
+// has it in an order shop with Done status.
+func (r *MongoReviewRepo) Create(ctx context.Context, review domain.Review) (domain.Review, error) {
+	if review.Rating < 1 || review.Rating > 5 {
+		return domain.Review{}, errors.Wrap(domain.ErrNotAllowed, "rating must be between 1 and 5")
+	}
+
+	session, err := r.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.Review{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		purchased, err := r.isPurchased(sessionContext, review.ProductID, review.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !purchased {
+			return nil, errors.Wrap(domain.ErrNotAllowed, "product was not purchased by the user")
+		}
+
+		var mgReview = entity.NewMgReview(review)
+		_, err = r.db.InsertOne(sessionContext, mgReview)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errors.Wrap(domain.ErrDuplicate, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return domain.Review{}, err
+	}
+
+	return r.GetByID(ctx, review.ID)
+}
+
+func (r *MongoReviewRepo) Delete(ctx context.Context, reviewID domain.ID) error {
+	_, err := r.db.DeleteOne(ctx, bson.M{"_id": reviewID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+func (r *MongoReviewRepo) isPurchased(ctx context.Context, productID, userID domain.ID) (bool, error) {
+	orderCustomerIDs, err := r.db.Database().Collection(OrderCustomerCollection).Distinct(ctx, "_id",
+		bson.M{"customer_id": userID})
+	if err != nil {
+		return false, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if len(orderCustomerIDs) == 0 {
+		return false, nil
+	}
+
+	orderShopIDs, err := r.db.Database().Collection(OrderShopCollection).Distinct(ctx, "_id",
+		bson.M{"order_customer_id": bson.M{"$in": orderCustomerIDs}, "status": entity.MgOrderShopDone})
+	if err != nil {
+		return false, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if len(orderShopIDs) == 0 {
+		return false, nil
+	}
+
+	count, err := r.db.Database().Collection(OrderShopProductCollection).CountDocuments(ctx,
+		bson.M{"order_shop_id": bson.M{"$in": orderShopIDs}, "product_id": productID})
+	if err != nil {
+		return false, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return count > 0, nil
+}
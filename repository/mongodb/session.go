@@ -0,0 +1,182 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+// SessionRevokedTTL is how long a revoked session is kept before the TTL
+// index removes it, expired sessions are removed right after expiry.
+const SessionRevokedTTL = 7 * 24 * time.Hour
+
+type MongoSessionRepo struct {
+	db *mongo.Collection
+}
+
+func NewSessionRepo(db *mongo.Database) *MongoSessionRepo {
+	collection := db.Collection(SessionCollection)
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{"token_hash", 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create session collection index, %v", err)
+	}
+
+	userIndexModel := mongo.IndexModel{
+		Keys: bson.D{{"user_id", 1}, {"created_at", -1}},
+	}
+	_, err = collection.Indexes().CreateOne(context.Background(), userIndexModel)
+	if err != nil {
+		log.Fatalf("unable to create session collection index, %v", err)
+	}
+
+	ttlIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{"expires_at", 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	_, err = collection.Indexes().CreateOne(context.Background(), ttlIndexModel)
+	if err != nil {
+		log.Fatalf("unable to create session collection ttl index, %v", err)
+	}
+
+	revokedTTLIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{"revoked_at", 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(SessionRevokedTTL.Seconds())),
+	}
+	_, err = collection.Indexes().CreateOne(context.Background(), revokedTTLIndexModel)
+	if err != nil {
+		log.Fatalf("unable to create session collection ttl index, %v", err)
+	}
+
+	return &MongoSessionRepo{
+		db: collection,
+	}
+}
+
+func (s *MongoSessionRepo) GetByID(ctx context.Context, sessionID domain.ID) (domain.Session, error) {
+	var mgSession entity.MgSession
+	if err := s.db.FindOne(ctx, bson.M{"_id": sessionID}).Decode(&mgSession); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.Session{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.Session{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgSession.ToDomain(), nil
+}
+
+// GetByRefreshToken returns the active session issued for the refresh token,
+// revoked and expired sessions are reported as not existing.
+func (s *MongoSessionRepo) GetByRefreshToken(ctx context.Context, refreshToken string) (domain.Session, error) {
+	filter := bson.M{
+		"token_hash": encryption.HashToken(refreshToken),
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now().UTC()},
+	}
+	var mgSession entity.MgSession
+	if err := s.db.FindOne(ctx, filter).Decode(&mgSession); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.Session{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.Session{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgSession.ToDomain(), nil
+}
+
+// GetByUserID returns the active sessions of the user, the newest first.
+func (s *MongoSessionRepo) GetByUserID(ctx context.Context, userID domain.ID) ([]domain.Session, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now().UTC()},
+	}
+	opts := options.Find().SetSort(bson.D{{"created_at", -1}, {"_id", 1}})
+	cursor, err := s.db.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgSessions []entity.MgSession
+	if err = cursor.All(ctx, &mgSessions); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	sessions := make([]domain.Session, len(mgSessions))
+	for i, mgSession := range mgSessions {
+		sessions[i] = mgSession.ToDomain()
+	}
+	return sessions, nil
+}
+
+// Create stores the session with the hash of refreshToken, the TokenHash of
+// session is ignored.
+func (s *MongoSessionRepo) Create(ctx context.Context, session domain.Session, refreshToken string) (domain.Session, error) {
+	count, err := s.db.Database().Collection(UserCollection).CountDocuments(ctx, bson.M{"_id": session.UserID})
+	if err != nil {
+		return domain.Session{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if count == 0 {
+		return domain.Session{}, errors.Wrap(domain.ErrNotExist, "user not found")
+	}
+
+	var mgSession = entity.NewMgSession(session)
+	mgSession.TokenHash = encryption.HashToken(refreshToken)
+	mgSession.CreatedAt = time.Now().UTC()
+	mgSession.ExpiresAt = session.ExpiresAt.UTC()
+	mgSession.RevokedAt = nil
+	_, err = s.db.InsertOne(ctx, mgSession)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.Session{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Session{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return s.GetByID(ctx, session.ID)
+}
+
+func (s *MongoSessionRepo) Revoke(ctx context.Context, sessionID domain.ID) error {
+	filter := bson.M{"_id": sessionID, "revoked_at": bson.M{"$exists": false}}
+	res, err := s.db.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now().UTC()}})
+	if err != nil {
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if res.MatchedCount == 0 {
+		return errors.Wrap(domain.ErrNotExist, "active session not found")
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every active session of the user and returns how
+// many sessions were revoked.
+func (s *MongoSessionRepo) RevokeAllForUser(ctx context.Context, userID domain.ID) (int64, error) {
+	filter := bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	res, err := s.db.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now().UTC()}})
+	if err != nil {
+		return 0, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	return res.ModifiedCount, nil
+}
+
+// PurgeExpired removes the sessions that expired or were revoked before
+// olderThan. The TTL indexes do the same on their own.
+func (s *MongoSessionRepo) PurgeExpired(ctx context.Context, olderThan time.Time) (int64, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"expires_at": bson.M{"$lt": olderThan.UTC()}},
+		bson.M{"revoked_at": bson.M{"$lt": olderThan.UTC()}},
+	}}
+	res, err := s.db.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,232 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+type MongoShipmentRepo struct {
+	db *mongo.Collection
+}
+
+func NewShipmentRepo(db *mongo.Database) *MongoShipmentRepo {
+	collection := db.Collection(ShipmentCollection)
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{"order_shop_id", 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create shipment collection index, %v", err)
+	}
+
+	overdueIndexModel := mongo.IndexModel{
+		Keys: bson.D{{"delivered_at", 1}, {"shipped_at", 1}},
+	}
+	_, err = collection.Indexes().CreateOne(context.Background(), overdueIndexModel)
+	if err != nil {
+		log.Fatalf("unable to create shipment collection index, %v", err)
+	}
+
+	eventIndexModel := mongo.IndexModel{
+		Keys: bson.D{{"shipment_id", 1}, {"occurred_at", 1}},
+	}
+	_, err = db.Collection(ShipmentEventCollection).Indexes().CreateOne(context.Background(), eventIndexModel)
+	if err != nil {
+		log.Fatalf("unable to create shipment event collection index, %v", err)
+	}
+
+	return &MongoShipmentRepo{
+		db: collection,
+	}
+}
+
+// loadEvents fills the event timelines of the shipments with a single query.
+func (s *MongoShipmentRepo) loadEvents(ctx context.Context, shipments []domain.Shipment) error {
+	if len(shipments) == 0 {
+		return nil
+	}
+	shipmentIDs := make([]string, len(shipments))
+	for i, shipment := range shipments {
+		shipmentIDs[i] = shipment.ID.String()
+	}
+
+	cursor, err := s.db.Database().Collection(ShipmentEventCollection).Find(ctx,
+		bson.M{"shipment_id": bson.M{"$in": shipmentIDs}},
+		options.Find().SetSort(bson.D{{"occurred_at", 1}, {"_id", 1}}))
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgEvents []entity.MgShipmentEvent
+	if err = cursor.All(ctx, &mgEvents); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	events := make(map[domain.ID][]domain.ShipmentEvent)
+	for _, mgEvent := range mgEvents {
+		event := mgEvent.ToDomain()
+		events[event.ShipmentID] = append(events[event.ShipmentID], event)
+	}
+	for i := range shipments {
+		shipments[i].Events = events[shipments[i].ID]
+	}
+	return nil
+}
+
+func (s *MongoShipmentRepo) getShipment(ctx context.Context, filter bson.M) (domain.Shipment, error) {
+	var mgShipment entity.MgShipment
+	if err := s.db.FindOne(ctx, filter).Decode(&mgShipment); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.Shipment{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.Shipment{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	shipments := []domain.Shipment{mgShipment.ToDomain()}
+	if err := s.loadEvents(ctx, shipments); err != nil {
+		return domain.Shipment{}, err
+	}
+	return shipments[0], nil
+}
+
+func (s *MongoShipmentRepo) GetByID(ctx context.Context, shipmentID domain.ID) (domain.Shipment, error) {
+	return s.getShipment(ctx, bson.M{"_id": shipmentID})
+}
+
+func (s *MongoShipmentRepo) GetByOrderShopID(ctx context.Context, orderShopID domain.ID) (domain.Shipment, error) {
+	return s.getShipment(ctx, bson.M{"order_shop_id": orderShopID})
+}
+
+// GetOverdueShipments returns the shipments that were shipped before
+// olderThan and are still not delivered, the oldest first.
+func (s *MongoShipmentRepo) GetOverdueShipments(ctx context.Context, olderThan time.Time) ([]domain.Shipment, error) {
+	filter := bson.M{"delivered_at": bson.M{"$exists": false}, "shipped_at": bson.M{"$lt": olderThan.UTC()}}
+	opts := options.Find().SetSort(bson.D{{"shipped_at", 1}, {"_id", 1}})
+	cursor, err := s.db.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgShipments []entity.MgShipment
+	if err = cursor.All(ctx, &mgShipments); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	shipments := make([]domain.Shipment, len(mgShipments))
+	for i, mgShipment := range mgShipments {
+		shipments[i] = mgShipment.ToDomain()
+	}
+	if err = s.loadEvents(ctx, shipments); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// Create registers the shipment of an order shop that is Ready or Done.
+// The shipped and delivered timestamps are only set by AppendEvent.
+func (s *MongoShipmentRepo) Create(ctx context.Context, shipment domain.Shipment) (domain.Shipment, error) {
+	var mgOrderShop entity.MgOrderShop
+	err := s.db.Database().Collection(OrderShopCollection).FindOne(ctx, bson.M{"_id": shipment.OrderShopID}).Decode(&mgOrderShop)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.Shipment{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.Shipment{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if mgOrderShop.Status == entity.MgOrderShopStart {
+		return domain.Shipment{}, errors.Wrap(domain.ErrNotAllowed, "order shop is not ready")
+	}
+
+	mgShipment := entity.NewMgShipment(shipment)
+	mgShipment.ShippedAt = nil
+	mgShipment.DeliveredAt = nil
+	mgShipment.CreatedAt = time.Now().UTC()
+	_, err = s.db.InsertOne(ctx, mgShipment)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.Shipment{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Shipment{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return s.GetByID(ctx, shipment.ID)
+}
+
+// Update changes the carrier and the tracking number of the shipment.
+func (s *MongoShipmentRepo) Update(ctx context.Context, shipment domain.Shipment) (domain.Shipment, error) {
+	update := bson.M{"$set": bson.M{"carrier": shipment.Carrier, "tracking_number": shipment.TrackingNumber}}
+	res, err := s.db.UpdateOne(ctx, bson.M{"_id": shipment.ID}, update)
+	if err != nil {
+		return domain.Shipment{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if res.MatchedCount == 0 {
+		return domain.Shipment{}, errors.Wrap(domain.ErrNotExist, "shipment not found")
+	}
+
+	return s.GetByID(ctx, shipment.ID)
+}
+
+// AppendEvent adds the event to the shipment timeline. Shipped and Delivered
+// events also set the matching shipment timestamps, a delivered shipment
+// accepts no more events. A zero OccurredAt means now.
+func (s *MongoShipmentRepo) AppendEvent(ctx context.Context, event domain.ShipmentEvent) (domain.Shipment, error) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	event.OccurredAt = event.OccurredAt.UTC()
+
+	session, err := s.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.Shipment{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		var mgShipment entity.MgShipment
+		if err := s.db.FindOne(sessionContext, bson.M{"_id": event.ShipmentID}).Decode(&mgShipment); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if mgShipment.DeliveredAt != nil {
+			return nil, errors.Wrap(domain.ErrNotAllowed, "shipment is already delivered")
+		}
+
+		_, err := s.db.Database().Collection(ShipmentEventCollection).InsertOne(sessionContext, entity.NewMgShipmentEvent(event))
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errors.Wrap(domain.ErrDuplicate, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+
+		set := bson.M{}
+		if mgShipment.ShippedAt == nil && (event.Type == domain.ShipmentEventShipped || event.Type == domain.ShipmentEventDelivered) {
+			set["shipped_at"] = event.OccurredAt
+		}
+		if event.Type == domain.ShipmentEventDelivered {
+			set["delivered_at"] = event.OccurredAt
+		}
+		if len(set) == 0 {
+			return nil, nil
+		}
+		if _, err = s.db.UpdateOne(sessionContext, bson.M{"_id": event.ShipmentID}, bson.M{"$set": set}); err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.Shipment{}, err
+	}
+
+	return s.GetByID(ctx, event.ShipmentID)
+}
@@ -3,15 +3,17 @@ package mongodb
 import (
 	"context"
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
+	"time"
 )
 
-type MongoShopRepo struct{
+type MongoShopRepo struct {
 	db *mongo.Collection
 }
 
@@ -27,13 +29,66 @@ func NewShopRepo(db *mongo.Database) *MongoShopRepo {
 		log.Fatalf("unable to create cart product collection index, %v", err)
 	}
 
+	collection = db.Collection(StockMovementCollection)
+	movementIndexModels := []mongo.IndexModel{
+		{
+			Keys: bson.D{{"shop_product_id", 1}, {"created_at", 1}},
+		},
+		{
+			Keys:    bson.D{{"variant_id", 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+
+	_, err = collection.Indexes().CreateMany(context.Background(), movementIndexModels)
+	if err != nil {
+		log.Fatalf("unable to create StockMovementCollection index, %v", err)
+	}
+
+	collection = db.Collection(ShopProductCollection)
+	indexModel = mongo.IndexModel{
+		Keys: bson.D{{"quantity", 1}, {"_id", 1}},
+	}
+
+	_, err = collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create ShopProductCollection quantity index, %v", err)
+	}
+
+	collection = db.Collection(ProductVariantCollection)
+	variantIndexModels := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"sku", 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{"shop_product_id", 1}},
+		},
+	}
+
+	_, err = collection.Indexes().CreateMany(context.Background(), variantIndexModels)
+	if err != nil {
+		log.Fatalf("unable to create ProductVariantCollection index, %v", err)
+	}
+
+	indexModel = mongo.IndexModel{
+		Keys: bson.D{{"moderation_status", 1}},
+	}
+	_, err = db.Collection(ShopCollection).Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create ShopCollection moderation index, %v", err)
+	}
+	createModerationIndex(db)
+
 	return &MongoShopRepo{
 		db: db.Collection(ShopCollection),
 	}
 }
 
+// GetShops lists the shops that are not suspended.
 func (s *MongoShopRepo) GetShops(ctx context.Context, limit, offset int64) ([]domain.Shop, error) {
-	cursor, err := s.db.Find(ctx, bson.M{}, options.Find().SetSkip(offset).SetLimit(limit))
+	filter := bson.M{"moderation_status": bson.M{"$ne": entity.MgShopSuspended}}
+	cursor, err := s.db.Find(ctx, filter, options.Find().SetSkip(offset).SetLimit(limit))
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, errors.Wrap(domain.ErrNotExist, err.Error())
@@ -49,7 +104,9 @@ func (s *MongoShopRepo) GetShops(ctx context.Context, limit, offset int64) ([]do
 
 	shops := make([]domain.Shop, len(mgShopsArray))
 	for i, shop := range mgShopsArray {
-		shops[i] = shop.ToDomain()
+		if shops[i], err = shop.ToDomain(); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
 		shopItems, err := s.getShopItemsByShopID(ctx, shops[i].ID)
 		if err != nil {
 			return nil, err
@@ -71,7 +128,10 @@ func (s *MongoShopRepo) GetShopByID(ctx context.Context, shopID domain.ID) (doma
 		return domain.Shop{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 	}
 
-	shop := mgShop.ToDomain()
+	shop, err := mgShop.ToDomain()
+	if err != nil {
+		return domain.Shop{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
 	shopItems, err := s.getShopItemsByShopID(ctx, shop.ID)
 	if err != nil {
 		return domain.Shop{}, err
@@ -82,7 +142,7 @@ func (s *MongoShopRepo) GetShopByID(ctx context.Context, shopID domain.ID) (doma
 }
 
 func (s *MongoShopRepo) GetShopBySellerID(ctx context.Context, sellerID domain.ID) ([]domain.Shop, error) {
-	cursor, err := s.db.Find(ctx, bson.M{"seller_id":sellerID})
+	cursor, err := s.db.Find(ctx, bson.M{"seller_id": sellerID})
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, errors.Wrap(domain.ErrNotExist, err.Error())
@@ -98,7 +158,9 @@ func (s *MongoShopRepo) GetShopBySellerID(ctx context.Context, sellerID domain.I
 
 	shops := make([]domain.Shop, len(mgShopsArray))
 	for i, shop := range mgShopsArray {
-		shops[i] = shop.ToDomain()
+		if shops[i], err = shop.ToDomain(); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
 		shopItems, err := s.getShopItemsByShopID(ctx, shops[i].ID)
 		if err != nil {
 			return nil, err
@@ -109,9 +171,18 @@ func (s *MongoShopRepo) GetShopBySellerID(ctx context.Context, sellerID domain.I
 	return shops, nil
 }
 
+// CreateShop stores a new shop, the default currency is required and is used
+// for the items and withdraws of the shop that do not set their own.
 func (s *MongoShopRepo) CreateShop(ctx context.Context, shop domain.Shop) (domain.Shop, error) {
-	var mgShop = entity.NewMgShop(shop)
-	_, err := s.db.InsertOne(ctx, mgShop)
+	if !currency.Valid(shop.DefaultCurrency) {
+		return domain.Shop{}, errors.Wrap(domain.ErrNotAllowed, "invalid default currency code")
+	}
+
+	mgShop, err := entity.NewMgShop(shop)
+	if err != nil {
+		return domain.Shop{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	_, err = s.db.InsertOne(ctx, mgShop)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			return domain.Shop{}, errors.Wrap(domain.ErrDuplicate, err.Error())
@@ -122,16 +193,30 @@ func (s *MongoShopRepo) CreateShop(ctx context.Context, shop domain.Shop) (domai
 	return s.GetShopByID(ctx, shop.ID)
 }
 
+// UpdateShop rewrites the shop fields, the moderation status is only changed
+// by SetModerationStatus.
 func (s *MongoShopRepo) UpdateShop(ctx context.Context, shop domain.Shop) (domain.Shop, error) {
-	var mgShop = entity.NewMgShop(shop)
-	_, err := s.db.ReplaceOne(ctx, bson.M{"_id": mgShop.ID}, mgShop)
+	if !currency.Valid(shop.DefaultCurrency) {
+		return domain.Shop{}, errors.Wrap(domain.ErrNotAllowed, "invalid default currency code")
+	}
+
+	current, err := s.GetShopByID(ctx, shop.ID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-		return domain.Shop{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		return domain.Shop{}, err
 	}
+	shop.ModerationStatus = current.ModerationStatus
+
+	mgShop, err := entity.NewMgShop(shop)
+	if err != nil {
+		return domain.Shop{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	_, err = s.db.ReplaceOne(ctx, bson.M{"_id": mgShop.ID}, mgShop)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.Shop{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
 		return domain.Shop{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 	}
-
 
 	return s.GetShopByID(ctx, shop.ID)
 }
@@ -144,8 +229,34 @@ func (s *MongoShopRepo) DeleteShop(ctx context.Context, shopID domain.ID) error
 	return nil
 }
 
+// suspendedShopIDs returns the IDs of the suspended shops, their items are
+// left out of the listings.
+func (s *MongoShopRepo) suspendedShopIDs(ctx context.Context) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := s.db.Find(ctx, bson.M{"moderation_status": entity.MgShopSuspended}, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var mgShops []entity.MgShop
+	if err = cursor.All(ctx, &mgShops); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	shopIDs := make([]string, len(mgShops))
+	for i, mgShop := range mgShops {
+		shopIDs[i] = mgShop.ID
+	}
+	return shopIDs, nil
+}
+
+// GetShopItems lists the items of the shops that are not suspended.
 func (s *MongoShopRepo) GetShopItems(ctx context.Context, limit, offset int64) ([]domain.ShopItem, error) {
-	cursor, err := s.db.Database().Collection(ShopProductCollection).Find(ctx, bson.M{}, options.Find().SetSkip(offset).SetLimit(limit))
+	suspended, err := s.suspendedShopIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"shop_id": bson.M{"$nin": suspended}}
+	cursor, err := s.db.Database().Collection(ShopProductCollection).Find(ctx, filter, options.Find().SetSkip(offset).SetLimit(limit))
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, errors.Wrap(domain.ErrNotExist, err.Error())
@@ -194,7 +305,20 @@ func (s *MongoShopRepo) GetShopItemByProductID(ctx context.Context, productID do
 	return mgShopItem.ToDomain(), nil
 }
 
+// CreateShopItem stores the product and its shop item, a product without a
+// currency is priced in the default currency of the shop.
 func (s *MongoShopRepo) CreateShopItem(ctx context.Context, shopItem domain.ShopItem, product domain.Product) (domain.ShopItem, error) {
+	if product.Currency == "" {
+		shop, err := s.GetShopByID(ctx, shopItem.ShopID)
+		if err != nil {
+			return domain.ShopItem{}, err
+		}
+		product.Currency = shop.DefaultCurrency
+	}
+	if !currency.Valid(product.Currency) {
+		return domain.ShopItem{}, errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+
 	session, err := s.db.Database().Client().StartSession()
 	if err != nil {
 		return domain.ShopItem{}, nil
@@ -202,6 +326,10 @@ func (s *MongoShopRepo) CreateShopItem(ctx context.Context, shopItem domain.Shop
 	defer session.EndSession(ctx)
 
 	err = mongo.WithSession(ctx, session, func(sessionContext mongo.SessionContext) error {
+		if err := checkCategoryExists(sessionContext, s.db.Database(), product.CategoryID); err != nil {
+			return err
+		}
+
 		var mgProduct = entity.NewMgProduct(product)
 		_, err := s.db.Database().Collection(ProductCollection).InsertOne(sessionContext, mgProduct)
 		if err != nil {
@@ -220,6 +348,11 @@ func (s *MongoShopRepo) CreateShopItem(ctx context.Context, shopItem domain.Shop
 			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 		}
 
+		if shopItem.Quantity != 0 {
+			movement := newStockMovement(shopItem.ID, shopItem.Quantity, domain.StockMovementRestock, "")
+			return insertStockMovement(sessionContext, s.db.Database(), movement)
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -230,26 +363,189 @@ func (s *MongoShopRepo) CreateShopItem(ctx context.Context, shopItem domain.Shop
 }
 
 func (s *MongoShopRepo) UpdateShopItem(ctx context.Context, shopItem domain.ShopItem) (domain.ShopItem, error) {
-	var mgShopItem = entity.NewMgShopItem(shopItem)
-	_, err := s.db.Database().Collection(ShopProductCollection).ReplaceOne(ctx, bson.M{"_id": mgShopItem.ID}, mgShopItem)
+	session, err := s.db.Database().Client().StartSession()
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return domain.ShopItem{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		return domain.ShopItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		var mgShopItem = entity.NewMgShopItem(shopItem)
+		var previous entity.MgShopItem
+		err := s.db.Database().Collection(ShopProductCollection).FindOneAndReplace(sessionContext,
+			bson.M{"_id": mgShopItem.ID}, mgShopItem,
+			options.FindOneAndReplace().SetReturnDocument(options.Before)).Decode(&previous)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 		}
-		return domain.ShopItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+
+		if delta := shopItem.Quantity - previous.Quantity; delta != 0 {
+			reason := domain.StockMovementRestock
+			if delta < 0 {
+				reason = domain.StockMovementAdjustment
+			}
+			return nil, insertStockMovement(sessionContext, s.db.Database(), newStockMovement(shopItem.ID, delta, reason, ""))
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		return domain.ShopItem{}, err
 	}
 
 	return s.GetShopItemByID(ctx, shopItem.ID)
 }
 
 func (s *MongoShopRepo) DeleteShopItem(ctx context.Context, shopItemID domain.ID) error {
-	_, err := s.db.Database().Collection(ShopProductCollection).DeleteOne(ctx, bson.M{"_id": shopItemID})
+	variantIDs, err := s.db.Database().Collection(ProductVariantCollection).Distinct(ctx, "_id",
+		bson.M{"shop_product_id": shopItemID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	if err = checkVariantsNotOrdered(ctx, s.db.Database(), variantIDs); err != nil {
+		return err
+	}
+
+	_, err = s.db.Database().Collection(ShopProductCollection).DeleteOne(ctx, bson.M{"_id": shopItemID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	_, err = s.db.Database().Collection(ProductVariantCollection).DeleteMany(ctx, bson.M{"shop_product_id": shopItemID})
 	if err != nil {
 		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
 	}
 	return nil
 }
 
+func (s *MongoShopRepo) GetLowStockItems(ctx context.Context, shopID domain.ID) ([]domain.ShopItem, error) {
+	filter := bson.M{
+		"shop_id": shopID,
+		"$expr":   bson.M{"$lte": bson.A{"$quantity", "$reorder_threshold"}},
+	}
+	cursor, err := s.db.Database().Collection(ShopProductCollection).Find(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgShopItems []entity.MgShopItem
+	err = cursor.All(ctx, &mgShopItems)
+	if err != nil {
+		return nil, err
+	}
+
+	shopItems := make([]domain.ShopItem, len(mgShopItems))
+	for i, shopItem := range mgShopItems {
+		shopItems[i] = shopItem.ToDomain()
+	}
+	return shopItems, nil
+}
+
+// GetOutOfStockProducts returns shop items of all shops with zero quantity
+// ordered by id. An empty cursor starts from the beginning, otherwise only
+// items with id greater than the cursor are returned.
+func (s *MongoShopRepo) GetOutOfStockProducts(ctx context.Context, limit int64, cursor domain.ID) ([]domain.ShopItem, error) {
+	filter := bson.M{"quantity": 0}
+	if cursor != "" {
+		filter["_id"] = bson.M{"$gt": cursor}
+	}
+	result, err := s.db.Database().Collection(ShopProductCollection).Find(ctx, filter,
+		options.Find().SetSort(bson.D{{"_id", 1}}).SetLimit(limit))
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgShopItems []entity.MgShopItem
+	err = result.All(ctx, &mgShopItems)
+	if err != nil {
+		return nil, err
+	}
+
+	shopItems := make([]domain.ShopItem, len(mgShopItems))
+	for i, shopItem := range mgShopItems {
+		shopItems[i] = shopItem.ToDomain()
+	}
+	return shopItems, nil
+}
+
+func (s *MongoShopRepo) GetStockHistory(ctx context.Context, shopItemID domain.ID) ([]domain.StockMovement, error) {
+	cursor, err := s.db.Database().Collection(StockMovementCollection).Find(ctx, bson.M{"shop_product_id": shopItemID},
+		options.Find().SetSort(bson.D{{"created_at", 1}, {"_id", 1}}))
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgStockMovements []entity.MgStockMovement
+	err = cursor.All(ctx, &mgStockMovements)
+	if err != nil {
+		return nil, err
+	}
+
+	movements := make([]domain.StockMovement, len(mgStockMovements))
+	for i, movement := range mgStockMovements {
+		movements[i] = movement.ToDomain()
+	}
+	return movements, nil
+}
+
+// ReconcileStock reports whether the stock_movement ledger of the shop item
+// sums up to its current quantity and the ledger of every variant of the
+// item to the quantity of the variant.
+func (s *MongoShopRepo) ReconcileStock(ctx context.Context, shopItemID domain.ID) (bool, error) {
+	shopItem, err := s.GetShopItemByID(ctx, shopItemID)
+	if err != nil {
+		return false, err
+	}
+
+	cursor, err := s.db.Database().Collection(StockMovementCollection).Aggregate(ctx, mongo.Pipeline{
+		{{"$match", bson.M{"shop_product_id": shopItemID, "variant_id": bson.M{"$exists": false}}}},
+		{{"$group", bson.M{"_id": nil, "total": bson.M{"$sum": "$delta"}}}},
+	})
+	if err != nil {
+		return false, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var sums []struct {
+		Total int64 `bson:"total"`
+	}
+	err = cursor.All(ctx, &sums)
+	if err != nil {
+		return false, err
+	}
+
+	var total int64
+	if len(sums) != 0 {
+		total = sums[0].Total
+	}
+	if total != shopItem.Quantity {
+		return false, nil
+	}
+
+	cursor, err = s.db.Database().Collection(ProductVariantCollection).Aggregate(ctx, mongo.Pipeline{
+		{{"$match", bson.M{"shop_product_id": shopItemID}}},
+		{{"$lookup", bson.M{
+			"from":         StockMovementCollection,
+			"localField":   "_id",
+			"foreignField": "variant_id",
+			"as":           "movements",
+		}}},
+		{{"$match", bson.M{"$expr": bson.M{"$ne": bson.A{"$quantity", bson.M{"$sum": "$movements.delta"}}}}}},
+		{{"$limit", 1}},
+		{{"$project", bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		return false, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mismatched []bson.M
+	if err = cursor.All(ctx, &mismatched); err != nil {
+		return false, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return len(mismatched) == 0, nil
+}
+
 func (s *MongoShopRepo) getShopItemsByShopID(ctx context.Context, shopID domain.ID) ([]domain.ShopItem, error) {
 	cursor, err := s.db.Database().Collection(ShopProductCollection).Find(ctx, bson.M{"shop_id": shopID})
 	if err != nil {
@@ -270,4 +566,243 @@ func (s *MongoShopRepo) getShopItemsByShopID(ctx context.Context, shopID domain.
 		shopItems[i] = shopItem.ToDomain()
 	}
 	return shopItems, nil
-}
\ No newline at end of file
+}
+func (s *MongoShopRepo) GetVariantByID(ctx context.Context, variantID domain.ID) (domain.ProductVariant, error) {
+	result := s.db.Database().Collection(ProductVariantCollection).FindOne(ctx, bson.M{"_id": variantID})
+
+	var mgVariant entity.MgProductVariant
+	if err := result.Decode(&mgVariant); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.ProductVariant{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.ProductVariant{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return mgVariant.ToDomain(), nil
+}
+
+func (s *MongoShopRepo) GetVariantsByShopItemID(ctx context.Context, shopItemID domain.ID) ([]domain.ProductVariant, error) {
+	cursor, err := s.db.Database().Collection(ProductVariantCollection).Find(ctx,
+		bson.M{"shop_product_id": shopItemID}, options.Find().SetSort(bson.D{{"sku", 1}}))
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgVariants []entity.MgProductVariant
+	err = cursor.All(ctx, &mgVariants)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make([]domain.ProductVariant, len(mgVariants))
+	for i, variant := range mgVariants {
+		variants[i] = variant.ToDomain()
+	}
+	return variants, nil
+}
+
+func (s *MongoShopRepo) CreateVariant(ctx context.Context, variant domain.ProductVariant) (domain.ProductVariant, error) {
+	session, err := s.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.ProductVariant{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		count, err := s.db.Database().Collection(ShopProductCollection).CountDocuments(sessionContext, bson.M{"_id": variant.ShopItemID})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if count == 0 {
+			return nil, errors.Wrap(domain.ErrNotExist, "shop item does not exist")
+		}
+
+		var mgVariant = entity.NewMgProductVariant(variant)
+		_, err = s.db.Database().Collection(ProductVariantCollection).InsertOne(sessionContext, mgVariant)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errors.Wrap(domain.ErrDuplicate, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+
+		if variant.Quantity != 0 {
+			movement := newVariantStockMovement(variant, variant.Quantity, domain.StockMovementRestock, "")
+			return nil, insertStockMovement(sessionContext, s.db.Database(), movement)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.ProductVariant{}, err
+	}
+
+	return s.GetVariantByID(ctx, variant.ID)
+}
+
+func (s *MongoShopRepo) UpdateVariant(ctx context.Context, variant domain.ProductVariant) (domain.ProductVariant, error) {
+	session, err := s.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.ProductVariant{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		var mgVariant = entity.NewMgProductVariant(variant)
+		var previous entity.MgProductVariant
+		err := s.db.Database().Collection(ProductVariantCollection).FindOneAndReplace(sessionContext,
+			bson.M{"_id": mgVariant.ID}, mgVariant,
+			options.FindOneAndReplace().SetReturnDocument(options.Before)).Decode(&previous)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errors.Wrap(domain.ErrDuplicate, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+
+		if delta := variant.Quantity - previous.Quantity; delta != 0 {
+			reason := domain.StockMovementRestock
+			if delta < 0 {
+				reason = domain.StockMovementAdjustment
+			}
+			movement := newVariantStockMovement(previous.ToDomain(), delta, reason, "")
+			return nil, insertStockMovement(sessionContext, s.db.Database(), movement)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.ProductVariant{}, err
+	}
+
+	return s.GetVariantByID(ctx, variant.ID)
+}
+
+// DeleteVariant deletes the variant with its stock ledger, a variant that has
+// been ordered stays for the order history.
+func (s *MongoShopRepo) DeleteVariant(ctx context.Context, variantID domain.ID) error {
+	if err := checkVariantsNotOrdered(ctx, s.db.Database(), []interface{}{variantID}); err != nil {
+		return err
+	}
+
+	_, err := s.db.Database().Collection(ProductVariantCollection).DeleteOne(ctx, bson.M{"_id": variantID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	_, err = s.db.Database().Collection(StockMovementCollection).DeleteMany(ctx, bson.M{"variant_id": variantID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// checkVariantsNotOrdered refuses to delete variants that order items refer
+// to, like the restricting foreign key of postgres does.
+func checkVariantsNotOrdered(ctx context.Context, db *mongo.Database, variantIDs []interface{}) error {
+	if len(variantIDs) == 0 {
+		return nil
+	}
+	count, err := db.Collection(OrderShopProductCollection).CountDocuments(ctx,
+		bson.M{"variant_id": bson.M{"$in": variantIDs}}, options.Count().SetLimit(1))
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	if count != 0 {
+		return errors.Wrap(domain.ErrNotAllowed, "variant has been ordered")
+	}
+	return nil
+}
+
+// GetShopsByModerationStatus lists the shops with the status for moderators,
+// suspended shops included.
+func (s *MongoShopRepo) GetShopsByModerationStatus(ctx context.Context, status domain.ShopModerationStatus, limit, offset int64) ([]domain.Shop, error) {
+	filter := bson.M{"moderation_status": entity.NewMgShopModerationStatus(status)}
+	if status == domain.ShopModerationApproved {
+		// shops stored before moderation have no status and count as approved
+		filter = bson.M{"moderation_status": bson.M{"$in": bson.A{entity.MgShopApproved, nil}}}
+	}
+	opts := options.Find().SetSort(bson.D{{"_id", 1}}).SetSkip(offset).SetLimit(limit)
+	cursor, err := s.db.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgShops []entity.MgShop
+	if err = cursor.All(ctx, &mgShops); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	shops := make([]domain.Shop, len(mgShops))
+	for i, mgShop := range mgShops {
+		if shops[i], err = mgShop.ToDomain(); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		shopItems, err := s.getShopItemsByShopID(ctx, shops[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		shops[i].Items = shopItems
+	}
+	return shops, nil
+}
+
+// SetModerationStatus moves the shop given by the TargetID of the action to
+// the status of the action, which must be Pending, Approve or Suspend. The
+// action is added to the moderation history of the shop.
+func (s *MongoShopRepo) SetModerationStatus(ctx context.Context, action domain.ModerationAction) (domain.Shop, error) {
+	var status domain.ShopModerationStatus
+	switch action.Action {
+	case domain.ModerationActionPending:
+		status = domain.ShopModerationPending
+	case domain.ModerationActionApprove:
+		status = domain.ShopModerationApproved
+	case domain.ModerationActionSuspend:
+		status = domain.ShopModerationSuspended
+	default:
+		return domain.Shop{}, errors.Wrap(domain.ErrNotAllowed, "not a shop moderation action")
+	}
+	action.CreatedAt = time.Now().UTC()
+
+	current, err := s.GetShopByID(ctx, action.TargetID)
+	if err != nil {
+		return domain.Shop{}, err
+	}
+	if current.ModerationStatus == status {
+		return domain.Shop{}, errors.Wrap(domain.ErrNotAllowed, "shop already has the moderation status")
+	}
+
+	session, err := s.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.Shop{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		// the filter on the status read above rejects concurrent transitions
+		filter := bson.M{"_id": action.TargetID, "moderation_status": entity.NewMgShopModerationStatus(current.ModerationStatus)}
+		if current.ModerationStatus == domain.ShopModerationApproved {
+			filter["moderation_status"] = bson.M{"$in": bson.A{entity.MgShopApproved, nil}}
+		}
+		res, err := s.db.UpdateOne(sessionContext, filter,
+			bson.M{"$set": bson.M{"moderation_status": entity.NewMgShopModerationStatus(status)}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		if res.MatchedCount == 0 {
+			return nil, errors.Wrap(domain.ErrNotAllowed, "shop moderation status changed concurrently")
+		}
+		return nil, addModerationAction(sessionContext, s.db.Database(), entity.MgModerationTargetShop, action)
+	})
+	if err != nil {
+		return domain.Shop{}, err
+	}
+
+	return s.GetShopByID(ctx, action.TargetID)
+}
+
+// GetModerationHistory returns the moderation actions taken on the shop,
+// the oldest first.
+func (s *MongoShopRepo) GetModerationHistory(ctx context.Context, shopID domain.ID) ([]domain.ModerationAction, error) {
+	return getModerationHistory(ctx, s.db.Database(), entity.MgModerationTargetShop, shopID)
+}
@@ -0,0 +1,247 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/bulkimport"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"sort"
+)
+
+// errImportDryRun aborts the transaction of a dry run batch once its writes
+// succeeded.
+var errImportDryRun = errors.New("import dry run")
+
+// ImportShopItems creates or updates the products and the stock of the shop
+// from a CSV or NDJSON stream. Every batch of bulkimport.BatchSize rows is
+// written in its own transaction with a single BulkWrite per collection,
+// stock changes are recorded in the stock_movement ledger. Rows with an
+// unknown category, an invalid currency or a product sold by another shop
+// are reported and skipped. A dry run runs the same writes and aborts every
+// batch. A batch that fails to write stops the import, the batches before it
+// stay written and the report of the rows read so far is returned with the
+// error.
+func (s *MongoShopRepo) ImportShopItems(ctx context.Context, shopID domain.ID, format domain.ImportFormat, data io.Reader, dryRun bool) (domain.ImportReport, error) {
+	shop, err := s.GetShopByID(ctx, shopID)
+	if err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	report := domain.ImportReport{DryRun: dryRun}
+	err = bulkimport.Read(data, format, bulkimport.BatchSize, func(rows []domain.ImportRow, rowErrors []domain.ImportRowError) error {
+		report.Rows += int64(len(rows) + len(rowErrors))
+		report.Errors = append(report.Errors, rowErrors...)
+		return s.importBatch(ctx, shop, rows, dryRun, &report)
+	})
+
+	sort.Slice(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Line < report.Errors[j].Line
+	})
+	return report, err
+}
+
+// importBatch writes the rows in a transaction. The shop items are read
+// inside it, so an import of the same products committed in the meantime
+// makes the transaction conflict on the product documents and retry with the
+// shop items of the other import, the errors of the reads and writes are
+// returned with their transient label for it. The batch is added to the
+// report once it is written.
+func (s *MongoShopRepo) importBatch(ctx context.Context, shop domain.Shop, rows []domain.ImportRow, dryRun bool, report *domain.ImportReport) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	session, err := s.db.Database().Client().StartSession()
+	if err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	var batch domain.ImportReport
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		batch = domain.ImportReport{}
+		productModels, shopItemModels, movements, err := s.importModels(sessionContext, shop, rows, &batch)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(productModels) != 0 {
+			_, err = s.db.Database().Collection(ProductCollection).BulkWrite(sessionContext, productModels,
+				options.BulkWrite().SetOrdered(false))
+			if err != nil {
+				return nil, wrapTxError(domain.ErrPersistenceFailed, err)
+			}
+
+			_, err = s.db.Database().Collection(ShopProductCollection).BulkWrite(sessionContext, shopItemModels,
+				options.BulkWrite().SetOrdered(false))
+			if err != nil {
+				if mongo.IsDuplicateKeyError(err) {
+					return nil, errors.Wrap(domain.ErrDuplicate, err.Error())
+				}
+				return nil, wrapTxError(domain.ErrPersistenceFailed, err)
+			}
+		}
+
+		if len(movements) != 0 {
+			_, err = s.db.Database().Collection(StockMovementCollection).InsertMany(sessionContext, movements)
+			if err != nil {
+				return nil, wrapTxError(domain.ErrPersistenceFailed, err)
+			}
+		}
+
+		if dryRun {
+			return nil, errImportDryRun
+		}
+		return nil, nil
+	})
+	if err = txError(err); err != nil && err != errImportDryRun {
+		return err
+	}
+
+	report.Errors = append(report.Errors, batch.Errors...)
+	report.Created += batch.Created
+	report.Updated += batch.Updated
+	return nil
+}
+
+// importModels validates the rows against the categories and the shop items
+// and returns the writes of the valid ones, the rows are counted in batch.
+func (s *MongoShopRepo) importModels(ctx context.Context, shop domain.Shop, rows []domain.ImportRow, batch *domain.ImportReport) ([]mongo.WriteModel, []mongo.WriteModel, []interface{}, error) {
+	categories, err := s.getImportCategories(ctx, rows)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	shopItems, foreign, err := s.getImportShopItems(ctx, shop.ID, rows)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var productModels []mongo.WriteModel
+	var shopItemModels []mongo.WriteModel
+	var movements []interface{}
+	for _, row := range rows {
+		if row.Product.Currency == "" {
+			row.Product.Currency = shop.DefaultCurrency
+		}
+		current, exists := shopItems[row.Product.ID]
+		var rowErr string
+		switch {
+		case !currency.Valid(row.Product.Currency):
+			rowErr = "invalid currency code"
+		case !categories[row.Product.CategoryID]:
+			rowErr = "category does not exist"
+		case foreign[row.Product.ID]:
+			rowErr = "product is sold by another shop"
+		}
+		if rowErr != "" {
+			batch.Errors = append(batch.Errors, domain.ImportRowError{Line: row.Line, Error: rowErr})
+			continue
+		}
+
+		mgProduct := entity.NewMgProduct(row.Product)
+		productModels = append(productModels, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": mgProduct.ID}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"name":        mgProduct.Name,
+				"description": mgProduct.Description,
+				"price":       mgProduct.Price,
+				"currency":    mgProduct.Currency,
+				"category_id": mgProduct.CategoryID,
+				"photo_url":   mgProduct.PhotoUrl,
+			}}).
+			SetUpsert(true))
+
+		var movement domain.StockMovement
+		if exists {
+			shopItemModels = append(shopItemModels, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": current.ID}).
+				SetUpdate(bson.M{"$set": bson.M{"quantity": row.Quantity, "reorder_threshold": row.ReorderThreshold}}))
+			if delta := row.Quantity - current.Quantity; delta > 0 {
+				movement = newStockMovement(domain.ID(current.ID), delta, domain.StockMovementRestock, "")
+			} else if delta < 0 {
+				movement = newStockMovement(domain.ID(current.ID), delta, domain.StockMovementAdjustment, "")
+			}
+			batch.Updated++
+		} else {
+			shopItem := domain.ShopItem{
+				ID:               domain.ID(uuid.NewString()),
+				ShopID:           shop.ID,
+				ProductID:        row.Product.ID,
+				Quantity:         row.Quantity,
+				ReorderThreshold: row.ReorderThreshold,
+			}
+			shopItemModels = append(shopItemModels, mongo.NewInsertOneModel().SetDocument(entity.NewMgShopItem(shopItem)))
+			if row.Quantity != 0 {
+				movement = newStockMovement(shopItem.ID, row.Quantity, domain.StockMovementRestock, "")
+			}
+			batch.Created++
+		}
+		if movement.ID != "" {
+			movements = append(movements, entity.NewMgStockMovement(movement))
+		}
+	}
+	return productModels, shopItemModels, movements, nil
+}
+
+// getImportCategories returns which categories of the rows exist.
+func (s *MongoShopRepo) getImportCategories(ctx context.Context, rows []domain.ImportRow) (map[domain.ID]bool, error) {
+	categoryIDs := make([]string, len(rows))
+	for i, row := range rows {
+		categoryIDs[i] = row.Product.CategoryID.String()
+	}
+
+	cursor, err := s.db.Database().Collection(CategoryCollection).Find(ctx,
+		bson.M{"_id": bson.M{"$in": categoryIDs}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, wrapTxError(domain.ErrPersistenceFailed, err)
+	}
+
+	var mgCategories []entity.MgCategory
+	if err = cursor.All(ctx, &mgCategories); err != nil {
+		return nil, wrapTxError(domain.ErrPersistenceFailed, err)
+	}
+
+	categories := make(map[domain.ID]bool, len(mgCategories))
+	for _, category := range mgCategories {
+		categories[domain.ID(category.ID)] = true
+	}
+	return categories, nil
+}
+
+// getImportShopItems returns the items of the shop selling the products of
+// the rows by product and the products other shops sell.
+func (s *MongoShopRepo) getImportShopItems(ctx context.Context, shopID domain.ID, rows []domain.ImportRow) (map[domain.ID]entity.MgShopItem, map[domain.ID]bool, error) {
+	productIDs := make([]string, len(rows))
+	for i, row := range rows {
+		productIDs[i] = row.Product.ID.String()
+	}
+
+	cursor, err := s.db.Database().Collection(ShopProductCollection).Find(ctx,
+		bson.M{"product_id": bson.M{"$in": productIDs}})
+	if err != nil {
+		return nil, nil, wrapTxError(domain.ErrPersistenceFailed, err)
+	}
+
+	var mgShopItems []entity.MgShopItem
+	if err = cursor.All(ctx, &mgShopItems); err != nil {
+		return nil, nil, wrapTxError(domain.ErrPersistenceFailed, err)
+	}
+
+	shopItems := make(map[domain.ID]entity.MgShopItem, len(mgShopItems))
+	foreign := make(map[domain.ID]bool)
+	for _, shopItem := range mgShopItems {
+		if domain.ID(shopItem.ShopID) == shopID {
+			shopItems[domain.ID(shopItem.ProductID)] = shopItem
+		} else {
+			foreign[domain.ID(shopItem.ProductID)] = true
+		}
+	}
+	return shopItems, foreign, nil
+}
@@ -0,0 +1,39 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+func newStockMovement(shopItemID domain.ID, delta int64, reason domain.StockMovementReason, referenceID domain.ID) domain.StockMovement {
+	return domain.StockMovement{
+		ID:          domain.ID(uuid.NewString()),
+		ShopItemID:  shopItemID,
+		Delta:       delta,
+		Reason:      reason,
+		ReferenceID: referenceID,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+// newVariantStockMovement records a change of the variant stock, the variants
+// keep their own quantity apart from the quantity of the shop item.
+func newVariantStockMovement(variant domain.ProductVariant, delta int64, reason domain.StockMovementReason, referenceID domain.ID) domain.StockMovement {
+	movement := newStockMovement(variant.ShopItemID, delta, reason, referenceID)
+	movement.VariantID = variant.ID
+	return movement
+}
+
+func insertStockMovement(ctx context.Context, db *mongo.Database, movement domain.StockMovement) error {
+	var mgStockMovement = entity.NewMgStockMovement(movement)
+	_, err := db.Collection(StockMovementCollection).InsertOne(ctx, mgStockMovement)
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
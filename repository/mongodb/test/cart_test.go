@@ -5,9 +5,11 @@ import (
 	"github.com/EmirShimshir/marketplace-core/domain"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/guregu/null"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/mongo"
 	"testing"
+	"time"
 )
 
 var cartItems = []domain.CartItem{
@@ -16,12 +18,16 @@ var cartItems = []domain.CartItem{
 		CartID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
 		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
 		Quantity:  2,
+		CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
 	},
 	domain.CartItem{
 		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702aa2"),
 		CartID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
 		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
 		Quantity:  1,
+		CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
 	},
 }
 
@@ -41,23 +47,44 @@ var updatedCartItem = domain.CartItem{
 
 var carts = []domain.Cart{
 	domain.Cart{
-		ID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
-		Price: 0,
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
+		Price:     0,
+		Currency:  "RUB",
+		CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
 		Items: []domain.CartItem{
 			cartItems[0],
 			cartItems[1],
 		},
 	},
 	domain.Cart{
-		ID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cd"),
-		Price: 0,
-		Items: []domain.CartItem{},
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cd"),
+		Price:     0,
+		Currency:  "RUB",
+		CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		Items:     []domain.CartItem{},
 	},
 }
 
 var updatedCart = domain.Cart{
-	ID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
-	Price: 2990,
+	ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
+	Price:     262970,
+	Currency:  "RUB",
+	CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	Items: []domain.CartItem{
+		cartItems[0],
+		cartItems[1],
+	},
+}
+
+var recalculatedCart = domain.Cart{
+	ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
+	Price:     262970,
+	Currency:  "RUB",
+	CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
 	Items: []domain.CartItem{
 		cartItems[0],
 		cartItems[1],
@@ -65,9 +92,12 @@ var updatedCart = domain.Cart{
 }
 
 var clearedCart = domain.Cart{
-	ID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
-	Price: 2990,
-	Items: []domain.CartItem{},
+	ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
+	Price:     2990,
+	Currency:  "RUB",
+	CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	Items:     []domain.CartItem{},
 }
 
 func InitCartsMongoDB(ctx context.Context, db *mongo.Database) error {
@@ -94,6 +124,27 @@ func InitCartItemsMongoDB(ctx context.Context, db *mongo.Database) error {
 	return nil
 }
 
+var guestCart = domain.Cart{
+	ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034ce"),
+	GuestToken: null.StringFrom("c9f0f895fb98ab9159f51fd0297e236d"),
+	Items:      []domain.CartItem{},
+}
+
+var guestCartItems = []domain.CartItem{
+	domain.CartItem{
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ab1"),
+		CartID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034ce"),
+		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
+		Quantity:  1,
+	},
+	domain.CartItem{
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ab2"),
+		CartID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034ce"),
+		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
+		Quantity:  1,
+	},
+}
+
 func TestCartRepository(t *testing.T) {
 	ctx := context.Background()
 	container, err := newMongoContainer(ctx)
@@ -130,6 +181,10 @@ func TestCartRepository(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	err = InitProductsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	t.Run("test get product 0", func(t *testing.T) {
 		repo := mongodb.NewCartRepo(db)
@@ -157,8 +212,47 @@ func TestCartRepository(t *testing.T) {
 		}
 	})
 
+	t.Run("test GetCartTotal", func(t *testing.T) {
+		repo := mongodb.NewCartRepo(db)
+		total, err := repo.GetCartTotal(ctx, carts[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetCartTotal: %v", err)
+		}
+		require.Equal(t, recalculatedCart.Price, total)
+	})
+
+	t.Run("test RecalculateCart", func(t *testing.T) {
+		repo := mongodb.NewCartRepo(db)
+		cart, err := repo.RecalculateCart(ctx, carts[0].ID)
+		if err != nil {
+			t.Errorf("failed to RecalculateCart: %v", err)
+		}
+		require.Equal(t, recalculatedCart, cart)
+	})
+
+	t.Run("test FindAbandonedCarts", func(t *testing.T) {
+		repo := mongodb.NewCartRepo(db)
+		olderThan := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+		found, err := repo.FindAbandonedCarts(ctx, olderThan, 100000)
+		if err != nil {
+			t.Errorf("failed to FindAbandonedCarts: %v", err)
+		}
+		require.Equal(t, []domain.Cart{recalculatedCart}, found)
+
+		found, err = repo.FindAbandonedCarts(ctx, olderThan, 300000)
+		if err != nil {
+			t.Errorf("failed to FindAbandonedCarts: %v", err)
+		}
+		require.Equal(t, 0, len(found))
+	})
+
 	t.Run("test update cart", func(t *testing.T) {
 		repo := mongodb.NewCartRepo(db)
+		wrongPrice := updatedCart
+		wrongPrice.Price = 2990
+		_, err = repo.UpdateCart(ctx, wrongPrice)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
 		cart, err := repo.UpdateCart(ctx, updatedCart)
 		if err != nil {
 			t.Errorf("failed to create cart: %v", err)
@@ -185,17 +279,36 @@ func TestCartRepository(t *testing.T) {
 			t.Errorf("failed to CreateCartItem: %v", err)
 		}
 
-		require.Equal(t, createdCartItem, found)
+		require.WithinDuration(t, time.Now(), found.CreatedAt, time.Minute)
+		require.Equal(t, found.CreatedAt, found.UpdatedAt)
+		expected := createdCartItem
+		expected.CreatedAt, expected.UpdatedAt = found.CreatedAt, found.UpdatedAt
+		require.Equal(t, expected, found)
+
+		cart, err := repo.GetCartByID(ctx, createdCartItem.CartID)
+		if err != nil {
+			t.Errorf("failed to get cart: %v", err)
+		}
+		require.Equal(t, found.UpdatedAt, cart.UpdatedAt)
 	})
 	t.Run("test UpdateCartItem", func(t *testing.T) {
 		repo := mongodb.NewCartRepo(db)
 
+		created, err := repo.GetCartItemByID(ctx, updatedCartItem.ID)
+		if err != nil {
+			t.Errorf("failed to GetCartItemByID: %v", err)
+		}
+
 		found, err := repo.UpdateCartItem(ctx, updatedCartItem)
 		if err != nil {
 			t.Errorf("failed to UpdateCartItem: %v", err)
 		}
 
-		require.Equal(t, updatedCartItem, found)
+		require.Equal(t, created.CreatedAt, found.CreatedAt)
+		require.False(t, found.UpdatedAt.Before(created.UpdatedAt))
+		expected := updatedCartItem
+		expected.CreatedAt, expected.UpdatedAt = found.CreatedAt, found.UpdatedAt
+		require.Equal(t, expected, found)
 	})
 	t.Run("test DeleteCartItem", func(t *testing.T) {
 		repo := mongodb.NewCartRepo(db)
@@ -204,5 +317,127 @@ func TestCartRepository(t *testing.T) {
 			t.Errorf("failed to DeleteCartItem: %v", err)
 		}
 	})
-}
 
+	t.Run("test PurgeStaleCartItems", func(t *testing.T) {
+		repo := mongodb.NewCartRepo(db)
+		deleted, err := repo.PurgeStaleCartItems(ctx, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Errorf("failed to PurgeStaleCartItems: %v", err)
+		}
+		require.Equal(t, int64(1), deleted)
+
+		_, err = repo.GetCartItemByID(ctx, createdCartItem.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+	t.Run("test guest cart", func(t *testing.T) {
+		repo := mongodb.NewCartRepo(db)
+		created, err := repo.CreateGuestCart(ctx, guestCart)
+		if err != nil {
+			t.Errorf("failed to CreateGuestCart: %v", err)
+		}
+		require.WithinDuration(t, time.Now(), created.CreatedAt, time.Minute)
+		expected := guestCart
+		expected.CreatedAt, expected.UpdatedAt = created.CreatedAt, created.UpdatedAt
+		require.Equal(t, expected, created)
+
+		found, err := repo.GetCartByGuestToken(ctx, guestCart.GuestToken.String)
+		if err != nil {
+			t.Errorf("failed to GetCartByGuestToken: %v", err)
+		}
+		require.Equal(t, created, found)
+
+		duplicate := guestCart
+		duplicate.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cf")
+		_, err = repo.CreateGuestCart(ctx, duplicate)
+		require.ErrorIs(t, err, domain.ErrDuplicate)
+	})
+	t.Run("test MergeCarts", func(t *testing.T) {
+		repo := mongodb.NewCartRepo(db)
+		_, err := repo.CreateCartItem(ctx, domain.CartItem{
+			ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702aa4"),
+			CartID:    carts[0].ID,
+			ProductID: cartItems[0].ProductID,
+			Quantity:  2,
+		})
+		if err != nil {
+			t.Errorf("failed to CreateCartItem: %v", err)
+		}
+		for _, cartItem := range guestCartItems {
+			_, err = repo.CreateCartItem(ctx, cartItem)
+			if err != nil {
+				t.Errorf("failed to CreateCartItem: %v", err)
+			}
+		}
+
+		merged, err := repo.MergeCarts(ctx, guestCart.ID, carts[0].ID)
+		if err != nil {
+			t.Errorf("failed to MergeCarts: %v", err)
+		}
+		quantities := make(map[domain.ID]int64)
+		for _, cartItem := range merged.Items {
+			require.Equal(t, carts[0].ID, cartItem.CartID)
+			quantities[cartItem.ProductID] = cartItem.Quantity
+		}
+		require.Equal(t, map[domain.ID]int64{
+			cartItems[0].ProductID: 3,
+			cartItems[1].ProductID: 1,
+		}, quantities)
+
+		_, err = repo.GetCartByID(ctx, guestCart.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		_, err = repo.MergeCarts(ctx, carts[0].ID, carts[1].ID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+	t.Run("test CreateCartItem with another currency", func(t *testing.T) {
+		usdProduct := createdProduct
+		usdProduct.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a9")
+		usdProduct.Currency = "USD"
+		_, err := db.Collection(mongodb.ProductCollection).InsertOne(ctx, entity.NewMgProduct(usdProduct))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := mongodb.NewCartRepo(db)
+		usdCart := domain.Cart{
+			ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cf"),
+			GuestToken: null.StringFrom("45c48cce2e2d7fbdea1afc51c7c6ad26"),
+		}
+		_, err = repo.CreateGuestCart(ctx, usdCart)
+		if err != nil {
+			t.Errorf("failed to CreateGuestCart: %v", err)
+		}
+		_, err = repo.CreateCartItem(ctx, domain.CartItem{
+			ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ab7"),
+			CartID:    usdCart.ID,
+			ProductID: usdProduct.ID,
+			Quantity:  1,
+		})
+		if err != nil {
+			t.Errorf("failed to CreateCartItem: %v", err)
+		}
+		cart, err := repo.GetCartByID(ctx, usdCart.ID)
+		if err != nil {
+			t.Errorf("failed to GetCartByID: %v", err)
+		}
+		require.Equal(t, "USD", cart.Currency)
+
+		_, err = repo.CreateCartItem(ctx, domain.CartItem{
+			ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ab8"),
+			CartID:    usdCart.ID,
+			ProductID: products[0].ID,
+			Quantity:  1,
+		})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		_, err = repo.CreateCartItem(ctx, domain.CartItem{
+			ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ab9"),
+			CartID:    carts[0].ID,
+			ProductID: usdProduct.ID,
+			Quantity:  1,
+		})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		_, err = repo.MergeCarts(ctx, usdCart.ID, carts[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+}
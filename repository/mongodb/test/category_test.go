@@ -0,0 +1,133 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+)
+
+var categories = []domain.Category{
+	domain.Category{
+		ID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c001"),
+		Slug: "electronic",
+		Name: "Electronic",
+	},
+	domain.Category{
+		ID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c006"),
+		Slug: "books",
+		Name: "Books",
+	},
+}
+
+var createdCategory = domain.Category{
+	ID:       domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c101"),
+	ParentID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c001"),
+	Slug:     "smartphones",
+	Name:     "Smartphones",
+}
+
+func InitCategoriesMongoDB(ctx context.Context, db *mongo.Database) error {
+	for _, category := range categories {
+		var mgCategory = entity.NewMgCategory(category)
+		_, err := db.Collection(mongodb.CategoryCollection).InsertOne(ctx, mgCategory)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestCategoryRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newMongoContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newMongoDB(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = InitCategoriesMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitProductsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test GetBySlug", func(t *testing.T) {
+		repo := mongodb.NewCategoryRepo(db)
+		found, err := repo.GetBySlug(ctx, categories[0].Slug)
+		if err != nil {
+			t.Errorf("failed to GetBySlug: %v", err)
+		}
+		require.Equal(t, categories[0], found)
+	})
+
+	t.Run("test GetChildren roots", func(t *testing.T) {
+		repo := mongodb.NewCategoryRepo(db)
+		found, err := repo.GetChildren(ctx, "")
+		if err != nil {
+			t.Errorf("failed to GetChildren: %v", err)
+		}
+		require.Equal(t, []domain.Category{categories[1], categories[0]}, found)
+	})
+
+	t.Run("test Create and GetDescendants", func(t *testing.T) {
+		repo := mongodb.NewCategoryRepo(db)
+		category, err := repo.Create(ctx, createdCategory)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.Equal(t, createdCategory, category)
+
+		found, err := repo.GetDescendants(ctx, categories[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetDescendants: %v", err)
+		}
+		require.Equal(t, []domain.Category{createdCategory}, found)
+	})
+
+	t.Run("test Update cycle", func(t *testing.T) {
+		repo := mongodb.NewCategoryRepo(db)
+		movedCategory := categories[0]
+		movedCategory.ParentID = createdCategory.ID
+		_, err := repo.Update(ctx, movedCategory)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+
+	t.Run("test GetByCategoryID", func(t *testing.T) {
+		phone := createdProduct
+		phone.CategoryID = createdCategory.ID
+		_, err := mongodb.NewProductRepo(db).Create(ctx, phone)
+		if err != nil {
+			t.Errorf("failed to Create product: %v", err)
+		}
+
+		found, err := mongodb.NewProductRepo(db).GetByCategoryID(ctx, categories[0].ID, 10, 0)
+		if err != nil {
+			t.Errorf("failed to GetByCategoryID: %v", err)
+		}
+		require.Equal(t, []domain.Product{products[0], phone}, found)
+	})
+}
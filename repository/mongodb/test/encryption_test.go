@@ -0,0 +1,282 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"strings"
+	"testing"
+	"time"
+)
+
+var encryptionKeys = map[string][]byte{
+	"k1": []byte("0123456789abcdef0123456789abcdef"),
+	"k2": []byte("fedcba9876543210fedcba9876543210"),
+}
+
+var blindIndexKey = []byte("blind-index-key")
+
+func setKeyProvider(t *testing.T, currentKeyID string) {
+	err := encryption.SetKeyProvider(encryption.NewStaticKeyProvider(currentKeyID, encryptionKeys, blindIndexKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		encryption.SetKeyProvider(nil)
+	})
+}
+
+func TestEncryption(t *testing.T) {
+	ctx := context.Background()
+	container, err := newMongoContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newMongoDB(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = InitUsersMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitShopsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitShopItemsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitProductsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test legacy plaintext readable", func(t *testing.T) {
+		setKeyProvider(t, "k1")
+		found, err := mongodb.NewUserRepo(db).GetByID(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.Equal(t, users[0].Email, found.Email)
+		require.Equal(t, users[0].Phone, found.Phone)
+	})
+	t.Run("test user PII encrypted", func(t *testing.T) {
+		setKeyProvider(t, "k1")
+		repo := mongodb.NewUserRepo(db)
+		found, err := repo.Create(ctx, createdUser)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.Equal(t, createdUser, found)
+
+		var stored bson.M
+		err = db.Collection(mongodb.UserCollection).FindOne(ctx, bson.M{"_id": createdUser.ID}).Decode(&stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.True(t, strings.HasPrefix(stored["email"].(string), "enc:k1:"))
+		require.NotContains(t, stored["email"], createdUser.Email)
+		require.NotContains(t, stored["phone"], createdUser.Phone.String)
+		require.Equal(t, encryption.BlindIndex(createdUser.Email), stored["email_hash"])
+
+		found, err = repo.GetByEmail(ctx, createdUser.Email)
+		if err != nil {
+			t.Errorf("failed to GetByEmail: %v", err)
+		}
+		require.Equal(t, createdUser, found)
+	})
+	t.Run("test key rotation", func(t *testing.T) {
+		setKeyProvider(t, "k2")
+		repo := mongodb.NewUserRepo(db)
+		found, err := repo.GetByEmail(ctx, createdUser.Email)
+		if err != nil {
+			t.Errorf("failed to GetByEmail: %v", err)
+		}
+		require.Equal(t, createdUser, found)
+
+		_, err = repo.Update(ctx, createdUser)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		var stored bson.M
+		err = db.Collection(mongodb.UserCollection).FindOne(ctx, bson.M{"_id": createdUser.ID}).Decode(&stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, "k2", encryption.KeyID(stored["email"].(string)))
+	})
+	t.Run("test shop and order PII encrypted", func(t *testing.T) {
+		setKeyProvider(t, "k1")
+		shop := shops[0]
+		shop.Requisites = "INN 7707083893"
+		found, err := mongodb.NewShopRepo(db).UpdateShop(ctx, shop)
+		if err != nil {
+			t.Errorf("failed to UpdateShop: %v", err)
+		}
+		require.Equal(t, shop.Requisites, found.Requisites)
+
+		var stored bson.M
+		err = db.Collection(mongodb.ShopCollection).FindOne(ctx, bson.M{"_id": shop.ID}).Decode(&stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, "k1", encryption.KeyID(stored["requisites"].(string)))
+
+		orderCustomer, err := mongodb.NewOrderRepo(db).CreateOrderCustomer(ctx, createdOrderCustomers[0])
+		if err != nil {
+			t.Errorf("failed to CreateOrderCustomer: %v", err)
+		}
+		require.Equal(t, createdOrderCustomers[0].Address, orderCustomer.Address)
+
+		err = db.Collection(mongodb.OrderCustomerCollection).FindOne(ctx, bson.M{"_id": orderCustomer.ID}).Decode(&stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, "k1", encryption.KeyID(stored["address"].(string)))
+	})
+	t.Run("test address PII encrypted", func(t *testing.T) {
+		setKeyProvider(t, "k1")
+		address, err := mongodb.NewUserAddressRepo(db).Create(ctx, createdUserAddresses[0])
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.Equal(t, createdUserAddresses[0], address)
+
+		var stored bson.M
+		err = db.Collection(mongodb.UserAddressCollection).FindOne(ctx, bson.M{"_id": address.ID}).Decode(&stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for field, value := range map[string]string{
+			"recipient":   address.Recipient,
+			"phone":       address.Phone,
+			"street":      address.Street,
+			"postal_code": address.PostalCode,
+		} {
+			require.Equal(t, "k1", encryption.KeyID(stored[field].(string)))
+			require.NotContains(t, stored[field], value)
+		}
+	})
+	t.Run("test ReencryptPII", func(t *testing.T) {
+		legacyID := "30e18bc1-4354-4937-9a3b-03cf0b70e0e1"
+		_, err := db.Collection(mongodb.UserCollection).InsertOne(ctx, bson.M{
+			"_id":      legacyID,
+			"cart_id":  "30e18bc1-4354-4937-9a3b-03cf0b70e0e2",
+			"name":     "Legacy",
+			"surname":  "User",
+			"email":    "legacy@mail.ru",
+			"password": "12345678",
+			"role":     "Customer",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		legacyAddress := bson.M{
+			"recipient":   "Petr Petrov",
+			"phone":       "+78888888888",
+			"country":     "Russia",
+			"city":        "Kazan",
+			"street":      "Baumana 1",
+			"postal_code": "420000",
+		}
+		legacyAddressID := "30e18bc1-4354-4937-9a3b-03cf0b70e0e3"
+		_, err = db.Collection(mongodb.UserAddressCollection).InsertOne(ctx, bson.M{
+			"_id": legacyAddressID, "user_id": legacyID, "recipient": legacyAddress["recipient"],
+			"phone": legacyAddress["phone"], "country": legacyAddress["country"], "city": legacyAddress["city"],
+			"street": legacyAddress["street"], "postal_code": legacyAddress["postal_code"], "is_default": true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		legacyOrderID := "30e18bc1-4354-4937-9a3b-03cf0b70e0e4"
+		_, err = db.Collection(mongodb.OrderCustomerCollection).InsertOne(ctx, bson.M{
+			"_id":              legacyOrderID,
+			"customer_id":      legacyID,
+			"address":          "420000, Russia, Kazan, Baumana 1",
+			"created_at":       time.Date(2024, 10, 13, 11, 30, 30, 0, time.UTC),
+			"currency":         "RUB",
+			"address_id":       legacyAddressID,
+			"shipping_address": legacyAddress,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		setKeyProvider(t, "k1")
+		repo := mongodb.NewUserRepo(db)
+		_, err = repo.GetByEmail(ctx, users[0].Email)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		for i := 0; i < 2; i++ {
+			err = mongodb.ReencryptPII(ctx, db)
+			if err != nil {
+				t.Errorf("failed to ReencryptPII: %v", err)
+			}
+		}
+
+		found, err := repo.GetByEmail(ctx, users[0].Email)
+		if err != nil {
+			t.Errorf("failed to GetByEmail: %v", err)
+		}
+		require.Equal(t, users[0].Email, found.Email)
+		require.Equal(t, users[0].Phone, found.Phone)
+		found, err = repo.GetByEmail(ctx, "legacy@mail.ru")
+		if err != nil {
+			t.Errorf("failed to GetByEmail: %v", err)
+		}
+		require.Equal(t, legacyID, found.ID.String())
+
+		for _, id := range []string{users[0].ID.String(), legacyID, createdUser.ID.String()} {
+			var stored bson.M
+			err = db.Collection(mongodb.UserCollection).FindOne(ctx, bson.M{"_id": id}).Decode(&stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			require.Equal(t, "k1", encryption.KeyID(stored["email"].(string)))
+		}
+
+		var storedAddress, storedOrder bson.M
+		err = db.Collection(mongodb.UserAddressCollection).FindOne(ctx, bson.M{"_id": legacyAddressID}).Decode(&storedAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.Collection(mongodb.OrderCustomerCollection).FindOne(ctx, bson.M{"_id": legacyOrderID}).Decode(&storedOrder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snapshot := storedOrder["shipping_address"].(bson.M)
+		for _, field := range []string{"recipient", "phone", "street", "postal_code"} {
+			require.Equal(t, "k1", encryption.KeyID(storedAddress[field].(string)))
+			require.NotContains(t, storedAddress[field], legacyAddress[field])
+			require.Equal(t, "k1", encryption.KeyID(snapshot[field].(string)))
+			require.NotContains(t, snapshot[field], legacyAddress[field])
+		}
+		require.Equal(t, legacyAddress["city"], snapshot["city"])
+
+		order, err := mongodb.NewOrderRepo(db).GetOrderCustomerByID(ctx, domain.ID(legacyOrderID))
+		if err != nil {
+			t.Errorf("failed to GetOrderCustomerByID: %v", err)
+		}
+		require.Equal(t, legacyAddress["recipient"], order.ShippingAddress.Recipient)
+		require.Equal(t, legacyAddress["postal_code"], order.ShippingAddress.PostalCode)
+	})
+}
@@ -0,0 +1,158 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	container, err := newMongoContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newMongoDB(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = InitShopsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitShopItemsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Collection(mongodb.ProductVariantCollection).InsertOne(ctx, entity.NewMgProductVariant(variants[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test opening stock", func(t *testing.T) {
+		repo := mongodb.NewShopRepo(db)
+		consistent, err := repo.ReconcileStock(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to ReconcileStock: %v", err)
+		}
+		require.False(t, consistent)
+
+		for i := 0; i < 2; i++ {
+			err = mongodb.Migrate(ctx, db)
+			if err != nil {
+				t.Errorf("failed to Migrate: %v", err)
+			}
+		}
+
+		history, err := repo.GetStockHistory(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetStockHistory: %v", err)
+		}
+		require.Equal(t, 2, len(history))
+		for _, movement := range history {
+			require.Equal(t, domain.StockMovementAdjustment, movement.Reason)
+			if movement.VariantID == variants[0].ID {
+				require.Equal(t, variants[0].Quantity, movement.Delta)
+			} else {
+				require.Equal(t, shopItems[0].Quantity, movement.Delta)
+			}
+		}
+
+		consistent, err = repo.ReconcileStock(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to ReconcileStock: %v", err)
+		}
+		require.True(t, consistent)
+	})
+
+	t.Run("test cart timestamps", func(t *testing.T) {
+		cartID := domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70a001")
+		_, err := db.Collection(mongodb.CartCollection).InsertOne(ctx, bson.M{"_id": cartID.String(), "price": 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mongodb.Migrate(ctx, db)
+		if err != nil {
+			t.Errorf("failed to Migrate: %v", err)
+		}
+
+		repo := mongodb.NewCartRepo(db)
+		cart, err := repo.GetCartByID(ctx, cartID)
+		if err != nil {
+			t.Errorf("failed to GetCartByID: %v", err)
+		}
+		require.False(t, cart.UpdatedAt.IsZero())
+		require.Equal(t, cart.CreatedAt, cart.UpdatedAt)
+	})
+
+	t.Run("test currency", func(t *testing.T) {
+		productID := domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70a002")
+		_, err := db.Collection(mongodb.ProductCollection).InsertOne(ctx, bson.M{"_id": productID.String(), "name": "legacy", "price": 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mongodb.Migrate(ctx, db)
+		if err != nil {
+			t.Errorf("failed to Migrate: %v", err)
+		}
+
+		var mgProduct entity.MgProduct
+		err = db.Collection(mongodb.ProductCollection).FindOne(ctx, bson.M{"_id": productID.String()}).Decode(&mgProduct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, "RUB", mgProduct.Currency)
+	})
+
+	t.Run("test user search fields", func(t *testing.T) {
+		mgUser, err := entity.NewMgUser(createdUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		collection := db.Collection(mongodb.UserCollection)
+		if _, err = collection.InsertOne(ctx, mgUser); err != nil {
+			t.Fatal(err)
+		}
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": mgUser.ID},
+			bson.M{"$unset": bson.M{"name_lower": "", "surname_lower": "", "phone_hash": ""}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = mongodb.Migrate(ctx, db)
+		if err != nil {
+			t.Errorf("failed to Migrate: %v", err)
+		}
+
+		repo := mongodb.NewUserRepo(db)
+		found, _, err := repo.SearchUsers(ctx, domain.UserQuery{
+			NamePrefix:    "CREATED",
+			SurnamePrefix: "createds",
+			Phone:         createdUser.Phone.String,
+		})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{createdUser}, found)
+	})
+}
@@ -35,6 +35,7 @@ var orderCustomers = []domain.OrderCustomer{
 	domain.OrderCustomer{
 		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ae1"),
 		CustomerID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+		Currency:   "RUB",
 		Address:    "Pushkina 1-2-3",
 		CreatedAt:  time.Date(2022, 10, 10, 11, 30, 30, 0, time.UTC),
 		OrderShops: orderShops,
@@ -65,16 +66,21 @@ var createdOrderCustomers = []domain.OrderCustomer{
 	domain.OrderCustomer{
 		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70eeee"),
 		CustomerID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+		Currency:   "RUB",
 		Address:    "Pushkina 1-2-4",
 		CreatedAt:  time.Date(2024, 10, 10, 11, 30, 30, 0, time.UTC),
+		TotalPrice: 389970,
 		OrderShops: createdorderShops,
 	},
 }
 
 func InitOrderCustomersMongoDB(ctx context.Context, db *mongo.Database) error {
 	for _, orderCustomer := range orderCustomers {
-		var mgOrderCustomer = entity.NewMgOrderCustomer(orderCustomer)
-		_, err := db.Collection(mongodb.OrderCustomerCollection).InsertOne(ctx, mgOrderCustomer)
+		mgOrderCustomer, err := entity.NewMgOrderCustomer(orderCustomer)
+		if err != nil {
+			return err
+		}
+		_, err = db.Collection(mongodb.OrderCustomerCollection).InsertOne(ctx, mgOrderCustomer)
 		if err != nil {
 			return err
 		}
@@ -107,6 +113,32 @@ func InitOrderShopItemsMongoDB(ctx context.Context, db *mongo.Database) error {
 	return nil
 }
 
+var variantOrderCustomer = domain.OrderCustomer{
+	ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70dee1"),
+	CustomerID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+	Currency:   "RUB",
+	Address:    "Pushkina 1-2-5",
+	CreatedAt:  time.Date(2024, 10, 11, 11, 30, 30, 0, time.UTC),
+	TotalPrice: 389970,
+	OrderShops: []domain.OrderShop{
+		domain.OrderShop{
+			ID:              domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70dee2"),
+			ShopID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+			OrderCustomerID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70dee1"),
+			Status:          domain.OrderShopStatusStart,
+			OrderShopItems: []domain.OrderShopItem{
+				domain.OrderShopItem{
+					ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70dee3"),
+					OrderShopID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70dee2"),
+					ProductID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
+					VariantID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70d001"),
+					Quantity:    3,
+				},
+			},
+		},
+	},
+}
+
 func TestOrderRepository(t *testing.T) {
 	ctx := context.Background()
 	container, err := newMongoContainer(ctx)
@@ -143,6 +175,14 @@ func TestOrderRepository(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	err = InitShopItemsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitProductsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	t.Run("test GetOrderCustomerByID", func(t *testing.T) {
 		repo := mongodb.NewOrderRepo(db)
@@ -161,5 +201,119 @@ func TestOrderRepository(t *testing.T) {
 		}
 
 		require.Equal(t, createdOrderCustomers[0], found)
+
+		shopRepo := mongodb.NewShopRepo(db)
+		history, err := shopRepo.GetStockHistory(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetStockHistory: %v", err)
+		}
+		require.Equal(t, 1, len(history))
+		require.Equal(t, -createdOrderShopItems[0].Quantity, history[0].Delta)
+		require.Equal(t, domain.StockMovementSale, history[0].Reason)
+	})
+
+	t.Run("test CreateOrderCustomer variant", func(t *testing.T) {
+		shopRepo := mongodb.NewShopRepo(db)
+		_, err := shopRepo.CreateVariant(ctx, variants[0])
+		if err != nil {
+			t.Errorf("failed to CreateVariant: %v", err)
+		}
+		shopItem, err := shopRepo.GetShopItemByID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetShopItemByID: %v", err)
+		}
+
+		repo := mongodb.NewOrderRepo(db)
+		found, err := repo.CreateOrderCustomer(ctx, variantOrderCustomer)
+		if err != nil {
+			t.Errorf("failed to CreateOrderCustomer: %v", err)
+		}
+		require.Equal(t, variantOrderCustomer, found)
+
+		variant, err := shopRepo.GetVariantByID(ctx, variants[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetVariantByID: %v", err)
+		}
+		require.Equal(t, int64(1), variant.Quantity)
+
+		unchanged, err := shopRepo.GetShopItemByID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetShopItemByID: %v", err)
+		}
+		require.Equal(t, shopItem.Quantity, unchanged.Quantity)
+
+		history, err := shopRepo.GetStockHistory(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetStockHistory: %v", err)
+		}
+		sale := history[len(history)-1]
+		require.Equal(t, variants[0].ID, sale.VariantID)
+		require.Equal(t, int64(-3), sale.Delta)
+		require.Equal(t, domain.StockMovementSale, sale.Reason)
+
+		var variantStock int64
+		for _, movement := range history {
+			if movement.VariantID == variants[0].ID {
+				variantStock += movement.Delta
+			}
+		}
+		require.Equal(t, variant.Quantity, variantStock)
+
+		err = shopRepo.DeleteVariant(ctx, variants[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+
+	t.Run("test CreateOrderCustomer wrong total", func(t *testing.T) {
+		orderCustomer := createdOrderCustomers[0]
+		orderCustomer.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70eef1")
+		orderCustomer.TotalPrice = 1
+
+		repo := mongodb.NewOrderRepo(db)
+		_, err := repo.CreateOrderCustomer(ctx, orderCustomer)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		_, err = repo.GetOrderCustomerByID(ctx, orderCustomer.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+	t.Run("test CreateOrderCustomer other shop", func(t *testing.T) {
+		shopRepo := mongodb.NewShopRepo(db)
+		shopItem, err := shopRepo.GetShopItemByID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetShopItemByID: %v", err)
+		}
+
+		orderShopItem := createdOrderShopItems[0]
+		orderShopItem.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70eef6")
+		orderShopItem.OrderShopID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70eef5")
+		orderShop := createdorderShops[0]
+		orderShop.ID = orderShopItem.OrderShopID
+		orderShop.ShopID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b2")
+		orderShop.OrderShopItems = []domain.OrderShopItem{orderShopItem}
+		orderCustomer := createdOrderCustomers[0]
+		orderCustomer.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70eef4")
+		orderShop.OrderCustomerID = orderCustomer.ID
+		orderCustomer.OrderShops = []domain.OrderShop{orderShop}
+
+		repo := mongodb.NewOrderRepo(db)
+		_, err = repo.CreateOrderCustomer(ctx, orderCustomer)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		unchanged, err := shopRepo.GetShopItemByID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetShopItemByID: %v", err)
+		}
+		require.Equal(t, shopItem.Quantity, unchanged.Quantity)
+	})
+	t.Run("test CreateOrderCustomer wrong currency", func(t *testing.T) {
+		orderCustomer := createdOrderCustomers[0]
+		orderCustomer.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70eef3")
+		orderCustomer.Currency = "USD"
+
+		repo := mongodb.NewOrderRepo(db)
+		_, err := repo.CreateOrderCustomer(ctx, orderCustomer)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		_, err = repo.GetOrderCustomerByID(ctx, orderCustomer.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
 	})
 }
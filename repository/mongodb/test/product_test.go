@@ -6,6 +6,7 @@ import (
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
 
+	"github.com/guregu/null"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/mongo"
 	"testing"
@@ -17,7 +18,8 @@ var products = []domain.Product{
 		Name:        "iphone 15",
 		Description: "apple IOS",
 		Price:       129990,
-		Category:    domain.ElectronicCategory,
+		Currency:    "RUB",
+		CategoryID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c001"),
 		PhotoUrl:    "photo/1.png",
 	},
 	domain.Product{
@@ -25,7 +27,8 @@ var products = []domain.Product{
 		Name:        "harry potter",
 		Description: "Rouling",
 		Price:       2990,
-		Category:    domain.BooksCategory,
+		Currency:    "RUB",
+		CategoryID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c006"),
 		PhotoUrl:    "photo/2.png",
 	},
 }
@@ -35,7 +38,8 @@ var createdProduct = domain.Product{
 	Name:        "new",
 	Description: "new",
 	Price:       129990,
-	Category:    domain.ElectronicCategory,
+	Currency:    "RUB",
+	CategoryID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c001"),
 	PhotoUrl:    "photo/new.png",
 }
 
@@ -44,10 +48,56 @@ var updatedProduct = domain.Product{
 	Name:        "iphone 15",
 	Description: "apple IOS 17",
 	Price:       129990,
-	Category:    domain.ElectronicCategory,
+	Currency:    "RUB",
+	CategoryID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c001"),
 	PhotoUrl:    "photo/1.png",
 }
 
+var attributedProduct = domain.Product{
+	ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
+	Name:        "harry potter",
+	Description: "Rouling",
+	Price:       2990,
+	Currency:    "RUB",
+	CategoryID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c006"),
+	PhotoUrl:    "photo/2.png",
+	Attributes:  map[string]interface{}{"isbn": "9780747532699", "pages": float64(320)},
+}
+
+var productImages = []domain.ProductImage{
+	domain.ProductImage{
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70b001"),
+		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
+		Url:       "photo/2_front.png",
+		AltText:   "front cover",
+		IsPrimary: true,
+	},
+	domain.ProductImage{
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70b002"),
+		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
+		Url:       "photo/2_back.png",
+		AltText:   "back cover",
+	},
+}
+
+var reorderedProductImages = []domain.ProductImage{
+	domain.ProductImage{
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70b002"),
+		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
+		Url:       "photo/2_back.png",
+		Position:  0,
+		AltText:   "back cover",
+	},
+	domain.ProductImage{
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70b001"),
+		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
+		Url:       "photo/2_front.png",
+		Position:  1,
+		AltText:   "front cover",
+		IsPrimary: true,
+	},
+}
+
 func InitProductsMongoDB(ctx context.Context, db *mongo.Database) error {
 	for _, product := range products {
 		var mgProduct = entity.NewMgProduct(product)
@@ -60,6 +110,13 @@ func InitProductsMongoDB(ctx context.Context, db *mongo.Database) error {
 	return nil
 }
 
+var productTranslation = domain.ProductTranslation{
+	ProductID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
+	Locale:      "ru",
+	Name:        "Гарри Поттер",
+	Description: "Роман о юном волшебнике",
+}
+
 func TestProductRepository(t *testing.T) {
 	ctx := context.Background()
 	container, err := newMongoContainer(ctx)
@@ -84,6 +141,10 @@ func TestProductRepository(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	err = InitCategoriesMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
 	err = InitProductsMongoDB(ctx, db)
 	if err != nil {
 		t.Fatal(err)
@@ -126,6 +187,150 @@ func TestProductRepository(t *testing.T) {
 			t.Errorf("failed to create product: %v", err)
 		}
 		require.Equal(t, product, updatedProduct)
+
+		err = InitCartItemsMongoDB(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repriced := updatedProduct
+		repriced.Currency = "EUR"
+		_, err = repo.Update(ctx, repriced)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+
+	t.Run("test get products by attributes", func(t *testing.T) {
+		repo := mongodb.NewProductRepo(db)
+		product, err := repo.Update(ctx, attributedProduct)
+		if err != nil {
+			t.Errorf("failed to update product: %v", err)
+		}
+		require.Equal(t, attributedProduct, product)
+
+		found, err := repo.GetByAttributes(ctx, []domain.AttributeFilter{
+			{Key: "isbn", Value: "9780747532699"},
+			{Key: "pages", Min: null.FloatFrom(300)},
+		}, 10, 0)
+		if err != nil {
+			t.Errorf("failed to GetByAttributes: %v", err)
+		}
+		require.Equal(t, []domain.Product{attributedProduct}, found)
+
+		found, err = repo.GetByAttributes(ctx, []domain.AttributeFilter{
+			{Key: "pages", Max: null.FloatFrom(100)},
+		}, 10, 0)
+		if err != nil {
+			t.Errorf("failed to GetByAttributes: %v", err)
+		}
+		require.Equal(t, 0, len(found))
+	})
+
+	t.Run("test product images", func(t *testing.T) {
+		repo := mongodb.NewProductRepo(db)
+		for _, image := range productImages {
+			_, err := repo.AddImage(ctx, image)
+			if err != nil {
+				t.Errorf("failed to AddImage: %v", err)
+			}
+		}
+		err := repo.ReorderImages(ctx, products[1].ID, []domain.ID{productImages[1].ID, productImages[1].ID})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		err = repo.ReorderImages(ctx, products[1].ID, []domain.ID{productImages[1].ID})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		err = repo.ReorderImages(ctx, products[1].ID, []domain.ID{productImages[1].ID, productImages[0].ID})
+		if err != nil {
+			t.Errorf("failed to ReorderImages: %v", err)
+		}
+
+		product, err := repo.GetByID(ctx, products[1].ID)
+		if err != nil {
+			t.Errorf("failed to get product with id: %v", err)
+		}
+		require.Equal(t, reorderedProductImages, product.Images)
+
+		err = repo.DeleteImage(ctx, productImages[0].ID)
+		if err != nil {
+			t.Errorf("failed to DeleteImage: %v", err)
+		}
+		product, err = repo.GetByID(ctx, products[1].ID)
+		if err != nil {
+			t.Errorf("failed to get product with id: %v", err)
+		}
+		require.Equal(t, reorderedProductImages[:1], product.Images)
+	})
+
+	t.Run("test product translations", func(t *testing.T) {
+		repo := mongodb.NewProductRepo(db)
+		translation, err := repo.SetTranslation(ctx, productTranslation)
+		if err != nil {
+			t.Errorf("failed to SetTranslation: %v", err)
+		}
+		require.Equal(t, productTranslation, translation)
+		_, err = repo.SetTranslation(ctx, domain.ProductTranslation{ProductID: products[1].ID, Locale: "russian"})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		_, err = repo.SetTranslation(ctx, domain.ProductTranslation{ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027ff"), Locale: "ru"})
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		translations, err := repo.GetTranslations(ctx, products[1].ID)
+		if err != nil {
+			t.Errorf("failed to GetTranslations: %v", err)
+		}
+		require.Equal(t, []domain.ProductTranslation{productTranslation}, translations)
+
+		// ru-RU falls back to ru, en-US has no translation and keeps the product texts
+		for locale, name := range map[string]string{"ru": productTranslation.Name, "ru-RU": productTranslation.Name, "en-US": products[1].Name} {
+			product, err := repo.GetByIDInLocale(ctx, products[1].ID, locale)
+			if err != nil {
+				t.Errorf("failed to GetByIDInLocale: %v", err)
+			}
+			require.Equal(t, name, product.Name)
+		}
+
+		byCategory, err := repo.GetByCategoryIDInLocale(ctx, products[1].CategoryID, "ru-RU", 10, 0)
+		if err != nil {
+			t.Errorf("failed to GetByCategoryIDInLocale: %v", err)
+		}
+		byAttributes, err := repo.GetByAttributesInLocale(ctx, nil, "ru-RU", 10, 0)
+		if err != nil {
+			t.Errorf("failed to GetByAttributesInLocale: %v", err)
+		}
+		for _, localized := range [][]domain.Product{byCategory, byAttributes} {
+			var translated int
+			for _, product := range localized {
+				if product.ID == products[1].ID {
+					require.Equal(t, productTranslation.Name, product.Name)
+					translated++
+				}
+			}
+			require.Equal(t, 1, translated)
+		}
+		_, err = repo.GetByCategoryIDInLocale(ctx, products[1].CategoryID, "russian", 10, 0)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		found, err := repo.Search(ctx, "ru", "волшебник", 10, 0)
+		if err != nil {
+			t.Errorf("failed to Search: %v", err)
+		}
+		require.Equal(t, 1, len(found))
+		require.Equal(t, products[1].ID, found[0].ID)
+		require.Equal(t, productTranslation.Description, found[0].Description)
+
+		found, err = repo.Search(ctx, "en", "iphone", 10, 0)
+		if err != nil {
+			t.Errorf("failed to Search: %v", err)
+		}
+		require.Equal(t, 1, len(found))
+		require.Equal(t, products[0].ID, found[0].ID)
+
+		err = repo.DeleteTranslation(ctx, products[1].ID, "ru")
+		if err != nil {
+			t.Errorf("failed to DeleteTranslation: %v", err)
+		}
+		product, err := repo.GetByIDInLocale(ctx, products[1].ID, "ru")
+		if err != nil {
+			t.Errorf("failed to GetByIDInLocale: %v", err)
+		}
+		require.Equal(t, products[1].Name, product.Name)
 	})
 
 	t.Run("test delete product", func(t *testing.T) {
@@ -0,0 +1,176 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+var createdPromoCode = domain.PromoCode{
+	ID:             domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70f001"),
+	Code:           "APPLE10",
+	Type:           domain.PromoCodePercentage,
+	Value:          10,
+	ShopID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+	ValidFrom:      time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	ValidTo:        time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
+	MaxUses:        100,
+	MaxUsesPerUser: 1,
+}
+
+func promoOrderCustomer(id string) domain.OrderCustomer {
+	orderCustomerID := domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70" + id + "01")
+	orderShopID := domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70" + id + "02")
+	return domain.OrderCustomer{
+		ID:          orderCustomerID,
+		CustomerID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+		Address:     "Pushkina 1-2-6",
+		CreatedAt:   time.Date(2024, 10, 12, 11, 30, 30, 0, time.UTC),
+		TotalPrice:  129990,
+		PromoCodeID: createdPromoCode.ID,
+		OrderShops: []domain.OrderShop{
+			domain.OrderShop{
+				ID:              orderShopID,
+				ShopID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+				OrderCustomerID: orderCustomerID,
+				Status:          domain.OrderShopStatusStart,
+				OrderShopItems: []domain.OrderShopItem{
+					domain.OrderShopItem{
+						ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70" + id + "03"),
+						OrderShopID: orderShopID,
+						ProductID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
+						Quantity:    1,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPromoCodeRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newMongoContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newMongoDB(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = InitProductsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitShopItemsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test Create", func(t *testing.T) {
+		repo := mongodb.NewPromoCodeRepo(db)
+		found, err := repo.Create(ctx, createdPromoCode)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.Equal(t, createdPromoCode, found)
+
+		found, err = repo.GetByCode(ctx, createdPromoCode.Code)
+		if err != nil {
+			t.Errorf("failed to GetByCode: %v", err)
+		}
+		require.Equal(t, createdPromoCode, found)
+
+		fixed := createdPromoCode
+		fixed.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70f002")
+		fixed.Code = "APPLE500"
+		fixed.Type = domain.PromoCodeFixed
+		fixed.Value = 50000
+		_, err = repo.Create(ctx, fixed)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+
+	t.Run("test CreateOrderCustomer with promo code", func(t *testing.T) {
+		repo := mongodb.NewPromoCodeRepo(db)
+		orderRepo := mongodb.NewOrderRepo(db)
+		order, err := orderRepo.CreateOrderCustomer(ctx, promoOrderCustomer("f1"))
+		if err != nil {
+			t.Errorf("failed to CreateOrderCustomer: %v", err)
+		}
+		require.Equal(t, createdPromoCode.ID, order.PromoCodeID)
+		require.Equal(t, int64(12999), order.Discount)
+		require.Equal(t, "RUB", order.Currency)
+
+		promoCode, err := repo.GetByID(ctx, createdPromoCode.ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.Equal(t, int64(1), promoCode.UsedCount)
+
+		_, err = orderRepo.CreateOrderCustomer(ctx, promoOrderCustomer("f2"))
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		promoCode, err = repo.GetByID(ctx, createdPromoCode.ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.Equal(t, int64(1), promoCode.UsedCount)
+	})
+
+	t.Run("test CreateOrderCustomer with promo code concurrently", func(t *testing.T) {
+		repo := mongodb.NewPromoCodeRepo(db)
+		promoCode := createdPromoCode
+		promoCode.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70f003")
+		promoCode.Code = "APPLE15"
+		_, err := repo.Create(ctx, promoCode)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		orderRepo := mongodb.NewOrderRepo(db)
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i, id := range []string{"f3", "f4"} {
+			orderCustomer := promoOrderCustomer(id)
+			orderCustomer.PromoCodeID = promoCode.ID
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = orderRepo.CreateOrderCustomer(ctx, orderCustomer)
+			}(i)
+		}
+		wg.Wait()
+
+		// the order that lost the write conflict is retried and sees the use
+		// of the other one
+		if errs[0] == nil {
+			require.ErrorIs(t, errs[1], domain.ErrNotAllowed)
+		} else {
+			require.ErrorIs(t, errs[0], domain.ErrNotAllowed)
+			require.NoError(t, errs[1])
+		}
+
+		found, err := repo.GetByID(ctx, promoCode.ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.Equal(t, int64(1), found.UsedCount)
+	})
+}
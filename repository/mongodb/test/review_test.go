@@ -0,0 +1,96 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var createdReview = domain.Review{
+	ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702af1"),
+	ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
+	UserID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+	Rating:    5,
+	Text:      "great phone",
+	CreatedAt: time.Date(2024, 10, 10, 11, 30, 30, 0, time.UTC),
+}
+
+func TestReviewRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newMongoContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newMongoDB(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = InitProductsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitOrderCustomersMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitOrderShopsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitOrderShopItemsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test Create not purchased", func(t *testing.T) {
+		repo := mongodb.NewReviewRepo(db)
+		_, err := repo.Create(ctx, createdReview)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+
+	t.Run("test Create", func(t *testing.T) {
+		doneOrderShop := orderShops[0]
+		doneOrderShop.Status = domain.OrderShopStatusDone
+		_, err := mongodb.NewOrderRepo(db).UpdateOrderShop(ctx, doneOrderShop)
+		if err != nil {
+			t.Errorf("failed to UpdateOrderShop: %v", err)
+		}
+
+		repo := mongodb.NewReviewRepo(db)
+		found, err := repo.Create(ctx, createdReview)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.Equal(t, createdReview, found)
+
+		reviews, err := repo.GetByProductID(ctx, createdReview.ProductID, 10, 0)
+		if err != nil {
+			t.Errorf("failed to GetByProductID: %v", err)
+		}
+		require.Equal(t, []domain.Review{createdReview}, reviews)
+
+		product, err := mongodb.NewProductRepo(db).GetByID(ctx, createdReview.ProductID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.Equal(t, float64(5), product.Rating)
+		require.Equal(t, int64(1), product.ReviewCount)
+	})
+}
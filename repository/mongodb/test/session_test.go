@@ -0,0 +1,161 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var sessionTokens = []string{"refresh-token-iphone", "refresh-token-linux"}
+
+func newSessions() []domain.Session {
+	expiresAt := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second)
+	return []domain.Session{
+		domain.Session{
+			ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fd01"),
+			UserID:     domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
+			DeviceInfo: "iPhone 15, Safari",
+			IP:         "10.0.0.1",
+			ExpiresAt:  expiresAt,
+		},
+		domain.Session{
+			ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fd02"),
+			UserID:     domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
+			DeviceInfo: "Firefox on Linux",
+			IP:         "10.0.0.2",
+			ExpiresAt:  expiresAt,
+		},
+	}
+}
+
+func sessionTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSessionRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newMongoContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newMongoDB(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = InitUsersMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := newSessions()
+
+	t.Run("test Create and GetByRefreshToken", func(t *testing.T) {
+		repo := mongodb.NewSessionRepo(db)
+		for i := range sessions {
+			found, err := repo.Create(ctx, sessions[i], sessionTokens[i])
+			if err != nil {
+				t.Errorf("failed to Create: %v", err)
+			}
+			require.WithinDuration(t, time.Now(), found.CreatedAt, time.Minute)
+			sessions[i].TokenHash = sessionTokenHash(sessionTokens[i])
+			sessions[i].CreatedAt = found.CreatedAt
+			require.Equal(t, sessions[i], found)
+		}
+
+		found, err := repo.GetByRefreshToken(ctx, sessionTokens[0])
+		if err != nil {
+			t.Errorf("failed to GetByRefreshToken: %v", err)
+		}
+		require.Equal(t, sessions[0], found)
+
+		_, err = repo.GetByRefreshToken(ctx, "unknown-token")
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		userSessions, err := repo.GetByUserID(ctx, sessions[0].UserID)
+		if err != nil {
+			t.Errorf("failed to GetByUserID: %v", err)
+		}
+		require.ElementsMatch(t, sessions, userSessions)
+
+		duplicate := sessions[0]
+		duplicate.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fd03")
+		_, err = repo.Create(ctx, duplicate, sessionTokens[0])
+		require.ErrorIs(t, err, domain.ErrDuplicate)
+
+		missing := sessions[0]
+		missing.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fd04")
+		missing.UserID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70ffff")
+		_, err = repo.Create(ctx, missing, "refresh-token-missing")
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+
+	t.Run("test Revoke and PurgeExpired", func(t *testing.T) {
+		repo := mongodb.NewSessionRepo(db)
+		err := repo.Revoke(ctx, sessions[0].ID)
+		if err != nil {
+			t.Errorf("failed to Revoke: %v", err)
+		}
+		_, err = repo.GetByRefreshToken(ctx, sessionTokens[0])
+		require.ErrorIs(t, err, domain.ErrNotExist)
+		err = repo.Revoke(ctx, sessions[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		found, err := repo.GetByID(ctx, sessions[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.True(t, found.RevokedAt.Valid)
+
+		purged, err := repo.PurgeExpired(ctx, time.Now().Add(time.Minute))
+		if err != nil {
+			t.Errorf("failed to PurgeExpired: %v", err)
+		}
+		require.Equal(t, int64(1), purged)
+		_, err = repo.GetByID(ctx, sessions[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+
+	t.Run("test RevokeAllForUser", func(t *testing.T) {
+		repo := mongodb.NewSessionRepo(db)
+		revoked, err := repo.RevokeAllForUser(ctx, sessions[1].UserID)
+		if err != nil {
+			t.Errorf("failed to RevokeAllForUser: %v", err)
+		}
+		require.Equal(t, int64(1), revoked)
+
+		userSessions, err := repo.GetByUserID(ctx, sessions[1].UserID)
+		if err != nil {
+			t.Errorf("failed to GetByUserID: %v", err)
+		}
+		require.Equal(t, 0, len(userSessions))
+	})
+
+	t.Run("test user Delete cascades", func(t *testing.T) {
+		err := mongodb.NewUserRepo(db).Delete(ctx, sessions[1].UserID)
+		if err != nil {
+			t.Errorf("failed to Delete: %v", err)
+		}
+		_, err = mongodb.NewSessionRepo(db).GetByID(ctx, sessions[1].ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+}
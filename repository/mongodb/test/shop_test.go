@@ -5,9 +5,12 @@ import (
 	"github.com/EmirShimshir/marketplace-core/domain"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/guregu/null"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
 	"testing"
+	"time"
 )
 
 var shopItems = []domain.ShopItem{
@@ -19,22 +22,69 @@ var shopItems = []domain.ShopItem{
 	},
 }
 
+var updatedShopItem = domain.ShopItem{
+	ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ac1"),
+	ShopID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+	ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
+	Quantity:  8,
+}
+
+var stockMovements = []domain.StockMovement{
+	domain.StockMovement{
+		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702bc1"),
+		ShopItemID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ac1"),
+		Delta:      5,
+		Reason:     domain.StockMovementAdjustment,
+		CreatedAt:  time.Date(2022, 10, 10, 11, 30, 30, 0, time.UTC),
+	},
+}
+
+var lowStockShopItem = domain.ShopItem{
+	ID:               domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ac1"),
+	ShopID:           domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+	ProductID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
+	Quantity:         0,
+	ReorderThreshold: 2,
+}
+
+var variants = []domain.ProductVariant{
+	domain.ProductVariant{
+		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70d001"),
+		ShopItemID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ac1"),
+		SKU:        "IPH15-128",
+		Attributes: map[string]string{"memory": "128GB", "color": "black"},
+		Quantity:   4,
+	},
+	domain.ProductVariant{
+		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70d002"),
+		ShopItemID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ac1"),
+		SKU:        "IPH15-256",
+		Attributes: map[string]string{"memory": "256GB", "color": "black"},
+		Price:      null.IntFrom(149990),
+		Quantity:   2,
+	},
+}
+
 var shops = []domain.Shop{
 	domain.Shop{
-		ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
-		SellerID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
-		Name:        "Apple Store",
-		Description: "found 1998",
-		Requisites:  "Alabama",
-		Email:       "Apple@mail.ru",
-		Items:       shopItems,
+		ID:              domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+		SellerID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
+		Name:            "Apple Store",
+		Description:     "found 1998",
+		Requisites:      "Alabama",
+		Email:           "Apple@mail.ru",
+		DefaultCurrency: "RUB",
+		Items:           shopItems,
 	},
 }
 
 func InitShopsMongoDB(ctx context.Context, db *mongo.Database) error {
 	for _, shop := range shops {
-		var mgShop = entity.NewMgShop(shop)
-		_, err := db.Collection(mongodb.ShopCollection).InsertOne(ctx, mgShop)
+		mgShop, err := entity.NewMgShop(shop)
+		if err != nil {
+			return err
+		}
+		_, err = db.Collection(mongodb.ShopCollection).InsertOne(ctx, mgShop)
 		if err != nil {
 			return err
 		}
@@ -55,6 +105,43 @@ func InitShopItemsMongoDB(ctx context.Context, db *mongo.Database) error {
 	return nil
 }
 
+func InitStockMovementsMongoDB(ctx context.Context, db *mongo.Database) error {
+	for _, movement := range stockMovements {
+		var mgStockMovement = entity.NewMgStockMovement(movement)
+		_, err := db.Collection(mongodb.StockMovementCollection).InsertOne(ctx, mgStockMovement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const importCSV = `product_id,name,price,category_id,quantity,currency
+30e18bc1-4354-4937-9a3b-03cf0b7027a1,iphone 15,139990,30e18bc1-4354-4937-9a3b-03cf0b70c001,7,
+30e18bc1-4354-4937-9a3b-03cf0b7027a4,ipad,59990,30e18bc1-4354-4937-9a3b-03cf0b70c001,3,
+30e18bc1-4354-4937-9a3b-03cf0b7027a5,broken,abc,30e18bc1-4354-4937-9a3b-03cf0b70c001,1,
+30e18bc1-4354-4937-9a3b-03cf0b7027a6,lost,100,30e18bc1-4354-4937-9a3b-03cf0b70c0ff,1,
+30e18bc1-4354-4937-9a3b-03cf0b7027a7,dollars,100,30e18bc1-4354-4937-9a3b-03cf0b70c001,1,usd
+30e18bc1-4354-4937-9a3b-03cf0b7027a4,ipad again,100,30e18bc1-4354-4937-9a3b-03cf0b70c001,1,
+`
+
+const importNDJSON = `{"product_id": "30e18bc1-4354-4937-9a3b-03cf0b7027a4", "name": "ipad", "price": 59990, "category_id": "30e18bc1-4354-4937-9a3b-03cf0b70c001", "quantity": 1}
+{"product_id": "30e18bc1-4354-4937-9a3b-03cf0b7027a5",
+`
+
+var importReport = domain.ImportReport{
+	Rows:    6,
+	Created: 1,
+	Updated: 1,
+	Errors: []domain.ImportRowError{
+		{Line: 4, Error: "price is not an integer"},
+		{Line: 5, Error: "category does not exist"},
+		{Line: 6, Error: "invalid currency code"},
+		{Line: 7, Error: "product is already listed on line 3"},
+	},
+}
+
 func TestShopRepository(t *testing.T) {
 	ctx := context.Background()
 	container, err := newMongoContainer(ctx)
@@ -99,6 +186,14 @@ func TestShopRepository(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	err = InitStockMovementsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitCategoriesMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	t.Run("test GetShops", func(t *testing.T) {
 		repo := mongodb.NewShopRepo(db)
@@ -132,4 +227,200 @@ func TestShopRepository(t *testing.T) {
 
 		require.Equal(t, []domain.Shop{shops[0]}, found)
 	})
+	t.Run("test SetModerationStatus", func(t *testing.T) {
+		repo := mongodb.NewShopRepo(db)
+		_, err := repo.SetModerationStatus(ctx, domain.ModerationAction{TargetID: shops[0].ID, Action: domain.ModerationActionBlock})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		_, err = repo.SetModerationStatus(ctx, domain.ModerationAction{TargetID: shops[0].ID, Action: domain.ModerationActionApprove})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		suspended, err := repo.SetModerationStatus(ctx, domain.ModerationAction{
+			TargetID:    shops[0].ID,
+			ModeratorID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+			Action:      domain.ModerationActionSuspend,
+			Reason:      "counterfeit goods",
+		})
+		if err != nil {
+			t.Errorf("failed to SetModerationStatus: %v", err)
+		}
+		require.Equal(t, domain.ShopModerationSuspended, suspended.ModerationStatus)
+
+		found, err := repo.GetShops(ctx, 2, 0)
+		if err != nil {
+			t.Errorf("failed to GetShops: %v", err)
+		}
+		require.Empty(t, found)
+
+		items, err := repo.GetShopItems(ctx, 2, 0)
+		if err != nil {
+			t.Errorf("failed to GetShopItems: %v", err)
+		}
+		require.Empty(t, items)
+
+		found, err = repo.GetShopsByModerationStatus(ctx, domain.ShopModerationSuspended, 2, 0)
+		if err != nil {
+			t.Errorf("failed to GetShopsByModerationStatus: %v", err)
+		}
+		require.Equal(t, []domain.Shop{suspended}, found)
+
+		updated := suspended
+		updated.ModerationStatus = domain.ShopModerationApproved
+		updated, err = repo.UpdateShop(ctx, updated)
+		if err != nil {
+			t.Errorf("failed to UpdateShop: %v", err)
+		}
+		require.Equal(t, domain.ShopModerationSuspended, updated.ModerationStatus)
+
+		approved, err := repo.SetModerationStatus(ctx, domain.ModerationAction{TargetID: shops[0].ID, Action: domain.ModerationActionApprove})
+		if err != nil {
+			t.Errorf("failed to SetModerationStatus: %v", err)
+		}
+		require.Equal(t, shops[0], approved)
+
+		history, err := repo.GetModerationHistory(ctx, shops[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetModerationHistory: %v", err)
+		}
+		require.Equal(t, 2, len(history))
+		require.Equal(t, domain.ModerationActionSuspend, history[0].Action)
+		require.Equal(t, "counterfeit goods", history[0].Reason)
+		require.Equal(t, domain.ModerationActionApprove, history[1].Action)
+	})
+
+	t.Run("test GetStockHistory", func(t *testing.T) {
+		repo := mongodb.NewShopRepo(db)
+		found, err := repo.GetStockHistory(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetStockHistory: %v", err)
+		}
+
+		require.Equal(t, stockMovements, found)
+	})
+
+	t.Run("test UpdateShopItem", func(t *testing.T) {
+		repo := mongodb.NewShopRepo(db)
+		found, err := repo.UpdateShopItem(ctx, updatedShopItem)
+		if err != nil {
+			t.Errorf("failed to UpdateShopItem: %v", err)
+		}
+		require.Equal(t, updatedShopItem, found)
+
+		history, err := repo.GetStockHistory(ctx, updatedShopItem.ID)
+		if err != nil {
+			t.Errorf("failed to GetStockHistory: %v", err)
+		}
+		require.Equal(t, 2, len(history))
+		require.Equal(t, int64(3), history[1].Delta)
+		require.Equal(t, domain.StockMovementRestock, history[1].Reason)
+
+		consistent, err := repo.ReconcileStock(ctx, updatedShopItem.ID)
+		if err != nil {
+			t.Errorf("failed to ReconcileStock: %v", err)
+		}
+		require.True(t, consistent)
+	})
+	t.Run("test GetLowStockItems", func(t *testing.T) {
+		repo := mongodb.NewShopRepo(db)
+		_, err := repo.UpdateShopItem(ctx, lowStockShopItem)
+		if err != nil {
+			t.Errorf("failed to UpdateShopItem: %v", err)
+		}
+
+		found, err := repo.GetLowStockItems(ctx, shops[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetLowStockItems: %v", err)
+		}
+		require.Equal(t, []domain.ShopItem{lowStockShopItem}, found)
+	})
+
+	t.Run("test GetOutOfStockProducts", func(t *testing.T) {
+		repo := mongodb.NewShopRepo(db)
+		found, err := repo.GetOutOfStockProducts(ctx, 10, "")
+		if err != nil {
+			t.Errorf("failed to GetOutOfStockProducts: %v", err)
+		}
+		require.Equal(t, []domain.ShopItem{lowStockShopItem}, found)
+
+		found, err = repo.GetOutOfStockProducts(ctx, 10, lowStockShopItem.ID)
+		if err != nil {
+			t.Errorf("failed to GetOutOfStockProducts: %v", err)
+		}
+		require.Equal(t, 0, len(found))
+	})
+
+	t.Run("test variants", func(t *testing.T) {
+		repo := mongodb.NewShopRepo(db)
+		for _, variant := range variants {
+			found, err := repo.CreateVariant(ctx, variant)
+			if err != nil {
+				t.Errorf("failed to CreateVariant: %v", err)
+			}
+			require.Equal(t, variant, found)
+		}
+		_, err := repo.CreateVariant(ctx, variants[0])
+		require.ErrorIs(t, err, domain.ErrDuplicate)
+
+		updatedVariant := variants[1]
+		updatedVariant.Quantity = 7
+		found, err := repo.UpdateVariant(ctx, updatedVariant)
+		if err != nil {
+			t.Errorf("failed to UpdateVariant: %v", err)
+		}
+		require.Equal(t, updatedVariant, found)
+
+		all, err := repo.GetVariantsByShopItemID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetVariantsByShopItemID: %v", err)
+		}
+		require.Equal(t, []domain.ProductVariant{variants[0], updatedVariant}, all)
+	})
+
+	t.Run("test ImportShopItems", func(t *testing.T) {
+		repo := mongodb.NewShopRepo(db)
+		dryRun := importReport
+		dryRun.DryRun = true
+		report, err := repo.ImportShopItems(ctx, shops[0].ID, domain.ImportFormatCSV, strings.NewReader(importCSV), true)
+		if err != nil {
+			t.Errorf("failed to ImportShopItems: %v", err)
+		}
+		require.Equal(t, dryRun, report)
+		_, err = repo.GetShopItemByProductID(ctx, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a4"))
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		report, err = repo.ImportShopItems(ctx, shops[0].ID, domain.ImportFormatCSV, strings.NewReader(importCSV), false)
+		if err != nil {
+			t.Errorf("failed to ImportShopItems: %v", err)
+		}
+		require.Equal(t, importReport, report)
+		found, err := repo.GetShopItemByID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetShopItemByID: %v", err)
+		}
+		require.Equal(t, int64(7), found.Quantity)
+
+		report, err = repo.ImportShopItems(ctx, shops[0].ID, domain.ImportFormatNDJSON, strings.NewReader(importNDJSON), false)
+		if err != nil {
+			t.Errorf("failed to ImportShopItems: %v", err)
+		}
+		require.Equal(t, int64(2), report.Rows)
+		require.Equal(t, int64(1), report.Updated)
+		require.Equal(t, 1, len(report.Errors))
+		require.Equal(t, int64(2), report.Errors[0].Line)
+
+		imported, err := repo.GetShopItemByProductID(ctx, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a4"))
+		if err != nil {
+			t.Errorf("failed to GetShopItemByProductID: %v", err)
+		}
+		require.Equal(t, int64(1), imported.Quantity)
+		for _, shopItemID := range []domain.ID{shopItems[0].ID, imported.ID} {
+			consistent, err := repo.ReconcileStock(ctx, shopItemID)
+			if err != nil {
+				t.Errorf("failed to ReconcileStock: %v", err)
+			}
+			require.True(t, consistent)
+		}
+
+		_, err = repo.ImportShopItems(ctx, shops[0].ID, domain.ImportFormatCSV, strings.NewReader("product_id,qty\n"), false)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
 }
@@ -0,0 +1,210 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var createdUserAddresses = []domain.UserAddress{
+	domain.UserAddress{
+		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fad1"),
+		UserID:     domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+		Recipient:  "Ivan Ivanov",
+		Phone:      "+79999999999",
+		Country:    "Russia",
+		City:       "Moscow",
+		Street:     "Pushkina 1-2-4",
+		PostalCode: "101000",
+		IsDefault:  true,
+	},
+	domain.UserAddress{
+		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fad2"),
+		UserID:     domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+		Recipient:  "Ivan Ivanov",
+		Phone:      "+79999999999",
+		Country:    "Russia",
+		City:       "Saint Petersburg",
+		Street:     "Nevsky 10",
+		PostalCode: "190000",
+		IsDefault:  false,
+	},
+}
+
+func TestUserAddressRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newMongoContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newMongoDB(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = InitUsersMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitProductsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitShopItemsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test Create and SetDefault", func(t *testing.T) {
+		repo := mongodb.NewUserAddressRepo(db)
+		for _, address := range createdUserAddresses {
+			found, err := repo.Create(ctx, address)
+			if err != nil {
+				t.Errorf("failed to Create: %v", err)
+			}
+			require.Equal(t, address, found)
+		}
+
+		missing := createdUserAddresses[0]
+		missing.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fad3")
+		missing.UserID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70ffff")
+		_, err := repo.Create(ctx, missing)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		addresses, err := repo.GetByUserID(ctx, createdUserAddresses[0].UserID)
+		if err != nil {
+			t.Errorf("failed to GetByUserID: %v", err)
+		}
+		require.Equal(t, createdUserAddresses, addresses)
+
+		err = repo.SetDefault(ctx, createdUserAddresses[1].UserID, createdUserAddresses[1].ID)
+		if err != nil {
+			t.Errorf("failed to SetDefault: %v", err)
+		}
+		expected := []domain.UserAddress{createdUserAddresses[1], createdUserAddresses[0]}
+		expected[0].IsDefault = true
+		expected[1].IsDefault = false
+		addresses, err = repo.GetByUserID(ctx, createdUserAddresses[0].UserID)
+		if err != nil {
+			t.Errorf("failed to GetByUserID: %v", err)
+		}
+		require.Equal(t, expected, addresses)
+
+		err = repo.SetDefault(ctx, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"), createdUserAddresses[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+
+	t.Run("test Update and Delete", func(t *testing.T) {
+		repo := mongodb.NewUserAddressRepo(db)
+		updated := createdUserAddresses[0]
+		updated.Phone = "+78888888888"
+		updated.IsDefault = true
+		found, err := repo.Update(ctx, updated)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		require.Equal(t, updated, found)
+
+		found, err = repo.GetByID(ctx, createdUserAddresses[1].ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.False(t, found.IsDefault)
+
+		err = repo.Delete(ctx, createdUserAddresses[1].ID)
+		if err != nil {
+			t.Errorf("failed to Delete: %v", err)
+		}
+		_, err = repo.GetByID(ctx, createdUserAddresses[1].ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+
+	t.Run("test CreateOrderCustomer with address", func(t *testing.T) {
+		repo := mongodb.NewUserAddressRepo(db)
+		address, err := repo.GetByID(ctx, createdUserAddresses[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+
+		orderCustomer := createdOrderCustomers[0]
+		orderCustomer.Address = ""
+		orderCustomer.AddressID = address.ID
+
+		orderRepo := mongodb.NewOrderRepo(db)
+		found, err := orderRepo.CreateOrderCustomer(ctx, orderCustomer)
+		if err != nil {
+			t.Errorf("failed to CreateOrderCustomer: %v", err)
+		}
+		expected := orderCustomer
+		expected.Address = "101000, Russia, Moscow, Pushkina 1-2-4"
+		expected.ShippingAddress = domain.OrderAddress{
+			Recipient:  address.Recipient,
+			Phone:      address.Phone,
+			Country:    address.Country,
+			City:       address.City,
+			Street:     address.Street,
+			PostalCode: address.PostalCode,
+		}
+		require.Equal(t, expected, found)
+
+		address.Street = "Arbat 5"
+		_, err = repo.Update(ctx, address)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		found, err = orderRepo.GetOrderCustomerByID(ctx, orderCustomer.ID)
+		if err != nil {
+			t.Errorf("failed to GetOrderCustomerByID: %v", err)
+		}
+		require.Equal(t, expected.ShippingAddress, found.ShippingAddress)
+	})
+
+	t.Run("test CreateOrderCustomer foreign address", func(t *testing.T) {
+		foreign := createdUserAddresses[1]
+		foreign.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fad4")
+		foreign.UserID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb")
+		_, err := mongodb.NewUserAddressRepo(db).Create(ctx, foreign)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		orderCustomer := createdOrderCustomers[0]
+		orderCustomer.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70eef2")
+		orderCustomer.AddressID = foreign.ID
+
+		orderRepo := mongodb.NewOrderRepo(db)
+		_, err = orderRepo.CreateOrderCustomer(ctx, orderCustomer)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		_, err = orderRepo.GetOrderCustomerByID(ctx, orderCustomer.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+
+	t.Run("test delete user", func(t *testing.T) {
+		userID := domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb")
+		err := mongodb.NewUserRepo(db).Delete(ctx, userID)
+		if err != nil {
+			t.Errorf("failed to Delete user: %v", err)
+		}
+		addresses, err := mongodb.NewUserAddressRepo(db).GetByUserID(ctx, userID)
+		if err != nil {
+			t.Errorf("failed to GetByUserID: %v", err)
+		}
+		require.Empty(t, addresses)
+	})
+}
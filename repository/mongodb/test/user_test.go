@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/EmirShimshir/marketplace-core/domain"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
@@ -9,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/mongo"
 	"testing"
+	"time"
 )
 
 var users = []domain.User{
@@ -58,8 +60,11 @@ var updatedUser = domain.User{
 
 func InitUsersMongoDB(ctx context.Context, db *mongo.Database) error {
 	for _, user := range users {
-		var mgUser = entity.NewMgUser(user)
-		_, err := db.Collection(mongodb.UserCollection).InsertOne(ctx, mgUser)
+		mgUser, err := entity.NewMgUser(user)
+		if err != nil {
+			return err
+		}
+		_, err = db.Collection(mongodb.UserCollection).InsertOne(ctx, mgUser)
 		if err != nil {
 			return err
 		}
@@ -68,6 +73,17 @@ func InitUsersMongoDB(ctx context.Context, db *mongo.Database) error {
 	return nil
 }
 
+var searchedUser = domain.User{
+	ID:       domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027ce"),
+	CartID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b111112"),
+	Name:     "Zinaida",
+	Surname:  "Zakharova",
+	Phone:    null.StringFrom("+79995554433"),
+	Email:    "zina@mail.ru",
+	Password: "password",
+	Role:     domain.UserSeller,
+}
+
 func TestUserRepository(t *testing.T) {
 	ctx := context.Background()
 	container, err := newMongoContainer(ctx)
@@ -96,6 +112,34 @@ func TestUserRepository(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	err = InitCartsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitShopsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitShopItemsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitWithdrawsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitOrderCustomersMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitOrderShopsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = InitOrderShopItemsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	t.Run("test get users", func(t *testing.T) {
 		repo := mongodb.NewUserRepo(db)
@@ -137,11 +181,248 @@ func TestUserRepository(t *testing.T) {
 		require.Equal(t, user, updatedUser)
 	})
 
+	t.Run("test ExportUserData", func(t *testing.T) {
+		repo := mongodb.NewUserRepo(db)
+		data, err := repo.ExportUserData(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to ExportUserData: %v", err)
+		}
+
+		var export domain.UserDataExport
+		err = json.Unmarshal(data, &export)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, users[0].Email, export.User.Email)
+		require.Empty(t, export.User.Password)
+		require.Equal(t, users[0].CartID, export.Cart.ID)
+		require.Equal(t, 1, len(export.Shops))
+		require.Equal(t, shops[0].ID, export.Shops[0].ID)
+		require.Equal(t, withdraws, export.Withdraws)
+		require.WithinDuration(t, time.Now(), export.ExportedAt, time.Minute)
+	})
+
+	t.Run("test EraseUser", func(t *testing.T) {
+		repo := mongodb.NewUserRepo(db)
+		err = repo.EraseUser(ctx, users[1].ID)
+		if err != nil {
+			t.Errorf("failed to EraseUser: %v", err)
+		}
+
+		erased, err := repo.GetByID(ctx, users[1].ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.NotEqual(t, updatedUser.Name, erased.Name)
+		require.NotEqual(t, updatedUser.Email, erased.Email)
+		require.False(t, erased.Phone.Valid)
+		require.Empty(t, erased.Password)
+
+		_, err = repo.GetByEmail(ctx, updatedUser.Email)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		orderCustomer, err := mongodb.NewOrderRepo(db).GetOrderCustomerByID(ctx, orderCustomers[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetOrderCustomerByID: %v", err)
+		}
+		require.Equal(t, users[1].ID, orderCustomer.CustomerID)
+		require.Equal(t, orderCustomers[0].TotalPrice, orderCustomer.TotalPrice)
+		require.Empty(t, orderCustomer.Address)
+	})
+
+	t.Run("test BlockUser and UnblockUser", func(t *testing.T) {
+		sessions := newSessions()
+		sessionRepo := mongodb.NewSessionRepo(db)
+		for i := range sessions {
+			_, err = sessionRepo.Create(ctx, sessions[i], sessionTokens[i])
+			if err != nil {
+				t.Errorf("failed to Create session: %v", err)
+			}
+		}
+
+		repo := mongodb.NewUserRepo(db)
+		block := domain.ModerationAction{
+			TargetID:    users[0].ID,
+			ModeratorID: users[1].ID,
+			Reason:      "fraud",
+		}
+		blocked, err := repo.BlockUser(ctx, block)
+		if err != nil {
+			t.Errorf("failed to BlockUser: %v", err)
+		}
+		require.True(t, blocked.BlockedAt.Valid)
+		require.Equal(t, "fraud", blocked.BlockedReason)
+
+		_, err = repo.BlockUser(ctx, block)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		userSessions, err := sessionRepo.GetByUserID(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetByUserID: %v", err)
+		}
+		require.Equal(t, len(sessions), len(userSessions))
+		for _, session := range userSessions {
+			require.True(t, session.RevokedAt.Valid)
+		}
+
+		updated, err := repo.Update(ctx, users[0])
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		require.Equal(t, blocked.BlockedAt, updated.BlockedAt)
+
+		unblocked, err := repo.UnblockUser(ctx, domain.ModerationAction{TargetID: users[0].ID, ModeratorID: users[1].ID})
+		if err != nil {
+			t.Errorf("failed to UnblockUser: %v", err)
+		}
+		require.Equal(t, users[0], unblocked)
+
+		_, err = repo.UnblockUser(ctx, domain.ModerationAction{TargetID: users[0].ID})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		history, err := repo.GetModerationHistory(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetModerationHistory: %v", err)
+		}
+		require.Equal(t, 2, len(history))
+		require.Equal(t, domain.ModerationActionBlock, history[0].Action)
+		require.Equal(t, users[1].ID, history[0].ModeratorID)
+		require.Equal(t, "fraud", history[0].Reason)
+		require.Equal(t, domain.ModerationActionUnblock, history[1].Action)
+	})
+
 	t.Run("test delete user", func(t *testing.T) {
 		repo := mongodb.NewUserRepo(db)
-		err = repo.Delete(ctx, users[0].CartID)
+		err = repo.Delete(ctx, users[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		err = repo.Delete(ctx, createdUser.ID)
 		if err != nil {
 			t.Errorf("failed to delete user: %v", err)
 		}
+		_, err = repo.GetByID(ctx, createdUser.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+}
+
+func TestSearchUsers(t *testing.T) {
+	ctx := context.Background()
+	container, err := newMongoContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newMongoDB(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = InitUsersMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := mongodb.NewUserRepo(db)
+	_, err = repo.Create(ctx, searchedUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test SearchUsers filters", func(t *testing.T) {
+		found, next, err := repo.SearchUsers(ctx, domain.UserQuery{NamePrefix: "ti"})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{users[0]}, found)
+		require.Empty(t, next)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{SurnamePrefix: "ZAKH"})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser}, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{NamePrefix: "%"})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Empty(t, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{Phone: users[0].Phone.String})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{users[0]}, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{Email: searchedUser.Email})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser}, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{Roles: []domain.UserRole{domain.UserSeller, domain.UserModerator}})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser}, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{Blocked: null.BoolFrom(true)})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Empty(t, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{Blocked: null.BoolFrom(false), Descending: true})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser, users[1], users[0]}, found)
+	})
+
+	t.Run("test SearchUsers pagination", func(t *testing.T) {
+		query := domain.UserQuery{SortBy: domain.UserSortBySurname, Limit: 2}
+		found, next, err := repo.SearchUsers(ctx, query)
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{users[0], users[1]}, found)
+		require.NotEmpty(t, next)
+
+		query.Cursor = next
+		found, next, err = repo.SearchUsers(ctx, query)
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser}, found)
+		require.Empty(t, next)
+
+		query = domain.UserQuery{Descending: true, Limit: 1}
+		found, next, err = repo.SearchUsers(ctx, query)
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser}, found)
+
+		query.Cursor = next
+		found, _, err = repo.SearchUsers(ctx, query)
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{users[1]}, found)
+
+		_, _, err = repo.SearchUsers(ctx, domain.UserQuery{Cursor: "not a cursor"})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
 	})
 }
@@ -0,0 +1,179 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+var userTokens = []domain.UserToken{
+	domain.UserToken{
+		ID:      domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fe01"),
+		UserID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
+		Purpose: domain.TokenPurposeEmailVerification,
+	},
+	domain.UserToken{
+		ID:      domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fe02"),
+		UserID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
+		Purpose: domain.TokenPurposePasswordReset,
+	},
+}
+
+func TestUserTokenRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newMongoContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newMongoDB(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = InitUsersMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test Consume email verification", func(t *testing.T) {
+		repo := mongodb.NewUserTokenRepo(db)
+		token := userTokens[0]
+		token.ExpiresAt = time.Now().Add(time.Hour)
+		found, err := repo.Create(ctx, token, "verify-email-token")
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.WithinDuration(t, time.Now(), found.CreatedAt, time.Minute)
+		require.NotEqual(t, "verify-email-token", found.TokenHash)
+		require.False(t, found.ConsumedAt.Valid)
+
+		_, err = repo.Consume(ctx, "verify-email-token", domain.TokenPurposePasswordReset)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		consumed, err := repo.Consume(ctx, "verify-email-token", domain.TokenPurposeEmailVerification)
+		if err != nil {
+			t.Errorf("failed to Consume: %v", err)
+		}
+		require.Equal(t, token.ID, consumed.ID)
+		require.True(t, consumed.ConsumedAt.Valid)
+
+		user, err := mongodb.NewUserRepo(db).GetByID(ctx, token.UserID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.True(t, user.EmailVerifiedAt.Valid)
+		require.WithinDuration(t, consumed.ConsumedAt.Time, user.EmailVerifiedAt.Time, time.Millisecond)
+
+		_, err = repo.Consume(ctx, "verify-email-token", domain.TokenPurposeEmailVerification)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		userRepo := mongodb.NewUserRepo(db)
+		user.EmailVerifiedAt = null.Time{}
+		user, err = userRepo.Update(ctx, user)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		require.True(t, user.EmailVerifiedAt.Valid)
+
+		user.Email = "changed@mail.ru"
+		user, err = userRepo.Update(ctx, user)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		require.False(t, user.EmailVerifiedAt.Valid)
+	})
+
+	t.Run("test Consume email verification after the email changed", func(t *testing.T) {
+		repo := mongodb.NewUserTokenRepo(db)
+		token := userTokens[0]
+		token.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fe03")
+		token.ExpiresAt = time.Now().Add(time.Hour)
+		_, err := repo.Create(ctx, token, "verify-old-email-token")
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		userRepo := mongodb.NewUserRepo(db)
+		user, err := userRepo.GetByID(ctx, token.UserID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		user.Email = "changed-again@mail.ru"
+		_, err = userRepo.Update(ctx, user)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+
+		_, err = repo.Consume(ctx, "verify-old-email-token", domain.TokenPurposeEmailVerification)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		user, err = userRepo.GetByID(ctx, token.UserID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.False(t, user.EmailVerifiedAt.Valid)
+	})
+
+	t.Run("test Consume concurrently", func(t *testing.T) {
+		repo := mongodb.NewUserTokenRepo(db)
+		token := userTokens[1]
+		token.ExpiresAt = time.Now().Add(time.Hour)
+		_, err := repo.Create(ctx, token, "reset-password-token")
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		results := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repo.Consume(ctx, "reset-password-token", domain.TokenPurposePasswordReset)
+				results <- err
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		consumed := 0
+		for err := range results {
+			if err == nil {
+				consumed++
+			} else {
+				require.ErrorIs(t, err, domain.ErrNotExist)
+			}
+		}
+		require.Equal(t, 1, consumed)
+	})
+
+	t.Run("test PurgeExpired", func(t *testing.T) {
+		repo := mongodb.NewUserTokenRepo(db)
+		purged, err := repo.PurgeExpired(ctx, time.Now().Add(2*time.Hour))
+		if err != nil {
+			t.Errorf("failed to PurgeExpired: %v", err)
+		}
+		require.Equal(t, int64(2), purged)
+
+		_, err = repo.GetByID(ctx, userTokens[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+}
@@ -11,30 +11,34 @@ import (
 )
 
 type Config struct {
-	User     string
-	Password string
-	Database string
+	ReplicaSet string
+	Database   string
 }
 
 var (
 	mongoConfig = Config{
-		User:     "root",
-		Password: "password",
-		Database: "marketplace",
+		ReplicaSet: "rs0",
+		Database:   "marketplace",
 	}
 )
 
+// newMongoContainer starts a single member replica set, the repositories
+// write in transactions and mongo runs them on replica sets only.
 func newMongoContainer(ctx context.Context) (*testmg.MongoDBContainer, error) {
 	return testmg.RunContainer(
 		ctx,
-		testmg.WithUsername(mongoConfig.User),
-		testmg.WithPassword(mongoConfig.Password),
+		testcontainers.CustomizeRequestOption(func(req *testcontainers.GenericContainerRequest) {
+			req.Cmd = []string{"--replSet", mongoConfig.ReplicaSet, "--bind_ip_all"}
+		}),
 		testcontainers.WithWaitStrategy(
-			wait.ForLog("Waiting for connections")))
+			wait.ForLog("Waiting for connections")),
+		testcontainers.WithAfterReadyCommand(testcontainers.NewRawCommand([]string{"mongosh", "--quiet", "--eval",
+			"rs.initiate(); while (!db.hello().isWritablePrimary) { sleep(100) }"})))
 }
 
 func newMongoDB(ctx context.Context, url string) (*mongo.Database, error) {
-	opts := options.Client().ApplyURI(url)
+	// the member is known by the container host name, connect to it directly
+	opts := options.Client().ApplyURI(url).SetDirect(true)
 	client, err := mongo.Connect(context.Background(), opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect mongo db: %s", err)
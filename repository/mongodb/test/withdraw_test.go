@@ -5,35 +5,58 @@ import (
 	"github.com/EmirShimshir/marketplace-core/domain"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/guregu/null"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/mongo"
+	"sync"
 	"testing"
+	"time"
 )
 
 var withdraws = []domain.Withdraw{
 	domain.Withdraw{
-		ID:      domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ad1"),
-		ShopID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
-		Comment: "comment",
-		Sum:     9999,
-		Status:  domain.WithdrawStatusDone,
+		ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ad1"),
+		ShopID:      domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+		Comment:     "comment",
+		Sum:         9999,
+		Currency:    "RUB",
+		Status:      domain.WithdrawStatusDone,
+		RequestedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		ApprovedAt:  null.TimeFrom(time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC)),
+		PaidAt:      null.TimeFrom(time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC)),
 	},
 }
 
 var createdWithdraw = domain.Withdraw{
-	ID:      domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ad2"),
-	ShopID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
-	Comment: "comment new",
-	Sum:     999,
-	Status:  domain.WithdrawStatusStart,
+	ID:       domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ad2"),
+	ShopID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+	Comment:  "comment new",
+	Sum:      999,
+	Currency: "RUB",
+	Status:   domain.WithdrawStatusStart,
 }
 
 var updatedWithdraw = domain.Withdraw{
-	ID:      domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ad1"),
-	ShopID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
-	Comment: "comment 2",
-	Sum:     99992,
-	Status:  domain.WithdrawStatusDone,
+	ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ad1"),
+	ShopID:      domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+	Comment:     "comment 2",
+	Sum:         9999,
+	Currency:    "RUB",
+	Status:      domain.WithdrawStatusDone,
+	RequestedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	ApprovedAt:  null.TimeFrom(time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC)),
+	PaidAt:      null.TimeFrom(time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC)),
+}
+
+func newWithdraw(id string) domain.Withdraw {
+	return domain.Withdraw{
+		ID:       domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70" + id),
+		ShopID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+		Comment:  "weekly payout",
+		Sum:      1500,
+		Currency: "RUB",
+		Status:   domain.WithdrawStatusStart,
+	}
 }
 
 func InitWithdrawsMongoDB(ctx context.Context, db *mongo.Database) error {
@@ -72,6 +95,11 @@ func TestWithdrawRepository(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	err = InitShopsMongoDB(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	err = InitWithdrawsMongoDB(ctx, db)
 	if err != nil {
 		t.Fatal(err)
@@ -112,11 +140,156 @@ func TestWithdrawRepository(t *testing.T) {
 
 	t.Run("test create", func(t *testing.T) {
 		repo := mongodb.NewWithdrawRepo(db)
-		withdraw, err := repo.Create(ctx, createdWithdraw)
+		invalid := createdWithdraw
+		invalid.Currency = "rub"
+		_, err = repo.Create(ctx, invalid)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		// the currency of the shop is used when none is given
+		input := createdWithdraw
+		input.Currency = ""
+		withdraw, err := repo.Create(ctx, input)
 		if err != nil {
 			t.Errorf("failed to create: %v", err)
 		}
-		require.Equal(t, withdraw, createdWithdraw)
+		require.WithinDuration(t, time.Now(), withdraw.RequestedAt, time.Minute)
+		expected := createdWithdraw
+		expected.RequestedAt = withdraw.RequestedAt
+		require.Equal(t, expected, withdraw)
+
+		done := createdWithdraw
+		done.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ad9")
+		done.Status = domain.WithdrawStatusDone
+		_, err = repo.Create(ctx, done)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+
+	t.Run("test TransitionWithdraw", func(t *testing.T) {
+		repo := mongodb.NewWithdrawRepo(db)
+		withdraw, err := repo.Create(ctx, newWithdraw("2ad3"))
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		reviewerID := domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc")
+
+		withdraw.Sum = 1200
+		withdraw, err = repo.Update(ctx, withdraw)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		require.Equal(t, int64(1200), withdraw.Sum)
+
+		_, err = repo.TransitionWithdraw(ctx, withdraw.ID, domain.WithdrawStatusDone, reviewerID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		_, err = repo.TransitionWithdraw(ctx, withdraw.ID, domain.WithdrawStatusReady, "")
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		_, err = repo.TransitionWithdraw(ctx, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70ffff"), domain.WithdrawStatusReady, reviewerID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		ready, err := repo.TransitionWithdraw(ctx, withdraw.ID, domain.WithdrawStatusReady, reviewerID)
+		if err != nil {
+			t.Errorf("failed to TransitionWithdraw: %v", err)
+		}
+		require.Equal(t, domain.WithdrawStatusReady, ready.Status)
+		require.Equal(t, reviewerID, ready.ReviewerID)
+		require.True(t, ready.ApprovedAt.Valid)
+		require.False(t, ready.PaidAt.Valid)
+
+		_, err = repo.TransitionWithdraw(ctx, withdraw.ID, domain.WithdrawStatusReady, reviewerID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		_, err = repo.TransitionWithdraw(ctx, withdraw.ID, domain.WithdrawStatusStart, reviewerID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		updated := ready
+		updated.Sum = 2000
+		_, err = repo.Update(ctx, updated)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		updated = ready
+		updated.Status = domain.WithdrawStatusDone
+		updated.Comment = "approved payout"
+		updated, err = repo.Update(ctx, updated)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		require.Equal(t, domain.WithdrawStatusReady, updated.Status)
+		require.Equal(t, "approved payout", updated.Comment)
+		require.Equal(t, int64(1200), updated.Sum)
+
+		paid, err := repo.TransitionWithdraw(ctx, withdraw.ID, domain.WithdrawStatusDone, "")
+		if err != nil {
+			t.Errorf("failed to TransitionWithdraw: %v", err)
+		}
+		require.Equal(t, domain.WithdrawStatusDone, paid.Status)
+		require.Equal(t, reviewerID, paid.ReviewerID)
+		require.Equal(t, ready.ApprovedAt, paid.ApprovedAt)
+		require.True(t, paid.PaidAt.Valid)
+	})
+
+	t.Run("test TransitionWithdraw concurrently", func(t *testing.T) {
+		repo := mongodb.NewWithdrawRepo(db)
+		withdraw, err := repo.Create(ctx, newWithdraw("2ad4"))
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		results := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := repo.TransitionWithdraw(ctx, withdraw.ID, domain.WithdrawStatusReady, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"))
+				results <- err
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		approved := 0
+		for err := range results {
+			if err == nil {
+				approved++
+			} else {
+				require.ErrorIs(t, err, domain.ErrNotAllowed)
+			}
+		}
+		require.Equal(t, 1, approved)
+	})
+
+	t.Run("test GetByStatus", func(t *testing.T) {
+		repo := mongodb.NewWithdrawRepo(db)
+		queue := []domain.Withdraw{newWithdraw("2ad5"), newWithdraw("2ad6"), newWithdraw("2ad7")}
+		for i := range queue {
+			queue[i], err = repo.Create(ctx, queue[i])
+			if err != nil {
+				t.Errorf("failed to Create: %v", err)
+			}
+		}
+
+		found, err := repo.GetByStatus(ctx, domain.WithdrawStatusStart, 1, "")
+		if err != nil {
+			t.Errorf("failed to GetByStatus: %v", err)
+		}
+		require.Equal(t, createdWithdraw.ID, found[0].ID)
+
+		found, err = repo.GetByStatus(ctx, domain.WithdrawStatusStart, 2, found[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetByStatus: %v", err)
+		}
+		require.Equal(t, queue[:2], found)
+
+		found, err = repo.GetByStatus(ctx, domain.WithdrawStatusStart, 2, found[1].ID)
+		if err != nil {
+			t.Errorf("failed to GetByStatus: %v", err)
+		}
+		require.Equal(t, queue[2:], found)
+
+		found, err = repo.GetByStatus(ctx, domain.WithdrawStatusDone, 10, "")
+		if err != nil {
+			t.Errorf("failed to GetByStatus: %v", err)
+		}
+		require.Equal(t, withdraws[0].ID, found[0].ID)
 	})
 
 	t.Run("test update", func(t *testing.T) {
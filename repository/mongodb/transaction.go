@@ -0,0 +1,36 @@
+package mongodb
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// transientTransactionError labels the errors WithTransaction retries the
+// transaction callback on, write conflicts with concurrent transactions among
+// them.
+const transientTransactionError = "TransientTransactionError"
+
+// wrapTxError wraps an error of a transaction callback in kind. A transient
+// error is returned as it is, wrapping it drops its label and WithTransaction
+// would not retry the callback.
+func wrapTxError(kind error, err error) error {
+	if isTransientTxError(err) {
+		return err
+	}
+	return errors.Wrap(kind, err.Error())
+}
+
+// txError returns the error of WithTransaction, a transient error still left
+// once the retries ran out is ErrTransactionError.
+func txError(err error) error {
+	if err != nil && isTransientTxError(err) {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	return err
+}
+
+func isTransientTxError(err error) bool {
+	var labeled mongo.LabeledError
+	return errors.As(err, &labeled) && labeled.HasErrorLabel(transientTransactionError)
+}
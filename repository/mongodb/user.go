@@ -2,23 +2,31 @@ package mongodb
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/EmirShimshir/marketplace-repository/repository/usersearch"
+	"github.com/guregu/null"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
+	"regexp"
+	"strings"
+	"time"
 )
 
-type MongoUserRepo struct{
+type MongoUserRepo struct {
 	db *mongo.Collection
 }
 
 func NewUserRepo(db *mongo.Database) *MongoUserRepo {
 	collection := db.Collection(UserCollection)
 	indexModel := mongo.IndexModel{
-		Keys:    bson.D{{"email", 1}},
+		Keys:    bson.D{{"email_hash", 1}},
 		Options: options.Index().SetUnique(true),
 	}
 
@@ -27,6 +35,21 @@ func NewUserRepo(db *mongo.Database) *MongoUserRepo {
 		log.Fatalf("unable to create user collection index, %v", err)
 	}
 
+	searchIndexModels := []mongo.IndexModel{
+		{Keys: bson.D{{"role", 1}, {"_id", 1}}},
+		{Keys: bson.D{{"name", 1}, {"_id", 1}}},
+		{Keys: bson.D{{"surname", 1}, {"_id", 1}}},
+		{Keys: bson.D{{"name_lower", 1}}},
+		{Keys: bson.D{{"surname_lower", 1}}},
+		{Keys: bson.D{{"phone_hash", 1}}, Options: options.Index().SetSparse(true)},
+		{Keys: bson.D{{"blocked_at", 1}}, Options: options.Index().SetSparse(true)},
+	}
+	_, err = collection.Indexes().CreateMany(context.Background(), searchIndexModels)
+	if err != nil {
+		log.Fatalf("unable to create user collection index, %v", err)
+	}
+	createModerationIndex(db)
+
 	return &MongoUserRepo{
 		db: collection,
 	}
@@ -49,14 +72,14 @@ func (u *MongoUserRepo) Get(ctx context.Context, limit, offset int64) ([]domain.
 
 	users := make([]domain.User, len(mgUsersArray))
 	for i, user := range mgUsersArray {
-		users[i] = user.ToDomain()
+		if users[i], err = user.ToDomain(); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
 	}
 
 	return users, nil
 }
 
-
-
 func (u *MongoUserRepo) GetByID(ctx context.Context, userID domain.ID) (domain.User, error) {
 	result := u.db.FindOne(ctx, bson.M{"_id": userID})
 
@@ -67,11 +90,15 @@ func (u *MongoUserRepo) GetByID(ctx context.Context, userID domain.ID) (domain.U
 		}
 		return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 	}
-	return mgUser.ToDomain(), nil
+	user, err := mgUser.ToDomain()
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return user, nil
 }
 
 func (u *MongoUserRepo) GetByEmail(ctx context.Context, email string) (domain.User, error) {
-	result := u.db.FindOne(ctx, bson.M{"email":email})
+	result := u.db.FindOne(ctx, bson.M{"email_hash": encryption.BlindIndex(email)})
 
 	var mgUser entity.MgUser
 	if err := result.Decode(&mgUser); err != nil {
@@ -80,7 +107,105 @@ func (u *MongoUserRepo) GetByEmail(ctx context.Context, email string) (domain.Us
 		}
 		return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 	}
-	return mgUser.ToDomain(), nil
+	user, err := mgUser.ToDomain()
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return user, nil
+}
+
+// SearchUsers lists the users matching the query for moderators. Name and
+// surname match by case-insensitive prefix. Email and phone are stored
+// encrypted, so they only match exactly through their blind indexes. The
+// returned cursor fetches the next page and is empty on the last page.
+func (u *MongoUserRepo) SearchUsers(ctx context.Context, query domain.UserQuery) ([]domain.User, string, error) {
+	var cursor usersearch.Cursor
+	if query.Cursor != "" {
+		var err error
+		if cursor, err = usersearch.DecodeCursor(query.Cursor); err != nil {
+			return nil, "", err
+		}
+	}
+	if query.Limit <= 0 {
+		query.Limit = usersearch.DefaultLimit
+	}
+
+	filter := bson.M{}
+	if len(query.Roles) > 0 {
+		roles := make([]string, len(query.Roles))
+		for i, role := range query.Roles {
+			roles[i] = entity.NewMgUserRole(role)
+		}
+		filter["role"] = bson.M{"$in": roles}
+	}
+	if query.NamePrefix != "" {
+		filter["name_lower"] = bson.M{"$regex": "^" + regexp.QuoteMeta(strings.ToLower(query.NamePrefix))}
+	}
+	if query.SurnamePrefix != "" {
+		filter["surname_lower"] = bson.M{"$regex": "^" + regexp.QuoteMeta(strings.ToLower(query.SurnamePrefix))}
+	}
+	if query.Email != "" {
+		filter["email_hash"] = encryption.BlindIndex(query.Email)
+	}
+	if query.Phone != "" {
+		filter["phone_hash"] = encryption.BlindIndex(query.Phone)
+	}
+	if query.Blocked.Valid {
+		filter["blocked_at"] = bson.M{"$exists": query.Blocked.Bool}
+	}
+
+	var field string
+	switch query.SortBy {
+	case domain.UserSortByName:
+		field = "name"
+	case domain.UserSortBySurname:
+		field = "surname"
+	}
+	direction, compare := 1, "$gt"
+	if query.Descending {
+		direction, compare = -1, "$lt"
+	}
+	sort := bson.D{{"_id", direction}}
+	if field != "" {
+		sort = bson.D{{field, direction}, {"_id", direction}}
+	}
+	if query.Cursor != "" {
+		if field == "" {
+			filter["_id"] = bson.M{compare: cursor.ID}
+		} else {
+			filter["$or"] = bson.A{
+				bson.M{field: bson.M{compare: cursor.Value}},
+				bson.M{field: cursor.Value, "_id": bson.M{compare: cursor.ID}},
+			}
+		}
+	}
+
+	opts := options.Find().SetSort(sort).SetLimit(query.Limit + 1)
+	result, err := u.db.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var mgUsers []entity.MgUser
+	if err = result.All(ctx, &mgUsers); err != nil {
+		return nil, "", errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	more := int64(len(mgUsers)) > query.Limit
+	if more {
+		mgUsers = mgUsers[:query.Limit]
+	}
+
+	users := make([]domain.User, len(mgUsers))
+	for i, mgUser := range mgUsers {
+		if users[i], err = mgUser.ToDomain(); err != nil {
+			return nil, "", errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	var next string
+	if more {
+		next = usersearch.NewCursor(users[len(users)-1], query.SortBy).Encode()
+	}
+	return users, next, nil
 }
 
 func (u *MongoUserRepo) Create(ctx context.Context, user domain.User) (domain.User, error) {
@@ -91,7 +216,8 @@ func (u *MongoUserRepo) Create(ctx context.Context, user domain.User) (domain.Us
 	defer session.EndSession(ctx)
 
 	err = mongo.WithSession(ctx, session, func(sessionContext mongo.SessionContext) error {
-		var mgCart = entity.NewMgCart(domain.Cart{ID: user.CartID, Price: 0})
+		now := time.Now().UTC()
+		var mgCart = entity.NewMgCart(domain.Cart{ID: user.CartID, Price: 0, CreatedAt: now, UpdatedAt: now})
 		_, err := u.db.Database().Collection(CartCollection).InsertOne(sessionContext, mgCart)
 		if err != nil {
 			if mongo.IsDuplicateKeyError(err) {
@@ -100,7 +226,10 @@ func (u *MongoUserRepo) Create(ctx context.Context, user domain.User) (domain.Us
 			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 		}
 
-		var mgUser = entity.NewMgUser(user)
+		mgUser, err := entity.NewMgUser(user)
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
 		_, err = u.db.InsertOne(sessionContext, mgUser)
 		if err != nil {
 			if mongo.IsDuplicateKeyError(err) {
@@ -118,23 +247,335 @@ func (u *MongoUserRepo) Create(ctx context.Context, user domain.User) (domain.Us
 	return u.GetByID(ctx, user.ID)
 }
 
+// Update rewrites the user fields, the block state is only changed by
+// BlockUser and UnblockUser and the email is only verified by consuming a
+// verification token. A changed email is no longer verified and the pending
+// verification tokens of the old email are deleted in the same transaction, a
+// concurrent Consume of one of them conflicts with it.
 func (u *MongoUserRepo) Update(ctx context.Context, user domain.User) (domain.User, error) {
-	var mgUser = entity.NewMgUser(user)
-	_, err := u.db.ReplaceOne(ctx, bson.M{"_id": mgUser.ID}, mgUser)
+	current, err := u.GetByID(ctx, user.ID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	user.BlockedAt = current.BlockedAt
+	user.BlockedReason = current.BlockedReason
+	user.EmailVerifiedAt = current.EmailVerifiedAt
+	if user.Email != current.Email {
+		user.EmailVerifiedAt = null.Time{}
+	}
+
+	mgUser, err := entity.NewMgUser(user)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return domain.User{}, errors.Wrap(domain.ErrNotExist, err.Error())
-		}
 		return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 	}
 
+	session, err := u.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		if user.Email != current.Email {
+			_, err := u.db.Database().Collection(UserTokenCollection).DeleteMany(sessionContext, bson.M{
+				"user_id":     mgUser.ID,
+				"purpose":     entity.NewMgTokenPurpose(domain.TokenPurposeEmailVerification),
+				"consumed_at": bson.M{"$exists": false},
+			})
+			if err != nil {
+				return nil, wrapTxError(domain.ErrDeleteFailed, err)
+			}
+		}
+		_, err := u.db.ReplaceOne(sessionContext, bson.M{"_id": mgUser.ID}, mgUser)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+			return nil, wrapTxError(domain.ErrPersistenceFailed, err)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.User{}, txError(err)
+	}
+
 	return u.GetByID(ctx, user.ID)
 }
 
+// Delete removes the user together with the addresses, the wishlists, the
+// sessions and the tokens of the user. A user with orders, shops or reviews is
+// not deleted, EraseUser anonymizes it instead.
 func (u *MongoUserRepo) Delete(ctx context.Context, userID domain.ID) error {
-	_, err := u.db.DeleteOne(ctx, bson.M{"_id": userID})
+	session, err := u.db.Database().Client().StartSession()
+	if err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		for collection, field := range map[string]string{
+			OrderCustomerCollection: "customer_id",
+			ShopCollection:          "seller_id",
+			ReviewCollection:        "user_id",
+		} {
+			count, err := u.db.Database().Collection(collection).CountDocuments(sessionContext, bson.M{field: userID},
+				options.Count().SetLimit(1))
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if count != 0 {
+				return nil, errors.Wrap(domain.ErrNotAllowed, "user has orders, shops or reviews, erase the user instead")
+			}
+		}
+
+		_, err := u.db.DeleteOne(sessionContext, bson.M{"_id": userID})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+		}
+		for _, collection := range []string{UserAddressCollection, SessionCollection, UserTokenCollection} {
+			_, err = u.db.Database().Collection(collection).DeleteMany(sessionContext, bson.M{"user_id": userID})
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+			}
+		}
+		return nil, deleteUserWishlists(sessionContext, u.db.Database(), userID)
+	})
+	return err
+}
+
+// deleteUserWishlists deletes the wishlists of the user with their items.
+func deleteUserWishlists(ctx context.Context, db *mongo.Database, userID domain.ID) error {
+	cursor, err := db.Collection(WishlistCollection).Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var mgWishlists []entity.MgWishlist
+	if err = cursor.All(ctx, &mgWishlists); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if len(mgWishlists) == 0 {
+		return nil
+	}
+
+	wishlistIDs := make([]string, len(mgWishlists))
+	for i, mgWishlist := range mgWishlists {
+		wishlistIDs[i] = mgWishlist.ID
+	}
+	_, err = db.Collection(WishlistProductCollection).DeleteMany(ctx, bson.M{"wishlist_id": bson.M{"$in": wishlistIDs}})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	_, err = db.Collection(WishlistCollection).DeleteMany(ctx, bson.M{"user_id": userID})
 	if err != nil {
 		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
 	}
 	return nil
 }
+
+// ExportUserData returns the personal data of the user as a JSON encoded
+// domain.UserDataExport. The password hash is left out.
+func (u *MongoUserRepo) ExportUserData(ctx context.Context, userID domain.ID) ([]byte, error) {
+	user, err := u.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = ""
+
+	db := u.db.Database()
+	export := domain.UserDataExport{
+		User:       user,
+		ExportedAt: time.Now().UTC(),
+	}
+	cartRepo := &MongoCartRepo{db: db.Collection(CartCollection)}
+	if export.Cart, err = cartRepo.GetCartByID(ctx, user.CartID); err != nil {
+		return nil, err
+	}
+	addressRepo := &MongoUserAddressRepo{db: db.Collection(UserAddressCollection)}
+	if export.Addresses, err = addressRepo.GetByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	orderRepo := &MongoOrderRepo{db: db.Collection(OrderCustomerCollection)}
+	if export.Orders, err = orderRepo.GetOrderCustomerByCustomerID(ctx, userID); err != nil {
+		return nil, err
+	}
+	shopRepo := &MongoShopRepo{db: db.Collection(ShopCollection)}
+	if export.Shops, err = shopRepo.GetShopBySellerID(ctx, userID); err != nil {
+		return nil, err
+	}
+	withdrawRepo := &MongoWithdrawRepo{db: db.Collection(WithdrawCollection)}
+	export.Withdraws = make([]domain.Withdraw, 0)
+	for _, shop := range export.Shops {
+		withdraws, err := withdrawRepo.GetByShopID(ctx, shop.ID)
+		if err != nil {
+			return nil, err
+		}
+		export.Withdraws = append(export.Withdraws, withdraws...)
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return data, nil
+}
+
+// erasedUser replaces the personal data of the user with placeholders, the
+// email stays unique so the document keeps satisfying its indexes.
+func erasedUser(user domain.User) domain.User {
+	user.Name = "Deleted"
+	user.Surname = "User"
+	user.Phone = null.String{}
+	user.Email = fmt.Sprintf("erased-%s@erased.invalid", user.ID)
+	user.Password = ""
+	user.EmailVerifiedAt = null.Time{}
+	return user
+}
+
+// EraseUser anonymizes the user instead of deleting it, so the orders, shops
+// and withdraws that sellers and accounting rely on are kept. The contact
+// details of the orders are cleared, the country and city of the shipping
+// address stay for tax reporting. Addresses, wishlists, cart items, sessions
+// and tokens of the user are deleted.
+func (u *MongoUserRepo) EraseUser(ctx context.Context, userID domain.ID) error {
+	user, err := u.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	session, err := u.db.Database().Client().StartSession()
+	if err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	db := u.db.Database()
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		mgUser, err := entity.NewMgUser(erasedUser(user))
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if _, err = u.db.ReplaceOne(sessionContext, bson.M{"_id": mgUser.ID}, mgUser); err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+
+		_, err = db.Collection(OrderCustomerCollection).UpdateMany(sessionContext, bson.M{"customer_id": userID},
+			bson.M{"$set": bson.M{"address": ""}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		_, err = db.Collection(OrderCustomerCollection).UpdateMany(sessionContext,
+			bson.M{"customer_id": userID, "shipping_address": bson.M{"$exists": true}},
+			bson.M{"$set": bson.M{
+				"shipping_address.recipient":   "",
+				"shipping_address.phone":       "",
+				"shipping_address.street":      "",
+				"shipping_address.postal_code": "",
+			}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+
+		_, err = db.Collection(CartProductCollection).DeleteMany(sessionContext, bson.M{"cart_id": user.CartID})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+		}
+		_, err = db.Collection(CartCollection).UpdateOne(sessionContext, bson.M{"_id": user.CartID},
+			bson.M{"$set": bson.M{"price": 0}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+
+		if err = deleteUserWishlists(sessionContext, db, userID); err != nil {
+			return nil, err
+		}
+		for _, collection := range []string{UserAddressCollection, SessionCollection, UserTokenCollection} {
+			_, err = db.Collection(collection).DeleteMany(sessionContext, bson.M{"user_id": userID})
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// BlockUser blocks the user given by the TargetID of the action and revokes
+// the active sessions of the user. The action is added to the moderation
+// history of the user, its type and time are set here.
+func (u *MongoUserRepo) BlockUser(ctx context.Context, action domain.ModerationAction) (domain.User, error) {
+	action.Action = domain.ModerationActionBlock
+	action.CreatedAt = time.Now().UTC()
+	if _, err := u.GetByID(ctx, action.TargetID); err != nil {
+		return domain.User{}, err
+	}
+
+	session, err := u.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		res, err := u.db.UpdateOne(sessionContext,
+			bson.M{"_id": action.TargetID, "blocked_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"blocked_at": action.CreatedAt, "blocked_reason": action.Reason}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		if res.MatchedCount == 0 {
+			return nil, errors.Wrap(domain.ErrNotAllowed, "user is already blocked")
+		}
+		_, err = u.db.Database().Collection(SessionCollection).UpdateMany(sessionContext,
+			bson.M{"user_id": action.TargetID, "revoked_at": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"revoked_at": action.CreatedAt}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		return nil, addModerationAction(sessionContext, u.db.Database(), entity.MgModerationTargetUser, action)
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return u.GetByID(ctx, action.TargetID)
+}
+
+// UnblockUser lifts the block of the user given by the TargetID of the
+// action and adds the action to the moderation history of the user.
+func (u *MongoUserRepo) UnblockUser(ctx context.Context, action domain.ModerationAction) (domain.User, error) {
+	action.Action = domain.ModerationActionUnblock
+	action.CreatedAt = time.Now().UTC()
+	if _, err := u.GetByID(ctx, action.TargetID); err != nil {
+		return domain.User{}, err
+	}
+
+	session, err := u.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		res, err := u.db.UpdateOne(sessionContext,
+			bson.M{"_id": action.TargetID, "blocked_at": bson.M{"$exists": true}},
+			bson.M{"$unset": bson.M{"blocked_at": "", "blocked_reason": ""}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		if res.MatchedCount == 0 {
+			return nil, errors.Wrap(domain.ErrNotAllowed, "user is not blocked")
+		}
+		return nil, addModerationAction(sessionContext, u.db.Database(), entity.MgModerationTargetUser, action)
+	})
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	return u.GetByID(ctx, action.TargetID)
+}
+
+// GetModerationHistory returns the moderation actions taken on the user,
+// the oldest first.
+func (u *MongoUserRepo) GetModerationHistory(ctx context.Context, userID domain.ID) ([]domain.ModerationAction, error) {
+	return getModerationHistory(ctx, u.db.Database(), entity.MgModerationTargetUser, userID)
+}
@@ -0,0 +1,208 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+)
+
+type MongoUserAddressRepo struct {
+	db *mongo.Collection
+}
+
+func NewUserAddressRepo(db *mongo.Database) *MongoUserAddressRepo {
+	collection := db.Collection(UserAddressCollection)
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{"user_id", 1}},
+	}
+	_, err := collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create user address collection index, %v", err)
+	}
+
+	defaultIndexModel := mongo.IndexModel{
+		Keys: bson.D{{"user_id", 1}, {"is_default", 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"is_default": true}),
+	}
+	_, err = collection.Indexes().CreateOne(context.Background(), defaultIndexModel)
+	if err != nil {
+		log.Fatalf("unable to create user address collection index, %v", err)
+	}
+
+	return &MongoUserAddressRepo{
+		db: collection,
+	}
+}
+
+func (u *MongoUserAddressRepo) GetByID(ctx context.Context, addressID domain.ID) (domain.UserAddress, error) {
+	var mgUserAddress entity.MgUserAddress
+	if err := u.db.FindOne(ctx, bson.M{"_id": addressID}).Decode(&mgUserAddress); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.UserAddress{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	address, err := mgUserAddress.ToDomain()
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return address, nil
+}
+
+// GetByUserID returns the addresses of the user, the default one comes first.
+func (u *MongoUserAddressRepo) GetByUserID(ctx context.Context, userID domain.ID) ([]domain.UserAddress, error) {
+	opts := options.Find().SetSort(bson.D{{"is_default", -1}, {"_id", 1}})
+	cursor, err := u.db.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgUserAddresses []entity.MgUserAddress
+	if err = cursor.All(ctx, &mgUserAddresses); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	addresses := make([]domain.UserAddress, len(mgUserAddresses))
+	for i, mgUserAddress := range mgUserAddresses {
+		if addresses[i], err = mgUserAddress.ToDomain(); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return addresses, nil
+}
+
+// clearDefault drops the default flag from every other address of the user,
+// so that at most one address stays the default.
+func (u *MongoUserAddressRepo) clearDefault(ctx context.Context, userID, addressID domain.ID) error {
+	filter := bson.M{"user_id": userID, "_id": bson.M{"$ne": addressID}, "is_default": true}
+	_, err := u.db.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"is_default": false}})
+	if err != nil {
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	return nil
+}
+
+func (u *MongoUserAddressRepo) Create(ctx context.Context, address domain.UserAddress) (domain.UserAddress, error) {
+	count, err := u.db.Database().Collection(UserCollection).CountDocuments(ctx, bson.M{"_id": address.UserID})
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if count == 0 {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrNotExist, "user not found")
+	}
+	mgUserAddress, err := entity.NewMgUserAddress(address)
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	session, err := u.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		if address.IsDefault {
+			if err := u.clearDefault(sessionContext, address.UserID, address.ID); err != nil {
+				return nil, err
+			}
+		}
+		_, err := u.db.InsertOne(sessionContext, mgUserAddress)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return nil, errors.Wrap(domain.ErrDuplicate, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.UserAddress{}, err
+	}
+
+	return u.GetByID(ctx, address.ID)
+}
+
+// Update rewrites the address fields, the owner of the address can not be changed.
+func (u *MongoUserAddressRepo) Update(ctx context.Context, address domain.UserAddress) (domain.UserAddress, error) {
+	current, err := u.GetByID(ctx, address.ID)
+	if err != nil {
+		return domain.UserAddress{}, err
+	}
+	address.UserID = current.UserID
+	mgUserAddress, err := entity.NewMgUserAddress(address)
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	session, err := u.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		if address.IsDefault {
+			if err := u.clearDefault(sessionContext, address.UserID, address.ID); err != nil {
+				return nil, err
+			}
+		}
+		_, err := u.db.ReplaceOne(sessionContext, bson.M{"_id": mgUserAddress.ID}, mgUserAddress)
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.UserAddress{}, err
+	}
+
+	return u.GetByID(ctx, address.ID)
+}
+
+func (u *MongoUserAddressRepo) Delete(ctx context.Context, addressID domain.ID) error {
+	_, err := u.db.DeleteOne(ctx, bson.M{"_id": addressID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// SetDefault makes the address the default one of the user.
+func (u *MongoUserAddressRepo) SetDefault(ctx context.Context, userID domain.ID, addressID domain.ID) error {
+	count, err := u.db.CountDocuments(ctx, bson.M{"_id": addressID, "user_id": userID})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if count == 0 {
+		return errors.Wrap(domain.ErrNotExist, "address of the user not found")
+	}
+
+	session, err := u.db.Database().Client().StartSession()
+	if err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		if err := u.clearDefault(sessionContext, userID, addressID); err != nil {
+			return nil, err
+		}
+		res, err := u.db.UpdateOne(sessionContext, bson.M{"_id": addressID, "user_id": userID},
+			bson.M{"$set": bson.M{"is_default": true}})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		if res.MatchedCount == 0 {
+			return nil, errors.Wrap(domain.ErrNotExist, "address of the user not found")
+		}
+		return nil, nil
+	})
+	return err
+}
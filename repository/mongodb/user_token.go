@@ -0,0 +1,137 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+type MongoUserTokenRepo struct {
+	db *mongo.Collection
+}
+
+func NewUserTokenRepo(db *mongo.Database) *MongoUserTokenRepo {
+	collection := db.Collection(UserTokenCollection)
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{"token_hash", 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create user token collection index, %v", err)
+	}
+
+	ttlIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{"expires_at", 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	_, err = collection.Indexes().CreateOne(context.Background(), ttlIndexModel)
+	if err != nil {
+		log.Fatalf("unable to create user token collection ttl index, %v", err)
+	}
+
+	return &MongoUserTokenRepo{
+		db: collection,
+	}
+}
+
+func (u *MongoUserTokenRepo) GetByID(ctx context.Context, tokenID domain.ID) (domain.UserToken, error) {
+	var mgUserToken entity.MgUserToken
+	if err := u.db.FindOne(ctx, bson.M{"_id": tokenID}).Decode(&mgUserToken); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.UserToken{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.UserToken{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgUserToken.ToDomain(), nil
+}
+
+// Create stores the token with the hash of rawToken, the TokenHash of token
+// is ignored.
+func (u *MongoUserTokenRepo) Create(ctx context.Context, token domain.UserToken, rawToken string) (domain.UserToken, error) {
+	count, err := u.db.Database().Collection(UserCollection).CountDocuments(ctx, bson.M{"_id": token.UserID})
+	if err != nil {
+		return domain.UserToken{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if count == 0 {
+		return domain.UserToken{}, errors.Wrap(domain.ErrNotExist, "user not found")
+	}
+
+	var mgUserToken = entity.NewMgUserToken(token)
+	mgUserToken.TokenHash = encryption.HashToken(rawToken)
+	mgUserToken.CreatedAt = time.Now().UTC()
+	mgUserToken.ExpiresAt = token.ExpiresAt.UTC()
+	mgUserToken.ConsumedAt = nil
+	_, err = u.db.InsertOne(ctx, mgUserToken)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.UserToken{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.UserToken{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return u.GetByID(ctx, token.ID)
+}
+
+// Consume marks the token as used and returns it. The check and the update
+// are a single FindOneAndUpdate, so of concurrent calls only one succeeds,
+// the others get ErrNotExist like for unknown or expired tokens. Consuming
+// an email verification token also marks the email of the user as verified.
+func (u *MongoUserTokenRepo) Consume(ctx context.Context, rawToken string, purpose domain.TokenPurpose) (domain.UserToken, error) {
+	session, err := u.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.UserToken{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	var mgUserToken entity.MgUserToken
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		now := time.Now().UTC()
+		filter := bson.M{
+			"token_hash":  encryption.HashToken(rawToken),
+			"purpose":     entity.NewMgTokenPurpose(purpose),
+			"consumed_at": bson.M{"$exists": false},
+			"expires_at":  bson.M{"$gt": now},
+		}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := u.db.FindOneAndUpdate(sessionContext, filter, bson.M{"$set": bson.M{"consumed_at": now}}, opts).Decode(&mgUserToken)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.Wrap(domain.ErrNotExist, "token is unknown, expired or already used")
+			}
+			return nil, wrapTxError(domain.ErrUpdateFailed, err)
+		}
+
+		if purpose == domain.TokenPurposeEmailVerification {
+			_, err = u.db.Database().Collection(UserCollection).UpdateOne(sessionContext,
+				bson.M{"_id": mgUserToken.UserID, "email_verified_at": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"email_verified_at": now}})
+			if err != nil {
+				return nil, wrapTxError(domain.ErrUpdateFailed, err)
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return domain.UserToken{}, txError(err)
+	}
+
+	return mgUserToken.ToDomain(), nil
+}
+
+// PurgeExpired removes the tokens that expired before olderThan, consumed or
+// not. The TTL index does the same on its own.
+func (u *MongoUserTokenRepo) PurgeExpired(ctx context.Context, olderThan time.Time) (int64, error) {
+	res, err := u.db.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": olderThan.UTC()}})
+	if err != nil {
+		return 0, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,355 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
+)
+
+type MongoWishlistRepo struct {
+	db *mongo.Collection
+}
+
+func NewWishlistRepo(db *mongo.Database) *MongoWishlistRepo {
+	collection := db.Collection(WishlistCollection)
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{"user_id", 1}, {"name", 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := collection.Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create wishlist collection index, %v", err)
+	}
+
+	itemIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{"wishlist_id", 1}, {"product_id", 1}, {"variant_id", 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err = db.Collection(WishlistProductCollection).Indexes().CreateOne(context.Background(), itemIndexModel)
+	if err != nil {
+		log.Fatalf("unable to create wishlist product collection index, %v", err)
+	}
+
+	return &MongoWishlistRepo{
+		db: collection,
+	}
+}
+
+func (w *MongoWishlistRepo) getWishlistItems(ctx context.Context, wishlistID domain.ID) ([]domain.WishlistItem, error) {
+	opts := options.Find().SetSort(bson.D{{"added_at", 1}, {"_id", 1}})
+	cursor, err := w.db.Database().Collection(WishlistProductCollection).Find(ctx, bson.M{"wishlist_id": wishlistID}, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgWishlistItems []entity.MgWishlistItem
+	if err = cursor.All(ctx, &mgWishlistItems); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	wishlistItems := make([]domain.WishlistItem, len(mgWishlistItems))
+	for i, wishlistItem := range mgWishlistItems {
+		wishlistItems[i] = wishlistItem.ToDomain()
+	}
+	return wishlistItems, nil
+}
+
+func (w *MongoWishlistRepo) GetByID(ctx context.Context, wishlistID domain.ID) (domain.Wishlist, error) {
+	var mgWishlist entity.MgWishlist
+	if err := w.db.FindOne(ctx, bson.M{"_id": wishlistID}).Decode(&mgWishlist); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.Wishlist{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.Wishlist{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	wishlistItems, err := w.getWishlistItems(ctx, wishlistID)
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	wishlist := mgWishlist.ToDomain()
+	wishlist.Items = wishlistItems
+	return wishlist, nil
+}
+
+func (w *MongoWishlistRepo) GetByUserID(ctx context.Context, userID domain.ID) ([]domain.Wishlist, error) {
+	opts := options.Find().SetSort(bson.D{{"created_at", 1}, {"name", 1}})
+	cursor, err := w.db.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgWishlists []entity.MgWishlist
+	if err = cursor.All(ctx, &mgWishlists); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	wishlists := make([]domain.Wishlist, len(mgWishlists))
+	for i, mgWishlist := range mgWishlists {
+		wishlist := mgWishlist.ToDomain()
+		wishlistItems, err := w.getWishlistItems(ctx, wishlist.ID)
+		if err != nil {
+			return nil, err
+		}
+		wishlist.Items = wishlistItems
+		wishlists[i] = wishlist
+	}
+	return wishlists, nil
+}
+
+func (w *MongoWishlistRepo) Create(ctx context.Context, wishlist domain.Wishlist) (domain.Wishlist, error) {
+	count, err := w.db.Database().Collection(UserCollection).CountDocuments(ctx, bson.M{"_id": wishlist.UserID})
+	if err != nil {
+		return domain.Wishlist{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if count == 0 {
+		return domain.Wishlist{}, errors.Wrap(domain.ErrNotExist, "user not found")
+	}
+
+	var mgWishlist = entity.NewMgWishlist(wishlist)
+	mgWishlist.CreatedAt = time.Now().UTC()
+	_, err = w.db.InsertOne(ctx, mgWishlist)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.Wishlist{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Wishlist{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return w.GetByID(ctx, wishlist.ID)
+}
+
+// Update renames the wishlist, the owner and the items are left untouched.
+func (w *MongoWishlistRepo) Update(ctx context.Context, wishlist domain.Wishlist) (domain.Wishlist, error) {
+	_, err := w.db.UpdateOne(ctx, bson.M{"_id": wishlist.ID}, bson.M{"$set": bson.M{"name": wishlist.Name}})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.Wishlist{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Wishlist{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+
+	return w.GetByID(ctx, wishlist.ID)
+}
+
+func (w *MongoWishlistRepo) Delete(ctx context.Context, wishlistID domain.ID) error {
+	session, err := w.db.Database().Client().StartSession()
+	if err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		_, err := w.db.Database().Collection(WishlistProductCollection).DeleteMany(sessionContext, bson.M{"wishlist_id": wishlistID})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+		}
+		_, err = w.db.DeleteOne(sessionContext, bson.M{"_id": wishlistID})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+		}
+		return nil, nil
+	})
+	return err
+}
+
+func (w *MongoWishlistRepo) GetItemByID(ctx context.Context, wishlistItemID domain.ID) (domain.WishlistItem, error) {
+	var mgWishlistItem entity.MgWishlistItem
+	err := w.db.Database().Collection(WishlistProductCollection).FindOne(ctx, bson.M{"_id": wishlistItemID}).Decode(&mgWishlistItem)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.WishlistItem{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.WishlistItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgWishlistItem.ToDomain(), nil
+}
+
+func (w *MongoWishlistRepo) AddItem(ctx context.Context, wishlistItem domain.WishlistItem) (domain.WishlistItem, error) {
+	count, err := w.db.CountDocuments(ctx, bson.M{"_id": wishlistItem.WishlistID})
+	if err != nil {
+		return domain.WishlistItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if count == 0 {
+		return domain.WishlistItem{}, errors.Wrap(domain.ErrNotExist, "wishlist not found")
+	}
+
+	var mgWishlistItem = entity.NewMgWishlistItem(wishlistItem)
+	mgWishlistItem.AddedAt = time.Now().UTC()
+	_, err = w.db.Database().Collection(WishlistProductCollection).InsertOne(ctx, mgWishlistItem)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.WishlistItem{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.WishlistItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return w.GetItemByID(ctx, wishlistItem.ID)
+}
+
+func (w *MongoWishlistRepo) DeleteItem(ctx context.Context, wishlistItemID domain.ID) error {
+	_, err := w.db.Database().Collection(WishlistProductCollection).DeleteOne(ctx, bson.M{"_id": wishlistItemID})
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// checkCartOwner makes sure the cart belongs to the owner of the wishlist.
+func (w *MongoWishlistRepo) checkCartOwner(ctx context.Context, wishlistID, cartID domain.ID) error {
+	var mgWishlist entity.MgWishlist
+	if err := w.db.FindOne(ctx, bson.M{"_id": wishlistID}).Decode(&mgWishlist); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	count, err := w.db.Database().Collection(UserCollection).CountDocuments(ctx, bson.M{"_id": mgWishlist.UserID, "cart_id": cartID})
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if count == 0 {
+		return errors.Wrap(domain.ErrNotAllowed, "cart does not belong to the wishlist owner")
+	}
+	return nil
+}
+
+// MoveToCart removes the item from the wishlist and puts it into the cart
+// of the wishlist owner, summing the quantity if the cart already has it.
+// Only the ID, the CartID and the Quantity of cartItem are used.
+func (w *MongoWishlistRepo) MoveToCart(ctx context.Context, wishlistItemID domain.ID, cartItem domain.CartItem) (domain.CartItem, error) {
+	if cartItem.Quantity < 1 {
+		return domain.CartItem{}, errors.Wrap(domain.ErrNotAllowed, "quantity must be positive")
+	}
+
+	session, err := w.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.CartItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	var mgCartItem entity.MgCartItem
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		items := w.db.Database().Collection(WishlistProductCollection)
+		var mgWishlistItem entity.MgWishlistItem
+		if err := items.FindOne(sessionContext, bson.M{"_id": wishlistItemID}).Decode(&mgWishlistItem); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if err := w.checkCartOwner(sessionContext, domain.ID(mgWishlistItem.WishlistID), cartItem.CartID); err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		expiresAt := now.Add(CartItemTTL)
+		cartItems := w.db.Database().Collection(CartProductCollection)
+		filter := bson.M{"cart_id": cartItem.CartID, "product_id": mgWishlistItem.ProductID, "variant_id": bson.M{"$exists": false}}
+		if mgWishlistItem.VariantID != "" {
+			filter["variant_id"] = mgWishlistItem.VariantID
+		}
+		update := bson.M{
+			"$inc": bson.M{"quantity": cartItem.Quantity},
+			"$set": bson.M{"updated_at": now, "expires_at": expiresAt},
+		}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := cartItems.FindOneAndUpdate(sessionContext, filter, update, opts).Decode(&mgCartItem)
+		if err == mongo.ErrNoDocuments {
+			mgCartItem = entity.NewMgCartItem(domain.CartItem{
+				ID:        cartItem.ID,
+				CartID:    cartItem.CartID,
+				ProductID: domain.ID(mgWishlistItem.ProductID),
+				VariantID: domain.ID(mgWishlistItem.VariantID),
+				Quantity:  cartItem.Quantity,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+			mgCartItem.ExpiresAt = &expiresAt
+			_, err = cartItems.InsertOne(sessionContext, mgCartItem)
+		}
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+
+		if _, err = items.DeleteOne(sessionContext, bson.M{"_id": wishlistItemID}); err != nil {
+			return nil, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+		}
+		return nil, touchCart(sessionContext, w.db.Database(), cartItem.CartID, now)
+	})
+	if err != nil {
+		return domain.CartItem{}, err
+	}
+
+	err = w.db.Database().Collection(CartProductCollection).FindOne(ctx, bson.M{"_id": mgCartItem.ID}).Decode(&mgCartItem)
+	if err != nil {
+		return domain.CartItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mgCartItem.ToDomain(), nil
+}
+
+// MoveFromCart removes the item from the cart and saves it to the wishlist
+// of the cart owner. Only the ID and the WishlistID of wishlistItem are used.
+func (w *MongoWishlistRepo) MoveFromCart(ctx context.Context, cartItemID domain.ID, wishlistItem domain.WishlistItem) (domain.WishlistItem, error) {
+	session, err := w.db.Database().Client().StartSession()
+	if err != nil {
+		return domain.WishlistItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	defer session.EndSession(ctx)
+
+	var mgWishlistItem entity.MgWishlistItem
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		cartItems := w.db.Database().Collection(CartProductCollection)
+		var mgCartItem entity.MgCartItem
+		if err := cartItems.FindOne(sessionContext, bson.M{"_id": cartItemID}).Decode(&mgCartItem); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		cartID := domain.ID(mgCartItem.CartID)
+		if err := w.checkCartOwner(sessionContext, wishlistItem.WishlistID, cartID); err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		items := w.db.Database().Collection(WishlistProductCollection)
+		filter := bson.M{"wishlist_id": wishlistItem.WishlistID, "product_id": mgCartItem.ProductID, "variant_id": bson.M{"$exists": false}}
+		if mgCartItem.VariantID != "" {
+			filter["variant_id"] = mgCartItem.VariantID
+		}
+		opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err := items.FindOneAndUpdate(sessionContext, filter, bson.M{"$set": bson.M{"added_at": now}}, opts).Decode(&mgWishlistItem)
+		if err == mongo.ErrNoDocuments {
+			mgWishlistItem = entity.NewMgWishlistItem(domain.WishlistItem{
+				ID:         wishlistItem.ID,
+				WishlistID: wishlistItem.WishlistID,
+				ProductID:  domain.ID(mgCartItem.ProductID),
+				VariantID:  domain.ID(mgCartItem.VariantID),
+				AddedAt:    now,
+			})
+			_, err = items.InsertOne(sessionContext, mgWishlistItem)
+		}
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+
+		if _, err = cartItems.DeleteOne(sessionContext, bson.M{"_id": cartItemID}); err != nil {
+			return nil, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+		}
+		return nil, touchCart(sessionContext, w.db.Database(), cartID, now)
+	})
+	if err != nil {
+		return domain.WishlistItem{}, err
+	}
+
+	return w.GetItemByID(ctx, domain.ID(mgWishlistItem.ID))
+}
@@ -3,11 +3,15 @@ package mongodb
 import (
 	"context"
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
 	"github.com/EmirShimshir/marketplace-repository/repository/mongodb/entity"
+	"github.com/guregu/null"
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"time"
 )
 
 type MongoWithdrawRepo struct {
@@ -15,6 +19,14 @@ type MongoWithdrawRepo struct {
 }
 
 func NewWithdrawRepo(db *mongo.Database) *MongoWithdrawRepo {
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{{"status", 1}, {"requested_at", 1}, {"_id", 1}},
+	}
+	_, err := db.Collection(WithdrawCollection).Indexes().CreateOne(context.Background(), indexModel)
+	if err != nil {
+		log.Fatalf("unable to create withdraw collection index, %v", err)
+	}
+
 	return &MongoWithdrawRepo{
 		db: db.Collection(WithdrawCollection),
 	}
@@ -79,7 +91,30 @@ func (w *MongoWithdrawRepo) GetByShopID(ctx context.Context, shopID domain.ID) (
 	return withdraws, nil
 }
 
+// Create stores a new withdraw request, which always starts in the Start
+// status. The request time is set here and a withdraw without a currency is
+// paid in the default currency of the shop.
 func (w *MongoWithdrawRepo) Create(ctx context.Context, withdraw domain.Withdraw) (domain.Withdraw, error) {
+	if withdraw.Status != domain.WithdrawStatusStart {
+		return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "withdraw must be created in the Start status")
+	}
+	if withdraw.Currency == "" {
+		shopRepo := &MongoShopRepo{db: w.db.Database().Collection(ShopCollection)}
+		shop, err := shopRepo.GetShopByID(ctx, withdraw.ShopID)
+		if err != nil {
+			return domain.Withdraw{}, err
+		}
+		withdraw.Currency = shop.DefaultCurrency
+	}
+	if !currency.Valid(withdraw.Currency) {
+		return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+	withdraw.RequestedAt = time.Now().UTC()
+	withdraw.ApprovedAt = null.Time{}
+	withdraw.PaidAt = null.Time{}
+	withdraw.ReviewerID = ""
+	withdraw.BatchID = ""
+
 	var mgWithdraw = entity.NewMgWithdraw(withdraw)
 	_, err := w.db.InsertOne(ctx, mgWithdraw)
 	if err != nil {
@@ -92,19 +127,104 @@ func (w *MongoWithdrawRepo) Create(ctx context.Context, withdraw domain.Withdraw
 	return w.GetByID(ctx, withdraw.ID)
 }
 
+// Update changes the comment and the sum of the withdraw, the status is
+// only changed by TransitionWithdraw. The sum can only change in the Start
+// status, an approved withdraw keeps the sum the reviewer approved.
 func (w *MongoWithdrawRepo) Update(ctx context.Context, withdraw domain.Withdraw) (domain.Withdraw, error) {
-	var mgWithdraw = entity.NewMgWithdraw(withdraw)
-	_, err := w.db.ReplaceOne(ctx, bson.M{"_id": mgWithdraw.ID}, mgWithdraw)
+	filter := bson.M{"_id": withdraw.ID, "$or": bson.A{
+		bson.M{"status": entity.MgWithdrawStart},
+		bson.M{"sum": withdraw.Sum},
+	}}
+	res, err := w.db.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"comment": withdraw.Comment, "sum": withdraw.Sum}})
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return domain.Withdraw{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		return domain.Withdraw{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if res.MatchedCount == 0 {
+		if _, err = w.GetByID(ctx, withdraw.ID); err != nil {
+			return domain.Withdraw{}, err
 		}
-		return domain.Withdraw{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "withdraw sum can only change in the Start status")
 	}
 
 	return w.GetByID(ctx, withdraw.ID)
 }
 
+// TransitionWithdraw moves the withdraw to the next status, Start to Ready
+// when the reviewer approves it and Ready to Done when it is paid. The
+// status is checked and changed by a single update, so a transition made
+// from a stale status is rejected with ErrNotAllowed. A withdraw attached to
+// a payout batch is paid when the batch is settled.
+func (w *MongoWithdrawRepo) TransitionWithdraw(ctx context.Context, withdrawID domain.ID, status domain.WithdrawStatus, reviewerID domain.ID) (domain.Withdraw, error) {
+	var filter, update bson.M
+	switch status {
+	case domain.WithdrawStatusReady:
+		if reviewerID == "" {
+			return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "withdraw approval needs a reviewer")
+		}
+		filter = bson.M{"_id": withdrawID, "status": entity.MgWithdrawStart}
+		update = bson.M{"$set": bson.M{
+			"status":      entity.MgWithdrawReady,
+			"approved_at": time.Now().UTC(),
+			"reviewer_id": reviewerID,
+		}}
+	case domain.WithdrawStatusDone:
+		filter = bson.M{"_id": withdrawID, "status": entity.MgWithdrawReady, "payout_batch_id": bson.M{"$exists": false}}
+		update = bson.M{"$set": bson.M{"status": entity.MgWithdrawDone, "paid_at": time.Now().UTC()}}
+	default:
+		return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "withdraw can not go back to the Start status")
+	}
+
+	res, err := w.db.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return domain.Withdraw{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if res.MatchedCount == 0 {
+		if _, err = w.GetByID(ctx, withdrawID); err != nil {
+			return domain.Withdraw{}, err
+		}
+		return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "withdraw is not in the previous status")
+	}
+
+	return w.GetByID(ctx, withdrawID)
+}
+
+// GetByStatus returns the withdraws with the status oldest request first,
+// to be worked as a queue. An empty cursor starts from the beginning,
+// otherwise only withdraws requested after the cursor withdraw are returned.
+func (w *MongoWithdrawRepo) GetByStatus(ctx context.Context, status domain.WithdrawStatus, limit int64, cursor domain.ID) ([]domain.Withdraw, error) {
+	filter := bson.M{"status": entity.NewMgWithdrawStatus(status)}
+	if cursor != "" {
+		var last entity.MgWithdraw
+		if err := w.db.FindOne(ctx, bson.M{"_id": cursor}).Decode(&last); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return []domain.Withdraw{}, nil
+			}
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		filter["$or"] = bson.A{
+			bson.M{"requested_at": bson.M{"$gt": last.RequestedAt}},
+			bson.M{"requested_at": last.RequestedAt, "_id": bson.M{"$gt": last.ID}},
+		}
+	}
+	opts := options.Find().SetSort(bson.D{{"requested_at", 1}, {"_id", 1}}).SetLimit(limit)
+	result, err := w.db.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	var mgWithdrawArray []entity.MgWithdraw
+	err = result.All(ctx, &mgWithdrawArray)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	withdraws := make([]domain.Withdraw, len(mgWithdrawArray))
+	for i, withdraw := range mgWithdrawArray {
+		withdraws[i] = withdraw.ToDomain()
+	}
+	return withdraws, nil
+}
+
 func (w *MongoWithdrawRepo) Delete(ctx context.Context, withdrawID domain.ID) error {
 	_, err := w.db.DeleteOne(ctx, bson.M{"_id": withdrawID})
 	if err != nil {
@@ -5,9 +5,12 @@ import (
 	"database/sql"
 	"github.com/EmirShimshir/marketplace-core/domain"
 	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
 	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"time"
 )
 
 type PostgresCartRepo struct {
@@ -26,8 +29,44 @@ const (
 	cartItemsDeleteByCartIDQuery = "DELETE FROM public.cart_product WHERE cart_id = $1"
 	cartItemGetByIQuery          = "SELECT * FROM public.cart_product WHERE id = $1"
 	cartItemDeleteQuery          = "DELETE FROM public.cart_product WHERE id = $1"
+	cartGetTotalQuery            = "SELECT COALESCE(SUM(COALESCE(v.price, p.price) * cp.quantity), 0)::bigint " +
+		"FROM public.cart_product cp JOIN public.product p ON p.id = cp.product_id " +
+		"LEFT JOIN public.product_variant v ON v.id = cp.variant_id WHERE cp.cart_id = $1"
+	cartRecalculateQuery   = "UPDATE public.cart SET price = (" + cartGetTotalQuery + ") WHERE id = $1"
+	cartUpdatePriceQuery   = "UPDATE public.cart SET price = $2 WHERE id = $1 AND $2::bigint = (" + cartGetTotalQuery + ")"
+	cartTouchQuery         = "UPDATE public.cart SET updated_at = $2 WHERE id = $1"
+	cartFindAbandonedQuery = "SELECT c.id FROM public.cart c " +
+		"JOIN public.cart_product cp ON cp.cart_id = c.id JOIN public.product p ON p.id = cp.product_id " +
+		"LEFT JOIN public.product_variant v ON v.id = cp.variant_id WHERE c.updated_at < $1 " +
+		"GROUP BY c.id HAVING SUM(COALESCE(v.price, p.price) * cp.quantity) >= $2 ORDER BY c.updated_at"
+	cartItemsPurgeQuery = "DELETE FROM public.cart_product WHERE updated_at < $1"
 )
 
+const (
+	cartGetByGuestTokenQuery        = "SELECT * FROM public.cart WHERE guest_token = $1"
+	cartGetGuestTokenForUpdateQuery = "SELECT guest_token FROM public.cart WHERE id = $1 FOR UPDATE"
+	cartDeleteQuery                 = "DELETE FROM public.cart WHERE id = $1"
+	cartMergeItemsQuery             = "INSERT INTO public.cart_product " +
+		"(id, cart_id, product_id, variant_id, quantity, created_at, updated_at) " +
+		"SELECT gen_random_uuid(), $2, product_id, variant_id, quantity, $3, $3 FROM public.cart_product WHERE cart_id = $1 " +
+		"ON CONFLICT (cart_id, product_id, coalesce(variant_id, '00000000-0000-0000-0000-000000000000')) " +
+		"DO UPDATE SET quantity = public.cart_product.quantity + EXCLUDED.quantity, updated_at = EXCLUDED.updated_at"
+)
+
+const (
+	cartGetCurrencyForUpdateQuery = "SELECT c.currency, EXISTS(SELECT 1 FROM public.cart_product cp " +
+		"WHERE cp.cart_id = c.id AND cp.id::text <> $2) AS has_items FROM public.cart c WHERE c.id = $1 FOR UPDATE OF c"
+	cartSetCurrencyQuery    = "UPDATE public.cart SET currency = $2 WHERE id = $1"
+	productGetCurrencyQuery = "SELECT currency FROM public.product WHERE id = $1 FOR SHARE"
+)
+
+// cartCurrency is the currency of a locked cart and whether the cart holds
+// items other than the one being written.
+type cartCurrency struct {
+	Currency null.String `db:"currency"`
+	HasItems bool        `db:"has_items"`
+}
+
 func (c *PostgresCartRepo) GetCartByID(ctx context.Context, cartID domain.ID) (domain.Cart, error) {
 	var pgCart entity.PgCart
 	if err := c.db.GetContext(ctx, &pgCart, cartGetByIDQuery, cartID); err != nil {
@@ -56,17 +95,252 @@ func (c *PostgresCartRepo) GetCartByID(ctx context.Context, cartID domain.ID) (d
 	return cart, nil
 }
 
-func (c *PostgresCartRepo) UpdateCart(ctx context.Context, cart domain.Cart) (domain.Cart, error) {
+func (c *PostgresCartRepo) GetCartByGuestToken(ctx context.Context, token string) (domain.Cart, error) {
+	var pgCart entity.PgCart
+	if err := c.db.GetContext(ctx, &pgCart, cartGetByGuestTokenQuery, token); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Cart{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Cart{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	return c.GetCartByID(ctx, domain.ID(pgCart.ID.String()))
+}
+
+func (c *PostgresCartRepo) CreateGuestCart(ctx context.Context, cart domain.Cart) (domain.Cart, error) {
+	if !cart.GuestToken.Valid || cart.GuestToken.String == "" {
+		return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "guest cart requires a token")
+	}
+
+	now := time.Now().UTC()
 	var pgCart = entity.NewPgCart(cart)
-	queryString := entity.UpdateQueryString(pgCart, "cart")
+	pgCart.CreatedAt = now
+	pgCart.UpdatedAt = now
+	queryString := entity.InsertQueryString(pgCart, "cart")
 	_, err := c.db.NamedExecContext(ctx, queryString, pgCart)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return domain.Cart{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Cart{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	return c.GetCartByID(ctx, cart.ID)
+}
+
+// MergeCarts moves the guest cart items into the user cart, summing the
+// quantities of items present in both, and deletes the guest cart. Carts
+// holding items in different currencies are not merged.
+func (c *PostgresCartRepo) MergeCarts(ctx context.Context, guestCartID, userCartID domain.ID) (domain.Cart, error) {
+	if guestCartID == userCartID {
+		return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "cart can not be merged into itself")
+	}
+
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return domain.Cart{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	guestToken, err := txGetCartGuestToken(ctx, tx, guestCartID)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+	if !guestToken.Valid {
+		tx.Rollback()
+		return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "source cart is not a guest cart")
+	}
+	userToken, err := txGetCartGuestToken(ctx, tx, userCartID)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+	if userToken.Valid {
+		tx.Rollback()
+		return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "target cart is a guest cart")
+	}
+
+	guestCurrency, err := txGetCartCurrency(ctx, tx, guestCartID, "")
+	if err != nil {
+		return domain.Cart{}, err
+	}
+	userCurrency, err := txGetCartCurrency(ctx, tx, userCartID, "")
+	if err != nil {
+		return domain.Cart{}, err
+	}
+	if guestCurrency.HasItems && guestCurrency.Currency != userCurrency.Currency {
+		if userCurrency.HasItems {
+			tx.Rollback()
+			return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "carts hold items in different currencies")
+		}
+		if _, err = tx.ExecContext(ctx, cartSetCurrencyQuery, userCartID, guestCurrency.Currency); err != nil {
+			tx.Rollback()
+			return domain.Cart{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+	}
+
+	now := time.Now().UTC()
+	if _, err = tx.ExecContext(ctx, cartMergeItemsQuery, guestCartID, userCartID, now); err != nil {
+		tx.Rollback()
 		return domain.Cart{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
 	}
+	if err = txTouchCart(ctx, tx, userCartID, now); err != nil {
+		return domain.Cart{}, err
+	}
+	if _, err = tx.ExecContext(ctx, cartDeleteQuery, guestCartID); err != nil {
+		tx.Rollback()
+		return domain.Cart{}, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.Cart{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return c.GetCartByID(ctx, userCartID)
+}
+
+// txGetCartGuestToken locks the cart and returns its guest token.
+func txGetCartGuestToken(ctx context.Context, tx *sqlx.Tx, cartID domain.ID) (null.String, error) {
+	var guestToken null.String
+	if err := tx.GetContext(ctx, &guestToken, cartGetGuestTokenForUpdateQuery, cartID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return null.String{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return null.String{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return guestToken, nil
+}
+
+// txGetCartCurrency locks the cart and returns its currency, the item with
+// the exceptItemID is not counted as an item of the cart.
+func txGetCartCurrency(ctx context.Context, tx *sqlx.Tx, cartID, exceptItemID domain.ID) (cartCurrency, error) {
+	var currency cartCurrency
+	if err := tx.GetContext(ctx, &currency, cartGetCurrencyForUpdateQuery, cartID, exceptItemID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return cartCurrency{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return cartCurrency{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return currency, nil
+}
+
+// txSetCartCurrency prices the cart in the currency of the product written
+// by the cart item. A cart holding other items in another currency is
+// rejected with ErrNotAllowed.
+func txSetCartCurrency(ctx context.Context, tx *sqlx.Tx, cartItem domain.CartItem) error {
+	current, err := txGetCartCurrency(ctx, tx, cartItem.CartID, cartItem.ID)
+	if err != nil {
+		return err
+	}
+
+	var currency string
+	if err = tx.GetContext(ctx, &currency, productGetCurrencyQuery, cartItem.ProductID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	if current.Currency.String == currency {
+		return nil
+	}
+	if current.HasItems {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrNotAllowed, "cart holds items in another currency")
+	}
+
+	if _, err = tx.ExecContext(ctx, cartSetCurrencyQuery, cartItem.CartID, currency); err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	return nil
+}
+
+// UpdateCart stores the price of the cart, which must be the total of the
+// cart items at the current product and variant prices. The currency of the
+// cart follows its items and can not be changed here.
+func (c *PostgresCartRepo) UpdateCart(ctx context.Context, cart domain.Cart) (domain.Cart, error) {
+	current, err := c.GetCartByID(ctx, cart.ID)
+	if err != nil {
+		return domain.Cart{}, err
+	}
+	if cart.Currency != "" && current.Currency != cart.Currency {
+		return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "cart currency does not match")
+	}
+
+	res, err := c.db.ExecContext(ctx, cartUpdatePriceQuery, cart.ID, cart.Price)
+	if err != nil {
+		return domain.Cart{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return domain.Cart{}, errors.Wrap(domain.ErrNotAllowed, "cart price does not match its items")
+	}
 
 	return c.GetCartByID(ctx, cart.ID)
 }
 
+func (c *PostgresCartRepo) GetCartTotal(ctx context.Context, cartID domain.ID) (int64, error) {
+	if _, err := c.GetCartByID(ctx, cartID); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	if err := c.db.GetContext(ctx, &total, cartGetTotalQuery, cartID); err != nil {
+		return 0, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return total, nil
+}
+
+// RecalculateCart stores the price computed from the cart items and the
+// current product and variant prices.
+func (c *PostgresCartRepo) RecalculateCart(ctx context.Context, cartID domain.ID) (domain.Cart, error) {
+	res, err := c.db.ExecContext(ctx, cartRecalculateQuery, cartID)
+	if err != nil {
+		return domain.Cart{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return domain.Cart{}, errors.Wrap(domain.ErrNotExist, "cart not found")
+	}
+
+	return c.GetCartByID(ctx, cartID)
+}
+
+func (c *PostgresCartRepo) FindAbandonedCarts(ctx context.Context, olderThan time.Time, minValue int64) ([]domain.Cart, error) {
+	var cartIDs []uuid.UUID
+	if err := c.db.SelectContext(ctx, &cartIDs, cartFindAbandonedQuery, olderThan, minValue); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	carts := make([]domain.Cart, len(cartIDs))
+	for i, cartID := range cartIDs {
+		cart, err := c.GetCartByID(ctx, domain.ID(cartID.String()))
+		if err != nil {
+			return nil, err
+		}
+		carts[i] = cart
+	}
+
+	return carts, nil
+}
+
+// PurgeStaleCartItems removes cart items not updated since olderThan, it is
+// meant to be run periodically by a scheduler.
+func (c *PostgresCartRepo) PurgeStaleCartItems(ctx context.Context, olderThan time.Time) (int64, error) {
+	res, err := c.db.ExecContext(ctx, cartItemsPurgeQuery, olderThan)
+	if err != nil {
+		return 0, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return affected, nil
+}
+
 func (c *PostgresCartRepo) ClearCart(ctx context.Context, cartID domain.ID) error {
 	_, err := c.db.ExecContext(ctx, cartItemsDeleteByCartIDQuery, cartID)
 	if err != nil {
@@ -88,10 +362,22 @@ func (c *PostgresCartRepo) GetCartItemByID(ctx context.Context, cartItemID domai
 }
 
 func (c *PostgresCartRepo) CreateCartItem(ctx context.Context, cartItem domain.CartItem) (domain.CartItem, error) {
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return domain.CartItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	if err = txSetCartCurrency(ctx, tx, cartItem); err != nil {
+		return domain.CartItem{}, err
+	}
+
+	now := time.Now().UTC()
 	var pgCartItem = entity.NewPgCartItem(cartItem)
+	pgCartItem.CreatedAt = now
+	pgCartItem.UpdatedAt = now
 	queryString := entity.InsertQueryString(pgCartItem, "cart_product")
-	_, err := c.db.NamedExecContext(ctx, queryString, pgCartItem)
+	_, err = tx.NamedExecContext(ctx, queryString, pgCartItem)
 	if err != nil {
+		tx.Rollback()
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
@@ -103,21 +389,64 @@ func (c *PostgresCartRepo) CreateCartItem(ctx context.Context, cartItem domain.C
 			return domain.CartItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 		}
 	}
+	if err = txTouchCart(ctx, tx, cartItem.CartID, now); err != nil {
+		return domain.CartItem{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.CartItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
 
 	return c.GetCartItemByID(ctx, cartItem.ID)
 }
 
 func (c *PostgresCartRepo) UpdateCartItem(ctx context.Context, cartItem domain.CartItem) (domain.CartItem, error) {
+	current, err := c.GetCartItemByID(ctx, cartItem.ID)
+	if err != nil {
+		return domain.CartItem{}, err
+	}
+
+	tx, err := c.db.Beginx()
+	if err != nil {
+		return domain.CartItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	if err = txSetCartCurrency(ctx, tx, cartItem); err != nil {
+		return domain.CartItem{}, err
+	}
+
+	now := time.Now().UTC()
 	var pgCartItem = entity.NewPgCartItem(cartItem)
+	pgCartItem.CreatedAt = current.CreatedAt
+	pgCartItem.UpdatedAt = now
 	queryString := entity.UpdateQueryString(pgCartItem, "cart_product")
-	_, err := c.db.NamedExecContext(ctx, queryString, pgCartItem)
+	_, err = tx.NamedExecContext(ctx, queryString, pgCartItem)
 	if err != nil {
+		tx.Rollback()
 		return domain.CartItem{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
 	}
+	if err = txTouchCart(ctx, tx, cartItem.CartID, now); err != nil {
+		return domain.CartItem{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.CartItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
 
 	return c.GetCartItemByID(ctx, cartItem.ID)
 }
 
+// txTouchCart marks the cart as updated at now.
+func txTouchCart(ctx context.Context, tx *sqlx.Tx, cartID domain.ID, now time.Time) error {
+	res, err := tx.ExecContext(ctx, cartTouchQuery, cartID, now)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrNotExist, "cart not found")
+	}
+	return nil
+}
+
 func (c *PostgresCartRepo) DeleteCartItem(ctx context.Context, cartItemID domain.ID) error {
 	_, err := c.db.ExecContext(ctx, cartItemDeleteQuery, cartItemID)
 	if err != nil {
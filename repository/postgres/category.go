@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type PostgresCategoryRepo struct {
+	db *sqlx.DB
+}
+
+func NewCategoryRepo(db *sqlx.DB) *PostgresCategoryRepo {
+	return &PostgresCategoryRepo{
+		db: db,
+	}
+}
+
+const (
+	categoryGetByIDQuery        = "SELECT * FROM public.category WHERE id = $1"
+	categoryGetBySlugQuery      = "SELECT * FROM public.category WHERE slug = $1"
+	categoryGetRootsQuery       = "SELECT * FROM public.category WHERE parent_id IS NULL ORDER BY slug"
+	categoryGetChildrenQuery    = "SELECT * FROM public.category WHERE parent_id = $1 ORDER BY slug"
+	categoryDeleteQuery         = "DELETE FROM public.category WHERE id = $1"
+	categoryGetDescendantsQuery = "WITH RECURSIVE tree AS (" +
+		"SELECT * FROM public.category WHERE parent_id = $1 " +
+		"UNION ALL SELECT c.* FROM public.category c JOIN tree t ON c.parent_id = t.id" +
+		") SELECT * FROM tree ORDER BY slug"
+)
+
+func (c *PostgresCategoryRepo) GetByID(ctx context.Context, categoryID domain.ID) (domain.Category, error) {
+	var pgCategory entity.PgCategory
+	if err := c.db.GetContext(ctx, &pgCategory, categoryGetByIDQuery, categoryID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Category{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Category{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgCategory.ToDomain(), nil
+}
+
+func (c *PostgresCategoryRepo) GetBySlug(ctx context.Context, slug string) (domain.Category, error) {
+	var pgCategory entity.PgCategory
+	if err := c.db.GetContext(ctx, &pgCategory, categoryGetBySlugQuery, slug); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Category{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Category{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgCategory.ToDomain(), nil
+}
+
+// GetChildren returns direct subcategories of the parent, an empty parentID
+// returns root categories.
+func (c *PostgresCategoryRepo) GetChildren(ctx context.Context, parentID domain.ID) ([]domain.Category, error) {
+	var pgCategories []entity.PgCategory
+	var err error
+	if parentID == "" {
+		err = c.db.SelectContext(ctx, &pgCategories, categoryGetRootsQuery)
+	} else {
+		err = c.db.SelectContext(ctx, &pgCategories, categoryGetChildrenQuery, parentID)
+	}
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	categories := make([]domain.Category, len(pgCategories))
+	for i, category := range pgCategories {
+		categories[i] = category.ToDomain()
+	}
+	return categories, nil
+}
+
+// GetDescendants returns all subcategories of the category at any depth.
+func (c *PostgresCategoryRepo) GetDescendants(ctx context.Context, categoryID domain.ID) ([]domain.Category, error) {
+	var pgCategories []entity.PgCategory
+	if err := c.db.SelectContext(ctx, &pgCategories, categoryGetDescendantsQuery, categoryID); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	categories := make([]domain.Category, len(pgCategories))
+	for i, category := range pgCategories {
+		categories[i] = category.ToDomain()
+	}
+	return categories, nil
+}
+
+func (c *PostgresCategoryRepo) Create(ctx context.Context, category domain.Category) (domain.Category, error) {
+	var pgCategory = entity.NewPgCategory(category)
+	queryString := entity.InsertQueryString(pgCategory, "category")
+	_, err := c.db.NamedExecContext(ctx, queryString, pgCategory)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == PgUniqueViolationCode {
+				return domain.Category{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else if pgErr.Code == PgForeignKeyViolationCode {
+				return domain.Category{}, errors.Wrap(domain.ErrNotExist, err.Error())
+			} else {
+				return domain.Category{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+		} else {
+			return domain.Category{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	return c.GetByID(ctx, category.ID)
+}
+
+// Update rejects moving a category under itself or one of its descendants.
+func (c *PostgresCategoryRepo) Update(ctx context.Context, category domain.Category) (domain.Category, error) {
+	if category.ParentID != "" {
+		descendants, err := c.GetDescendants(ctx, category.ID)
+		if err != nil {
+			return domain.Category{}, err
+		}
+		for _, descendant := range descendants {
+			if descendant.ID == category.ParentID {
+				return domain.Category{}, errors.Wrap(domain.ErrNotAllowed, "category parent is its descendant")
+			}
+		}
+	}
+
+	var pgCategory = entity.NewPgCategory(category)
+	queryString := entity.UpdateQueryString(pgCategory, "category")
+	_, err := c.db.NamedExecContext(ctx, queryString, pgCategory)
+	if err != nil {
+		return domain.Category{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+
+	return c.GetByID(ctx, category.ID)
+}
+
+func (c *PostgresCategoryRepo) Delete(ctx context.Context, categoryID domain.ID) error {
+	_, err := c.db.ExecContext(ctx, categoryDeleteQuery, categoryID)
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
@@ -3,41 +3,65 @@ package entity
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
 	"github.com/google/uuid"
+	"github.com/guregu/null"
+	"time"
 )
 
 type PgCart struct {
-	ID    uuid.UUID `db:"id"`
-	Price int64     `db:"price"`
+	ID         uuid.UUID   `db:"id"`
+	Price      int64       `db:"price"`
+	Currency   null.String `db:"currency"`
+	GuestToken null.String `db:"guest_token"`
+	CreatedAt  time.Time   `db:"created_at"`
+	UpdatedAt  time.Time   `db:"updated_at"`
 }
 
 func (c *PgCart) ToDomain() domain.Cart {
 	return domain.Cart{
-		ID:    domain.ID(c.ID.String()),
-		Price: c.Price,
+		ID:         domain.ID(c.ID.String()),
+		Price:      c.Price,
+		Currency:   c.Currency.String,
+		GuestToken: c.GuestToken,
+		CreatedAt:  c.CreatedAt,
+		UpdatedAt:  c.UpdatedAt,
 	}
 }
 
 func NewPgCart(cart domain.Cart) PgCart {
 	id, _ := uuid.Parse(cart.ID.String())
 	return PgCart{
-		ID:    id,
-		Price: cart.Price,
+		ID:         id,
+		Price:      cart.Price,
+		Currency:   null.NewString(cart.Currency, cart.Currency != ""),
+		GuestToken: cart.GuestToken,
+		CreatedAt:  cart.CreatedAt,
+		UpdatedAt:  cart.UpdatedAt,
 	}
 }
 
 type PgCartItem struct {
-	ID        uuid.UUID `db:"id"`
-	CartID    uuid.UUID `db:"cart_id"`
-	ProductID uuid.UUID `db:"product_id"`
-	Quantity  int64     `db:"quantity"`
+	ID        uuid.UUID     `db:"id"`
+	CartID    uuid.UUID     `db:"cart_id"`
+	ProductID uuid.UUID     `db:"product_id"`
+	VariantID uuid.NullUUID `db:"variant_id"`
+	Quantity  int64         `db:"quantity"`
+	CreatedAt time.Time     `db:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at"`
 }
 
 func (ci *PgCartItem) ToDomain() domain.CartItem {
+	var variantID domain.ID
+	if ci.VariantID.Valid {
+		variantID = domain.ID(ci.VariantID.UUID.String())
+	}
 	return domain.CartItem{
 		ID:        domain.ID(ci.ID.String()),
 		CartID:    domain.ID(ci.CartID.String()),
 		ProductID: domain.ID(ci.ProductID.String()),
+		VariantID: variantID,
 		Quantity:  ci.Quantity,
+		CreatedAt: ci.CreatedAt,
+		UpdatedAt: ci.UpdatedAt,
 	}
 }
 
@@ -45,10 +69,18 @@ func NewPgCartItem(cartItem domain.CartItem) PgCartItem {
 	id, _ := uuid.Parse(cartItem.ID.String())
 	cartID, _ := uuid.Parse(cartItem.CartID.String())
 	productID, _ := uuid.Parse(cartItem.ProductID.String())
+	var variantID uuid.NullUUID
+	if cartItem.VariantID != "" {
+		variantID.UUID, _ = uuid.Parse(cartItem.VariantID.String())
+		variantID.Valid = true
+	}
 	return PgCartItem{
 		ID:        id,
 		CartID:    cartID,
 		ProductID: productID,
+		VariantID: variantID,
 		Quantity:  cartItem.Quantity,
+		CreatedAt: cartItem.CreatedAt,
+		UpdatedAt: cartItem.UpdatedAt,
 	}
 }
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+)
+
+type PgCategory struct {
+	ID       uuid.UUID     `db:"id"`
+	ParentID uuid.NullUUID `db:"parent_id"`
+	Slug     string        `db:"slug"`
+	Name     string        `db:"name"`
+}
+
+func (c *PgCategory) ToDomain() domain.Category {
+	var parentID domain.ID
+	if c.ParentID.Valid {
+		parentID = domain.ID(c.ParentID.UUID.String())
+	}
+	return domain.Category{
+		ID:       domain.ID(c.ID.String()),
+		ParentID: parentID,
+		Slug:     c.Slug,
+		Name:     c.Name,
+	}
+}
+
+func NewPgCategory(category domain.Category) PgCategory {
+	id, _ := uuid.Parse(category.ID.String())
+	var parentID uuid.NullUUID
+	if category.ParentID != "" {
+		parentID.UUID, _ = uuid.Parse(category.ParentID.String())
+		parentID.Valid = true
+	}
+	return PgCategory{
+		ID:       id,
+		ParentID: parentID,
+		Slug:     category.Slug,
+		Name:     category.Name,
+	}
+}
@@ -0,0 +1,95 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	PgModerationTargetUser = "User"
+	PgModerationTargetShop = "Shop"
+)
+
+const (
+	PgModerationBlock   = "Block"
+	PgModerationUnblock = "Unblock"
+	PgModerationPending = "Pending"
+	PgModerationApprove = "Approve"
+	PgModerationSuspend = "Suspend"
+)
+
+type PgModerationAction struct {
+	ID          uuid.UUID     `db:"id"`
+	TargetType  string        `db:"target_type"`
+	TargetID    uuid.UUID     `db:"target_id"`
+	ModeratorID uuid.NullUUID `db:"moderator_id"`
+	Action      string        `db:"action"`
+	Reason      string        `db:"reason"`
+	CreatedAt   time.Time     `db:"created_at"`
+}
+
+func (m *PgModerationAction) ToDomain() domain.ModerationAction {
+	var action domain.ModerationActionType
+	switch m.Action {
+	case PgModerationBlock:
+		action = domain.ModerationActionBlock
+	case PgModerationUnblock:
+		action = domain.ModerationActionUnblock
+	case PgModerationPending:
+		action = domain.ModerationActionPending
+	case PgModerationApprove:
+		action = domain.ModerationActionApprove
+	case PgModerationSuspend:
+		action = domain.ModerationActionSuspend
+	}
+	var moderatorID domain.ID
+	if m.ModeratorID.Valid {
+		moderatorID = domain.ID(m.ModeratorID.UUID.String())
+	}
+
+	return domain.ModerationAction{
+		ID:          domain.ID(m.ID.String()),
+		TargetID:    domain.ID(m.TargetID.String()),
+		ModeratorID: moderatorID,
+		Action:      action,
+		Reason:      m.Reason,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+func NewPgModerationActionType(action domain.ModerationActionType) string {
+	switch action {
+	case domain.ModerationActionBlock:
+		return PgModerationBlock
+	case domain.ModerationActionUnblock:
+		return PgModerationUnblock
+	case domain.ModerationActionPending:
+		return PgModerationPending
+	case domain.ModerationActionApprove:
+		return PgModerationApprove
+	case domain.ModerationActionSuspend:
+		return PgModerationSuspend
+	}
+	return ""
+}
+
+func NewPgModerationAction(targetType string, action domain.ModerationAction) PgModerationAction {
+	id, _ := uuid.Parse(action.ID.String())
+	targetID, _ := uuid.Parse(action.TargetID.String())
+	var moderatorID uuid.NullUUID
+	if action.ModeratorID != "" {
+		moderatorID.UUID, _ = uuid.Parse(action.ModeratorID.String())
+		moderatorID.Valid = true
+	}
+
+	return PgModerationAction{
+		ID:          id,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		ModeratorID: moderatorID,
+		Action:      NewPgModerationActionType(action.Action),
+		Reason:      action.Reason,
+		CreatedAt:   action.CreatedAt,
+	}
+}
@@ -2,41 +2,92 @@ package entity
 
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
 	"github.com/google/uuid"
 	"time"
 )
 
 type PgOrderCustomer struct {
-	ID         uuid.UUID `db:"id"`
-	CustomerID uuid.UUID `db:"customer_id"`
-	Address    string    `db:"address"`
-	CreatedAt  time.Time `db:"created_at"`
-	TotalPrice int64     `db:"total_price"`
-	Payed      bool      `db:"payed"`
+	ID          uuid.UUID     `db:"id"`
+	CustomerID  uuid.UUID     `db:"customer_id"`
+	Address     string        `db:"address"`
+	CreatedAt   time.Time     `db:"created_at"`
+	TotalPrice  int64         `db:"total_price"`
+	Currency    string        `db:"currency"`
+	Payed       bool          `db:"payed"`
+	PromoCodeID uuid.NullUUID `db:"promo_code_id"`
+	Discount    int64         `db:"discount"`
+	AddressID   uuid.NullUUID `db:"address_id"`
+	// ShippingAddress is the snapshot of the address taken at order creation.
+	ShippingAddress PgOrderAddress `db:"shipping_address"`
 }
 
-func (oc *PgOrderCustomer) ToDomain() domain.OrderCustomer {
-	return domain.OrderCustomer{
-		ID:         domain.ID(oc.ID.String()),
-		CustomerID: domain.ID(oc.CustomerID.String()),
-		Address:    oc.Address,
-		CreatedAt:  oc.CreatedAt,
-		TotalPrice: oc.TotalPrice,
-		Payed:      oc.Payed,
+func (oc *PgOrderCustomer) ToDomain() (domain.OrderCustomer, error) {
+	address, err := encryption.Decrypt(oc.Address)
+	if err != nil {
+		return domain.OrderCustomer{}, err
+	}
+	shippingAddress, err := oc.ShippingAddress.ToDomain()
+	if err != nil {
+		return domain.OrderCustomer{}, err
+	}
+	var promoCodeID domain.ID
+	if oc.PromoCodeID.Valid {
+		promoCodeID = domain.ID(oc.PromoCodeID.UUID.String())
+	}
+	var addressID domain.ID
+	if oc.AddressID.Valid {
+		addressID = domain.ID(oc.AddressID.UUID.String())
 	}
+	return domain.OrderCustomer{
+		ID:              domain.ID(oc.ID.String()),
+		CustomerID:      domain.ID(oc.CustomerID.String()),
+		Address:         address,
+		CreatedAt:       oc.CreatedAt,
+		TotalPrice:      oc.TotalPrice,
+		Currency:        oc.Currency,
+		Payed:           oc.Payed,
+		PromoCodeID:     promoCodeID,
+		Discount:        oc.Discount,
+		AddressID:       addressID,
+		ShippingAddress: shippingAddress,
+	}, nil
 }
 
-func NewPgOrderCustomer(orderCustomer domain.OrderCustomer) PgOrderCustomer {
+func NewPgOrderCustomer(orderCustomer domain.OrderCustomer) (PgOrderCustomer, error) {
+	address, err := encryption.Encrypt(orderCustomer.Address)
+	if err != nil {
+		return PgOrderCustomer{}, err
+	}
+	shippingAddress, err := NewPgOrderAddress(orderCustomer.ShippingAddress)
+	if err != nil {
+		return PgOrderCustomer{}, err
+	}
 	id, _ := uuid.Parse(orderCustomer.ID.String())
 	customerID, _ := uuid.Parse(orderCustomer.CustomerID.String())
-	return PgOrderCustomer{
-		ID:         id,
-		CustomerID: customerID,
-		Address:    orderCustomer.Address,
-		CreatedAt:  orderCustomer.CreatedAt,
-		TotalPrice: orderCustomer.TotalPrice,
-		Payed:      orderCustomer.Payed,
+	var promoCodeID uuid.NullUUID
+	if orderCustomer.PromoCodeID != "" {
+		promoCodeID.UUID, _ = uuid.Parse(orderCustomer.PromoCodeID.String())
+		promoCodeID.Valid = true
+	}
+	var addressID uuid.NullUUID
+	if orderCustomer.AddressID != "" {
+		addressID.UUID, _ = uuid.Parse(orderCustomer.AddressID.String())
+		addressID.Valid = true
 	}
+	return PgOrderCustomer{
+		ID:              id,
+		CustomerID:      customerID,
+		Address:         address,
+		CreatedAt:       orderCustomer.CreatedAt,
+		TotalPrice:      orderCustomer.TotalPrice,
+		Currency:        orderCustomer.Currency,
+		Payed:           orderCustomer.Payed,
+		PromoCodeID:     promoCodeID,
+		Discount:        orderCustomer.Discount,
+		AddressID:       addressID,
+		ShippingAddress: shippingAddress,
+	}, nil
 }
 
 const (
@@ -97,17 +148,23 @@ func NewPgOrderShop(orderShop domain.OrderShop) PgOrderShop {
 }
 
 type PgOrderShopItem struct {
-	ID          uuid.UUID `db:"id"`
-	OrderShopID uuid.UUID `db:"order_shop_id"`
-	ProductID   uuid.UUID `db:"product_id"`
-	Quantity    int64     `db:"quantity"`
+	ID          uuid.UUID     `db:"id"`
+	OrderShopID uuid.UUID     `db:"order_shop_id"`
+	ProductID   uuid.UUID     `db:"product_id"`
+	VariantID   uuid.NullUUID `db:"variant_id"`
+	Quantity    int64         `db:"quantity"`
 }
 
 func (osi *PgOrderShopItem) ToDomain() domain.OrderShopItem {
+	var variantID domain.ID
+	if osi.VariantID.Valid {
+		variantID = domain.ID(osi.VariantID.UUID.String())
+	}
 	return domain.OrderShopItem{
 		ID:          domain.ID(osi.ID.String()),
 		OrderShopID: domain.ID(osi.OrderShopID.String()),
 		ProductID:   domain.ID(osi.ProductID.String()),
+		VariantID:   variantID,
 		Quantity:    osi.Quantity,
 	}
 }
@@ -116,10 +173,16 @@ func NewPgOrderShopItem(orderShopItem domain.OrderShopItem) PgOrderShopItem {
 	id, _ := uuid.Parse(orderShopItem.ID.String())
 	orderShopID, _ := uuid.Parse(orderShopItem.OrderShopID.String())
 	productID, _ := uuid.Parse(orderShopItem.ProductID.String())
+	var variantID uuid.NullUUID
+	if orderShopItem.VariantID != "" {
+		variantID.UUID, _ = uuid.Parse(orderShopItem.VariantID.String())
+		variantID.Valid = true
+	}
 	return PgOrderShopItem{
 		ID:          id,
 		OrderShopID: orderShopID,
 		ProductID:   productID,
+		VariantID:   variantID,
 		Quantity:    orderShopItem.Quantity,
 	}
 }
@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
+	"time"
+)
+
+const (
+	PgPayoutBatchOpen    = "Open"
+	PgPayoutBatchSettled = "Settled"
+)
+
+type PgPayoutBatch struct {
+	ID        uuid.UUID `db:"id"`
+	Total     int64     `db:"total"`
+	Currency  string    `db:"currency"`
+	Status    string    `db:"status"`
+	CreatedAt time.Time `db:"created_at"`
+	SettledAt null.Time `db:"settled_at"`
+}
+
+func (b *PgPayoutBatch) ToDomain() domain.PayoutBatch {
+	var status domain.PayoutBatchStatus
+	switch b.Status {
+	case PgPayoutBatchOpen:
+		status = domain.PayoutBatchOpen
+	case PgPayoutBatchSettled:
+		status = domain.PayoutBatchSettled
+	}
+
+	return domain.PayoutBatch{
+		ID:        domain.ID(b.ID.String()),
+		Total:     b.Total,
+		Currency:  b.Currency,
+		Status:    status,
+		CreatedAt: b.CreatedAt,
+		SettledAt: b.SettledAt,
+	}
+}
+
+func NewPgPayoutBatch(batch domain.PayoutBatch) PgPayoutBatch {
+	id, _ := uuid.Parse(batch.ID.String())
+	var status string
+	switch batch.Status {
+	case domain.PayoutBatchOpen:
+		status = PgPayoutBatchOpen
+	case domain.PayoutBatchSettled:
+		status = PgPayoutBatchSettled
+	}
+
+	return PgPayoutBatch{
+		ID:        id,
+		Total:     batch.Total,
+		Currency:  batch.Currency,
+		Status:    status,
+		CreatedAt: batch.CreatedAt,
+		SettledAt: batch.SettledAt,
+	}
+}
@@ -1,77 +1,94 @@
 package entity
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"github.com/EmirShimshir/marketplace-core/domain"
 	"github.com/google/uuid"
 )
 
-const (
-	PgProductElectronic = "Electronic"
-	PgProductFashion    = "Fashion"
-	PgProductHome       = "Home"
-	PgProductHealth     = "Health"
-	PgProductSport      = "Sport"
-	PgProductBooks      = "Books"
-)
-
 type PgProduct struct {
-	ID          uuid.UUID `db:"id"`
-	Name        string    `db:"name"`
-	Description string    `db:"description"`
-	Price       int64     `db:"price"`
-	Category    string    `db:"category"`
-	PhotoUrl    string    `db:"photo_url"`
+	ID          uuid.UUID           `db:"id"`
+	Name        string              `db:"name"`
+	Description string              `db:"description"`
+	Price       int64               `db:"price"`
+	Currency    string              `db:"currency"`
+	CategoryID  uuid.UUID           `db:"category_id"`
+	PhotoUrl    string              `db:"photo_url"`
+	Attributes  PgProductAttributes `db:"attributes"`
 }
 
 func (u *PgProduct) ToDomain() domain.Product {
-	var productCategory domain.ProductCategory
-	switch u.Category {
-	case PgProductElectronic:
-		productCategory = domain.ElectronicCategory
-	case PgProductFashion:
-		productCategory = domain.FashionCategory
-	case PgProductHome:
-		productCategory = domain.HomeCategory
-	case PgProductHealth:
-		productCategory = domain.HealthCategory
-	case PgProductSport:
-		productCategory = domain.SportCategory
-	case PgProductBooks:
-		productCategory = domain.BooksCategory
-	}
 	return domain.Product{
 		ID:          domain.ID(u.ID.String()),
 		Name:        u.Name,
 		Description: u.Description,
 		Price:       u.Price,
-		Category:    productCategory,
+		Currency:    u.Currency,
+		CategoryID:  domain.ID(u.CategoryID.String()),
 		PhotoUrl:    u.PhotoUrl,
+		Attributes:  u.Attributes,
 	}
 }
 
 func NewPgProduct(product domain.Product) PgProduct {
 	id, _ := uuid.Parse(product.ID.String())
-	var productCategory string
-	switch product.Category {
-	case domain.ElectronicCategory:
-		productCategory = PgProductElectronic
-	case domain.FashionCategory:
-		productCategory = PgProductFashion
-	case domain.HomeCategory:
-		productCategory = PgProductHome
-	case domain.HealthCategory:
-		productCategory = PgProductHealth
-	case domain.SportCategory:
-		productCategory = PgProductSport
-	case domain.BooksCategory:
-		productCategory = PgProductBooks
-	}
+	categoryID, _ := uuid.Parse(product.CategoryID.String())
 	return PgProduct{
 		ID:          id,
 		Name:        product.Name,
 		Description: product.Description,
 		Price:       product.Price,
-		Category:    productCategory,
+		Currency:    product.Currency,
+		CategoryID:  categoryID,
 		PhotoUrl:    product.PhotoUrl,
+		Attributes:  product.Attributes,
 	}
 }
+
+// PgProductAttributes maps open product attributes onto a jsonb column, an
+// empty object is read back as a nil map.
+type PgProductAttributes map[string]interface{}
+
+func (a PgProductAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *PgProductAttributes) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported attributes type %T", src)
+	}
+	if err := json.Unmarshal(data, a); err != nil {
+		return err
+	}
+	if len(*a) == 0 {
+		*a = nil
+	}
+	return nil
+}
+
+type PgRatedProduct struct {
+	PgProduct
+	Rating      float64 `db:"rating"`
+	ReviewCount int64   `db:"review_count"`
+}
+
+func (p *PgRatedProduct) ToDomain() domain.Product {
+	product := p.PgProduct.ToDomain()
+	product.Rating = p.Rating
+	product.ReviewCount = p.ReviewCount
+	return product
+}
@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+)
+
+type PgProductImage struct {
+	ID        uuid.UUID `db:"id"`
+	ProductID uuid.UUID `db:"product_id"`
+	Url       string    `db:"url"`
+	Position  int64     `db:"position"`
+	AltText   string    `db:"alt_text"`
+	IsPrimary bool      `db:"is_primary"`
+}
+
+func (i *PgProductImage) ToDomain() domain.ProductImage {
+	return domain.ProductImage{
+		ID:        domain.ID(i.ID.String()),
+		ProductID: domain.ID(i.ProductID.String()),
+		Url:       i.Url,
+		Position:  i.Position,
+		AltText:   i.AltText,
+		IsPrimary: i.IsPrimary,
+	}
+}
+
+func NewPgProductImage(image domain.ProductImage) PgProductImage {
+	id, _ := uuid.Parse(image.ID.String())
+	productID, _ := uuid.Parse(image.ProductID.String())
+	return PgProductImage{
+		ID:        id,
+		ProductID: productID,
+		Url:       image.Url,
+		Position:  image.Position,
+		AltText:   image.AltText,
+		IsPrimary: image.IsPrimary,
+	}
+}
@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+)
+
+type PgProductTranslation struct {
+	ProductID   uuid.UUID `db:"product_id"`
+	Locale      string    `db:"locale"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+}
+
+func (t *PgProductTranslation) ToDomain() domain.ProductTranslation {
+	return domain.ProductTranslation{
+		ProductID:   domain.ID(t.ProductID.String()),
+		Locale:      t.Locale,
+		Name:        t.Name,
+		Description: t.Description,
+	}
+}
+
+func NewPgProductTranslation(translation domain.ProductTranslation) PgProductTranslation {
+	productID, _ := uuid.Parse(translation.ProductID.String())
+	return PgProductTranslation{
+		ProductID:   productID,
+		Locale:      translation.Locale,
+		Name:        translation.Name,
+		Description: translation.Description,
+	}
+}
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
+)
+
+// PgAttributes maps a string map onto a jsonb column.
+type PgAttributes map[string]string
+
+func (a PgAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *PgAttributes) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("unsupported attributes type %T", src)
+	}
+}
+
+type PgProductVariant struct {
+	ID         uuid.UUID    `db:"id"`
+	ShopItemID uuid.UUID    `db:"shop_product_id"`
+	SKU        string       `db:"sku"`
+	Attributes PgAttributes `db:"attributes"`
+	Price      null.Int     `db:"price"`
+	Quantity   int64        `db:"quantity"`
+}
+
+func (v *PgProductVariant) ToDomain() domain.ProductVariant {
+	return domain.ProductVariant{
+		ID:         domain.ID(v.ID.String()),
+		ShopItemID: domain.ID(v.ShopItemID.String()),
+		SKU:        v.SKU,
+		Attributes: v.Attributes,
+		Price:      v.Price,
+		Quantity:   v.Quantity,
+	}
+}
+
+func NewPgProductVariant(variant domain.ProductVariant) PgProductVariant {
+	id, _ := uuid.Parse(variant.ID.String())
+	shopItemID, _ := uuid.Parse(variant.ShopItemID.String())
+	return PgProductVariant{
+		ID:         id,
+		ShopItemID: shopItemID,
+		SKU:        variant.SKU,
+		Attributes: variant.Attributes,
+		Price:      variant.Price,
+		Quantity:   variant.Quantity,
+	}
+}
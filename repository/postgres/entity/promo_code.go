@@ -0,0 +1,85 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
+	"time"
+)
+
+const (
+	PgPromoCodePercentage = "Percentage"
+	PgPromoCodeFixed      = "Fixed"
+)
+
+type PgPromoCode struct {
+	ID             uuid.UUID     `db:"id"`
+	Code           string        `db:"code"`
+	Type           string        `db:"type"`
+	Value          int64         `db:"value"`
+	Currency       null.String   `db:"currency"`
+	ShopID         uuid.NullUUID `db:"shop_id"`
+	ValidFrom      time.Time     `db:"valid_from"`
+	ValidTo        time.Time     `db:"valid_to"`
+	MaxUses        int64         `db:"max_uses"`
+	MaxUsesPerUser int64         `db:"max_uses_per_user"`
+	UsedCount      int64         `db:"used_count"`
+}
+
+func (p *PgPromoCode) ToDomain() domain.PromoCode {
+	var promoCodeType domain.PromoCodeType
+	switch p.Type {
+	case PgPromoCodePercentage:
+		promoCodeType = domain.PromoCodePercentage
+	case PgPromoCodeFixed:
+		promoCodeType = domain.PromoCodeFixed
+	}
+	var shopID domain.ID
+	if p.ShopID.Valid {
+		shopID = domain.ID(p.ShopID.UUID.String())
+	}
+
+	return domain.PromoCode{
+		ID:             domain.ID(p.ID.String()),
+		Code:           p.Code,
+		Type:           promoCodeType,
+		Value:          p.Value,
+		Currency:       p.Currency.String,
+		ShopID:         shopID,
+		ValidFrom:      p.ValidFrom,
+		ValidTo:        p.ValidTo,
+		MaxUses:        p.MaxUses,
+		MaxUsesPerUser: p.MaxUsesPerUser,
+		UsedCount:      p.UsedCount,
+	}
+}
+
+func NewPgPromoCode(promoCode domain.PromoCode) PgPromoCode {
+	id, _ := uuid.Parse(promoCode.ID.String())
+	var shopID uuid.NullUUID
+	if promoCode.ShopID != "" {
+		shopID.UUID, _ = uuid.Parse(promoCode.ShopID.String())
+		shopID.Valid = true
+	}
+	var promoCodeType string
+	switch promoCode.Type {
+	case domain.PromoCodePercentage:
+		promoCodeType = PgPromoCodePercentage
+	case domain.PromoCodeFixed:
+		promoCodeType = PgPromoCodeFixed
+	}
+
+	return PgPromoCode{
+		ID:             id,
+		Code:           promoCode.Code,
+		Type:           promoCodeType,
+		Value:          promoCode.Value,
+		Currency:       null.NewString(promoCode.Currency, promoCode.Currency != ""),
+		ShopID:         shopID,
+		ValidFrom:      promoCode.ValidFrom,
+		ValidTo:        promoCode.ValidTo,
+		MaxUses:        promoCode.MaxUses,
+		MaxUsesPerUser: promoCode.MaxUsesPerUser,
+		UsedCount:      promoCode.UsedCount,
+	}
+}
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"time"
+)
+
+type PgReview struct {
+	ID        uuid.UUID `db:"id"`
+	ProductID uuid.UUID `db:"product_id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Rating    int64     `db:"rating"`
+	Text      string    `db:"text"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (r *PgReview) ToDomain() domain.Review {
+	return domain.Review{
+		ID:        domain.ID(r.ID.String()),
+		ProductID: domain.ID(r.ProductID.String()),
+		UserID:    domain.ID(r.UserID.String()),
+		Rating:    r.Rating,
+		Text:      r.Text,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+func NewPgReview(review domain.Review) PgReview {
+	id, _ := uuid.Parse(review.ID.String())
+	productID, _ := uuid.Parse(review.ProductID.String())
+	userID, _ := uuid.Parse(review.UserID.String())
+	return PgReview{
+		ID:        id,
+		ProductID: productID,
+		UserID:    userID,
+		Rating:    review.Rating,
+		Text:      review.Text,
+		CreatedAt: review.CreatedAt,
+	}
+}
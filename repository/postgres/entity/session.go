@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
+	"time"
+)
+
+type PgSession struct {
+	ID         uuid.UUID `db:"id"`
+	UserID     uuid.UUID `db:"user_id"`
+	TokenHash  string    `db:"token_hash"`
+	DeviceInfo string    `db:"device_info"`
+	IP         string    `db:"ip"`
+	CreatedAt  time.Time `db:"created_at"`
+	ExpiresAt  time.Time `db:"expires_at"`
+	RevokedAt  null.Time `db:"revoked_at"`
+}
+
+func (s *PgSession) ToDomain() domain.Session {
+	return domain.Session{
+		ID:         domain.ID(s.ID.String()),
+		UserID:     domain.ID(s.UserID.String()),
+		TokenHash:  s.TokenHash,
+		DeviceInfo: s.DeviceInfo,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt,
+		ExpiresAt:  s.ExpiresAt,
+		RevokedAt:  s.RevokedAt,
+	}
+}
+
+func NewPgSession(session domain.Session) PgSession {
+	id, _ := uuid.Parse(session.ID.String())
+	userID, _ := uuid.Parse(session.UserID.String())
+	return PgSession{
+		ID:         id,
+		UserID:     userID,
+		TokenHash:  session.TokenHash,
+		DeviceInfo: session.DeviceInfo,
+		IP:         session.IP,
+		CreatedAt:  session.CreatedAt,
+		ExpiresAt:  session.ExpiresAt,
+		RevokedAt:  session.RevokedAt,
+	}
+}
@@ -0,0 +1,108 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
+	"time"
+)
+
+type PgShipment struct {
+	ID             uuid.UUID `db:"id"`
+	OrderShopID    uuid.UUID `db:"order_shop_id"`
+	Carrier        string    `db:"carrier"`
+	TrackingNumber string    `db:"tracking_number"`
+	ShippedAt      null.Time `db:"shipped_at"`
+	DeliveredAt    null.Time `db:"delivered_at"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+func (s *PgShipment) ToDomain() domain.Shipment {
+	return domain.Shipment{
+		ID:             domain.ID(s.ID.String()),
+		OrderShopID:    domain.ID(s.OrderShopID.String()),
+		Carrier:        s.Carrier,
+		TrackingNumber: s.TrackingNumber,
+		ShippedAt:      s.ShippedAt,
+		DeliveredAt:    s.DeliveredAt,
+		CreatedAt:      s.CreatedAt,
+	}
+}
+
+func NewPgShipment(shipment domain.Shipment) PgShipment {
+	id, _ := uuid.Parse(shipment.ID.String())
+	orderShopID, _ := uuid.Parse(shipment.OrderShopID.String())
+	return PgShipment{
+		ID:             id,
+		OrderShopID:    orderShopID,
+		Carrier:        shipment.Carrier,
+		TrackingNumber: shipment.TrackingNumber,
+		ShippedAt:      shipment.ShippedAt,
+		DeliveredAt:    shipment.DeliveredAt,
+		CreatedAt:      shipment.CreatedAt,
+	}
+}
+
+const (
+	PgShipmentEventShipped   = "Shipped"
+	PgShipmentEventInTransit = "InTransit"
+	PgShipmentEventDelivered = "Delivered"
+	PgShipmentEventFailed    = "Failed"
+)
+
+type PgShipmentEvent struct {
+	ID          uuid.UUID `db:"id"`
+	ShipmentID  uuid.UUID `db:"shipment_id"`
+	Type        string    `db:"type"`
+	Location    string    `db:"location"`
+	Description string    `db:"description"`
+	OccurredAt  time.Time `db:"occurred_at"`
+}
+
+func (e *PgShipmentEvent) ToDomain() domain.ShipmentEvent {
+	var eventType domain.ShipmentEventType
+	switch e.Type {
+	case PgShipmentEventShipped:
+		eventType = domain.ShipmentEventShipped
+	case PgShipmentEventInTransit:
+		eventType = domain.ShipmentEventInTransit
+	case PgShipmentEventDelivered:
+		eventType = domain.ShipmentEventDelivered
+	case PgShipmentEventFailed:
+		eventType = domain.ShipmentEventFailed
+	}
+
+	return domain.ShipmentEvent{
+		ID:          domain.ID(e.ID.String()),
+		ShipmentID:  domain.ID(e.ShipmentID.String()),
+		Type:        eventType,
+		Location:    e.Location,
+		Description: e.Description,
+		OccurredAt:  e.OccurredAt,
+	}
+}
+
+func NewPgShipmentEvent(event domain.ShipmentEvent) PgShipmentEvent {
+	id, _ := uuid.Parse(event.ID.String())
+	shipmentID, _ := uuid.Parse(event.ShipmentID.String())
+	var eventType string
+	switch event.Type {
+	case domain.ShipmentEventShipped:
+		eventType = PgShipmentEventShipped
+	case domain.ShipmentEventInTransit:
+		eventType = PgShipmentEventInTransit
+	case domain.ShipmentEventDelivered:
+		eventType = PgShipmentEventDelivered
+	case domain.ShipmentEventFailed:
+		eventType = PgShipmentEventFailed
+	}
+
+	return PgShipmentEvent{
+		ID:          id,
+		ShipmentID:  shipmentID,
+		Type:        eventType,
+		Location:    event.Location,
+		Description: event.Description,
+		OccurredAt:  event.OccurredAt,
+	}
+}
@@ -2,55 +2,98 @@ package entity
 
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
 	"github.com/google/uuid"
 )
 
+const (
+	PgShopApproved  = "Approved"
+	PgShopPending   = "Pending"
+	PgShopSuspended = "Suspended"
+)
+
 type PgShop struct {
-	ID          uuid.UUID `db:"id"`
-	SellerID    uuid.UUID `db:"seller_id"`
-	Name        string    `db:"name"`
-	Description string    `db:"description"`
-	Requisites  string    `db:"requisites"`
-	Email       string    `db:"email"`
+	ID               uuid.UUID `db:"id"`
+	SellerID         uuid.UUID `db:"seller_id"`
+	Name             string    `db:"name"`
+	Description      string    `db:"description"`
+	Requisites       string    `db:"requisites"`
+	Email            string    `db:"email"`
+	ModerationStatus string    `db:"moderation_status"`
+	DefaultCurrency  string    `db:"default_currency"`
 }
 
-func (s *PgShop) ToDomain() domain.Shop {
-	return domain.Shop{
-		ID:          domain.ID(s.ID.String()),
-		SellerID:    domain.ID(s.SellerID.String()),
-		Name:        s.Name,
-		Description: s.Description,
-		Requisites:  s.Requisites,
-		Email:       s.Email,
+func (s *PgShop) ToDomain() (domain.Shop, error) {
+	requisites, err := encryption.Decrypt(s.Requisites)
+	if err != nil {
+		return domain.Shop{}, err
+	}
+	var moderationStatus domain.ShopModerationStatus
+	switch s.ModerationStatus {
+	case PgShopApproved:
+		moderationStatus = domain.ShopModerationApproved
+	case PgShopPending:
+		moderationStatus = domain.ShopModerationPending
+	case PgShopSuspended:
+		moderationStatus = domain.ShopModerationSuspended
 	}
+	return domain.Shop{
+		ID:               domain.ID(s.ID.String()),
+		SellerID:         domain.ID(s.SellerID.String()),
+		Name:             s.Name,
+		Description:      s.Description,
+		Requisites:       requisites,
+		Email:            s.Email,
+		ModerationStatus: moderationStatus,
+		DefaultCurrency:  s.DefaultCurrency,
+	}, nil
 }
 
-func NewPgShop(shop domain.Shop) PgShop {
+func NewPgShop(shop domain.Shop) (PgShop, error) {
 	id, _ := uuid.Parse(shop.ID.String())
 	sellerID, _ := uuid.Parse(shop.SellerID.String())
+	requisites, err := encryption.Encrypt(shop.Requisites)
+	if err != nil {
+		return PgShop{}, err
+	}
 	return PgShop{
-		ID:          id,
-		SellerID:    sellerID,
-		Name:        shop.Name,
-		Description: shop.Description,
-		Requisites:  shop.Requisites,
-		Email:       shop.Email,
+		ID:               id,
+		SellerID:         sellerID,
+		Name:             shop.Name,
+		Description:      shop.Description,
+		Requisites:       requisites,
+		Email:            shop.Email,
+		ModerationStatus: NewPgShopModerationStatus(shop.ModerationStatus),
+		DefaultCurrency:  shop.DefaultCurrency,
+	}, nil
+}
+
+func NewPgShopModerationStatus(status domain.ShopModerationStatus) string {
+	switch status {
+	case domain.ShopModerationPending:
+		return PgShopPending
+	case domain.ShopModerationSuspended:
+		return PgShopSuspended
+	default:
+		return PgShopApproved
 	}
 }
 
 type PgShopItem struct {
-	ID        uuid.UUID `db:"id"`
-	ShopID    uuid.UUID `db:"shop_id"`
-	ProductID uuid.UUID `db:"product_id"`
-	Quantity  int64     `db:"quantity"`
+	ID               uuid.UUID `db:"id"`
+	ShopID           uuid.UUID `db:"shop_id"`
+	ProductID        uuid.UUID `db:"product_id"`
+	Quantity         int64     `db:"quantity"`
+	ReorderThreshold int64     `db:"reorder_threshold"`
 }
 
 func (si *PgShopItem) ToDomain() domain.ShopItem {
 	return domain.ShopItem{
-		ID:        domain.ID(si.ID.String()),
-		ShopID:    domain.ID(si.ShopID.String()),
-		ProductID: domain.ID(si.ProductID.String()),
-		Quantity:  si.Quantity,
+		ID:               domain.ID(si.ID.String()),
+		ShopID:           domain.ID(si.ShopID.String()),
+		ProductID:        domain.ID(si.ProductID.String()),
+		Quantity:         si.Quantity,
+		ReorderThreshold: si.ReorderThreshold,
 	}
 }
 
@@ -59,9 +102,10 @@ func NewPgShopItem(shopItem domain.ShopItem) PgShopItem {
 	shopID, _ := uuid.Parse(shopItem.ShopID.String())
 	productID, _ := uuid.Parse(shopItem.ProductID.String())
 	return PgShopItem{
-		ID:        id,
-		ShopID:    shopID,
-		ProductID: productID,
-		Quantity:  shopItem.Quantity,
+		ID:               id,
+		ShopID:           shopID,
+		ProductID:        productID,
+		Quantity:         shopItem.Quantity,
+		ReorderThreshold: shopItem.ReorderThreshold,
 	}
 }
@@ -0,0 +1,94 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	PgStockMovementRestock    = "Restock"
+	PgStockMovementSale       = "Sale"
+	PgStockMovementCancel     = "Cancel"
+	PgStockMovementAdjustment = "Adjustment"
+)
+
+type PgStockMovement struct {
+	ID          uuid.UUID     `db:"id"`
+	ShopItemID  uuid.UUID     `db:"shop_product_id"`
+	VariantID   uuid.NullUUID `db:"variant_id"`
+	Delta       int64         `db:"delta"`
+	Reason      string        `db:"reason"`
+	ReferenceID uuid.NullUUID `db:"reference_id"`
+	CreatedAt   time.Time     `db:"created_at"`
+}
+
+func (sm *PgStockMovement) ToDomain() domain.StockMovement {
+	var reason domain.StockMovementReason
+	switch sm.Reason {
+	case PgStockMovementRestock:
+		reason = domain.StockMovementRestock
+	case PgStockMovementSale:
+		reason = domain.StockMovementSale
+	case PgStockMovementCancel:
+		reason = domain.StockMovementCancel
+	case PgStockMovementAdjustment:
+		reason = domain.StockMovementAdjustment
+	}
+
+	var variantID domain.ID
+	if sm.VariantID.Valid {
+		variantID = domain.ID(sm.VariantID.UUID.String())
+	}
+	var referenceID domain.ID
+	if sm.ReferenceID.Valid {
+		referenceID = domain.ID(sm.ReferenceID.UUID.String())
+	}
+
+	return domain.StockMovement{
+		ID:          domain.ID(sm.ID.String()),
+		ShopItemID:  domain.ID(sm.ShopItemID.String()),
+		VariantID:   variantID,
+		Delta:       sm.Delta,
+		Reason:      reason,
+		ReferenceID: referenceID,
+		CreatedAt:   sm.CreatedAt,
+	}
+}
+
+func NewPgStockMovement(movement domain.StockMovement) PgStockMovement {
+	id, _ := uuid.Parse(movement.ID.String())
+	shopItemID, _ := uuid.Parse(movement.ShopItemID.String())
+	var reason string
+	switch movement.Reason {
+	case domain.StockMovementRestock:
+		reason = PgStockMovementRestock
+	case domain.StockMovementSale:
+		reason = PgStockMovementSale
+	case domain.StockMovementCancel:
+		reason = PgStockMovementCancel
+	case domain.StockMovementAdjustment:
+		reason = PgStockMovementAdjustment
+	}
+
+	var variantID uuid.NullUUID
+	if movement.VariantID != "" {
+		variantID.UUID, _ = uuid.Parse(movement.VariantID.String())
+		variantID.Valid = true
+	}
+	var referenceID uuid.NullUUID
+	if movement.ReferenceID != "" {
+		referenceID.UUID, _ = uuid.Parse(movement.ReferenceID.String())
+		referenceID.Valid = true
+	}
+
+	return PgStockMovement{
+		ID:          id,
+		ShopItemID:  shopItemID,
+		VariantID:   variantID,
+		Delta:       movement.Delta,
+		Reason:      reason,
+		ReferenceID: referenceID,
+		CreatedAt:   movement.CreatedAt,
+	}
+}
@@ -2,6 +2,7 @@ package entity
 
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
 	"github.com/google/uuid"
 	"github.com/guregu/null"
 )
@@ -13,17 +14,33 @@ const (
 )
 
 type PgUser struct {
-	ID       uuid.UUID   `db:"id"`
-	CartID   uuid.UUID   `db:"cart_id"`
-	Name     string      `db:"name"`
-	Surname  string      `db:"surname"`
-	Phone    null.String `db:"phone"`
-	Email    string      `db:"email"`
-	Password string      `db:"password"`
-	Role     string      `db:"role"`
+	ID      uuid.UUID   `db:"id"`
+	CartID  uuid.UUID   `db:"cart_id"`
+	Name    string      `db:"name"`
+	Surname string      `db:"surname"`
+	Phone   null.String `db:"phone"`
+	// PhoneHash is the blind index of the phone, Phone itself is encrypted.
+	PhoneHash null.String `db:"phone_hash"`
+	Email     string      `db:"email"`
+	// EmailHash is the blind index of the email, Email itself is encrypted.
+	EmailHash string `db:"email_hash"`
+	Password  string `db:"password"`
+	Role      string `db:"role"`
+	// EmailVerifiedAt is set once the user confirms the email address.
+	EmailVerifiedAt null.Time `db:"email_verified_at"`
+	BlockedAt       null.Time `db:"blocked_at"`
+	BlockedReason   string    `db:"blocked_reason"`
 }
 
-func (u *PgUser) ToDomain() domain.User {
+func (u *PgUser) ToDomain() (domain.User, error) {
+	phone, err := encryption.DecryptNull(u.Phone)
+	if err != nil {
+		return domain.User{}, err
+	}
+	email, err := encryption.Decrypt(u.Email)
+	if err != nil {
+		return domain.User{}, err
+	}
 	var userRole domain.UserRole
 	switch u.Role {
 	case PgUserCustomer:
@@ -34,37 +51,59 @@ func (u *PgUser) ToDomain() domain.User {
 		userRole = domain.UserModerator
 	}
 	return domain.User{
-		ID:       domain.ID(u.ID.String()),
-		CartID:   domain.ID(u.CartID.String()),
-		Name:     u.Name,
-		Surname:  u.Surname,
-		Phone:    u.Phone,
-		Email:    u.Email,
-		Password: u.Password,
-		Role:     userRole,
-	}
+		ID:              domain.ID(u.ID.String()),
+		CartID:          domain.ID(u.CartID.String()),
+		Name:            u.Name,
+		Surname:         u.Surname,
+		Phone:           phone,
+		Email:           email,
+		Password:        u.Password,
+		Role:            userRole,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		BlockedAt:       u.BlockedAt,
+		BlockedReason:   u.BlockedReason,
+	}, nil
 }
 
-func NewPgUser(user domain.User) PgUser {
+func NewPgUser(user domain.User) (PgUser, error) {
 	id, _ := uuid.Parse(user.ID.String())
 	cartID, _ := uuid.Parse(user.CartID.String())
-	var userRole string
-	switch user.Role {
-	case domain.UserCustomer:
-		userRole = PgUserCustomer
-	case domain.UserSeller:
-		userRole = PgUserSeller
-	case domain.UserModerator:
-		userRole = PgUserModerator
+	var phoneHash null.String
+	if user.Phone.Valid {
+		phoneHash = null.StringFrom(encryption.BlindIndex(user.Phone.String))
+	}
+	phone, err := encryption.EncryptNull(user.Phone)
+	if err != nil {
+		return PgUser{}, err
+	}
+	email, err := encryption.Encrypt(user.Email)
+	if err != nil {
+		return PgUser{}, err
 	}
 	return PgUser{
-		ID:       id,
-		CartID:   cartID,
-		Name:     user.Name,
-		Surname:  user.Surname,
-		Phone:    user.Phone,
-		Email:    user.Email,
-		Password: user.Password,
-		Role:     userRole,
+		ID:              id,
+		CartID:          cartID,
+		Name:            user.Name,
+		Surname:         user.Surname,
+		Phone:           phone,
+		PhoneHash:       phoneHash,
+		Email:           email,
+		EmailHash:       encryption.BlindIndex(user.Email),
+		Password:        user.Password,
+		Role:            NewPgUserRole(user.Role),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		BlockedAt:       user.BlockedAt,
+		BlockedReason:   user.BlockedReason,
+	}, nil
+}
+
+func NewPgUserRole(role domain.UserRole) string {
+	switch role {
+	case domain.UserSeller:
+		return PgUserSeller
+	case domain.UserModerator:
+		return PgUserModerator
+	default:
+		return PgUserCustomer
 	}
 }
@@ -0,0 +1,151 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+	"github.com/google/uuid"
+)
+
+type PgUserAddress struct {
+	ID         uuid.UUID `db:"id"`
+	UserID     uuid.UUID `db:"user_id"`
+	Recipient  string    `db:"recipient"`
+	Phone      string    `db:"phone"`
+	Country    string    `db:"country"`
+	City       string    `db:"city"`
+	Street     string    `db:"street"`
+	PostalCode string    `db:"postal_code"`
+	IsDefault  bool      `db:"is_default"`
+}
+
+func (a *PgUserAddress) ToDomain() (domain.UserAddress, error) {
+	address := domain.UserAddress{
+		ID:         domain.ID(a.ID.String()),
+		UserID:     domain.ID(a.UserID.String()),
+		Recipient:  a.Recipient,
+		Phone:      a.Phone,
+		Country:    a.Country,
+		City:       a.City,
+		Street:     a.Street,
+		PostalCode: a.PostalCode,
+		IsDefault:  a.IsDefault,
+	}
+	err := decryptFields(&address.Recipient, &address.Phone, &address.Street, &address.PostalCode)
+	if err != nil {
+		return domain.UserAddress{}, err
+	}
+	return address, nil
+}
+
+func NewPgUserAddress(address domain.UserAddress) (PgUserAddress, error) {
+	id, _ := uuid.Parse(address.ID.String())
+	userID, _ := uuid.Parse(address.UserID.String())
+	pgAddress := PgUserAddress{
+		ID:         id,
+		UserID:     userID,
+		Recipient:  address.Recipient,
+		Phone:      address.Phone,
+		Country:    address.Country,
+		City:       address.City,
+		Street:     address.Street,
+		PostalCode: address.PostalCode,
+		IsDefault:  address.IsDefault,
+	}
+	err := encryptFields(&pgAddress.Recipient, &pgAddress.Phone, &pgAddress.Street, &pgAddress.PostalCode)
+	if err != nil {
+		return PgUserAddress{}, err
+	}
+	return pgAddress, nil
+}
+
+// PgOrderAddress maps the address snapshot of an order onto a jsonb column,
+// an empty snapshot is stored as NULL. The recipient, the phone, the street
+// and the postal code are encrypted like in PgUserAddress.
+type PgOrderAddress struct {
+	Recipient  string `json:"recipient"`
+	Phone      string `json:"phone"`
+	Country    string `json:"country"`
+	City       string `json:"city"`
+	Street     string `json:"street"`
+	PostalCode string `json:"postal_code"`
+}
+
+func (a PgOrderAddress) Value() (driver.Value, error) {
+	if a == (PgOrderAddress{}) {
+		return nil, nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *PgOrderAddress) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = PgOrderAddress{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return fmt.Errorf("unsupported order address type %T", src)
+	}
+}
+
+func (a PgOrderAddress) ToDomain() (domain.OrderAddress, error) {
+	address := domain.OrderAddress{
+		Recipient:  a.Recipient,
+		Phone:      a.Phone,
+		Country:    a.Country,
+		City:       a.City,
+		Street:     a.Street,
+		PostalCode: a.PostalCode,
+	}
+	err := decryptFields(&address.Recipient, &address.Phone, &address.Street, &address.PostalCode)
+	if err != nil {
+		return domain.OrderAddress{}, err
+	}
+	return address, nil
+}
+
+func NewPgOrderAddress(address domain.OrderAddress) (PgOrderAddress, error) {
+	pgAddress := PgOrderAddress{
+		Recipient:  address.Recipient,
+		Phone:      address.Phone,
+		Country:    address.Country,
+		City:       address.City,
+		Street:     address.Street,
+		PostalCode: address.PostalCode,
+	}
+	err := encryptFields(&pgAddress.Recipient, &pgAddress.Phone, &pgAddress.Street, &pgAddress.PostalCode)
+	if err != nil {
+		return PgOrderAddress{}, err
+	}
+	return pgAddress, nil
+}
+
+// encryptFields encrypts the given fields in place.
+func encryptFields(fields ...*string) error {
+	for _, field := range fields {
+		encrypted, err := encryption.Encrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = encrypted
+	}
+	return nil
+}
+
+// decryptFields decrypts the given fields in place.
+func decryptFields(fields ...*string) error {
+	for _, field := range fields {
+		decrypted, err := encryption.Decrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = decrypted
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
+	"time"
+)
+
+const (
+	PgTokenEmailVerification = "EmailVerification"
+	PgTokenPasswordReset     = "PasswordReset"
+)
+
+type PgUserToken struct {
+	ID         uuid.UUID `db:"id"`
+	UserID     uuid.UUID `db:"user_id"`
+	TokenHash  string    `db:"token_hash"`
+	Purpose    string    `db:"purpose"`
+	CreatedAt  time.Time `db:"created_at"`
+	ExpiresAt  time.Time `db:"expires_at"`
+	ConsumedAt null.Time `db:"consumed_at"`
+}
+
+func (t *PgUserToken) ToDomain() domain.UserToken {
+	var purpose domain.TokenPurpose
+	switch t.Purpose {
+	case PgTokenEmailVerification:
+		purpose = domain.TokenPurposeEmailVerification
+	case PgTokenPasswordReset:
+		purpose = domain.TokenPurposePasswordReset
+	}
+
+	return domain.UserToken{
+		ID:         domain.ID(t.ID.String()),
+		UserID:     domain.ID(t.UserID.String()),
+		TokenHash:  t.TokenHash,
+		Purpose:    purpose,
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  t.ExpiresAt,
+		ConsumedAt: t.ConsumedAt,
+	}
+}
+
+func NewPgTokenPurpose(purpose domain.TokenPurpose) string {
+	switch purpose {
+	case domain.TokenPurposeEmailVerification:
+		return PgTokenEmailVerification
+	case domain.TokenPurposePasswordReset:
+		return PgTokenPasswordReset
+	}
+	return ""
+}
+
+func NewPgUserToken(token domain.UserToken) PgUserToken {
+	id, _ := uuid.Parse(token.ID.String())
+	userID, _ := uuid.Parse(token.UserID.String())
+	return PgUserToken{
+		ID:         id,
+		UserID:     userID,
+		TokenHash:  token.TokenHash,
+		Purpose:    NewPgTokenPurpose(token.Purpose),
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		ConsumedAt: token.ConsumedAt,
+	}
+}
@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/google/uuid"
+	"time"
+)
+
+type PgWishlist struct {
+	ID        uuid.UUID `db:"id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (w *PgWishlist) ToDomain() domain.Wishlist {
+	return domain.Wishlist{
+		ID:        domain.ID(w.ID.String()),
+		UserID:    domain.ID(w.UserID.String()),
+		Name:      w.Name,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func NewPgWishlist(wishlist domain.Wishlist) PgWishlist {
+	id, _ := uuid.Parse(wishlist.ID.String())
+	userID, _ := uuid.Parse(wishlist.UserID.String())
+	return PgWishlist{
+		ID:        id,
+		UserID:    userID,
+		Name:      wishlist.Name,
+		CreatedAt: wishlist.CreatedAt,
+	}
+}
+
+type PgWishlistItem struct {
+	ID         uuid.UUID     `db:"id"`
+	WishlistID uuid.UUID     `db:"wishlist_id"`
+	ProductID  uuid.UUID     `db:"product_id"`
+	VariantID  uuid.NullUUID `db:"variant_id"`
+	AddedAt    time.Time     `db:"added_at"`
+}
+
+func (wi *PgWishlistItem) ToDomain() domain.WishlistItem {
+	var variantID domain.ID
+	if wi.VariantID.Valid {
+		variantID = domain.ID(wi.VariantID.UUID.String())
+	}
+	return domain.WishlistItem{
+		ID:         domain.ID(wi.ID.String()),
+		WishlistID: domain.ID(wi.WishlistID.String()),
+		ProductID:  domain.ID(wi.ProductID.String()),
+		VariantID:  variantID,
+		AddedAt:    wi.AddedAt,
+	}
+}
+
+func NewPgWishlistItem(wishlistItem domain.WishlistItem) PgWishlistItem {
+	id, _ := uuid.Parse(wishlistItem.ID.String())
+	wishlistID, _ := uuid.Parse(wishlistItem.WishlistID.String())
+	productID, _ := uuid.Parse(wishlistItem.ProductID.String())
+	var variantID uuid.NullUUID
+	if wishlistItem.VariantID != "" {
+		variantID.UUID, _ = uuid.Parse(wishlistItem.VariantID.String())
+		variantID.Valid = true
+	}
+	return PgWishlistItem{
+		ID:         id,
+		WishlistID: wishlistID,
+		ProductID:  productID,
+		VariantID:  variantID,
+		AddedAt:    wishlistItem.AddedAt,
+	}
+}
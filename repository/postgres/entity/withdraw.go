@@ -3,6 +3,7 @@ package entity
 import (
 	"github.com/EmirShimshir/marketplace-core/domain"
 	"github.com/google/uuid"
+	"github.com/guregu/null"
 )
 
 const (
@@ -12,11 +13,19 @@ const (
 )
 
 type PgWithdraw struct {
-	ID      uuid.UUID `db:"id"`
-	ShopID  uuid.UUID `db:"shop_id"`
-	Comment string    `db:"comment"`
-	Sum     int64     `db:"sum"`
-	Status  string    `db:"status"`
+	ID       uuid.UUID `db:"id"`
+	ShopID   uuid.UUID `db:"shop_id"`
+	Comment  string    `db:"comment"`
+	Sum      int64     `db:"sum"`
+	Status   string    `db:"status"`
+	Currency string    `db:"currency"`
+	// RequestedAt is NULL for the withdraws requested before it was recorded,
+	// their domain RequestedAt is zero.
+	RequestedAt null.Time     `db:"requested_at"`
+	ApprovedAt  null.Time     `db:"approved_at"`
+	PaidAt      null.Time     `db:"paid_at"`
+	ReviewerID  uuid.NullUUID `db:"reviewer_id"`
+	BatchID     uuid.NullUUID `db:"payout_batch_id"`
 }
 
 func (w *PgWithdraw) ToDomain() domain.Withdraw {
@@ -29,34 +38,66 @@ func (w *PgWithdraw) ToDomain() domain.Withdraw {
 	case PgWithdrawDone:
 		withdrawStatus = domain.WithdrawStatusDone
 	}
+	var reviewerID domain.ID
+	if w.ReviewerID.Valid {
+		reviewerID = domain.ID(w.ReviewerID.UUID.String())
+	}
+	var batchID domain.ID
+	if w.BatchID.Valid {
+		batchID = domain.ID(w.BatchID.UUID.String())
+	}
 
 	return domain.Withdraw{
-		ID:      domain.ID(w.ID.String()),
-		ShopID:  domain.ID(w.ShopID.String()),
-		Comment: w.Comment,
-		Sum:     w.Sum,
-		Status:  withdrawStatus,
+		ID:          domain.ID(w.ID.String()),
+		ShopID:      domain.ID(w.ShopID.String()),
+		Comment:     w.Comment,
+		Sum:         w.Sum,
+		Status:      withdrawStatus,
+		Currency:    w.Currency,
+		RequestedAt: w.RequestedAt.Time,
+		ApprovedAt:  w.ApprovedAt,
+		PaidAt:      w.PaidAt,
+		ReviewerID:  reviewerID,
+		BatchID:     batchID,
+	}
+}
+
+func NewPgWithdrawStatus(status domain.WithdrawStatus) string {
+	switch status {
+	case domain.WithdrawStatusReady:
+		return PgWithdrawReady
+	case domain.WithdrawStatusDone:
+		return PgWithdrawDone
+	default:
+		return PgWithdrawStart
 	}
 }
 
 func NewPgWithdraw(withdraw domain.Withdraw) PgWithdraw {
 	id, _ := uuid.Parse(withdraw.ID.String())
 	shopID, _ := uuid.Parse(withdraw.ShopID.String())
-	var withdrawStatus string
-	switch withdraw.Status {
-	case domain.WithdrawStatusStart:
-		withdrawStatus = PgWithdrawStart
-	case domain.WithdrawStatusReady:
-		withdrawStatus = PgWithdrawReady
-	case domain.WithdrawStatusDone:
-		withdrawStatus = PgWithdrawDone
+	var reviewerID uuid.NullUUID
+	if withdraw.ReviewerID != "" {
+		reviewerID.UUID, _ = uuid.Parse(withdraw.ReviewerID.String())
+		reviewerID.Valid = true
+	}
+	var batchID uuid.NullUUID
+	if withdraw.BatchID != "" {
+		batchID.UUID, _ = uuid.Parse(withdraw.BatchID.String())
+		batchID.Valid = true
 	}
 
 	return PgWithdraw{
-		ID:      id,
-		ShopID:  shopID,
-		Comment: withdraw.Comment,
-		Sum:     withdraw.Sum,
-		Status:  withdrawStatus,
+		ID:          id,
+		ShopID:      shopID,
+		Comment:     withdraw.Comment,
+		Sum:         withdraw.Sum,
+		Status:      NewPgWithdrawStatus(withdraw.Status),
+		Currency:    withdraw.Currency,
+		RequestedAt: null.NewTime(withdraw.RequestedAt, !withdraw.RequestedAt.IsZero()),
+		ApprovedAt:  withdraw.ApprovedAt,
+		PaidAt:      withdraw.PaidAt,
+		ReviewerID:  reviewerID,
+		BatchID:     batchID,
 	}
 }
@@ -1,4 +1,5 @@
 package postgres
 
 var PgUniqueViolationCode = "23505"
+var PgForeignKeyViolationCode = "23503"
 var PgEnumValueError = "22P02"
@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+const (
+	moderationHistoryQuery = "SELECT * FROM public.moderation_action " +
+		"WHERE target_type = $1 AND target_id = $2 ORDER BY created_at, id"
+)
+
+// txAddModerationAction records the action in the moderation history of
+// the target.
+func txAddModerationAction(ctx context.Context, tx *sqlx.Tx, targetType string, action domain.ModerationAction) error {
+	pgAction := entity.NewPgModerationAction(targetType, action)
+	queryString := entity.InsertQueryString(pgAction, "moderation_action")
+	if _, err := tx.NamedExecContext(ctx, queryString, pgAction); err != nil {
+		tx.Rollback()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == PgUniqueViolationCode {
+				return errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else if pgErr.Code == PgForeignKeyViolationCode {
+				return errors.Wrap(domain.ErrNotExist, err.Error())
+			}
+		}
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
+
+func getModerationHistory(ctx context.Context, db *sqlx.DB, targetType string, targetID domain.ID) ([]domain.ModerationAction, error) {
+	var pgActions []entity.PgModerationAction
+	if err := db.SelectContext(ctx, &pgActions, moderationHistoryQuery, targetType, targetID); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	actions := make([]domain.ModerationAction, len(pgActions))
+	for i, pgAction := range pgActions {
+		actions[i] = pgAction.ToDomain()
+	}
+	return actions, nil
+}
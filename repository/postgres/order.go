@@ -4,10 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
 	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/google/uuid"
 	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"strings"
 )
 
 type PostgresOrderRepo struct {
@@ -21,7 +25,7 @@ func NewOrderRepo(db *sqlx.DB) *PostgresOrderRepo {
 }
 
 const (
-	orderGetShopItemByProductIDQuery    = "SELECT * FROM public.shop_product WHERE product_id = $1"
+	orderTakeShopItemQuantityQuery      = "UPDATE public.shop_product SET quantity = quantity - $2 WHERE product_id = $1 AND shop_id = $3 AND quantity >= $2 RETURNING id"
 	orderGetOrderShopByID               = "SELECT * FROM public.order_shop WHERE id = $1"
 	orderGetOrderShopItemsByOrderShopID = "SELECT * FROM public.order_shop_product WHERE order_shop_id = $1"
 	orderGetOrderCustomerByID           = "SELECT * FROM public.order_customer WHERE id = $1"
@@ -30,6 +34,11 @@ const (
 	orderGetNoNotifiedOrderShops        = "SELECT * FROM public.order_shop WHERE notified = 'false'"
 	orderGetOrderShopByShopID           = "SELECT * FROM public.order_shop WHERE shop_id = $1"
 	orderUpdatePaymentStatus            = "UPDATE public.order_customer SET payed = 'true' WHERE id = $1"
+	orderGetVariantForUpdate            = "SELECT v.* FROM public.product_variant v " +
+		"JOIN public.shop_product sp ON sp.id = v.shop_product_id WHERE v.id = $1 AND sp.product_id = $2 AND sp.shop_id = $3 FOR UPDATE OF v"
+	orderGetItemPrice = "SELECT COALESCE(v.price, p.price), p.currency FROM public.product p " +
+		"LEFT JOIN public.product_variant v ON v.id = $2 WHERE p.id = $1"
+	orderGetUserAddress = "SELECT * FROM public.user_address WHERE id = $1"
 )
 
 func (o *PostgresOrderRepo) GetOrderCustomerByCustomerID(ctx context.Context, customerID domain.ID) ([]domain.OrderCustomer, error) {
@@ -41,9 +50,8 @@ func (o *PostgresOrderRepo) GetOrderCustomerByCustomerID(ctx context.Context, cu
 	}
 	orderCustomers := make([]domain.OrderCustomer, len(pgOrderCustomers))
 	for i := range orderCustomers {
-		orderCustomers[i] = pgOrderCustomers[i].ToDomain()
 		var err error
-		orderCustomers[i], err = o.GetOrderCustomerByID(ctx, orderCustomers[i].ID)
+		orderCustomers[i], err = o.GetOrderCustomerByID(ctx, domain.ID(pgOrderCustomers[i].ID.String()))
 		if err != nil {
 			return nil, err
 		}
@@ -60,7 +68,10 @@ func (o *PostgresOrderRepo) GetOrderCustomerByID(ctx context.Context, OrderCusto
 			return domain.OrderCustomer{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 		}
 	}
-	orderCustomer := pgOrderCustomer.ToDomain()
+	orderCustomer, err := pgOrderCustomer.ToDomain()
+	if err != nil {
+		return domain.OrderCustomer{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
 
 	var pgOrderShops []entity.PgOrderShop
 	if err := o.db.SelectContext(ctx, &pgOrderShops, orderGetOrderShopByOrderCustomerID, OrderCustomerID); err != nil {
@@ -137,8 +148,11 @@ func (o *PostgresOrderRepo) GetNoNotifiedOrderShops(ctx context.Context) ([]doma
 	return orderShops, nil
 }
 
-func (o *PostgresOrderRepo) getPgEntities(orderCustomer domain.OrderCustomer) (entity.PgOrderCustomer, []entity.PgOrderShop, []entity.PgOrderShopItem) {
-	pgOrderCustomer := entity.NewPgOrderCustomer(orderCustomer)
+func (o *PostgresOrderRepo) getPgEntities(orderCustomer domain.OrderCustomer) (entity.PgOrderCustomer, []entity.PgOrderShop, []entity.PgOrderShopItem, error) {
+	pgOrderCustomer, err := entity.NewPgOrderCustomer(orderCustomer)
+	if err != nil {
+		return entity.PgOrderCustomer{}, nil, nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
 	pgOrderShops := make([]entity.PgOrderShop, 0)
 	pgOrderShopItems := make([]entity.PgOrderShopItem, 0)
 	for _, orderShop := range orderCustomer.OrderShops {
@@ -147,7 +161,7 @@ func (o *PostgresOrderRepo) getPgEntities(orderCustomer domain.OrderCustomer) (e
 			pgOrderShopItems = append(pgOrderShopItems, entity.NewPgOrderShopItem(item))
 		}
 	}
-	return pgOrderCustomer, pgOrderShops, pgOrderShopItems
+	return pgOrderCustomer, pgOrderShops, pgOrderShopItems, nil
 }
 
 func (o *PostgresOrderRepo) txInsertOrderCustomer(ctx context.Context, tx *sqlx.Tx, pgOrderCustomer entity.PgOrderCustomer) error {
@@ -207,9 +221,36 @@ func (o *PostgresOrderRepo) txInsertOrderShopItem(ctx context.Context, tx *sqlx.
 	return nil
 }
 
-func (o *PostgresOrderRepo) txUpdateShopItem(ctx context.Context, tx *sqlx.Tx, pgOrderShopItem entity.PgOrderShopItem) error {
-	var pgShopItem entity.PgShopItem
-	if err := tx.GetContext(ctx, &pgShopItem, orderGetShopItemByProductIDQuery, pgOrderShopItem.ProductID); err != nil {
+// txUpdateShopItem takes the ordered quantity from the stock of the product
+// in the shop of the order shop.
+func (o *PostgresOrderRepo) txUpdateShopItem(ctx context.Context, tx *sqlx.Tx, pgOrderShopItem entity.PgOrderShopItem, shopID uuid.UUID) error {
+	if pgOrderShopItem.VariantID.Valid {
+		return o.txUpdateVariant(ctx, tx, pgOrderShopItem, shopID)
+	}
+
+	var shopItemID uuid.UUID
+	err := tx.GetContext(ctx, &shopItemID, orderTakeShopItemQuantityQuery,
+		pgOrderShopItem.ProductID, pgOrderShopItem.Quantity, shopID)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return errors.Wrap(domain.ErrNotExist, "product is not available")
+		} else {
+			return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+	}
+
+	movement := newStockMovement(domain.ID(shopItemID.String()), -pgOrderShopItem.Quantity,
+		domain.StockMovementSale, domain.ID(pgOrderShopItem.OrderShopID.String()))
+	return txInsertStockMovement(ctx, tx, movement)
+}
+
+// txUpdateVariant takes the ordered quantity from the variant stock, the
+// variant must belong to the shop item of the ordered product in the shop.
+func (o *PostgresOrderRepo) txUpdateVariant(ctx context.Context, tx *sqlx.Tx, pgOrderShopItem entity.PgOrderShopItem, shopID uuid.UUID) error {
+	var pgVariant entity.PgProductVariant
+	if err := tx.GetContext(ctx, &pgVariant, orderGetVariantForUpdate,
+		pgOrderShopItem.VariantID.UUID, pgOrderShopItem.ProductID, shopID); err != nil {
 		tx.Rollback()
 		if err == sql.ErrNoRows {
 			return errors.Wrap(domain.ErrNotExist, err.Error())
@@ -217,35 +258,153 @@ func (o *PostgresOrderRepo) txUpdateShopItem(ctx context.Context, tx *sqlx.Tx, p
 			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 		}
 	}
-	pgShopItem.Quantity -= pgOrderShopItem.Quantity
-	queryString := entity.UpdateQueryString(pgShopItem, "shop_product")
-	_, err := tx.NamedExecContext(ctx, queryString, pgShopItem)
+	pgVariant.Quantity -= pgOrderShopItem.Quantity
+	queryString := entity.UpdateQueryString(pgVariant, "product_variant")
+	_, err := tx.NamedExecContext(ctx, queryString, pgVariant)
 	if err != nil {
 		tx.Rollback()
 		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
 	}
 
+	movement := newVariantStockMovement(pgVariant.ToDomain(), -pgOrderShopItem.Quantity,
+		domain.StockMovementSale, domain.ID(pgOrderShopItem.OrderShopID.String()))
+	return txInsertStockMovement(ctx, tx, movement)
+}
+
+// txGetItemPrice returns the current unit price of the order item and its
+// currency, the variant price override takes precedence over the product
+// price and is in the currency of the product.
+func txGetItemPrice(ctx context.Context, tx *sqlx.Tx, pgOrderShopItem entity.PgOrderShopItem) (int64, string, error) {
+	var price int64
+	var currency string
+	row := tx.QueryRowxContext(ctx, orderGetItemPrice, pgOrderShopItem.ProductID, pgOrderShopItem.VariantID)
+	if err := row.Scan(&price, &currency); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return 0, "", errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return 0, "", errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return price, currency, nil
+}
+
+// txCheckOrderTotal rejects the order when its declared total price differs
+// from the sum of the current item prices or when the items are priced in
+// different currencies. An order without a currency takes the currency of
+// its items.
+func txCheckOrderTotal(ctx context.Context, tx *sqlx.Tx, pgOrderCustomer *entity.PgOrderCustomer, pgOrderShopItems []entity.PgOrderShopItem) error {
+	var total int64
+	for _, pgOrderShopItem := range pgOrderShopItems {
+		price, currency, err := txGetItemPrice(ctx, tx, pgOrderShopItem)
+		if err != nil {
+			return err
+		}
+		if pgOrderCustomer.Currency == "" {
+			pgOrderCustomer.Currency = currency
+		}
+		if currency != pgOrderCustomer.Currency {
+			tx.Rollback()
+			return errors.Wrapf(domain.ErrNotAllowed, "order item is priced in %s, order is in %s", currency, pgOrderCustomer.Currency)
+		}
+		total += price * pgOrderShopItem.Quantity
+	}
+	if !currency.Valid(pgOrderCustomer.Currency) {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+	if total != pgOrderCustomer.TotalPrice {
+		tx.Rollback()
+		return errors.Wrapf(domain.ErrNotAllowed, "order total price %d differs from computed %d", pgOrderCustomer.TotalPrice, total)
+	}
+	return nil
+}
+
+// txSnapshotAddress copies the chosen address of the customer into the order,
+// so later edits of the address book do not change placed orders.
+func txSnapshotAddress(ctx context.Context, tx *sqlx.Tx, pgOrderCustomer *entity.PgOrderCustomer) error {
+	var pgUserAddress entity.PgUserAddress
+	if err := tx.GetContext(ctx, &pgUserAddress, orderGetUserAddress, pgOrderCustomer.AddressID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	if pgUserAddress.UserID != pgOrderCustomer.CustomerID {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrNotAllowed, "address does not belong to the customer")
+	}
+	userAddress, err := pgUserAddress.ToDomain()
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	pgOrderCustomer.ShippingAddress, err = entity.NewPgOrderAddress(domain.OrderAddress{
+		Recipient:  userAddress.Recipient,
+		Phone:      userAddress.Phone,
+		Country:    userAddress.Country,
+		City:       userAddress.City,
+		Street:     userAddress.Street,
+		PostalCode: userAddress.PostalCode,
+	})
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if pgOrderCustomer.Address == "" {
+		address, err := encryption.Encrypt(strings.Join([]string{userAddress.PostalCode,
+			userAddress.Country, userAddress.City, userAddress.Street}, ", "))
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		pgOrderCustomer.Address = address
+	}
 	return nil
 }
 
 func (o *PostgresOrderRepo) CreateOrderCustomer(ctx context.Context, orderCustomer domain.OrderCustomer) (domain.OrderCustomer, error) {
-	pgOrderCustomer, pgOrderShops, pgOrderShopItems := o.getPgEntities(orderCustomer)
+	pgOrderCustomer, pgOrderShops, pgOrderShopItems, err := o.getPgEntities(orderCustomer)
+	if err != nil {
+		return domain.OrderCustomer{}, err
+	}
 	tx, err := o.db.Beginx()
 	if err != nil {
 		return domain.OrderCustomer{}, errors.Wrap(domain.ErrTransactionError, err.Error())
 	}
+	err = txCheckOrderTotal(ctx, tx, &pgOrderCustomer, pgOrderShopItems)
+	if err != nil {
+		return domain.OrderCustomer{}, err
+	}
+	orderCustomer.Currency = pgOrderCustomer.Currency
+	if pgOrderCustomer.AddressID.Valid {
+		if err = txSnapshotAddress(ctx, tx, &pgOrderCustomer); err != nil {
+			return domain.OrderCustomer{}, err
+		}
+	}
+	pgOrderCustomer.Discount = 0
+	if orderCustomer.PromoCodeID != "" {
+		pgOrderCustomer.Discount, err = txRedeemPromoCode(ctx, tx, orderCustomer)
+		if err != nil {
+			return domain.OrderCustomer{}, err
+		}
+	}
 	err = o.txInsertOrderCustomer(ctx, tx, pgOrderCustomer)
 	if err != nil {
 		return domain.OrderCustomer{}, err
 	}
+	shopIDs := make(map[uuid.UUID]uuid.UUID, len(pgOrderShops))
 	for _, pgOrderShop := range pgOrderShops {
 		err = o.txInsertOrderShop(ctx, tx, pgOrderShop)
 		if err != nil {
 			return domain.OrderCustomer{}, err
 		}
+		shopIDs[pgOrderShop.ID] = pgOrderShop.ShopID
 	}
 	for _, pgOrderShopItem := range pgOrderShopItems {
-		err = o.txUpdateShopItem(ctx, tx, pgOrderShopItem)
+		err = o.txUpdateShopItem(ctx, tx, pgOrderShopItem, shopIDs[pgOrderShopItem.OrderShopID])
 		if err != nil {
 			return domain.OrderCustomer{}, err
 		}
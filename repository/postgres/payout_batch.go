@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"strconv"
+	"time"
+)
+
+type PostgresPayoutBatchRepo struct {
+	db *sqlx.DB
+}
+
+func NewPayoutBatchRepo(db *sqlx.DB) *PostgresPayoutBatchRepo {
+	return &PostgresPayoutBatchRepo{
+		db: db,
+	}
+}
+
+const (
+	payoutBatchGetQuery          = "SELECT * FROM public.payout_batch ORDER BY created_at, id LIMIT $1 OFFSET $2"
+	payoutBatchGetByIDQuery      = "SELECT * FROM public.payout_batch WHERE id = $1"
+	payoutBatchSetTotalQuery     = "UPDATE public.payout_batch SET total = $2 WHERE id = $1"
+	payoutBatchSettleQuery       = "UPDATE public.payout_batch SET status = 'Settled', settled_at = $2 WHERE id = $1 AND status = 'Open'"
+	payoutBatchWithdrawsQuery    = "SELECT * FROM public.withdraw WHERE payout_batch_id = $1 ORDER BY id"
+	payoutBatchAttachQuery       = "UPDATE public.withdraw SET payout_batch_id = $1 WHERE status = 'Ready' AND payout_batch_id IS NULL AND currency = $2 RETURNING sum"
+	payoutBatchPayWithdrawsQuery = "UPDATE public.withdraw SET status = 'Done', paid_at = $2 WHERE payout_batch_id = $1 AND status = 'Ready'"
+	payoutBatchGetForUpdateQuery = "SELECT * FROM public.payout_batch WHERE id = $1 FOR UPDATE"
+)
+
+// payoutBatchCSVHeader is the header of the bank transfer list of a batch,
+// the amount is in the minor units the withdraw sum is stored in.
+var payoutBatchCSVHeader = []string{"withdraw_id", "shop_id", "shop_name", "requisites", "amount", "comment"}
+
+func (p *PostgresPayoutBatchRepo) loadWithdraws(ctx context.Context, batch *domain.PayoutBatch) error {
+	var pgWithdraws []entity.PgWithdraw
+	if err := p.db.SelectContext(ctx, &pgWithdraws, payoutBatchWithdrawsQuery, batch.ID); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	batch.Withdraws = make([]domain.Withdraw, len(pgWithdraws))
+	for i, pgWithdraw := range pgWithdraws {
+		batch.Withdraws[i] = pgWithdraw.ToDomain()
+	}
+	return nil
+}
+
+func (p *PostgresPayoutBatchRepo) Get(ctx context.Context, limit, offset int64) ([]domain.PayoutBatch, error) {
+	var pgBatches []entity.PgPayoutBatch
+	if err := p.db.SelectContext(ctx, &pgBatches, payoutBatchGetQuery, limit, offset); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	batches := make([]domain.PayoutBatch, len(pgBatches))
+	for i, pgBatch := range pgBatches {
+		batches[i] = pgBatch.ToDomain()
+		if err := p.loadWithdraws(ctx, &batches[i]); err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
+func (p *PostgresPayoutBatchRepo) GetByID(ctx context.Context, batchID domain.ID) (domain.PayoutBatch, error) {
+	var pgBatch entity.PgPayoutBatch
+	if err := p.db.GetContext(ctx, &pgBatch, payoutBatchGetByIDQuery, batchID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.PayoutBatch{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.PayoutBatch{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	batch := pgBatch.ToDomain()
+	if err := p.loadWithdraws(ctx, &batch); err != nil {
+		return domain.PayoutBatch{}, err
+	}
+	return batch, nil
+}
+
+// Create opens a new batch and attaches every Ready withdraw in the currency
+// of the batch that is not in a batch yet, the total is the sum of the
+// attached withdraws. Only the ID and the currency of the batch are taken
+// from the argument. A batch without withdraws is not created and
+// ErrNotAllowed is returned.
+func (p *PostgresPayoutBatchRepo) Create(ctx context.Context, batch domain.PayoutBatch) (domain.PayoutBatch, error) {
+	if !currency.Valid(batch.Currency) {
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	pgBatch := entity.NewPgPayoutBatch(domain.PayoutBatch{
+		ID:        batch.ID,
+		Currency:  batch.Currency,
+		Status:    domain.PayoutBatchOpen,
+		CreatedAt: time.Now().UTC(),
+	})
+	queryString := entity.InsertQueryString(pgBatch, "payout_batch")
+	if _, err = tx.NamedExecContext(ctx, queryString, pgBatch); err != nil {
+		tx.Rollback()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return domain.PayoutBatch{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	// rows attached by a concurrent batch are skipped once its lock is released
+	var sums []int64
+	if err = tx.SelectContext(ctx, &sums, payoutBatchAttachQuery, batch.ID, batch.Currency); err != nil {
+		tx.Rollback()
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if len(sums) == 0 {
+		tx.Rollback()
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrNotAllowed, "no ready withdraws to pay")
+	}
+
+	var total int64
+	for _, sum := range sums {
+		total += sum
+	}
+	if _, err = tx.ExecContext(ctx, payoutBatchSetTotalQuery, batch.ID, total); err != nil {
+		tx.Rollback()
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return p.GetByID(ctx, batch.ID)
+}
+
+// Settle marks the batch as paid out and moves its withdraws to Done in the
+// same transaction. A batch can only be settled once.
+func (p *PostgresPayoutBatchRepo) Settle(ctx context.Context, batchID domain.ID) (domain.PayoutBatch, error) {
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var pgBatch entity.PgPayoutBatch
+	if err = tx.GetContext(ctx, &pgBatch, payoutBatchGetForUpdateQuery, batchID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.PayoutBatch{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.PayoutBatch{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	if pgBatch.Status != entity.PgPayoutBatchOpen {
+		tx.Rollback()
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrNotAllowed, "payout batch is already settled")
+	}
+
+	now := time.Now().UTC()
+	if _, err = tx.ExecContext(ctx, payoutBatchSettleQuery, batchID, now); err != nil {
+		tx.Rollback()
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if _, err = tx.ExecContext(ctx, payoutBatchPayWithdrawsQuery, batchID, now); err != nil {
+		tx.Rollback()
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.PayoutBatch{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return p.GetByID(ctx, batchID)
+}
+
+// ExportCSV returns the bank transfer list of the batch, one row per
+// withdraw with the requisites of the shop to pay.
+func (p *PostgresPayoutBatchRepo) ExportCSV(ctx context.Context, batchID domain.ID) ([]byte, error) {
+	batch, err := p.GetByID(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err = w.Write(payoutBatchCSVHeader); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	shopRepo := NewShopRepo(p.db)
+	shops := make(map[domain.ID]domain.Shop)
+	for _, withdraw := range batch.Withdraws {
+		shop, ok := shops[withdraw.ShopID]
+		if !ok {
+			shop, err = shopRepo.GetShopByID(ctx, withdraw.ShopID)
+			if err != nil {
+				return nil, err
+			}
+			shops[withdraw.ShopID] = shop
+		}
+
+		err = w.Write([]string{
+			withdraw.ID.String(),
+			shop.ID.String(),
+			shop.Name,
+			shop.Requisites,
+			strconv.FormatInt(withdraw.Sum, 10),
+			withdraw.Comment,
+		})
+		if err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return buf.Bytes(), nil
+}
@@ -3,11 +3,17 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
+	"github.com/EmirShimshir/marketplace-repository/repository/i18n"
 	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/google/uuid"
 	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"strings"
 )
 
 type PostgresProductRepo struct {
@@ -21,13 +27,53 @@ func NewProductRepo(db *sqlx.DB) *PostgresProductRepo {
 }
 
 const (
-	productGetQuery     = "SELECT * FROM public.product LIMIT $1 OFFSET $2"
-	productGetByIDQuery = "SELECT * FROM public.product WHERE id = $1"
-	productDeleteQuery  = "DELETE FROM public.product WHERE id = $1"
+	productRatedSelect = "SELECT p.*, COALESCE(r.rating, 0) AS rating, r.review_count FROM public.product p " +
+		"LEFT JOIN LATERAL (SELECT AVG(rating)::float8 AS rating, COUNT(*) AS review_count " +
+		"FROM public.review WHERE product_id = p.id) r ON true"
+	productGetQuery      = productRatedSelect + " LIMIT $1 OFFSET $2"
+	productGetByIDQuery  = productRatedSelect + " WHERE p.id = $1"
+	productGetByCategory = productRatedSelect + " WHERE p.category_id IN (WITH RECURSIVE tree AS (" +
+		"SELECT id FROM public.category WHERE id = $1 " +
+		"UNION ALL SELECT c.id FROM public.category c JOIN tree t ON c.parent_id = t.id" +
+		") SELECT id FROM tree) ORDER BY p.id LIMIT $2 OFFSET $3"
+	productDeleteQuery       = "DELETE FROM public.product WHERE id = $1"
+	productLockQuery         = "SELECT id FROM public.product WHERE id = $1 FOR UPDATE"
+	productLockCurrencyQuery = "SELECT currency FROM public.product WHERE id = $1 FOR UPDATE"
+	productInCartsQuery      = "SELECT EXISTS (SELECT 1 FROM public.cart_product cp JOIN public.cart c ON c.id = cp.cart_id " +
+		"WHERE cp.product_id = $1 AND c.currency = $2)"
+)
+
+const (
+	imageGetByProductsQuery = "SELECT * FROM public.product_image WHERE product_id IN (?) ORDER BY position, id"
+	imageGetByIDQuery       = "SELECT * FROM public.product_image WHERE id = $1"
+	imageNextPositionQuery  = "SELECT COALESCE(MAX(position) + 1, 0) FROM public.product_image WHERE product_id = $1"
+	imageClearPrimaryQuery  = "UPDATE public.product_image SET is_primary = false WHERE product_id = $1"
+	imageCountQuery         = "SELECT COUNT(*) FROM public.product_image WHERE product_id = $1"
+	imageSetPositionQuery   = "UPDATE public.product_image SET position = $1 WHERE id = $2 AND product_id = $3"
+	imageDeleteQuery        = "DELETE FROM public.product_image WHERE id = $1"
+)
+
+const (
+	translationColumns     = "product_id, locale, name, description"
+	translationUpsertQuery = "INSERT INTO public.product_translation (" + translationColumns + ") VALUES ($1, $2, $3, $4) " +
+		"ON CONFLICT (product_id, locale) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description"
+	translationGetQuery = "SELECT " + translationColumns + " FROM public.product_translation " +
+		"WHERE product_id = $1 ORDER BY locale"
+	translationGetByProductsQuery = "SELECT " + translationColumns + " FROM public.product_translation " +
+		"WHERE product_id IN (?) AND locale IN (?)"
+	translationDeleteQuery = "DELETE FROM public.product_translation WHERE product_id = $1 AND locale = $2"
+	productSearchQuery     = productRatedSelect + " JOIN (SELECT id, MAX(rank) AS rank FROM (" +
+		"SELECT t.product_id AS id, ts_rank(t.search_vector, q) AS rank " +
+		"FROM public.product_translation t, plainto_tsquery(public.product_search_config(?), ?) q " +
+		"WHERE t.locale IN (?) AND t.search_vector @@ q " +
+		"UNION ALL SELECT id, ts_rank(to_tsvector('simple', name || ' ' || description), q) " +
+		"FROM public.product, plainto_tsquery('simple', ?) q " +
+		"WHERE to_tsvector('simple', name || ' ' || description) @@ q" +
+		") m GROUP BY id) s ON s.id = p.id ORDER BY s.rank DESC, p.id LIMIT ? OFFSET ?"
 )
 
 func (p *PostgresProductRepo) Get(ctx context.Context, limit, offset int64) ([]domain.Product, error) {
-	var pgProducts []entity.PgProduct
+	var pgProducts []entity.PgRatedProduct
 	if err := p.db.SelectContext(ctx, &pgProducts, productGetQuery, limit, offset); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.Wrap(domain.ErrNotExist, err.Error())
@@ -40,11 +86,14 @@ func (p *PostgresProductRepo) Get(ctx context.Context, limit, offset int64) ([]d
 	for i, product := range pgProducts {
 		products[i] = product.ToDomain()
 	}
+	if err := p.loadImages(ctx, products); err != nil {
+		return nil, err
+	}
 	return products, nil
 }
 
 func (p *PostgresProductRepo) GetByID(ctx context.Context, productID domain.ID) (domain.Product, error) {
-	var pgProduct entity.PgProduct
+	var pgProduct entity.PgRatedProduct
 	if err := p.db.GetContext(ctx, &pgProduct, productGetByIDQuery, productID); err != nil {
 		if err == sql.ErrNoRows {
 			return domain.Product{}, errors.Wrap(domain.ErrNotExist, err.Error())
@@ -52,10 +101,88 @@ func (p *PostgresProductRepo) GetByID(ctx context.Context, productID domain.ID)
 			return domain.Product{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 		}
 	}
-	return pgProduct.ToDomain(), nil
+	products := []domain.Product{pgProduct.ToDomain()}
+	if err := p.loadImages(ctx, products); err != nil {
+		return domain.Product{}, err
+	}
+	return products[0], nil
+}
+
+// GetByCategoryID returns products of the category and all of its
+// subcategories.
+func (p *PostgresProductRepo) GetByCategoryID(ctx context.Context, categoryID domain.ID, limit, offset int64) ([]domain.Product, error) {
+	var pgProducts []entity.PgRatedProduct
+	if err := p.db.SelectContext(ctx, &pgProducts, productGetByCategory, categoryID, limit, offset); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	products := make([]domain.Product, len(pgProducts))
+	for i, product := range pgProducts {
+		products[i] = product.ToDomain()
+	}
+	if err := p.loadImages(ctx, products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetByAttributes lists products matching all attribute filters. Equality
+// filters use jsonb containment and are served by the GIN index, range
+// filters only match numeric attribute values.
+func (p *PostgresProductRepo) GetByAttributes(ctx context.Context, filters []domain.AttributeFilter, limit, offset int64) ([]domain.Product, error) {
+	conditions := []string{"true"}
+	var args []interface{}
+	param := func(arg interface{}) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	for _, filter := range filters {
+		if filter.Value != nil {
+			contained, err := json.Marshal(map[string]interface{}{filter.Key: filter.Value})
+			if err != nil {
+				return nil, errors.Wrap(domain.ErrNotAllowed, err.Error())
+			}
+			conditions = append(conditions, "p.attributes @> "+param(string(contained))+"::jsonb")
+		}
+		if filter.Min.Valid || filter.Max.Valid {
+			key := param(filter.Key)
+			number := "CASE WHEN jsonb_typeof(p.attributes -> " + key + "::text) = 'number' " +
+				"THEN (p.attributes ->> " + key + "::text)::numeric END"
+			if filter.Min.Valid {
+				conditions = append(conditions, number+" >= "+param(filter.Min.Float64)+"::numeric")
+			}
+			if filter.Max.Valid {
+				conditions = append(conditions, number+" <= "+param(filter.Max.Float64)+"::numeric")
+			}
+		}
+	}
+	query := productRatedSelect + " WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY p.id LIMIT " + param(limit) + " OFFSET " + param(offset)
+
+	var pgProducts []entity.PgRatedProduct
+	if err := p.db.SelectContext(ctx, &pgProducts, query, args...); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	products := make([]domain.Product, len(pgProducts))
+	for i, product := range pgProducts {
+		products[i] = product.ToDomain()
+	}
+	if err := p.loadImages(ctx, products); err != nil {
+		return nil, err
+	}
+	return products, nil
 }
 
 func (p *PostgresProductRepo) Create(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if !currency.Valid(product.Currency) {
+		return domain.Product{}, errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+
 	var pgProduct = entity.NewPgProduct(product)
 	queryString := entity.InsertQueryString(pgProduct, "product")
 	_, err := p.db.NamedExecContext(ctx, queryString, pgProduct)
@@ -64,6 +191,8 @@ func (p *PostgresProductRepo) Create(ctx context.Context, product domain.Product
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
 				return domain.Product{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else if pgErr.Code == PgForeignKeyViolationCode {
+				return domain.Product{}, errors.Wrap(domain.ErrNotExist, err.Error())
 			} else {
 				return domain.Product{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 			}
@@ -75,13 +204,50 @@ func (p *PostgresProductRepo) Create(ctx context.Context, product domain.Product
 	return p.GetByID(ctx, product.ID)
 }
 
+// Update rewrites the product. The currency of a product in carts priced in
+// its current currency can not change, the carts would mix currencies.
 func (p *PostgresProductRepo) Update(ctx context.Context, product domain.Product) (domain.Product, error) {
+	if !currency.Valid(product.Currency) {
+		return domain.Product{}, errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return domain.Product{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	// the lock keeps cart writes, which read the currency with FOR SHARE,
+	// from adding the product until the update is committed
+	var current string
+	if err = tx.GetContext(ctx, &current, productLockCurrencyQuery, product.ID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.Product{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.Product{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if current != product.Currency {
+		var inCarts bool
+		if err = tx.GetContext(ctx, &inCarts, productInCartsQuery, product.ID, current); err != nil {
+			tx.Rollback()
+			return domain.Product{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if inCarts {
+			tx.Rollback()
+			return domain.Product{}, errors.Wrap(domain.ErrNotAllowed, "product is in carts priced in its current currency")
+		}
+	}
+
 	var pgProduct = entity.NewPgProduct(product)
 	queryString := entity.UpdateQueryString(pgProduct, "product")
-	_, err := p.db.NamedExecContext(ctx, queryString, pgProduct)
-	if err != nil {
+	if _, err = tx.NamedExecContext(ctx, queryString, pgProduct); err != nil {
+		tx.Rollback()
 		return domain.Product{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
 	}
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return domain.Product{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
 
 	return p.GetByID(ctx, product.ID)
 }
@@ -93,3 +259,327 @@ func (p *PostgresProductRepo) Delete(ctx context.Context, productID domain.ID) e
 	}
 	return nil
 }
+
+// AddImage appends the image to the end of the product gallery. A primary
+// image replaces the previous primary one.
+func (p *PostgresProductRepo) AddImage(ctx context.Context, image domain.ProductImage) (domain.ProductImage, error) {
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return domain.ProductImage{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var productID uuid.UUID
+	if err = tx.GetContext(ctx, &productID, productLockQuery, image.ProductID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.ProductImage{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.ProductImage{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	if err = tx.GetContext(ctx, &image.Position, imageNextPositionQuery, image.ProductID); err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	if image.IsPrimary {
+		if _, err = tx.ExecContext(ctx, imageClearPrimaryQuery, image.ProductID); err != nil {
+			tx.Rollback()
+			return domain.ProductImage{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	var pgImage = entity.NewPgProductImage(image)
+	queryString := entity.InsertQueryString(pgImage, "product_image")
+	if _, err = tx.NamedExecContext(ctx, queryString, pgImage); err != nil {
+		tx.Rollback()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return domain.ProductImage{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.ProductImage{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	if err = tx.Commit(); err != nil {
+		return domain.ProductImage{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	if err = p.db.GetContext(ctx, &pgImage, imageGetByIDQuery, image.ID); err != nil {
+		return domain.ProductImage{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return pgImage.ToDomain(), nil
+}
+
+// ReorderImages sets gallery positions to the order of imageIDs, which must
+// list every image of the product exactly once.
+func (p *PostgresProductRepo) ReorderImages(ctx context.Context, productID domain.ID, imageIDs []domain.ID) error {
+	seen := make(map[domain.ID]bool, len(imageIDs))
+	for _, imageID := range imageIDs {
+		if seen[imageID] {
+			return errors.Wrap(domain.ErrNotAllowed, "image is listed twice")
+		}
+		seen[imageID] = true
+	}
+
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var lockedID uuid.UUID
+	if err = tx.GetContext(ctx, &lockedID, productLockQuery, productID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+
+	var count int
+	if err = tx.GetContext(ctx, &count, imageCountQuery, productID); err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if count != len(imageIDs) {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrNotAllowed, "image list does not match product gallery")
+	}
+
+	for position, imageID := range imageIDs {
+		result, err := tx.ExecContext(ctx, imageSetPositionQuery, position, imageID, productID)
+		if err != nil {
+			tx.Rollback()
+			return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+		if affected, _ := result.RowsAffected(); affected != 1 {
+			tx.Rollback()
+			return errors.Wrap(domain.ErrNotExist, "image does not belong to product")
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	return nil
+}
+
+func (p *PostgresProductRepo) DeleteImage(ctx context.Context, imageID domain.ID) error {
+	_, err := p.db.ExecContext(ctx, imageDeleteQuery, imageID)
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// SetTranslation creates or replaces the name and the description of the
+// product in the locale of the translation.
+func (p *PostgresProductRepo) SetTranslation(ctx context.Context, translation domain.ProductTranslation) (domain.ProductTranslation, error) {
+	if !i18n.ValidLocale(translation.Locale) {
+		return domain.ProductTranslation{}, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+
+	pgTranslation := entity.NewPgProductTranslation(translation)
+	_, err := p.db.ExecContext(ctx, translationUpsertQuery, pgTranslation.ProductID, pgTranslation.Locale,
+		pgTranslation.Name, pgTranslation.Description)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgForeignKeyViolationCode {
+			return domain.ProductTranslation{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		}
+		return domain.ProductTranslation{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return pgTranslation.ToDomain(), nil
+}
+
+// GetTranslations returns every translation of the product ordered by
+// locale.
+func (p *PostgresProductRepo) GetTranslations(ctx context.Context, productID domain.ID) ([]domain.ProductTranslation, error) {
+	var pgTranslations []entity.PgProductTranslation
+	if err := p.db.SelectContext(ctx, &pgTranslations, translationGetQuery, productID); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	translations := make([]domain.ProductTranslation, len(pgTranslations))
+	for i, translation := range pgTranslations {
+		translations[i] = translation.ToDomain()
+	}
+	return translations, nil
+}
+
+func (p *PostgresProductRepo) DeleteTranslation(ctx context.Context, productID domain.ID, locale string) error {
+	_, err := p.db.ExecContext(ctx, translationDeleteQuery, productID, locale)
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// GetInLocale works like Get with the names and the descriptions in the
+// preferred locale. A product without a translation in the locale falls
+// back to its language and then to its own name and description.
+func (p *PostgresProductRepo) GetInLocale(ctx context.Context, locale string, limit, offset int64) ([]domain.Product, error) {
+	if !i18n.ValidLocale(locale) {
+		return nil, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+	products, err := p.Get(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.localize(ctx, products, locale); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetByIDInLocale works like GetByID with the same fallback as GetInLocale.
+func (p *PostgresProductRepo) GetByIDInLocale(ctx context.Context, productID domain.ID, locale string) (domain.Product, error) {
+	if !i18n.ValidLocale(locale) {
+		return domain.Product{}, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+	product, err := p.GetByID(ctx, productID)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	products := []domain.Product{product}
+	if err = p.localize(ctx, products, locale); err != nil {
+		return domain.Product{}, err
+	}
+	return products[0], nil
+}
+
+// GetByCategoryIDInLocale works like GetByCategoryID with the same fallback
+// as GetInLocale.
+func (p *PostgresProductRepo) GetByCategoryIDInLocale(ctx context.Context, categoryID domain.ID, locale string, limit, offset int64) ([]domain.Product, error) {
+	if !i18n.ValidLocale(locale) {
+		return nil, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+	products, err := p.GetByCategoryID(ctx, categoryID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.localize(ctx, products, locale); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// GetByAttributesInLocale works like GetByAttributes with the same fallback
+// as GetInLocale.
+func (p *PostgresProductRepo) GetByAttributesInLocale(ctx context.Context, filters []domain.AttributeFilter, locale string, limit, offset int64) ([]domain.Product, error) {
+	if !i18n.ValidLocale(locale) {
+		return nil, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+	products, err := p.GetByAttributes(ctx, filters, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.localize(ctx, products, locale); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// Search finds products by the words of the query. Translations in the
+// preferred locale are matched with the stemmer of its language, so any
+// word form matches, and the untranslated texts are matched as is. The
+// products are returned in the preferred locale, the best match first by
+// the best ts_rank of their texts.
+func (p *PostgresProductRepo) Search(ctx context.Context, locale, query string, limit, offset int64) ([]domain.Product, error) {
+	if !i18n.ValidLocale(locale) {
+		return nil, errors.Wrap(domain.ErrNotAllowed, "invalid locale")
+	}
+
+	searchQuery, args, err := sqlx.In(productSearchQuery, locale, query, i18n.LocaleChain(locale), query, limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var pgProducts []entity.PgRatedProduct
+	if err = p.db.SelectContext(ctx, &pgProducts, p.db.Rebind(searchQuery), args...); err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	products := make([]domain.Product, len(pgProducts))
+	for i, product := range pgProducts {
+		products[i] = product.ToDomain()
+	}
+	if err = p.loadImages(ctx, products); err != nil {
+		return nil, err
+	}
+	if err = p.localize(ctx, products, locale); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// localize replaces the names and the descriptions of the products with
+// their translations to the locale with a single query, trying the locales
+// of i18n.LocaleChain in order.
+func (p *PostgresProductRepo) localize(ctx context.Context, products []domain.Product, locale string) error {
+	if len(products) == 0 {
+		return nil
+	}
+	productIDs := make([]domain.ID, len(products))
+	for i, product := range products {
+		productIDs[i] = product.ID
+	}
+
+	chain := i18n.LocaleChain(locale)
+	query, args, err := sqlx.In(translationGetByProductsQuery, productIDs, chain)
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var pgTranslations []entity.PgProductTranslation
+	if err = p.db.SelectContext(ctx, &pgTranslations, p.db.Rebind(query), args...); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	translations := make(map[domain.ID]map[string]domain.ProductTranslation)
+	for _, pgTranslation := range pgTranslations {
+		translation := pgTranslation.ToDomain()
+		if translations[translation.ProductID] == nil {
+			translations[translation.ProductID] = make(map[string]domain.ProductTranslation)
+		}
+		translations[translation.ProductID][translation.Locale] = translation
+	}
+	for i := range products {
+		for _, candidate := range chain {
+			if translation, ok := translations[products[i].ID][candidate]; ok {
+				products[i].Name = translation.Name
+				products[i].Description = translation.Description
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// loadImages fills the ordered image galleries of the products with a
+// single query.
+func (p *PostgresProductRepo) loadImages(ctx context.Context, products []domain.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	productIDs := make([]domain.ID, len(products))
+	for i, product := range products {
+		productIDs[i] = product.ID
+	}
+
+	query, args, err := sqlx.In(imageGetByProductsQuery, productIDs)
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var pgImages []entity.PgProductImage
+	if err = p.db.SelectContext(ctx, &pgImages, p.db.Rebind(query), args...); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	images := make(map[domain.ID][]domain.ProductImage)
+	for _, pgImage := range pgImages {
+		image := pgImage.ToDomain()
+		images[image.ProductID] = append(images[image.ProductID], image)
+	}
+	for i := range products {
+		products[i].Images = images[products[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type PostgresPromoCodeRepo struct {
+	db *sqlx.DB
+}
+
+func NewPromoCodeRepo(db *sqlx.DB) *PostgresPromoCodeRepo {
+	return &PostgresPromoCodeRepo{
+		db: db,
+	}
+}
+
+const (
+	promoCodeGetByIDQuery   = "SELECT * FROM public.promo_code WHERE id = $1"
+	promoCodeGetByCodeQuery = "SELECT * FROM public.promo_code WHERE code = $1"
+	promoCodeDeleteQuery    = "DELETE FROM public.promo_code WHERE id = $1"
+	promoCodeGetForUpdate   = "SELECT * FROM public.promo_code WHERE id = $1 FOR UPDATE"
+	promoCodeUserUsesQuery  = "SELECT COUNT(*) FROM public.order_customer WHERE promo_code_id = $1 AND customer_id = $2"
+	promoCodeAddUseQuery    = "UPDATE public.promo_code SET used_count = used_count + 1 WHERE id = $1"
+)
+
+func (p *PostgresPromoCodeRepo) GetByID(ctx context.Context, promoCodeID domain.ID) (domain.PromoCode, error) {
+	var pgPromoCode entity.PgPromoCode
+	if err := p.db.GetContext(ctx, &pgPromoCode, promoCodeGetByIDQuery, promoCodeID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.PromoCode{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.PromoCode{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgPromoCode.ToDomain(), nil
+}
+
+func (p *PostgresPromoCodeRepo) GetByCode(ctx context.Context, code string) (domain.PromoCode, error) {
+	var pgPromoCode entity.PgPromoCode
+	if err := p.db.GetContext(ctx, &pgPromoCode, promoCodeGetByCodeQuery, code); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.PromoCode{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.PromoCode{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgPromoCode.ToDomain(), nil
+}
+
+// checkPromoCodeCurrency requires a currency for fixed amount codes, the
+// currency of a percentage code is not stored.
+func checkPromoCodeCurrency(promoCode *domain.PromoCode) error {
+	if promoCode.Type != domain.PromoCodeFixed {
+		promoCode.Currency = ""
+		return nil
+	}
+	if !currency.Valid(promoCode.Currency) {
+		return errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+	return nil
+}
+
+func (p *PostgresPromoCodeRepo) Create(ctx context.Context, promoCode domain.PromoCode) (domain.PromoCode, error) {
+	if err := checkPromoCodeCurrency(&promoCode); err != nil {
+		return domain.PromoCode{}, err
+	}
+
+	var pgPromoCode = entity.NewPgPromoCode(promoCode)
+	queryString := entity.InsertQueryString(pgPromoCode, "promo_code")
+	_, err := p.db.NamedExecContext(ctx, queryString, pgPromoCode)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == PgUniqueViolationCode {
+				return domain.PromoCode{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else if pgErr.Code == PgForeignKeyViolationCode {
+				return domain.PromoCode{}, errors.Wrap(domain.ErrNotExist, err.Error())
+			} else {
+				return domain.PromoCode{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+		} else {
+			return domain.PromoCode{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	return p.GetByID(ctx, promoCode.ID)
+}
+
+func (p *PostgresPromoCodeRepo) Update(ctx context.Context, promoCode domain.PromoCode) (domain.PromoCode, error) {
+	if err := checkPromoCodeCurrency(&promoCode); err != nil {
+		return domain.PromoCode{}, err
+	}
+
+	var pgPromoCode = entity.NewPgPromoCode(promoCode)
+	queryString := entity.UpdateQueryString(pgPromoCode, "promo_code")
+	_, err := p.db.NamedExecContext(ctx, queryString, pgPromoCode)
+	if err != nil {
+		return domain.PromoCode{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+
+	return p.GetByID(ctx, promoCode.ID)
+}
+
+// Delete fails for codes that were already redeemed, orders keep a
+// reference to the code they used.
+func (p *PostgresPromoCodeRepo) Delete(ctx context.Context, promoCodeID domain.ID) error {
+	_, err := p.db.ExecContext(ctx, promoCodeDeleteQuery, promoCodeID)
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// txRedeemPromoCode validates the promo code of the order against its
+// validity window and usage limits, counts one use and returns the discount.
+// The discount is computed from current item prices, limited to the items of
+// the promo code shop when the code is shop scoped. A fixed amount code only
+// applies to orders in its currency.
+func txRedeemPromoCode(ctx context.Context, tx *sqlx.Tx, orderCustomer domain.OrderCustomer) (int64, error) {
+	var pgPromoCode entity.PgPromoCode
+	if err := tx.GetContext(ctx, &pgPromoCode, promoCodeGetForUpdate, orderCustomer.PromoCodeID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return 0, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return 0, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	promoCode := pgPromoCode.ToDomain()
+
+	now := time.Now().UTC()
+	if now.Before(promoCode.ValidFrom) || now.After(promoCode.ValidTo) {
+		tx.Rollback()
+		return 0, errors.Wrap(domain.ErrNotAllowed, "promo code is not active")
+	}
+	if promoCode.Type == domain.PromoCodeFixed && promoCode.Currency != orderCustomer.Currency {
+		tx.Rollback()
+		return 0, errors.Wrap(domain.ErrNotAllowed, "promo code is in another currency")
+	}
+	if promoCode.MaxUses != 0 && promoCode.UsedCount >= promoCode.MaxUses {
+		tx.Rollback()
+		return 0, errors.Wrap(domain.ErrNotAllowed, "promo code is exhausted")
+	}
+	if promoCode.MaxUsesPerUser != 0 {
+		var uses int64
+		if err := tx.GetContext(ctx, &uses, promoCodeUserUsesQuery, promoCode.ID, orderCustomer.CustomerID); err != nil {
+			tx.Rollback()
+			return 0, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if uses >= promoCode.MaxUsesPerUser {
+			tx.Rollback()
+			return 0, errors.Wrap(domain.ErrNotAllowed, "promo code is exhausted for the user")
+		}
+	}
+
+	var amount int64
+	for _, orderShop := range orderCustomer.OrderShops {
+		if promoCode.ShopID != "" && promoCode.ShopID != orderShop.ShopID {
+			continue
+		}
+		for _, item := range orderShop.OrderShopItems {
+			price, _, err := txGetItemPrice(ctx, tx, entity.NewPgOrderShopItem(item))
+			if err != nil {
+				return 0, err
+			}
+			amount += price * item.Quantity
+		}
+	}
+	if amount == 0 {
+		tx.Rollback()
+		return 0, errors.Wrap(domain.ErrNotAllowed, "promo code does not apply to the order")
+	}
+
+	if _, err := tx.ExecContext(ctx, promoCodeAddUseQuery, promoCode.ID); err != nil {
+		tx.Rollback()
+		return 0, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+
+	return promoCodeDiscount(promoCode, amount), nil
+}
+
+func promoCodeDiscount(promoCode domain.PromoCode, amount int64) int64 {
+	switch promoCode.Type {
+	case domain.PromoCodePercentage:
+		return amount * promoCode.Value / 100
+	case domain.PromoCodeFixed:
+		if promoCode.Value < amount {
+			return promoCode.Value
+		}
+		return amount
+	}
+	return 0
+}
@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+const reencryptBatchSize = 500
+
+const (
+	reencryptUsersQuery = "SELECT * FROM public.user " +
+		"WHERE id > $1 AND left(email, length($2)) <> $2 ORDER BY id LIMIT $3"
+	reencryptUserQuery  = "UPDATE public.user SET email = :email, email_hash = :email_hash, phone = :phone, phone_hash = :phone_hash WHERE id = :id"
+	reencryptShopsQuery = "SELECT * FROM public.shop " +
+		"WHERE id > $1 AND requisites <> '' AND left(requisites, length($2)) <> $2 ORDER BY id LIMIT $3"
+	reencryptShopQuery           = "UPDATE public.shop SET requisites = :requisites WHERE id = :id"
+	reencryptOrderCustomersQuery = "SELECT * FROM public.order_customer WHERE id > $1 AND (" +
+		"(address <> '' AND left(address, length($2)) <> $2) OR " +
+		"(shipping_address->>'recipient' <> '' AND left(shipping_address->>'recipient', length($2)) <> $2) OR " +
+		"(shipping_address->>'phone' <> '' AND left(shipping_address->>'phone', length($2)) <> $2) OR " +
+		"(shipping_address->>'street' <> '' AND left(shipping_address->>'street', length($2)) <> $2) OR " +
+		"(shipping_address->>'postal_code' <> '' AND left(shipping_address->>'postal_code', length($2)) <> $2)) " +
+		"ORDER BY id LIMIT $3"
+	reencryptOrderCustomerQuery = "UPDATE public.order_customer SET address = :address, shipping_address = :shipping_address WHERE id = :id"
+	reencryptUserAddressesQuery = "SELECT * FROM public.user_address WHERE id > $1 AND (" +
+		"(recipient <> '' AND left(recipient, length($2)) <> $2) OR " +
+		"(phone <> '' AND left(phone, length($2)) <> $2) OR " +
+		"(street <> '' AND left(street, length($2)) <> $2) OR " +
+		"(postal_code <> '' AND left(postal_code, length($2)) <> $2)) " +
+		"ORDER BY id LIMIT $3"
+	reencryptUserAddressQuery = "UPDATE public.user_address SET recipient = :recipient, phone = :phone, " +
+		"street = :street, postal_code = :postal_code WHERE id = :id"
+)
+
+// ReencryptPII encrypts the personal data stored in plaintext or with an
+// older key with the current key and recomputes the email and phone blind
+// indexes. The personal data are the email and phone of the users, the shop
+// requisites, the user addresses and the address and address snapshot of the
+// orders. The rows written before encryption was enabled keep a plain
+// SHA-256 email_hash and phone_hash from the sql migrations, so GetByEmail
+// and SearchUsers find them only after this ran with the key provider set.
+// Run it after the migrations and after every key rotation. It does nothing
+// without a key provider and only touches the rows not encrypted with the
+// current key yet, so it is safe to run again.
+func ReencryptPII(ctx context.Context, db *sqlx.DB) error {
+	prefix := encryption.CurrentPrefix()
+	if prefix == "" {
+		return nil
+	}
+	if err := reencryptUsers(ctx, db, prefix); err != nil {
+		return err
+	}
+	if err := reencryptShops(ctx, db, prefix); err != nil {
+		return err
+	}
+	if err := reencryptUserAddresses(ctx, db, prefix); err != nil {
+		return err
+	}
+	return reencryptOrderCustomers(ctx, db, prefix)
+}
+
+func reencryptUsers(ctx context.Context, db *sqlx.DB, prefix string) error {
+	lastID := uuid.Nil
+	for {
+		var pgUsers []entity.PgUser
+		err := db.SelectContext(ctx, &pgUsers, reencryptUsersQuery, lastID, prefix, reencryptBatchSize)
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		for _, pgUser := range pgUsers {
+			user, err := pgUser.ToDomain()
+			if err != nil {
+				return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if pgUser, err = entity.NewPgUser(user); err != nil {
+				return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if _, err = db.NamedExecContext(ctx, reencryptUserQuery, pgUser); err != nil {
+				return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+			}
+		}
+		if len(pgUsers) < reencryptBatchSize {
+			return nil
+		}
+		lastID = pgUsers[len(pgUsers)-1].ID
+	}
+}
+
+func reencryptShops(ctx context.Context, db *sqlx.DB, prefix string) error {
+	lastID := uuid.Nil
+	for {
+		var pgShops []entity.PgShop
+		err := db.SelectContext(ctx, &pgShops, reencryptShopsQuery, lastID, prefix, reencryptBatchSize)
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		for _, pgShop := range pgShops {
+			shop, err := pgShop.ToDomain()
+			if err != nil {
+				return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if pgShop, err = entity.NewPgShop(shop); err != nil {
+				return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if _, err = db.NamedExecContext(ctx, reencryptShopQuery, pgShop); err != nil {
+				return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+			}
+		}
+		if len(pgShops) < reencryptBatchSize {
+			return nil
+		}
+		lastID = pgShops[len(pgShops)-1].ID
+	}
+}
+
+func reencryptOrderCustomers(ctx context.Context, db *sqlx.DB, prefix string) error {
+	lastID := uuid.Nil
+	for {
+		var pgOrderCustomers []entity.PgOrderCustomer
+		err := db.SelectContext(ctx, &pgOrderCustomers, reencryptOrderCustomersQuery, lastID, prefix, reencryptBatchSize)
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		for _, pgOrderCustomer := range pgOrderCustomers {
+			orderCustomer, err := pgOrderCustomer.ToDomain()
+			if err != nil {
+				return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if pgOrderCustomer, err = entity.NewPgOrderCustomer(orderCustomer); err != nil {
+				return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if _, err = db.NamedExecContext(ctx, reencryptOrderCustomerQuery, pgOrderCustomer); err != nil {
+				return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+			}
+		}
+		if len(pgOrderCustomers) < reencryptBatchSize {
+			return nil
+		}
+		lastID = pgOrderCustomers[len(pgOrderCustomers)-1].ID
+	}
+}
+
+func reencryptUserAddresses(ctx context.Context, db *sqlx.DB, prefix string) error {
+	lastID := uuid.Nil
+	for {
+		var pgUserAddresses []entity.PgUserAddress
+		err := db.SelectContext(ctx, &pgUserAddresses, reencryptUserAddressesQuery, lastID, prefix, reencryptBatchSize)
+		if err != nil {
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		for _, pgUserAddress := range pgUserAddresses {
+			address, err := pgUserAddress.ToDomain()
+			if err != nil {
+				return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if pgUserAddress, err = entity.NewPgUserAddress(address); err != nil {
+				return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+			if _, err = db.NamedExecContext(ctx, reencryptUserAddressQuery, pgUserAddress); err != nil {
+				return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+			}
+		}
+		if len(pgUserAddresses) < reencryptBatchSize {
+			return nil
+		}
+		lastID = pgUserAddresses[len(pgUserAddresses)-1].ID
+	}
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type PostgresReviewRepo struct {
+	db *sqlx.DB
+}
+
+func NewReviewRepo(db *sqlx.DB) *PostgresReviewRepo {
+	return &PostgresReviewRepo{
+		db: db,
+	}
+}
+
+const (
+	reviewGetByIDQuery        = "SELECT * FROM public.review WHERE id = $1"
+	reviewGetByProductIDQuery = "SELECT * FROM public.review WHERE product_id = $1 ORDER BY created_at DESC, id LIMIT $2 OFFSET $3"
+	reviewDeleteQuery         = "DELETE FROM public.review WHERE id = $1"
+	reviewIsPurchasedQuery    = "SELECT EXISTS (SELECT 1 FROM public.order_shop_product osp " +
+		"JOIN public.order_shop os ON os.id = osp.order_shop_id " +
+		"JOIN public.order_customer oc ON oc.id = os.order_customer_id " +
+		"WHERE osp.product_id = $1 AND oc.customer_id = $2 AND os.status = 'Done')"
+)
+
+func (r *PostgresReviewRepo) GetByID(ctx context.Context, reviewID domain.ID) (domain.Review, error) {
+	var pgReview entity.PgReview
+	if err := r.db.GetContext(ctx, &pgReview, reviewGetByIDQuery, reviewID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Review{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Review{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgReview.ToDomain(), nil
+}
+
+func (r *PostgresReviewRepo) GetByProductID(ctx context.Context, productID domain.ID, limit, offset int64) ([]domain.Review, error) {
+	var pgReviews []entity.PgReview
+	if err := r.db.SelectContext(ctx, &pgReviews, reviewGetByProductIDQuery, productID, limit, offset); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	reviews := make([]domain.Review, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = review.ToDomain()
+	}
+	return reviews, nil
+}
+
+// Create stores the review only if the user has bought the product, i.e.
+// has it in an order shop with Done status.
+func (r *PostgresReviewRepo) Create(ctx context.Context, review domain.Review) (domain.Review, error) {
+	if review.Rating < 1 || review.Rating > 5 {
+		return domain.Review{}, errors.Wrap(domain.ErrNotAllowed, "rating must be between 1 and 5")
+	}
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return domain.Review{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var purchased bool
+	if err = tx.GetContext(ctx, &purchased, reviewIsPurchasedQuery, review.ProductID, review.UserID); err != nil {
+		tx.Rollback()
+		return domain.Review{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if !purchased {
+		tx.Rollback()
+		return domain.Review{}, errors.Wrap(domain.ErrNotAllowed, "product was not purchased by the user")
+	}
+
+	var pgReview = entity.NewPgReview(review)
+	queryString := entity.InsertQueryString(pgReview, "review")
+	_, err = tx.NamedExecContext(ctx, queryString, pgReview)
+	if err != nil {
+		tx.Rollback()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == PgUniqueViolationCode {
+				return domain.Review{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else {
+				return domain.Review{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+		} else {
+			return domain.Review{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return domain.Review{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return r.GetByID(ctx, review.ID)
+}
+
+func (r *PostgresReviewRepo) Delete(ctx context.Context, reviewID domain.ID) error {
+	_, err := r.db.ExecContext(ctx, reviewDeleteQuery, reviewID)
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/guregu/null"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type PostgresSessionRepo struct {
+	db *sqlx.DB
+}
+
+func NewSessionRepo(db *sqlx.DB) *PostgresSessionRepo {
+	return &PostgresSessionRepo{
+		db: db,
+	}
+}
+
+const (
+	sessionGetByIDQuery     = "SELECT * FROM public.user_session WHERE id = $1"
+	sessionGetActiveQuery   = "SELECT * FROM public.user_session WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > $2"
+	sessionGetByUserIDQuery = "SELECT * FROM public.user_session " +
+		"WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2 ORDER BY created_at DESC, id"
+	sessionRevokeQuery       = "UPDATE public.user_session SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL"
+	sessionRevokeAllQuery    = "UPDATE public.user_session SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL"
+	sessionPurgeExpiredQuery = "DELETE FROM public.user_session WHERE expires_at < $1 OR revoked_at < $1"
+)
+
+func (s *PostgresSessionRepo) GetByID(ctx context.Context, sessionID domain.ID) (domain.Session, error) {
+	var pgSession entity.PgSession
+	if err := s.db.GetContext(ctx, &pgSession, sessionGetByIDQuery, sessionID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Session{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Session{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgSession.ToDomain(), nil
+}
+
+// GetByRefreshToken returns the active session issued for the refresh token,
+// revoked and expired sessions are reported as not existing.
+func (s *PostgresSessionRepo) GetByRefreshToken(ctx context.Context, refreshToken string) (domain.Session, error) {
+	var pgSession entity.PgSession
+	err := s.db.GetContext(ctx, &pgSession, sessionGetActiveQuery, encryption.HashToken(refreshToken), time.Now().UTC())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Session{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Session{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgSession.ToDomain(), nil
+}
+
+// GetByUserID returns the active sessions of the user, the newest first.
+func (s *PostgresSessionRepo) GetByUserID(ctx context.Context, userID domain.ID) ([]domain.Session, error) {
+	var pgSessions []entity.PgSession
+	if err := s.db.SelectContext(ctx, &pgSessions, sessionGetByUserIDQuery, userID, time.Now().UTC()); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	sessions := make([]domain.Session, len(pgSessions))
+	for i, pgSession := range pgSessions {
+		sessions[i] = pgSession.ToDomain()
+	}
+	return sessions, nil
+}
+
+// Create stores the session with the hash of refreshToken, the TokenHash of
+// session is ignored.
+func (s *PostgresSessionRepo) Create(ctx context.Context, session domain.Session, refreshToken string) (domain.Session, error) {
+	var pgSession = entity.NewPgSession(session)
+	pgSession.TokenHash = encryption.HashToken(refreshToken)
+	pgSession.CreatedAt = time.Now().UTC()
+	pgSession.ExpiresAt = session.ExpiresAt.UTC()
+	pgSession.RevokedAt = null.Time{}
+	queryString := entity.InsertQueryString(pgSession, "user_session")
+	_, err := s.db.NamedExecContext(ctx, queryString, pgSession)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == PgUniqueViolationCode {
+				return domain.Session{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else if pgErr.Code == PgForeignKeyViolationCode {
+				return domain.Session{}, errors.Wrap(domain.ErrNotExist, err.Error())
+			} else {
+				return domain.Session{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+		} else {
+			return domain.Session{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	return s.GetByID(ctx, session.ID)
+}
+
+func (s *PostgresSessionRepo) Revoke(ctx context.Context, sessionID domain.ID) error {
+	res, err := s.db.ExecContext(ctx, sessionRevokeQuery, sessionID, time.Now().UTC())
+	if err != nil {
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return errors.Wrap(domain.ErrNotExist, "active session not found")
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every active session of the user and returns how
+// many sessions were revoked.
+func (s *PostgresSessionRepo) RevokeAllForUser(ctx context.Context, userID domain.ID) (int64, error) {
+	res, err := s.db.ExecContext(ctx, sessionRevokeAllQuery, userID, time.Now().UTC())
+	if err != nil {
+		return 0, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
+
+// PurgeExpired removes the sessions that expired or were revoked before
+// olderThan. It is meant to be run on a schedule.
+func (s *PostgresSessionRepo) PurgeExpired(ctx context.Context, olderThan time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, sessionPurgeExpiredQuery, olderThan.UTC())
+	if err != nil {
+		return 0, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
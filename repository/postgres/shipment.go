@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/guregu/null"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type PostgresShipmentRepo struct {
+	db *sqlx.DB
+}
+
+func NewShipmentRepo(db *sqlx.DB) *PostgresShipmentRepo {
+	return &PostgresShipmentRepo{
+		db: db,
+	}
+}
+
+const (
+	shipmentGetByIDQuery          = "SELECT * FROM public.shipment WHERE id = $1"
+	shipmentGetByOrderShopIDQuery = "SELECT * FROM public.shipment WHERE order_shop_id = $1"
+	shipmentGetOverdueQuery       = "SELECT * FROM public.shipment WHERE delivered_at IS NULL AND shipped_at < $1 ORDER BY shipped_at, id"
+	shipmentGetForUpdateQuery     = "SELECT * FROM public.shipment WHERE id = $1 FOR UPDATE"
+	shipmentUpdateQuery           = "UPDATE public.shipment SET carrier = $2, tracking_number = $3 WHERE id = $1"
+	shipmentSetShippedQuery       = "UPDATE public.shipment SET shipped_at = COALESCE(shipped_at, $2) WHERE id = $1"
+	shipmentSetDeliveredQuery     = "UPDATE public.shipment SET shipped_at = COALESCE(shipped_at, $2), delivered_at = $2 WHERE id = $1"
+	shipmentOrderShopStatusQuery  = "SELECT status FROM public.order_shop WHERE id = $1 FOR SHARE"
+	shipmentEventsGetQuery        = "SELECT * FROM public.shipment_event WHERE shipment_id IN (?) ORDER BY occurred_at, id"
+)
+
+// loadEvents fills the event timelines of the shipments with a single query.
+func (s *PostgresShipmentRepo) loadEvents(ctx context.Context, shipments []domain.Shipment) error {
+	if len(shipments) == 0 {
+		return nil
+	}
+	shipmentIDs := make([]domain.ID, len(shipments))
+	for i, shipment := range shipments {
+		shipmentIDs[i] = shipment.ID
+	}
+
+	query, args, err := sqlx.In(shipmentEventsGetQuery, shipmentIDs)
+	if err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var pgEvents []entity.PgShipmentEvent
+	if err = s.db.SelectContext(ctx, &pgEvents, s.db.Rebind(query), args...); err != nil {
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	events := make(map[domain.ID][]domain.ShipmentEvent)
+	for _, pgEvent := range pgEvents {
+		event := pgEvent.ToDomain()
+		events[event.ShipmentID] = append(events[event.ShipmentID], event)
+	}
+	for i := range shipments {
+		shipments[i].Events = events[shipments[i].ID]
+	}
+	return nil
+}
+
+func (s *PostgresShipmentRepo) getShipment(ctx context.Context, query string, arg interface{}) (domain.Shipment, error) {
+	var pgShipment entity.PgShipment
+	if err := s.db.GetContext(ctx, &pgShipment, query, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Shipment{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Shipment{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	shipments := []domain.Shipment{pgShipment.ToDomain()}
+	if err := s.loadEvents(ctx, shipments); err != nil {
+		return domain.Shipment{}, err
+	}
+	return shipments[0], nil
+}
+
+func (s *PostgresShipmentRepo) GetByID(ctx context.Context, shipmentID domain.ID) (domain.Shipment, error) {
+	return s.getShipment(ctx, shipmentGetByIDQuery, shipmentID)
+}
+
+func (s *PostgresShipmentRepo) GetByOrderShopID(ctx context.Context, orderShopID domain.ID) (domain.Shipment, error) {
+	return s.getShipment(ctx, shipmentGetByOrderShopIDQuery, orderShopID)
+}
+
+// GetOverdueShipments returns the shipments that were shipped before
+// olderThan and are still not delivered, the oldest first.
+func (s *PostgresShipmentRepo) GetOverdueShipments(ctx context.Context, olderThan time.Time) ([]domain.Shipment, error) {
+	var pgShipments []entity.PgShipment
+	if err := s.db.SelectContext(ctx, &pgShipments, shipmentGetOverdueQuery, olderThan.UTC()); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	shipments := make([]domain.Shipment, len(pgShipments))
+	for i, pgShipment := range pgShipments {
+		shipments[i] = pgShipment.ToDomain()
+	}
+	if err := s.loadEvents(ctx, shipments); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// Create registers the shipment of an order shop that is Ready or Done.
+// The shipped and delivered timestamps are only set by AppendEvent.
+func (s *PostgresShipmentRepo) Create(ctx context.Context, shipment domain.Shipment) (domain.Shipment, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return domain.Shipment{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var status string
+	if err = tx.GetContext(ctx, &status, shipmentOrderShopStatusQuery, shipment.OrderShopID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.Shipment{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Shipment{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	if status == entity.PgOrderShopStart {
+		tx.Rollback()
+		return domain.Shipment{}, errors.Wrap(domain.ErrNotAllowed, "order shop is not ready")
+	}
+
+	pgShipment := entity.NewPgShipment(shipment)
+	pgShipment.ShippedAt = null.Time{}
+	pgShipment.DeliveredAt = null.Time{}
+	pgShipment.CreatedAt = time.Now().UTC()
+	queryString := entity.InsertQueryString(pgShipment, "shipment")
+	_, err = tx.NamedExecContext(ctx, queryString, pgShipment)
+	if err != nil {
+		tx.Rollback()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return domain.Shipment{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Shipment{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.Shipment{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return s.GetByID(ctx, shipment.ID)
+}
+
+// Update changes the carrier and the tracking number of the shipment.
+func (s *PostgresShipmentRepo) Update(ctx context.Context, shipment domain.Shipment) (domain.Shipment, error) {
+	res, err := s.db.ExecContext(ctx, shipmentUpdateQuery, shipment.ID, shipment.Carrier, shipment.TrackingNumber)
+	if err != nil {
+		return domain.Shipment{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return domain.Shipment{}, errors.Wrap(domain.ErrNotExist, "shipment not found")
+	}
+
+	return s.GetByID(ctx, shipment.ID)
+}
+
+// AppendEvent adds the event to the shipment timeline. Shipped and Delivered
+// events also set the matching shipment timestamps, a delivered shipment
+// accepts no more events. A zero OccurredAt means now.
+func (s *PostgresShipmentRepo) AppendEvent(ctx context.Context, event domain.ShipmentEvent) (domain.Shipment, error) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	event.OccurredAt = event.OccurredAt.UTC()
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return domain.Shipment{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var pgShipment entity.PgShipment
+	if err = tx.GetContext(ctx, &pgShipment, shipmentGetForUpdateQuery, event.ShipmentID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.Shipment{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Shipment{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	if pgShipment.DeliveredAt.Valid {
+		tx.Rollback()
+		return domain.Shipment{}, errors.Wrap(domain.ErrNotAllowed, "shipment is already delivered")
+	}
+
+	pgEvent := entity.NewPgShipmentEvent(event)
+	queryString := entity.InsertQueryString(pgEvent, "shipment_event")
+	if _, err = tx.NamedExecContext(ctx, queryString, pgEvent); err != nil {
+		tx.Rollback()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return domain.Shipment{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Shipment{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	switch event.Type {
+	case domain.ShipmentEventShipped:
+		_, err = tx.ExecContext(ctx, shipmentSetShippedQuery, event.ShipmentID, event.OccurredAt)
+	case domain.ShipmentEventDelivered:
+		_, err = tx.ExecContext(ctx, shipmentSetDeliveredQuery, event.ShipmentID, event.OccurredAt)
+	}
+	if err != nil {
+		tx.Rollback()
+		return domain.Shipment{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.Shipment{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return s.GetByID(ctx, event.ShipmentID)
+}
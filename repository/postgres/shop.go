@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
 	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
 	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"time"
 )
 
 type PostgresShopRepo struct {
@@ -21,17 +23,40 @@ func NewShopRepo(db *sqlx.DB) *PostgresShopRepo {
 }
 
 const (
-	shopGetQuery                = "SELECT * FROM public.shop LIMIT $1 OFFSET $2"
+	shopGetQuery                = "SELECT * FROM public.shop WHERE moderation_status <> 'Suspended' LIMIT $1 OFFSET $2"
 	shopGetByIDQuery            = "SELECT * FROM public.shop WHERE id = $1"
 	shopGetBySellerIDQuery      = "SELECT * FROM public.shop WHERE seller_id = $1"
 	shopDeleteQuery             = "DELETE FROM public.shop WHERE id = $1"
-	shopItemsGetQuery           = "SELECT * FROM public.shop_product LIMIT $1 OFFSET $2"
+	shopItemsGetQuery           = "SELECT sp.* FROM public.shop_product sp JOIN public.shop s ON s.id = sp.shop_id WHERE s.moderation_status <> 'Suspended' LIMIT $1 OFFSET $2"
 	shopItemGetByIDQuery        = "SELECT * FROM public.shop_product WHERE id = $1"
 	shopItemGetByProductIDQuery = "SELECT * FROM public.shop_product WHERE product_id = $1"
 	shopItemsGetByShopID        = "SELECT * FROM public.shop_product WHERE shop_id = $1"
 	shopItemDeleteQuery         = "DELETE FROM public.shop_product WHERE id = $1"
+	shopItemGetForUpdateQuery   = "SELECT * FROM public.shop_product WHERE id = $1 FOR UPDATE"
+	stockHistoryGetQuery        = "SELECT * FROM public.stock_movement WHERE shop_product_id = $1 ORDER BY created_at, id"
+	shopItemsLowStockQuery      = "SELECT * FROM public.shop_product WHERE shop_id = $1 AND quantity <= reorder_threshold"
+	shopItemsOutOfStockQuery    = "SELECT * FROM public.shop_product WHERE quantity = 0 ORDER BY id LIMIT $1"
+	shopItemsOutOfStockByCursor = "SELECT * FROM public.shop_product WHERE quantity = 0 AND id > $2 ORDER BY id LIMIT $1"
+	stockReconcileQuery         = "SELECT sp.quantity = COALESCE(SUM(sm.delta), 0) FROM public.shop_product sp " +
+		"LEFT JOIN public.stock_movement sm ON sm.shop_product_id = sp.id AND sm.variant_id IS NULL WHERE sp.id = $1 GROUP BY sp.id"
+	variantStockReconcileQuery = "SELECT COUNT(*) FROM public.product_variant v WHERE v.shop_product_id = $1 AND v.quantity <> " +
+		"(SELECT COALESCE(SUM(sm.delta), 0) FROM public.stock_movement sm WHERE sm.variant_id = v.id)"
 )
 
+const (
+	shopGetByModerationStatusQuery = "SELECT * FROM public.shop WHERE moderation_status = $1 ORDER BY id LIMIT $2 OFFSET $3"
+	shopGetModerationStatusQuery   = "SELECT moderation_status FROM public.shop WHERE id = $1 FOR UPDATE"
+	shopSetModerationStatusQuery   = "UPDATE public.shop SET moderation_status = $2 WHERE id = $1"
+)
+
+const (
+	variantGetByIDQuery         = "SELECT * FROM public.product_variant WHERE id = $1"
+	variantGetForUpdateQuery    = "SELECT * FROM public.product_variant WHERE id = $1 FOR UPDATE"
+	variantGetByShopItemIDQuery = "SELECT * FROM public.product_variant WHERE shop_product_id = $1 ORDER BY sku"
+	variantDeleteQuery          = "DELETE FROM public.product_variant WHERE id = $1"
+)
+
+// GetShops lists the shops that are not suspended.
 func (o *PostgresShopRepo) GetShops(ctx context.Context, limit, offset int64) ([]domain.Shop, error) {
 	var pgShops []entity.PgShop
 	if err := o.db.SelectContext(ctx, &pgShops, shopGetQuery, limit, offset); err != nil {
@@ -44,7 +69,10 @@ func (o *PostgresShopRepo) GetShops(ctx context.Context, limit, offset int64) ([
 
 	shops := make([]domain.Shop, len(pgShops))
 	for i, shop := range pgShops {
-		shops[i] = shop.ToDomain()
+		var err error
+		if shops[i], err = shop.ToDomain(); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
 		shopItems, err := o.getShopItemsByShopID(ctx, shops[i].ID)
 		if err != nil {
 			return nil, err
@@ -65,7 +93,10 @@ func (o *PostgresShopRepo) GetShopByID(ctx context.Context, shopID domain.ID) (d
 		}
 	}
 
-	shop := pgShop.ToDomain()
+	shop, err := pgShop.ToDomain()
+	if err != nil {
+		return domain.Shop{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
 	shopItems, err := o.getShopItemsByShopID(ctx, shop.ID)
 	if err != nil {
 		return domain.Shop{}, err
@@ -87,7 +118,10 @@ func (o *PostgresShopRepo) GetShopBySellerID(ctx context.Context, sellerID domai
 
 	shops := make([]domain.Shop, len(pgShops))
 	for i, shop := range pgShops {
-		shops[i] = shop.ToDomain()
+		var err error
+		if shops[i], err = shop.ToDomain(); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
 		shopItems, err := o.getShopItemsByShopID(ctx, shops[i].ID)
 		if err != nil {
 			return nil, err
@@ -98,10 +132,19 @@ func (o *PostgresShopRepo) GetShopBySellerID(ctx context.Context, sellerID domai
 	return shops, nil
 }
 
+// CreateShop stores a new shop, the default currency is required and is used
+// for the items and withdraws of the shop that do not set their own.
 func (o *PostgresShopRepo) CreateShop(ctx context.Context, shop domain.Shop) (domain.Shop, error) {
-	var pgShop = entity.NewPgShop(shop)
+	if !currency.Valid(shop.DefaultCurrency) {
+		return domain.Shop{}, errors.Wrap(domain.ErrNotAllowed, "invalid default currency code")
+	}
+
+	pgShop, err := entity.NewPgShop(shop)
+	if err != nil {
+		return domain.Shop{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
 	queryString := entity.InsertQueryString(pgShop, "shop")
-	_, err := o.db.NamedExecContext(ctx, queryString, pgShop)
+	_, err = o.db.NamedExecContext(ctx, queryString, pgShop)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -117,10 +160,25 @@ func (o *PostgresShopRepo) CreateShop(ctx context.Context, shop domain.Shop) (do
 	return o.GetShopByID(ctx, shop.ID)
 }
 
+// UpdateShop rewrites the shop fields, the moderation status is only changed
+// by SetModerationStatus.
 func (o *PostgresShopRepo) UpdateShop(ctx context.Context, shop domain.Shop) (domain.Shop, error) {
-	var pgShop = entity.NewPgShop(shop)
+	if !currency.Valid(shop.DefaultCurrency) {
+		return domain.Shop{}, errors.Wrap(domain.ErrNotAllowed, "invalid default currency code")
+	}
+
+	current, err := o.GetShopByID(ctx, shop.ID)
+	if err != nil {
+		return domain.Shop{}, err
+	}
+	shop.ModerationStatus = current.ModerationStatus
+
+	pgShop, err := entity.NewPgShop(shop)
+	if err != nil {
+		return domain.Shop{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
 	queryString := entity.UpdateQueryString(pgShop, "shop")
-	_, err := o.db.NamedExecContext(ctx, queryString, pgShop)
+	_, err = o.db.NamedExecContext(ctx, queryString, pgShop)
 	if err != nil {
 		return domain.Shop{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
 	}
@@ -135,6 +193,7 @@ func (o *PostgresShopRepo) DeleteShop(ctx context.Context, shopID domain.ID) err
 	return nil
 }
 
+// GetShopItems lists the items of the shops that are not suspended.
 func (o *PostgresShopRepo) GetShopItems(ctx context.Context, limit, offset int64) ([]domain.ShopItem, error) {
 	var pgShopItems []entity.PgShopItem
 	if err := o.db.SelectContext(ctx, &pgShopItems, shopItemsGetQuery, limit, offset); err != nil {
@@ -174,7 +233,20 @@ func (o *PostgresShopRepo) GetShopItemByProductID(ctx context.Context, productID
 	return pgShopItem.ToDomain(), nil
 }
 
+// CreateShopItem stores the product and its shop item, a product without a
+// currency is priced in the default currency of the shop.
 func (o *PostgresShopRepo) CreateShopItem(ctx context.Context, shopItem domain.ShopItem, product domain.Product) (domain.ShopItem, error) {
+	if product.Currency == "" {
+		shop, err := o.GetShopByID(ctx, shopItem.ShopID)
+		if err != nil {
+			return domain.ShopItem{}, err
+		}
+		product.Currency = shop.DefaultCurrency
+	}
+	if !currency.Valid(product.Currency) {
+		return domain.ShopItem{}, errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+
 	tx, err := o.db.Beginx()
 	if err != nil {
 		return domain.ShopItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
@@ -214,6 +286,13 @@ func (o *PostgresShopRepo) CreateShopItem(ctx context.Context, shopItem domain.S
 		}
 	}
 
+	if shopItem.Quantity != 0 {
+		movement := newStockMovement(shopItem.ID, shopItem.Quantity, domain.StockMovementRestock, "")
+		if err = txInsertStockMovement(ctx, tx, movement); err != nil {
+			return domain.ShopItem{}, err
+		}
+	}
+
 	if err = tx.Commit(); err != nil {
 		tx.Rollback()
 		return domain.ShopItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
@@ -223,24 +302,136 @@ func (o *PostgresShopRepo) CreateShopItem(ctx context.Context, shopItem domain.S
 }
 
 func (o *PostgresShopRepo) UpdateShopItem(ctx context.Context, shopItem domain.ShopItem) (domain.ShopItem, error) {
+	tx, err := o.db.Beginx()
+	if err != nil {
+		return domain.ShopItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var current entity.PgShopItem
+	if err = tx.GetContext(ctx, &current, shopItemGetForUpdateQuery, shopItem.ID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.ShopItem{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.ShopItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
 	var pgShopItem = entity.NewPgShopItem(shopItem)
 	queryString := entity.UpdateQueryString(pgShopItem, "shop_product")
-	_, err := o.db.NamedExecContext(ctx, queryString, pgShopItem)
+	_, err = tx.NamedExecContext(ctx, queryString, pgShopItem)
 	if err != nil {
+		tx.Rollback()
 		return domain.ShopItem{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
 	}
 
+	if delta := shopItem.Quantity - current.Quantity; delta != 0 {
+		reason := domain.StockMovementRestock
+		if delta < 0 {
+			reason = domain.StockMovementAdjustment
+		}
+		if err = txInsertStockMovement(ctx, tx, newStockMovement(shopItem.ID, delta, reason, "")); err != nil {
+			return domain.ShopItem{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return domain.ShopItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
 	return o.GetShopItemByID(ctx, shopItem.ID)
 }
 
 func (o *PostgresShopRepo) DeleteShopItem(ctx context.Context, shopItemID domain.ID) error {
 	_, err := o.db.ExecContext(ctx, shopItemDeleteQuery, shopItemID)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgForeignKeyViolationCode {
+			return errors.Wrap(domain.ErrNotAllowed, "a variant of the shop item has been ordered")
+		}
 		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
 	}
 	return nil
 }
 
+func (o *PostgresShopRepo) GetLowStockItems(ctx context.Context, shopID domain.ID) ([]domain.ShopItem, error) {
+	var pgShopItems []entity.PgShopItem
+	if err := o.db.SelectContext(ctx, &pgShopItems, shopItemsLowStockQuery, shopID); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	shopItems := make([]domain.ShopItem, len(pgShopItems))
+	for i, item := range pgShopItems {
+		shopItems[i] = item.ToDomain()
+	}
+	return shopItems, nil
+}
+
+// GetOutOfStockProducts returns shop items of all shops with zero quantity
+// ordered by id. An empty cursor starts from the beginning, otherwise only
+// items with id greater than the cursor are returned.
+func (o *PostgresShopRepo) GetOutOfStockProducts(ctx context.Context, limit int64, cursor domain.ID) ([]domain.ShopItem, error) {
+	var pgShopItems []entity.PgShopItem
+	var err error
+	if cursor == "" {
+		err = o.db.SelectContext(ctx, &pgShopItems, shopItemsOutOfStockQuery, limit)
+	} else {
+		err = o.db.SelectContext(ctx, &pgShopItems, shopItemsOutOfStockByCursor, limit, cursor)
+	}
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	shopItems := make([]domain.ShopItem, len(pgShopItems))
+	for i, item := range pgShopItems {
+		shopItems[i] = item.ToDomain()
+	}
+	return shopItems, nil
+}
+
+func (o *PostgresShopRepo) GetStockHistory(ctx context.Context, shopItemID domain.ID) ([]domain.StockMovement, error) {
+	var pgStockMovements []entity.PgStockMovement
+	if err := o.db.SelectContext(ctx, &pgStockMovements, stockHistoryGetQuery, shopItemID); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	movements := make([]domain.StockMovement, len(pgStockMovements))
+	for i, movement := range pgStockMovements {
+		movements[i] = movement.ToDomain()
+	}
+	return movements, nil
+}
+
+// ReconcileStock reports whether the stock_movement ledger of the shop item
+// sums up to its current quantity and the ledger of every variant of the
+// item to the quantity of the variant.
+func (o *PostgresShopRepo) ReconcileStock(ctx context.Context, shopItemID domain.ID) (bool, error) {
+	var consistent bool
+	if err := o.db.GetContext(ctx, &consistent, stockReconcileQuery, shopItemID); err != nil {
+		if err == sql.ErrNoRows {
+			return false, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return false, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	if !consistent {
+		return false, nil
+	}
+
+	var mismatched int64
+	if err := o.db.GetContext(ctx, &mismatched, variantStockReconcileQuery, shopItemID); err != nil {
+		return false, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return mismatched == 0, nil
+}
+
 func (o *PostgresShopRepo) getShopItemsByShopID(ctx context.Context, shopID domain.ID) ([]domain.ShopItem, error) {
 	var pgShopItems []entity.PgShopItem
 	if err := o.db.SelectContext(ctx, &pgShopItems, shopItemsGetByShopID, shopID); err != nil {
@@ -255,3 +446,209 @@ func (o *PostgresShopRepo) getShopItemsByShopID(ctx context.Context, shopID doma
 	}
 	return shopItems, nil
 }
+
+func (o *PostgresShopRepo) GetVariantByID(ctx context.Context, variantID domain.ID) (domain.ProductVariant, error) {
+	var pgVariant entity.PgProductVariant
+	if err := o.db.GetContext(ctx, &pgVariant, variantGetByIDQuery, variantID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.ProductVariant{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.ProductVariant{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgVariant.ToDomain(), nil
+}
+
+func (o *PostgresShopRepo) GetVariantsByShopItemID(ctx context.Context, shopItemID domain.ID) ([]domain.ProductVariant, error) {
+	var pgVariants []entity.PgProductVariant
+	if err := o.db.SelectContext(ctx, &pgVariants, variantGetByShopItemIDQuery, shopItemID); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	variants := make([]domain.ProductVariant, len(pgVariants))
+	for i, variant := range pgVariants {
+		variants[i] = variant.ToDomain()
+	}
+	return variants, nil
+}
+
+func (o *PostgresShopRepo) CreateVariant(ctx context.Context, variant domain.ProductVariant) (domain.ProductVariant, error) {
+	tx, err := o.db.Beginx()
+	if err != nil {
+		return domain.ProductVariant{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var pgVariant = entity.NewPgProductVariant(variant)
+	queryString := entity.InsertQueryString(pgVariant, "product_variant")
+	_, err = tx.NamedExecContext(ctx, queryString, pgVariant)
+	if err != nil {
+		tx.Rollback()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == PgUniqueViolationCode {
+				return domain.ProductVariant{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else if pgErr.Code == PgForeignKeyViolationCode {
+				return domain.ProductVariant{}, errors.Wrap(domain.ErrNotExist, err.Error())
+			} else {
+				return domain.ProductVariant{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+		} else {
+			return domain.ProductVariant{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	if variant.Quantity != 0 {
+		movement := newVariantStockMovement(variant, variant.Quantity, domain.StockMovementRestock, "")
+		if err = txInsertStockMovement(ctx, tx, movement); err != nil {
+			return domain.ProductVariant{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return domain.ProductVariant{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return o.GetVariantByID(ctx, variant.ID)
+}
+
+func (o *PostgresShopRepo) UpdateVariant(ctx context.Context, variant domain.ProductVariant) (domain.ProductVariant, error) {
+	tx, err := o.db.Beginx()
+	if err != nil {
+		return domain.ProductVariant{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var current entity.PgProductVariant
+	if err = tx.GetContext(ctx, &current, variantGetForUpdateQuery, variant.ID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.ProductVariant{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.ProductVariant{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	var pgVariant = entity.NewPgProductVariant(variant)
+	queryString := entity.UpdateQueryString(pgVariant, "product_variant")
+	_, err = tx.NamedExecContext(ctx, queryString, pgVariant)
+	if err != nil {
+		tx.Rollback()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return domain.ProductVariant{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.ProductVariant{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+
+	if delta := variant.Quantity - current.Quantity; delta != 0 {
+		reason := domain.StockMovementRestock
+		if delta < 0 {
+			reason = domain.StockMovementAdjustment
+		}
+		if err = txInsertStockMovement(ctx, tx, newVariantStockMovement(current.ToDomain(), delta, reason, "")); err != nil {
+			return domain.ProductVariant{}, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return domain.ProductVariant{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return o.GetVariantByID(ctx, variant.ID)
+}
+
+// DeleteVariant deletes the variant with its stock ledger, a variant that has
+// been ordered stays for the order history.
+func (o *PostgresShopRepo) DeleteVariant(ctx context.Context, variantID domain.ID) error {
+	_, err := o.db.ExecContext(ctx, variantDeleteQuery, variantID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgForeignKeyViolationCode {
+			return errors.Wrap(domain.ErrNotAllowed, "variant has been ordered")
+		}
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// GetShopsByModerationStatus lists the shops with the status for moderators,
+// suspended shops included.
+func (o *PostgresShopRepo) GetShopsByModerationStatus(ctx context.Context, status domain.ShopModerationStatus, limit, offset int64) ([]domain.Shop, error) {
+	var pgShops []entity.PgShop
+	err := o.db.SelectContext(ctx, &pgShops, shopGetByModerationStatusQuery,
+		entity.NewPgShopModerationStatus(status), limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	shops := make([]domain.Shop, len(pgShops))
+	for i, shop := range pgShops {
+		var err error
+		if shops[i], err = shop.ToDomain(); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		shopItems, err := o.getShopItemsByShopID(ctx, shops[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		shops[i].Items = shopItems
+	}
+	return shops, nil
+}
+
+// SetModerationStatus moves the shop given by the TargetID of the action to
+// the status of the action, which must be Pending, Approve or Suspend. The
+// action is added to the moderation history of the shop.
+func (o *PostgresShopRepo) SetModerationStatus(ctx context.Context, action domain.ModerationAction) (domain.Shop, error) {
+	var status domain.ShopModerationStatus
+	switch action.Action {
+	case domain.ModerationActionPending:
+		status = domain.ShopModerationPending
+	case domain.ModerationActionApprove:
+		status = domain.ShopModerationApproved
+	case domain.ModerationActionSuspend:
+		status = domain.ShopModerationSuspended
+	default:
+		return domain.Shop{}, errors.Wrap(domain.ErrNotAllowed, "not a shop moderation action")
+	}
+	action.CreatedAt = time.Now().UTC()
+
+	tx, err := o.db.Beginx()
+	if err != nil {
+		return domain.Shop{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	var current string
+	if err = tx.GetContext(ctx, &current, shopGetModerationStatusQuery, action.TargetID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.Shop{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Shop{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	if current == entity.NewPgShopModerationStatus(status) {
+		tx.Rollback()
+		return domain.Shop{}, errors.Wrap(domain.ErrNotAllowed, "shop already has the moderation status")
+	}
+	if _, err = tx.ExecContext(ctx, shopSetModerationStatusQuery, action.TargetID, entity.NewPgShopModerationStatus(status)); err != nil {
+		tx.Rollback()
+		return domain.Shop{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if err = txAddModerationAction(ctx, tx, entity.PgModerationTargetShop, action); err != nil {
+		return domain.Shop{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.Shop{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return o.GetShopByID(ctx, action.TargetID)
+}
+
+// GetModerationHistory returns the moderation actions taken on the shop,
+// the oldest first.
+func (o *PostgresShopRepo) GetModerationHistory(ctx context.Context, shopID domain.ID) ([]domain.ModerationAction, error) {
+	return getModerationHistory(ctx, o.db, entity.PgModerationTargetShop, shopID)
+}
@@ -0,0 +1,248 @@
+package postgres
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/bulkimport"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"io"
+	"sort"
+)
+
+const (
+	// the advisory locks are taken in id order, so concurrent imports of the
+	// same products wait for each other instead of deadlocking
+	importLockProductsQuery = "SELECT pg_advisory_xact_lock(hashtextextended(id, 0)) " +
+		"FROM unnest(ARRAY[?]::text[]) AS id ORDER BY id"
+	importCategoriesQuery = "SELECT id FROM public.category WHERE id IN (?)"
+	importShopItemsQuery  = "SELECT * FROM public.shop_product WHERE product_id IN (?) FOR UPDATE"
+	importProductsQuery   = "INSERT INTO public.product (id, name, description, price, currency, category_id, photo_url) " +
+		"VALUES (:id, :name, :description, :price, :currency, :category_id, :photo_url) " +
+		"ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description, price = EXCLUDED.price, " +
+		"currency = EXCLUDED.currency, category_id = EXCLUDED.category_id, photo_url = EXCLUDED.photo_url"
+	importShopItemsUpsertQuery = "INSERT INTO public.shop_product (id, shop_id, product_id, quantity, reorder_threshold) " +
+		"VALUES (:id, :shop_id, :product_id, :quantity, :reorder_threshold) " +
+		"ON CONFLICT (shop_id, product_id) DO UPDATE SET quantity = EXCLUDED.quantity, reorder_threshold = EXCLUDED.reorder_threshold"
+	importStockMovementsQuery = "INSERT INTO public.stock_movement (id, shop_product_id, delta, reason, reference_id, created_at) " +
+		"VALUES (:id, :shop_product_id, :delta, :reason, :reference_id, :created_at)"
+)
+
+// ImportShopItems creates or updates the products and the stock of the shop
+// from a CSV or NDJSON stream. Every batch of bulkimport.BatchSize rows is
+// written in its own transaction with a multi-row insert per table, stock
+// changes are recorded in the stock_movement ledger. Rows with an unknown
+// category, an invalid currency or a product sold by another shop are
+// reported and skipped. A dry run runs the same statements and rolls every
+// batch back. A batch that fails to write stops the import, the batches
+// before it stay written and the report of the rows read so far is returned
+// with the error.
+func (o *PostgresShopRepo) ImportShopItems(ctx context.Context, shopID domain.ID, format domain.ImportFormat, data io.Reader, dryRun bool) (domain.ImportReport, error) {
+	shop, err := o.GetShopByID(ctx, shopID)
+	if err != nil {
+		return domain.ImportReport{}, err
+	}
+
+	report := domain.ImportReport{DryRun: dryRun}
+	err = bulkimport.Read(data, format, bulkimport.BatchSize, func(rows []domain.ImportRow, rowErrors []domain.ImportRowError) error {
+		report.Rows += int64(len(rows) + len(rowErrors))
+		report.Errors = append(report.Errors, rowErrors...)
+		return o.importBatch(ctx, shop, rows, dryRun, &report)
+	})
+
+	sort.Slice(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Line < report.Errors[j].Line
+	})
+	return report, err
+}
+
+// importBatch writes the rows in a transaction, the batch is added to the
+// report once it is written.
+func (o *PostgresShopRepo) importBatch(ctx context.Context, shop domain.Shop, rows []domain.ImportRow, dryRun bool, report *domain.ImportReport) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	tx, err := o.db.Beginx()
+	if err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	if err = txLockImportProducts(ctx, tx, rows); err != nil {
+		return err
+	}
+	categories, err := txGetImportCategories(ctx, tx, rows)
+	if err != nil {
+		return err
+	}
+	shopItems, foreign, err := txGetImportShopItems(ctx, tx, shop.ID, rows)
+	if err != nil {
+		return err
+	}
+
+	var batch domain.ImportReport
+	var pgProducts []entity.PgProduct
+	var pgShopItems []entity.PgShopItem
+	var pgMovements []entity.PgStockMovement
+	for _, row := range rows {
+		if row.Product.Currency == "" {
+			row.Product.Currency = shop.DefaultCurrency
+		}
+		current, exists := shopItems[row.Product.ID]
+		var rowErr string
+		switch {
+		case !currency.Valid(row.Product.Currency):
+			rowErr = "invalid currency code"
+		case !categories[row.Product.CategoryID]:
+			rowErr = "category does not exist"
+		case foreign[row.Product.ID]:
+			rowErr = "product is sold by another shop"
+		}
+		if rowErr != "" {
+			batch.Errors = append(batch.Errors, domain.ImportRowError{Line: row.Line, Error: rowErr})
+			continue
+		}
+
+		shopItem := domain.ShopItem{
+			ID:               domain.ID(uuid.NewString()),
+			ShopID:           shop.ID,
+			ProductID:        row.Product.ID,
+			Quantity:         row.Quantity,
+			ReorderThreshold: row.ReorderThreshold,
+		}
+		var movement domain.StockMovement
+		if exists {
+			shopItem.ID = domain.ID(current.ID.String())
+			if delta := row.Quantity - current.Quantity; delta > 0 {
+				movement = newStockMovement(shopItem.ID, delta, domain.StockMovementRestock, "")
+			} else if delta < 0 {
+				movement = newStockMovement(shopItem.ID, delta, domain.StockMovementAdjustment, "")
+			}
+			batch.Updated++
+		} else {
+			if row.Quantity != 0 {
+				movement = newStockMovement(shopItem.ID, row.Quantity, domain.StockMovementRestock, "")
+			}
+			batch.Created++
+		}
+
+		pgProducts = append(pgProducts, entity.NewPgProduct(row.Product))
+		pgShopItems = append(pgShopItems, entity.NewPgShopItem(shopItem))
+		if movement.ID != "" {
+			pgMovements = append(pgMovements, entity.NewPgStockMovement(movement))
+		}
+	}
+
+	if len(pgProducts) != 0 {
+		if _, err = tx.NamedExecContext(ctx, importProductsQuery, pgProducts); err != nil {
+			tx.Rollback()
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+		if _, err = tx.NamedExecContext(ctx, importShopItemsUpsertQuery, pgShopItems); err != nil {
+			tx.Rollback()
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	if len(pgMovements) != 0 {
+		if _, err = tx.NamedExecContext(ctx, importStockMovementsQuery, pgMovements); err != nil {
+			tx.Rollback()
+			return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	if dryRun {
+		tx.Rollback()
+	} else if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	report.Errors = append(report.Errors, batch.Errors...)
+	report.Created += batch.Created
+	report.Updated += batch.Updated
+	return nil
+}
+
+// txLockImportProducts locks the products of the rows, the existing and the
+// new ones, until the transaction ends. Another shop importing the same
+// product waits for the batch, so it sees the shop item of the batch in its
+// ownership check.
+func txLockImportProducts(ctx context.Context, tx *sqlx.Tx, rows []domain.ImportRow) error {
+	productIDs := make([]string, len(rows))
+	for i, row := range rows {
+		productIDs[i] = row.Product.ID.String()
+	}
+
+	query, args, err := sqlx.In(importLockProductsQuery, productIDs)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if _, err = tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
+
+// txGetImportCategories returns which categories of the rows exist.
+func txGetImportCategories(ctx context.Context, tx *sqlx.Tx, rows []domain.ImportRow) (map[domain.ID]bool, error) {
+	categoryIDs := make([]domain.ID, len(rows))
+	for i, row := range rows {
+		categoryIDs[i] = row.Product.CategoryID
+	}
+
+	query, args, err := sqlx.In(importCategoriesQuery, categoryIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var found []uuid.UUID
+	if err = tx.SelectContext(ctx, &found, tx.Rebind(query), args...); err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	categories := make(map[domain.ID]bool, len(found))
+	for _, id := range found {
+		categories[domain.ID(id.String())] = true
+	}
+	return categories, nil
+}
+
+// txGetImportShopItems locks the shop items already selling the products of
+// the rows, so the stock deltas are taken from their current quantity. It
+// returns the items of the shop by product and the products other shops
+// sell.
+func txGetImportShopItems(ctx context.Context, tx *sqlx.Tx, shopID domain.ID, rows []domain.ImportRow) (map[domain.ID]entity.PgShopItem, map[domain.ID]bool, error) {
+	productIDs := make([]domain.ID, len(rows))
+	for i, row := range rows {
+		productIDs[i] = row.Product.ID
+	}
+
+	query, args, err := sqlx.In(importShopItemsQuery, productIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	var pgShopItems []entity.PgShopItem
+	if err = tx.SelectContext(ctx, &pgShopItems, tx.Rebind(query), args...); err != nil {
+		tx.Rollback()
+		return nil, nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	shopItems := make(map[domain.ID]entity.PgShopItem, len(pgShopItems))
+	foreign := make(map[domain.ID]bool)
+	for _, shopItem := range pgShopItems {
+		productID := domain.ID(shopItem.ProductID.String())
+		if domain.ID(shopItem.ShopID.String()) == shopID {
+			shopItems[productID] = shopItem
+		} else {
+			foreign[productID] = true
+		}
+	}
+	return shopItems, foreign, nil
+}
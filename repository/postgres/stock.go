@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"time"
+)
+
+func newStockMovement(shopItemID domain.ID, delta int64, reason domain.StockMovementReason, referenceID domain.ID) domain.StockMovement {
+	return domain.StockMovement{
+		ID:          domain.ID(uuid.NewString()),
+		ShopItemID:  shopItemID,
+		Delta:       delta,
+		Reason:      reason,
+		ReferenceID: referenceID,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+// newVariantStockMovement records a change of the variant stock, the variants
+// keep their own quantity apart from the quantity of the shop item.
+func newVariantStockMovement(variant domain.ProductVariant, delta int64, reason domain.StockMovementReason, referenceID domain.ID) domain.StockMovement {
+	movement := newStockMovement(variant.ShopItemID, delta, reason, referenceID)
+	movement.VariantID = variant.ID
+	return movement
+}
+
+func txInsertStockMovement(ctx context.Context, tx *sqlx.Tx, movement domain.StockMovement) error {
+	var pgStockMovement = entity.NewPgStockMovement(movement)
+	queryString := entity.InsertQueryString(pgStockMovement, "stock_movement")
+	_, err := tx.NamedExecContext(ctx, queryString, pgStockMovement)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
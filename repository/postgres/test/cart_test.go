@@ -4,8 +4,10 @@ import (
 	"context"
 	"github.com/EmirShimshir/marketplace-core/domain"
 	repository "github.com/EmirShimshir/marketplace-repository/repository/postgres"
+	"github.com/guregu/null"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 var cartItems = []domain.CartItem{
@@ -14,12 +16,16 @@ var cartItems = []domain.CartItem{
 		CartID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
 		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
 		Quantity:  2,
+		CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
 	},
 	domain.CartItem{
 		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702aa2"),
 		CartID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
 		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
 		Quantity:  1,
+		CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
 	},
 }
 
@@ -39,23 +45,44 @@ var updatedCartItem = domain.CartItem{
 
 var carts = []domain.Cart{
 	domain.Cart{
-		ID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
-		Price: 0,
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
+		Price:     0,
+		Currency:  "RUB",
+		CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
 		Items: []domain.CartItem{
 			cartItems[0],
 			cartItems[1],
 		},
 	},
 	domain.Cart{
-		ID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cd"),
-		Price: 0,
-		Items: []domain.CartItem{},
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cd"),
+		Price:     0,
+		Currency:  "RUB",
+		CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+		Items:     []domain.CartItem{},
 	},
 }
 
 var updatedCart = domain.Cart{
-	ID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
-	Price: 2990,
+	ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
+	Price:     262970,
+	Currency:  "RUB",
+	CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	Items: []domain.CartItem{
+		cartItems[0],
+		cartItems[1],
+	},
+}
+
+var recalculatedCart = domain.Cart{
+	ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
+	Price:     262970,
+	Currency:  "RUB",
+	CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
 	Items: []domain.CartItem{
 		cartItems[0],
 		cartItems[1],
@@ -63,9 +90,33 @@ var updatedCart = domain.Cart{
 }
 
 var clearedCart = domain.Cart{
-	ID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
-	Price: 2990,
-	Items: []domain.CartItem{},
+	ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cc"),
+	Price:     2990,
+	Currency:  "RUB",
+	CreatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	UpdatedAt: time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	Items:     []domain.CartItem{},
+}
+
+var guestCart = domain.Cart{
+	ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034ce"),
+	GuestToken: null.StringFrom("c9f0f895fb98ab9159f51fd0297e236d"),
+	Items:      []domain.CartItem{},
+}
+
+var guestCartItems = []domain.CartItem{
+	domain.CartItem{
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ab1"),
+		CartID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034ce"),
+		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
+		Quantity:  1,
+	},
+	domain.CartItem{
+		ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ab2"),
+		CartID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034ce"),
+		ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
+		Quantity:  1,
+	},
 }
 
 func TestCartRepository(t *testing.T) {
@@ -154,6 +205,11 @@ func TestCartRepository(t *testing.T) {
 		defer db.Close()
 
 		repo := repository.NewCartRepo(db)
+		wrongPrice := updatedCart
+		wrongPrice.Price = 2990
+		_, err = repo.UpdateCart(ctx, wrongPrice)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
 		cart, err := repo.UpdateCart(ctx, updatedCart)
 		if err != nil {
 			t.Errorf("failed to create cart: %v", err)
@@ -161,6 +217,50 @@ func TestCartRepository(t *testing.T) {
 		require.Equal(t, cart, updatedCart)
 	})
 
+	t.Run("test GetCartTotal", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCartRepo(db)
+		total, err := repo.GetCartTotal(ctx, carts[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetCartTotal: %v", err)
+		}
+		require.Equal(t, recalculatedCart.Price, total)
+	})
+
+	t.Run("test RecalculateCart", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCartRepo(db)
+		cart, err := repo.RecalculateCart(ctx, carts[0].ID)
+		if err != nil {
+			t.Errorf("failed to RecalculateCart: %v", err)
+		}
+		require.Equal(t, recalculatedCart, cart)
+	})
+
 	t.Run("test clear cart", func(t *testing.T) {
 		t.Cleanup(func() {
 			err = container.Restore(ctx)
@@ -206,7 +306,17 @@ func TestCartRepository(t *testing.T) {
 			t.Errorf("failed to CreateCartItem: %v", err)
 		}
 
-		require.Equal(t, createdCartItem, found)
+		require.WithinDuration(t, time.Now(), found.CreatedAt, time.Minute)
+		require.Equal(t, found.CreatedAt, found.UpdatedAt)
+		expected := createdCartItem
+		expected.CreatedAt, expected.UpdatedAt = found.CreatedAt, found.UpdatedAt
+		require.Equal(t, expected, found)
+
+		cart, err := repo.GetCartByID(ctx, createdCartItem.CartID)
+		if err != nil {
+			t.Errorf("failed to get cart: %v", err)
+		}
+		require.Equal(t, found.UpdatedAt, cart.UpdatedAt)
 	})
 	t.Run("test UpdateCartItem", func(t *testing.T) {
 		t.Cleanup(func() {
@@ -224,12 +334,21 @@ func TestCartRepository(t *testing.T) {
 
 		repo := repository.NewCartRepo(db)
 
+		created, err := repo.CreateCartItem(ctx, createdCartItem)
+		if err != nil {
+			t.Errorf("failed to CreateCartItem: %v", err)
+		}
+
 		found, err := repo.UpdateCartItem(ctx, updatedCartItem)
 		if err != nil {
 			t.Errorf("failed to UpdateCartItem: %v", err)
 		}
 
-		require.Equal(t, updatedCartItem, found)
+		require.Equal(t, created.CreatedAt, found.CreatedAt)
+		require.False(t, found.UpdatedAt.Before(created.UpdatedAt))
+		expected := updatedCartItem
+		expected.CreatedAt, expected.UpdatedAt = found.CreatedAt, found.UpdatedAt
+		require.Equal(t, expected, found)
 	})
 	t.Run("test DeleteCartItem", func(t *testing.T) {
 		t.Cleanup(func() {
@@ -251,4 +370,205 @@ func TestCartRepository(t *testing.T) {
 			t.Errorf("failed to DeleteCartItem: %v", err)
 		}
 	})
+
+	t.Run("test FindAbandonedCarts", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCartRepo(db)
+		olderThan := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+		found, err := repo.FindAbandonedCarts(ctx, olderThan, 100000)
+		if err != nil {
+			t.Errorf("failed to FindAbandonedCarts: %v", err)
+		}
+		require.Equal(t, []domain.Cart{carts[0]}, found)
+
+		found, err = repo.FindAbandonedCarts(ctx, olderThan, 300000)
+		if err != nil {
+			t.Errorf("failed to FindAbandonedCarts: %v", err)
+		}
+		require.Equal(t, 0, len(found))
+	})
+	t.Run("test PurgeStaleCartItems", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCartRepo(db)
+		deleted, err := repo.PurgeStaleCartItems(ctx, time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Errorf("failed to PurgeStaleCartItems: %v", err)
+		}
+		require.Equal(t, int64(2), deleted)
+
+		found, err := repo.GetCartByID(ctx, carts[0].ID)
+		if err != nil {
+			t.Errorf("failed to get cart: %v", err)
+		}
+		require.Equal(t, 0, len(found.Items))
+	})
+	t.Run("test guest cart", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCartRepo(db)
+		created, err := repo.CreateGuestCart(ctx, guestCart)
+		if err != nil {
+			t.Errorf("failed to CreateGuestCart: %v", err)
+		}
+		require.WithinDuration(t, time.Now(), created.CreatedAt, time.Minute)
+		expected := guestCart
+		expected.CreatedAt, expected.UpdatedAt = created.CreatedAt, created.UpdatedAt
+		require.Equal(t, expected, created)
+
+		found, err := repo.GetCartByGuestToken(ctx, guestCart.GuestToken.String)
+		if err != nil {
+			t.Errorf("failed to GetCartByGuestToken: %v", err)
+		}
+		require.Equal(t, created, found)
+
+		duplicate := guestCart
+		duplicate.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cf")
+		_, err = repo.CreateGuestCart(ctx, duplicate)
+		require.ErrorIs(t, err, domain.ErrDuplicate)
+	})
+	t.Run("test MergeCarts", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCartRepo(db)
+		_, err = repo.CreateGuestCart(ctx, guestCart)
+		if err != nil {
+			t.Errorf("failed to CreateGuestCart: %v", err)
+		}
+		for _, cartItem := range guestCartItems {
+			_, err = repo.CreateCartItem(ctx, cartItem)
+			if err != nil {
+				t.Errorf("failed to CreateCartItem: %v", err)
+			}
+		}
+
+		merged, err := repo.MergeCarts(ctx, guestCart.ID, carts[0].ID)
+		if err != nil {
+			t.Errorf("failed to MergeCarts: %v", err)
+		}
+		quantities := make(map[domain.ID]int64)
+		for _, cartItem := range merged.Items {
+			require.Equal(t, carts[0].ID, cartItem.CartID)
+			quantities[cartItem.ProductID] = cartItem.Quantity
+		}
+		require.Equal(t, map[domain.ID]int64{
+			cartItems[0].ProductID: 3,
+			cartItems[1].ProductID: 2,
+		}, quantities)
+
+		_, err = repo.GetCartByID(ctx, guestCart.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		_, err = repo.MergeCarts(ctx, carts[0].ID, carts[1].ID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+	t.Run("test CreateCartItem with another currency", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		usdProduct := createdProduct
+		usdProduct.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a9")
+		usdProduct.Currency = "USD"
+		_, err = repository.NewProductRepo(db).Create(ctx, usdProduct)
+		if err != nil {
+			t.Errorf("failed to Create product: %v", err)
+		}
+
+		repo := repository.NewCartRepo(db)
+		usdCart := domain.Cart{
+			ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cf"),
+			GuestToken: null.StringFrom("45c48cce2e2d7fbdea1afc51c7c6ad26"),
+		}
+		_, err = repo.CreateGuestCart(ctx, usdCart)
+		if err != nil {
+			t.Errorf("failed to CreateGuestCart: %v", err)
+		}
+		_, err = repo.CreateCartItem(ctx, domain.CartItem{
+			ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ab7"),
+			CartID:    usdCart.ID,
+			ProductID: usdProduct.ID,
+			Quantity:  1,
+		})
+		if err != nil {
+			t.Errorf("failed to CreateCartItem: %v", err)
+		}
+		cart, err := repo.GetCartByID(ctx, usdCart.ID)
+		if err != nil {
+			t.Errorf("failed to GetCartByID: %v", err)
+		}
+		require.Equal(t, "USD", cart.Currency)
+
+		_, err = repo.CreateCartItem(ctx, domain.CartItem{
+			ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ab8"),
+			CartID:    usdCart.ID,
+			ProductID: products[0].ID,
+			Quantity:  1,
+		})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		_, err = repo.CreateCartItem(ctx, domain.CartItem{
+			ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ab9"),
+			CartID:    carts[0].ID,
+			ProductID: usdProduct.ID,
+			Quantity:  1,
+		})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		_, err = repo.MergeCarts(ctx, usdCart.ID, carts[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
 }
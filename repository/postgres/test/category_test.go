@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	repository "github.com/EmirShimshir/marketplace-repository/repository/postgres"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var electronicCategory = domain.Category{
+	ID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c001"),
+	Slug: "electronic",
+	Name: "Electronic",
+}
+
+var createdCategory = domain.Category{
+	ID:       domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c101"),
+	ParentID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70c001"),
+	Slug:     "smartphones",
+	Name:     "Smartphones",
+}
+
+func TestCategoryRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test GetBySlug", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCategoryRepo(db)
+		found, err := repo.GetBySlug(ctx, electronicCategory.Slug)
+		if err != nil {
+			t.Errorf("failed to GetBySlug: %v", err)
+		}
+		require.Equal(t, electronicCategory, found)
+	})
+
+	t.Run("test GetChildren roots", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCategoryRepo(db)
+		found, err := repo.GetChildren(ctx, "")
+		if err != nil {
+			t.Errorf("failed to GetChildren: %v", err)
+		}
+		require.Equal(t, 6, len(found))
+	})
+
+	t.Run("test Create and GetDescendants", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCategoryRepo(db)
+		category, err := repo.Create(ctx, createdCategory)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.Equal(t, createdCategory, category)
+
+		found, err := repo.GetDescendants(ctx, electronicCategory.ID)
+		if err != nil {
+			t.Errorf("failed to GetDescendants: %v", err)
+		}
+		require.Equal(t, []domain.Category{createdCategory}, found)
+	})
+
+	t.Run("test Update cycle", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCategoryRepo(db)
+		_, err = repo.Create(ctx, createdCategory)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		movedCategory := electronicCategory
+		movedCategory.ParentID = createdCategory.ID
+		_, err = repo.Update(ctx, movedCategory)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+
+	t.Run("test GetByCategoryID", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = repository.NewCategoryRepo(db).Create(ctx, createdCategory)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		phone := createdProduct
+		phone.CategoryID = createdCategory.ID
+		_, err = repository.NewProductRepo(db).Create(ctx, phone)
+		if err != nil {
+			t.Errorf("failed to Create product: %v", err)
+		}
+
+		found, err := repository.NewProductRepo(db).GetByCategoryID(ctx, electronicCategory.ID, 10, 0)
+		if err != nil {
+			t.Errorf("failed to GetByCategoryID: %v", err)
+		}
+		require.Equal(t, []domain.Product{products[0], phone}, found)
+	})
+}
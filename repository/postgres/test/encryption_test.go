@@ -0,0 +1,341 @@
+package postgres
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+	repository "github.com/EmirShimshir/marketplace-repository/repository/postgres"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+var encryptionKeys = map[string][]byte{
+	"k1": []byte("0123456789abcdef0123456789abcdef"),
+	"k2": []byte("fedcba9876543210fedcba9876543210"),
+}
+
+var blindIndexKey = []byte("blind-index-key")
+
+func setKeyProvider(t *testing.T, currentKeyID string) {
+	err := encryption.SetKeyProvider(encryption.NewStaticKeyProvider(currentKeyID, encryptionKeys, blindIndexKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		encryption.SetKeyProvider(nil)
+	})
+}
+
+func TestEncryption(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test user PII encrypted", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		setKeyProvider(t, "k1")
+		repo := repository.NewUserRepo(db)
+		found, err := repo.Create(ctx, createdUser)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.Equal(t, createdUser, found)
+
+		var stored struct {
+			Email     string      `db:"email"`
+			Phone     null.String `db:"phone"`
+			EmailHash string      `db:"email_hash"`
+		}
+		err = db.GetContext(ctx, &stored, "SELECT email, phone, email_hash FROM public.user WHERE id = $1", createdUser.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.True(t, strings.HasPrefix(stored.Email, "enc:k1:"))
+		require.NotContains(t, stored.Email, createdUser.Email)
+		require.NotContains(t, stored.Phone.String, createdUser.Phone.String)
+		require.Equal(t, encryption.BlindIndex(createdUser.Email), stored.EmailHash)
+
+		found, err = repo.GetByEmail(ctx, createdUser.Email)
+		if err != nil {
+			t.Errorf("failed to GetByEmail: %v", err)
+		}
+		require.Equal(t, createdUser, found)
+	})
+
+	t.Run("test key rotation", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		setKeyProvider(t, "k1")
+		repo := repository.NewUserRepo(db)
+		_, err = repo.Create(ctx, createdUser)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		setKeyProvider(t, "k2")
+		found, err := repo.GetByEmail(ctx, createdUser.Email)
+		if err != nil {
+			t.Errorf("failed to GetByEmail: %v", err)
+		}
+		require.Equal(t, createdUser, found)
+
+		_, err = repo.Update(ctx, createdUser)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		var email string
+		err = db.GetContext(ctx, &email, "SELECT email FROM public.user WHERE id = $1", createdUser.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, "k2", encryption.KeyID(email))
+	})
+
+	t.Run("test legacy plaintext readable", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		setKeyProvider(t, "k1")
+		found, err := repository.NewUserRepo(db).GetByID(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.Equal(t, users[0].Email, found.Email)
+		require.Equal(t, users[0].Phone, found.Phone)
+	})
+
+	t.Run("test shop and order PII encrypted", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		setKeyProvider(t, "k1")
+		shop := shops[0]
+		shop.Requisites = "INN 7707083893"
+		found, err := repository.NewShopRepo(db).UpdateShop(ctx, shop)
+		if err != nil {
+			t.Errorf("failed to UpdateShop: %v", err)
+		}
+		require.Equal(t, shop.Requisites, found.Requisites)
+
+		var requisites string
+		err = db.GetContext(ctx, &requisites, "SELECT requisites FROM public.shop WHERE id = $1", shop.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, "k1", encryption.KeyID(requisites))
+
+		orderCustomer, err := repository.NewOrderRepo(db).CreateOrderCustomer(ctx, createdOrderCustomers[0])
+		if err != nil {
+			t.Errorf("failed to CreateOrderCustomer: %v", err)
+		}
+		require.Equal(t, createdOrderCustomers[0].Address, orderCustomer.Address)
+
+		var address string
+		err = db.GetContext(ctx, &address, "SELECT address FROM public.order_customer WHERE id = $1", orderCustomer.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, "k1", encryption.KeyID(address))
+	})
+
+	t.Run("test address PII encrypted and reencrypted", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		type storedAddress struct {
+			Recipient  string `db:"recipient"`
+			Phone      string `db:"phone"`
+			Street     string `db:"street"`
+			PostalCode string `db:"postal_code"`
+		}
+		requireEncrypted := func(keyID string, stored storedAddress, address domain.UserAddress) {
+			for _, field := range [][2]string{
+				{stored.Recipient, address.Recipient},
+				{stored.Phone, address.Phone},
+				{stored.Street, address.Street},
+				{stored.PostalCode, address.PostalCode},
+			} {
+				require.Equal(t, keyID, encryption.KeyID(field[0]))
+				require.NotContains(t, field[0], field[1])
+			}
+		}
+		readStored := func(orderCustomerID domain.ID) (storedAddress, storedAddress) {
+			var address, snapshot storedAddress
+			err := db.GetContext(ctx, &address, "SELECT recipient, phone, street, postal_code "+
+				"FROM public.user_address WHERE id = $1", createdUserAddresses[0].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = db.GetContext(ctx, &snapshot, "SELECT shipping_address->>'recipient' AS recipient, "+
+				"shipping_address->>'phone' AS phone, shipping_address->>'street' AS street, "+
+				"shipping_address->>'postal_code' AS postal_code FROM public.order_customer WHERE id = $1", orderCustomerID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return address, snapshot
+		}
+
+		address, err := repository.NewUserAddressRepo(db).Create(ctx, createdUserAddresses[0])
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		orderCustomer := createdOrderCustomers[0]
+		orderCustomer.AddressID = address.ID
+		_, err = repository.NewOrderRepo(db).CreateOrderCustomer(ctx, orderCustomer)
+		if err != nil {
+			t.Errorf("failed to CreateOrderCustomer: %v", err)
+		}
+		stored, snapshot := readStored(orderCustomer.ID)
+		require.Equal(t, address.Street, stored.Street)
+		require.Equal(t, address.Street, snapshot.Street)
+
+		setKeyProvider(t, "k1")
+		err = repository.ReencryptPII(ctx, db)
+		if err != nil {
+			t.Errorf("failed to ReencryptPII: %v", err)
+		}
+		stored, snapshot = readStored(orderCustomer.ID)
+		requireEncrypted("k1", stored, address)
+		requireEncrypted("k1", snapshot, address)
+
+		found, err := repository.NewOrderRepo(db).GetOrderCustomerByID(ctx, orderCustomer.ID)
+		if err != nil {
+			t.Errorf("failed to GetOrderCustomerByID: %v", err)
+		}
+		require.Equal(t, address.Recipient, found.ShippingAddress.Recipient)
+		require.Equal(t, address.PostalCode, found.ShippingAddress.PostalCode)
+
+		setKeyProvider(t, "k2")
+		address.Street = "Arbat 5"
+		address, err = repository.NewUserAddressRepo(db).Update(ctx, address)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		stored, _ = readStored(orderCustomer.ID)
+		requireEncrypted("k2", stored, address)
+	})
+
+	t.Run("test ReencryptPII", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		setKeyProvider(t, "k1")
+		repo := repository.NewUserRepo(db)
+		_, err = repo.GetByEmail(ctx, users[0].Email)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		for i := 0; i < 2; i++ {
+			err = repository.ReencryptPII(ctx, db)
+			if err != nil {
+				t.Errorf("failed to ReencryptPII: %v", err)
+			}
+		}
+
+		found, err := repo.GetByEmail(ctx, users[0].Email)
+		if err != nil {
+			t.Errorf("failed to GetByEmail: %v", err)
+		}
+		require.Equal(t, users[0].Email, found.Email)
+		require.Equal(t, users[0].Phone, found.Phone)
+
+		searched, _, err := repo.SearchUsers(ctx, domain.UserQuery{Phone: users[0].Phone.String})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, 1, len(searched))
+		require.Equal(t, users[0].ID, searched[0].ID)
+
+		var stored struct {
+			Email      string `db:"email"`
+			Requisites string `db:"requisites"`
+			Address    string `db:"address"`
+		}
+		err = db.GetContext(ctx, &stored, "SELECT u.email, s.requisites, oc.address FROM public.user u, public.shop s, "+
+			"public.order_customer oc WHERE u.id = $1 AND s.id = $2 AND oc.id = $3", users[0].ID, shops[0].ID, orderCustomers[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, "k1", encryption.KeyID(stored.Email))
+		require.Equal(t, "k1", encryption.KeyID(stored.Requisites))
+		require.Equal(t, "k1", encryption.KeyID(stored.Address))
+	})
+}
@@ -0,0 +1,22 @@
+create type promo_code_type as enum ('Percentage', 'Fixed');
+create table public.promo_code (
+     id uuid primary key,
+     code varchar(64) unique not null,
+     type promo_code_type not null,
+     value bigint not null,
+     shop_id uuid,
+     valid_from timestamp not null,
+     valid_to timestamp not null,
+     max_uses bigint not null default 0,
+     max_uses_per_user bigint not null default 0,
+     used_count bigint not null default 0,
+     foreign key (shop_id) references public.shop(id) on delete cascade,
+     check (value > 0),
+     check (type <> 'Percentage' or value <= 100),
+     check (valid_from < valid_to),
+     check (max_uses = 0 or used_count <= max_uses)
+);
+
+alter table public.order_customer add column promo_code_id uuid references public.promo_code(id) on delete restrict;
+alter table public.order_customer add column discount bigint not null default 0;
+create index idx_order_customer_promo_code on public.order_customer (promo_code_id, customer_id);
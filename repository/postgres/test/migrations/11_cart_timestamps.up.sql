@@ -0,0 +1,11 @@
+alter table public.cart add column created_at timestamp not null default now();
+alter table public.cart add column updated_at timestamp not null default now();
+alter table public.cart_product add column created_at timestamp not null default now();
+alter table public.cart_product add column updated_at timestamp not null default now();
+
+create index idx_cart_updated_at on public.cart (updated_at);
+create index idx_cart_product_updated_at on public.cart_product (updated_at);
+
+-- carts that existed before the timestamps
+update public.cart set created_at = '2024-10-01 10:00:00', updated_at = '2024-10-01 10:00:00';
+update public.cart_product set created_at = '2024-10-01 10:00:00', updated_at = '2024-10-01 10:00:00';
@@ -0,0 +1 @@
+alter table public.cart add column guest_token varchar(255) unique;
@@ -0,0 +1,21 @@
+create table public.wishlist (
+     id uuid primary key,
+     user_id uuid not null,
+     name varchar(255) not null,
+     created_at timestamp not null,
+     foreign key (user_id) references public.user(id) on delete cascade,
+     constraint uc_wishlist_name unique (user_id, name)
+);
+
+create table public.wishlist_product (
+     id uuid primary key,
+     wishlist_id uuid not null,
+     product_id uuid not null,
+     variant_id uuid,
+     added_at timestamp not null,
+     foreign key (wishlist_id) references public.wishlist(id) on delete cascade,
+     foreign key (product_id) references public.product(id) on delete cascade,
+     foreign key (variant_id) references public.product_variant(id) on delete cascade
+);
+create unique index uc_wishlist_product on public.wishlist_product
+    (wishlist_id, product_id, coalesce(variant_id, '00000000-0000-0000-0000-000000000000'));
@@ -0,0 +1,17 @@
+create table public.user_address (
+     id uuid primary key,
+     user_id uuid not null,
+     recipient varchar(255) not null,
+     phone varchar(32) not null,
+     country varchar(64) not null,
+     city varchar(255) not null,
+     street varchar(255) not null,
+     postal_code varchar(32) not null,
+     is_default bool not null default false,
+     foreign key (user_id) references public.user(id) on delete cascade
+);
+create index idx_user_address_user on public.user_address (user_id);
+create unique index uc_user_address_default on public.user_address (user_id) where is_default;
+
+alter table public.order_customer add column address_id uuid references public.user_address(id) on delete set null;
+alter table public.order_customer add column shipping_address jsonb;
@@ -0,0 +1,24 @@
+create type shipment_event_type as enum ('Shipped', 'InTransit', 'Delivered', 'Failed');
+
+create table public.shipment (
+     id uuid primary key,
+     order_shop_id uuid not null unique,
+     carrier varchar(255) not null,
+     tracking_number varchar(255) not null,
+     shipped_at timestamp,
+     delivered_at timestamp,
+     created_at timestamp not null,
+     foreign key (order_shop_id) references public.order_shop(id) on delete cascade
+);
+create index idx_shipment_in_transit on public.shipment (shipped_at) where delivered_at is null;
+
+create table public.shipment_event (
+     id uuid primary key,
+     shipment_id uuid not null,
+     type shipment_event_type not null,
+     location varchar(255) not null,
+     description text not null,
+     occurred_at timestamp not null,
+     foreign key (shipment_id) references public.shipment(id) on delete cascade
+);
+create index idx_shipment_event_shipment on public.shipment_event (shipment_id, occurred_at);
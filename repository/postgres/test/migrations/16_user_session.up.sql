@@ -0,0 +1,13 @@
+create table public.user_session (
+     id uuid primary key,
+     user_id uuid not null,
+     token_hash varchar(64) not null unique,
+     device_info varchar(255) not null,
+     ip varchar(64) not null,
+     created_at timestamp not null,
+     expires_at timestamp not null,
+     revoked_at timestamp,
+     foreign key (user_id) references public.user(id) on delete cascade
+);
+create index idx_user_session_user on public.user_session (user_id);
+create index idx_user_session_expires_at on public.user_session (expires_at);
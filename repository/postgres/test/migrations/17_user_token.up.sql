@@ -0,0 +1,16 @@
+alter table public.user add column email_verified_at timestamp;
+
+create type token_purpose as enum ('EmailVerification', 'PasswordReset');
+
+create table public.user_token (
+     id uuid primary key,
+     user_id uuid not null,
+     token_hash varchar(64) not null unique,
+     purpose token_purpose not null,
+     created_at timestamp not null,
+     expires_at timestamp not null,
+     consumed_at timestamp,
+     foreign key (user_id) references public.user(id) on delete cascade
+);
+create index idx_user_token_user on public.user_token (user_id, purpose);
+create index idx_user_token_expires_at on public.user_token (expires_at);
@@ -0,0 +1,8 @@
+alter table public.user drop constraint user_email_key;
+alter table public.user alter column email type text;
+alter table public.user alter column phone type text;
+
+alter table public.user add column email_hash varchar(64);
+update public.user set email_hash = encode(sha256(convert_to(email, 'UTF8')), 'hex');
+alter table public.user alter column email_hash set not null;
+alter table public.user add constraint uc_user_email_hash unique (email_hash);
@@ -0,0 +1,14 @@
+-- blocked_at backs the blocked filter, blocking the account sets it
+alter table public.user add column blocked_at timestamp;
+
+-- plain SHA-256 like email_hash, ReencryptPII rewrites it with the keyed blind index
+alter table public.user add column phone_hash varchar(64);
+update public.user set phone_hash = encode(sha256(convert_to(phone, 'UTF8')), 'hex') where phone is not null;
+
+create index idx_user_role on public.user (role, id);
+create index idx_user_name on public.user (name, id);
+create index idx_user_surname on public.user (surname, id);
+create index idx_user_name_prefix on public.user (lower(name) text_pattern_ops);
+create index idx_user_surname_prefix on public.user (lower(surname) text_pattern_ops);
+create index idx_user_phone_hash on public.user (phone_hash);
+create index idx_user_blocked_at on public.user (blocked_at) where blocked_at is not null;
@@ -0,0 +1,21 @@
+alter table public.user add column blocked_reason text not null default '';
+
+create type shop_moderation_status as enum ('Pending', 'Approved', 'Suspended');
+alter table public.shop add column moderation_status shop_moderation_status not null default 'Approved';
+create index idx_shop_suspended on public.shop (id) where moderation_status = 'Suspended';
+
+create type moderation_target as enum ('User', 'Shop');
+create type moderation_action_type as enum ('Block', 'Unblock', 'Pending', 'Approve', 'Suspend');
+
+-- the target is not a foreign key, the history outlives deleted users and shops
+create table public.moderation_action (
+     id uuid primary key,
+     target_type moderation_target not null,
+     target_id uuid not null,
+     moderator_id uuid,
+     action moderation_action_type not null,
+     reason text not null,
+     created_at timestamp not null,
+     foreign key (moderator_id) references public.user(id) on delete set null
+);
+create index idx_moderation_action_target on public.moderation_action (target_type, target_id, created_at);
@@ -0,0 +1,12 @@
+-- the request, approval and payment times of the withdraws that existed
+-- before the timestamps are unknown and stay NULL, only new withdraws get
+-- the default
+alter table public.withdraw add column requested_at timestamp;
+alter table public.withdraw alter column requested_at set default now();
+alter table public.withdraw add column approved_at timestamp;
+alter table public.withdraw add column paid_at timestamp;
+alter table public.withdraw add column reviewer_id uuid;
+alter table public.withdraw add foreign key (reviewer_id) references public.user(id) on delete set null;
+
+-- withdraws with an unknown request time come first in the queue
+create index idx_withdraw_status_queue on public.withdraw (status, coalesce(requested_at, '-infinity'), id);
@@ -0,0 +1,12 @@
+create type payout_batch_status as enum ('Open', 'Settled');
+create table public.payout_batch (
+     id uuid primary key,
+     total bigint not null,
+     status payout_batch_status not null,
+     created_at timestamp not null,
+     settled_at timestamp
+);
+
+alter table public.withdraw add column payout_batch_id uuid;
+alter table public.withdraw add foreign key (payout_batch_id) references public.payout_batch(id);
+create index idx_withdraw_payout_batch on public.withdraw (payout_batch_id);
@@ -0,0 +1,29 @@
+-- amounts are stored in the minor units of an ISO 4217 currency, the rows
+-- that existed before were all priced in roubles
+alter table public.shop add column default_currency varchar(3) not null default 'RUB'
+    check (default_currency ~ '^[A-Z]{3}$');
+alter table public.shop alter column default_currency drop default;
+
+alter table public.product add column currency varchar(3) not null default 'RUB'
+    check (currency ~ '^[A-Z]{3}$');
+alter table public.product alter column currency drop default;
+
+-- a cart takes the currency of its first item
+alter table public.cart add column currency varchar(3) check (currency ~ '^[A-Z]{3}$');
+update public.cart set currency = 'RUB';
+
+alter table public.order_customer add column currency varchar(3) not null default 'RUB'
+    check (currency ~ '^[A-Z]{3}$');
+alter table public.order_customer alter column currency drop default;
+
+alter table public.withdraw add column currency varchar(3) not null default 'RUB'
+    check (currency ~ '^[A-Z]{3}$');
+alter table public.withdraw alter column currency drop default;
+
+alter table public.payout_batch add column currency varchar(3) not null default 'RUB'
+    check (currency ~ '^[A-Z]{3}$');
+alter table public.payout_batch alter column currency drop default;
+
+-- only fixed amount promo codes have a currency
+alter table public.promo_code add column currency varchar(3) check (currency ~ '^[A-Z]{3}$');
+update public.promo_code set currency = 'RUB' where type = 'Fixed';
@@ -0,0 +1,26 @@
+-- full text search configuration used for the texts of a locale, the
+-- language part of the locale picks the stemmer
+create function public.product_search_config(locale text) returns regconfig
+    language sql immutable as
+$$
+select case split_part(locale, '-', 1)
+    when 'ru' then 'russian'::regconfig
+    when 'en' then 'english'::regconfig
+    else 'simple'::regconfig
+end
+$$;
+
+create table public.product_translation (
+     product_id uuid not null,
+     locale varchar(5) not null check (locale ~ '^[a-z]{2}(-[A-Z]{2})?$'),
+     name varchar(255) not null,
+     description text not null,
+     search_vector tsvector generated always as
+         (to_tsvector(public.product_search_config(locale), name || ' ' || description)) stored,
+     primary key (product_id, locale),
+     foreign key (product_id) references public.product(id) on delete cascade
+);
+create index idx_product_translation_search on public.product_translation using gin (search_vector);
+
+-- untranslated product texts are searched without stemming
+create index idx_product_search on public.product using gin (to_tsvector('simple', name || ' ' || description));
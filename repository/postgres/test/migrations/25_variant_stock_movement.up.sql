@@ -0,0 +1,13 @@
+-- keep the order history of deleted variants, a sold variant can not be deleted
+alter table public.order_shop_product drop constraint order_shop_product_variant_id_fkey;
+alter table public.order_shop_product add constraint order_shop_product_variant_id_fkey
+    foreign key (variant_id) references public.product_variant(id) on delete restrict;
+
+alter table public.stock_movement add column variant_id uuid references public.product_variant(id) on delete cascade;
+create index idx_stock_movement_variant on public.stock_movement (variant_id, created_at) where variant_id is not null;
+
+-- opening balance for the variant stock that existed before the variant ledger
+insert into public.stock_movement (id, shop_product_id, variant_id, delta, reason, created_at)
+select gen_random_uuid(), shop_product_id, id, quantity, 'Adjustment', now()
+from public.product_variant
+where quantity <> 0;
@@ -0,0 +1,10 @@
+-- a user with orders, shops or reviews is erased with EraseUser instead of deleted
+alter table public.order_customer drop constraint order_customer_customer_id_fkey;
+alter table public.order_customer add constraint order_customer_customer_id_fkey
+    foreign key (customer_id) references public.user(id) on delete restrict;
+alter table public.shop drop constraint shop_seller_id_fkey;
+alter table public.shop add constraint shop_seller_id_fkey
+    foreign key (seller_id) references public.user(id) on delete restrict;
+alter table public.review drop constraint review_user_id_fkey;
+alter table public.review add constraint review_user_id_fkey
+    foreign key (user_id) references public.user(id) on delete restrict;
@@ -0,0 +1,19 @@
+create extension if not exists pgcrypto;
+
+create type stock_movement_reason as enum ('Restock', 'Sale', 'Cancel', 'Adjustment');
+create table public.stock_movement (
+     id uuid primary key,
+     shop_product_id uuid not null,
+     delta bigint not null,
+     reason stock_movement_reason not null,
+     reference_id uuid,
+     created_at timestamp not null,
+     foreign key (shop_product_id) references public.shop_product(id) on delete cascade
+);
+create index idx_stock_movement_shop_product on public.stock_movement (shop_product_id, created_at);
+
+-- opening balance for the stock that existed before the ledger
+insert into public.stock_movement (id, shop_product_id, delta, reason, created_at)
+select gen_random_uuid(), id, quantity, 'Adjustment', now()
+from public.shop_product
+where quantity <> 0;
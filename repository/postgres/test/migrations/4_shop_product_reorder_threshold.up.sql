@@ -0,0 +1,6 @@
+alter table public.shop_product
+    add column reorder_threshold bigint not null default 0,
+    add constraint shop_product_reorder_threshold_check check (reorder_threshold >= 0);
+
+create index idx_shop_product_low_stock on public.shop_product (shop_id) where quantity <= reorder_threshold;
+create index idx_shop_product_out_of_stock on public.shop_product (id) where quantity = 0;
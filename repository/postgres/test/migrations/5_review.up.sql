@@ -0,0 +1,13 @@
+create table public.review (
+     id uuid primary key,
+     product_id uuid not null,
+     user_id uuid not null,
+     rating smallint not null,
+     text text not null,
+     created_at timestamp not null,
+     foreign key (product_id) references public.product(id) on delete cascade,
+     foreign key (user_id) references public.user(id) on delete cascade,
+     check (rating between 1 and 5),
+     constraint uc_review unique (product_id,user_id)
+);
+create index idx_review_product_created on public.review (product_id, created_at desc);
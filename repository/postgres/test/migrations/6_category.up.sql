@@ -0,0 +1,29 @@
+create table public.category (
+     id uuid primary key,
+     parent_id uuid,
+     slug varchar(255) unique not null,
+     name varchar(255) not null,
+     foreign key (parent_id) references public.category(id) on delete restrict,
+     check (parent_id <> id)
+);
+create index idx_category_parent on public.category (parent_id);
+
+-- former product_category enum values
+insert into public.category (id, slug, name)
+values ('30e18bc1-4354-4937-9a3b-03cf0b70c001', 'electronic', 'Electronic'),
+       ('30e18bc1-4354-4937-9a3b-03cf0b70c002', 'fashion', 'Fashion'),
+       ('30e18bc1-4354-4937-9a3b-03cf0b70c003', 'home', 'Home'),
+       ('30e18bc1-4354-4937-9a3b-03cf0b70c004', 'health', 'Health'),
+       ('30e18bc1-4354-4937-9a3b-03cf0b70c005', 'sport', 'Sport'),
+       ('30e18bc1-4354-4937-9a3b-03cf0b70c006', 'books', 'Books');
+
+alter table public.product add column category_id uuid references public.category(id) on delete restrict;
+update public.product p
+set category_id = c.id
+from public.category c
+where c.slug = lower(p.category::text);
+alter table public.product alter column category_id set not null;
+alter table public.product drop column category;
+drop type product_category;
+
+create index idx_product_category on public.product (category_id);
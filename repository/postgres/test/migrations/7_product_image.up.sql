@@ -0,0 +1,11 @@
+create table public.product_image (
+     id uuid primary key,
+     product_id uuid not null,
+     url varchar(255) not null,
+     position int not null,
+     alt_text varchar(255) not null default '',
+     is_primary boolean not null default false,
+     foreign key (product_id) references public.product(id) on delete cascade
+);
+create index idx_product_image_position on public.product_image (product_id, position);
+create unique index uc_product_image_primary on public.product_image (product_id) where is_primary;
@@ -0,0 +1,22 @@
+create table public.product_variant (
+     id uuid primary key,
+     shop_product_id uuid not null,
+     sku varchar(64) unique not null,
+     attributes jsonb not null default '{}',
+     price bigint,
+     quantity bigint not null,
+     foreign key (shop_product_id) references public.shop_product(id) on delete cascade,
+     check (quantity >= 0),
+     check (price is null or price >= 0)
+);
+create index idx_product_variant_shop_product on public.product_variant (shop_product_id);
+
+alter table public.cart_product add column variant_id uuid references public.product_variant(id) on delete cascade;
+alter table public.cart_product drop constraint uc_cart_product;
+create unique index uc_cart_product on public.cart_product
+    (cart_id, product_id, coalesce(variant_id, '00000000-0000-0000-0000-000000000000'));
+
+alter table public.order_shop_product add column variant_id uuid references public.product_variant(id) on delete cascade;
+alter table public.order_shop_product drop constraint uc_order_shop_product;
+create unique index uc_order_shop_product on public.order_shop_product
+    (order_shop_id, product_id, coalesce(variant_id, '00000000-0000-0000-0000-000000000000'));
@@ -0,0 +1,2 @@
+alter table public.product add column attributes jsonb not null default '{}';
+create index idx_product_attributes on public.product using gin (attributes);
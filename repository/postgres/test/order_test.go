@@ -33,6 +33,7 @@ var orderCustomers = []domain.OrderCustomer{
 	domain.OrderCustomer{
 		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ae1"),
 		CustomerID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+		Currency:   "RUB",
 		Address:    "Pushkina 1-2-3",
 		CreatedAt:  time.Date(2022, 10, 10, 11, 30, 30, 0, time.UTC),
 		OrderShops: orderShops,
@@ -63,12 +64,40 @@ var createdOrderCustomers = []domain.OrderCustomer{
 	domain.OrderCustomer{
 		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70eeee"),
 		CustomerID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+		Currency:   "RUB",
 		Address:    "Pushkina 1-2-4",
 		CreatedAt:  time.Date(2024, 10, 10, 11, 30, 30, 0, time.UTC),
+		TotalPrice: 389970,
 		OrderShops: createdorderShops,
 	},
 }
 
+var variantOrderCustomer = domain.OrderCustomer{
+	ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70dee1"),
+	CustomerID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+	Currency:   "RUB",
+	Address:    "Pushkina 1-2-5",
+	CreatedAt:  time.Date(2024, 10, 11, 11, 30, 30, 0, time.UTC),
+	TotalPrice: 389970,
+	OrderShops: []domain.OrderShop{
+		domain.OrderShop{
+			ID:              domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70dee2"),
+			ShopID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+			OrderCustomerID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70dee1"),
+			Status:          domain.OrderShopStatusStart,
+			OrderShopItems: []domain.OrderShopItem{
+				domain.OrderShopItem{
+					ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70dee3"),
+					OrderShopID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70dee2"),
+					ProductID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
+					VariantID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70d001"),
+					Quantity:    3,
+				},
+			},
+		},
+	},
+}
+
 func TestOrderRepository(t *testing.T) {
 	ctx := context.Background()
 	container, err := newPostgresContainer(ctx)
@@ -131,5 +160,197 @@ func TestOrderRepository(t *testing.T) {
 		}
 
 		require.Equal(t, createdOrderCustomers[0], found)
+
+		shopRepo := repository.NewShopRepo(db)
+		history, err := shopRepo.GetStockHistory(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetStockHistory: %v", err)
+		}
+		require.Equal(t, 2, len(history))
+		require.Equal(t, -createdOrderShopItems[0].Quantity, history[1].Delta)
+		require.Equal(t, domain.StockMovementSale, history[1].Reason)
+		require.Equal(t, createdorderShops[0].ID, history[1].ReferenceID)
+	})
+
+	t.Run("test CreateOrderCustomer variant", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		shopRepo := repository.NewShopRepo(db)
+		_, err = shopRepo.CreateVariant(ctx, variants[0])
+		if err != nil {
+			t.Errorf("failed to CreateVariant: %v", err)
+		}
+
+		repo := repository.NewOrderRepo(db)
+		found, err := repo.CreateOrderCustomer(ctx, variantOrderCustomer)
+		if err != nil {
+			t.Errorf("failed to CreateOrderCustomer: %v", err)
+		}
+		require.Equal(t, variantOrderCustomer, found)
+
+		variant, err := shopRepo.GetVariantByID(ctx, variants[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetVariantByID: %v", err)
+		}
+		require.Equal(t, int64(1), variant.Quantity)
+
+		shopItem, err := shopRepo.GetShopItemByID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetShopItemByID: %v", err)
+		}
+		require.Equal(t, shopItems[0].Quantity, shopItem.Quantity)
+
+		history, err := shopRepo.GetStockHistory(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetStockHistory: %v", err)
+		}
+		require.Equal(t, 3, len(history))
+		require.Equal(t, variants[0].ID, history[2].VariantID)
+		require.Equal(t, int64(-3), history[2].Delta)
+		require.Equal(t, domain.StockMovementSale, history[2].Reason)
+
+		consistent, err := shopRepo.ReconcileStock(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to ReconcileStock: %v", err)
+		}
+		require.True(t, consistent)
+
+		err = shopRepo.DeleteVariant(ctx, variants[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+
+	t.Run("test CreateOrderCustomer wrong total", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		orderCustomer := createdOrderCustomers[0]
+		orderCustomer.TotalPrice = 1
+
+		repo := repository.NewOrderRepo(db)
+		_, err = repo.CreateOrderCustomer(ctx, orderCustomer)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		_, err = repo.GetOrderCustomerByID(ctx, orderCustomer.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+	t.Run("test CreateOrderCustomer out of stock", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		orderShopItem := createdOrderShopItems[0]
+		orderShopItem.Quantity = shopItems[0].Quantity + 1
+		orderShop := createdorderShops[0]
+		orderShop.OrderShopItems = []domain.OrderShopItem{orderShopItem}
+		orderCustomer := createdOrderCustomers[0]
+		orderCustomer.TotalPrice = createdOrderCustomers[0].TotalPrice / createdOrderShopItems[0].Quantity * orderShopItem.Quantity
+		orderCustomer.OrderShops = []domain.OrderShop{orderShop}
+
+		repo := repository.NewOrderRepo(db)
+		_, err = repo.CreateOrderCustomer(ctx, orderCustomer)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		shopRepo := repository.NewShopRepo(db)
+		shopItem, err := shopRepo.GetShopItemByID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetShopItemByID: %v", err)
+		}
+		require.Equal(t, shopItems[0].Quantity, shopItem.Quantity)
+	})
+
+	t.Run("test CreateOrderCustomer other shop", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		shopRepo := repository.NewShopRepo(db)
+		otherShop := shops[0]
+		otherShop.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b2")
+		otherShop.Name = "Samsung Store"
+		otherShop.Email = "Samsung@mail.ru"
+		otherShop.Items = nil
+		_, err = shopRepo.CreateShop(ctx, otherShop)
+		if err != nil {
+			t.Errorf("failed to CreateShop: %v", err)
+		}
+
+		orderShop := createdorderShops[0]
+		orderShop.ShopID = otherShop.ID
+		orderCustomer := createdOrderCustomers[0]
+		orderCustomer.OrderShops = []domain.OrderShop{orderShop}
+
+		repo := repository.NewOrderRepo(db)
+		_, err = repo.CreateOrderCustomer(ctx, orderCustomer)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		shopItem, err := shopRepo.GetShopItemByID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetShopItemByID: %v", err)
+		}
+		require.Equal(t, shopItems[0].Quantity, shopItem.Quantity)
+	})
+
+	t.Run("test CreateOrderCustomer wrong currency", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		orderCustomer := createdOrderCustomers[0]
+		orderCustomer.Currency = "USD"
+
+		repo := repository.NewOrderRepo(db)
+		_, err = repo.CreateOrderCustomer(ctx, orderCustomer)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		_, err = repo.GetOrderCustomerByID(ctx, orderCustomer.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
 	})
 }
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	repository "github.com/EmirShimshir/marketplace-repository/repository/postgres"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+var payoutBatchID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70ba01")
+
+func TestPayoutBatchRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test batch lifecycle", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		withdrawRepo := repository.NewWithdrawRepo(db)
+		reviewerID := domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc")
+		pending := []domain.Withdraw{newWithdraw("2ae3"), newWithdraw("2ae4"), newWithdraw("2ae5")}
+		pending[1].Sum = 2500
+		pending[2].Currency = "USD"
+		for i := range pending {
+			_, err = withdrawRepo.Create(ctx, pending[i])
+			if err != nil {
+				t.Errorf("failed to Create withdraw: %v", err)
+			}
+		}
+		for _, withdraw := range pending {
+			_, err = withdrawRepo.TransitionWithdraw(ctx, withdraw.ID, domain.WithdrawStatusReady, reviewerID)
+			if err != nil {
+				t.Errorf("failed to TransitionWithdraw: %v", err)
+			}
+		}
+
+		repo := repository.NewPayoutBatchRepo(db)
+		batch, err := repo.Create(ctx, domain.PayoutBatch{ID: payoutBatchID, Currency: "RUB"})
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.Equal(t, domain.PayoutBatchOpen, batch.Status)
+		require.Equal(t, "RUB", batch.Currency)
+		require.Equal(t, pending[0].Sum+pending[1].Sum, batch.Total)
+		require.Equal(t, 2, len(batch.Withdraws))
+		for i, withdraw := range batch.Withdraws {
+			require.Equal(t, pending[i].ID, withdraw.ID)
+			require.Equal(t, payoutBatchID, withdraw.BatchID)
+			require.Equal(t, domain.WithdrawStatusReady, withdraw.Status)
+		}
+
+		// the USD withdraw is ready but is not paid by a RUB batch
+		_, err = repo.Create(ctx, domain.PayoutBatch{ID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70ba02"), Currency: "RUB"})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		_, err = repo.GetByID(ctx, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70ba02"))
+		require.ErrorIs(t, err, domain.ErrNotExist)
+		_, err = withdrawRepo.TransitionWithdraw(ctx, pending[0].ID, domain.WithdrawStatusDone, "")
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		data, err := repo.ExportCSV(ctx, payoutBatchID)
+		if err != nil {
+			t.Errorf("failed to ExportCSV: %v", err)
+		}
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, [][]string{
+			{"withdraw_id", "shop_id", "shop_name", "requisites", "amount", "comment"},
+			{pending[0].ID.String(), shops[0].ID.String(), shops[0].Name, shops[0].Requisites, "1500", "weekly payout"},
+			{pending[1].ID.String(), shops[0].ID.String(), shops[0].Name, shops[0].Requisites, "2500", "weekly payout"},
+		}, records)
+
+		settled, err := repo.Settle(ctx, payoutBatchID)
+		if err != nil {
+			t.Errorf("failed to Settle: %v", err)
+		}
+		require.Equal(t, domain.PayoutBatchSettled, settled.Status)
+		require.True(t, settled.SettledAt.Valid)
+		for _, withdraw := range settled.Withdraws {
+			require.Equal(t, domain.WithdrawStatusDone, withdraw.Status)
+			require.Equal(t, settled.SettledAt, withdraw.PaidAt)
+		}
+
+		_, err = repo.Settle(ctx, payoutBatchID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		_, err = repo.Settle(ctx, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70ba02"))
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		other, err := withdrawRepo.GetByID(ctx, pending[2].ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.Equal(t, domain.WithdrawStatusReady, other.Status)
+		require.Empty(t, other.BatchID)
+
+		batches, err := repo.Get(ctx, 10, 0)
+		if err != nil {
+			t.Errorf("failed to Get: %v", err)
+		}
+		require.Equal(t, []domain.PayoutBatch{settled}, batches)
+	})
+}
@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	repository "github.com/EmirShimshir/marketplace-repository/repository/postgres"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var sessionTokens = []string{"refresh-token-iphone", "refresh-token-linux"}
+
+func newSessions() []domain.Session {
+	expiresAt := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second)
+	return []domain.Session{
+		domain.Session{
+			ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fd01"),
+			UserID:     domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
+			DeviceInfo: "iPhone 15, Safari",
+			IP:         "10.0.0.1",
+			ExpiresAt:  expiresAt,
+		},
+		domain.Session{
+			ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fd02"),
+			UserID:     domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
+			DeviceInfo: "Firefox on Linux",
+			IP:         "10.0.0.2",
+			ExpiresAt:  expiresAt,
+		},
+	}
+}
+
+func sessionTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSessionRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test Create and GetByRefreshToken", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSessionRepo(db)
+		sessions := newSessions()
+		for i := range sessions {
+			found, err := repo.Create(ctx, sessions[i], sessionTokens[i])
+			if err != nil {
+				t.Errorf("failed to Create: %v", err)
+			}
+			require.WithinDuration(t, time.Now(), found.CreatedAt, time.Minute)
+			sessions[i].TokenHash = sessionTokenHash(sessionTokens[i])
+			sessions[i].CreatedAt = found.CreatedAt
+			require.Equal(t, sessions[i], found)
+		}
+
+		found, err := repo.GetByRefreshToken(ctx, sessionTokens[0])
+		if err != nil {
+			t.Errorf("failed to GetByRefreshToken: %v", err)
+		}
+		require.Equal(t, sessions[0], found)
+
+		_, err = repo.GetByRefreshToken(ctx, "unknown-token")
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		userSessions, err := repo.GetByUserID(ctx, sessions[0].UserID)
+		if err != nil {
+			t.Errorf("failed to GetByUserID: %v", err)
+		}
+		require.Equal(t, []domain.Session{sessions[1], sessions[0]}, userSessions)
+
+		duplicate := sessions[0]
+		duplicate.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fd03")
+		_, err = repo.Create(ctx, duplicate, sessionTokens[0])
+		require.ErrorIs(t, err, domain.ErrDuplicate)
+
+		missing := sessions[0]
+		missing.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fd04")
+		missing.UserID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70ffff")
+		_, err = repo.Create(ctx, missing, "refresh-token-missing")
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+
+	t.Run("test Revoke and RevokeAllForUser", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSessionRepo(db)
+		sessions := newSessions()
+		for i := range sessions {
+			_, err = repo.Create(ctx, sessions[i], sessionTokens[i])
+			if err != nil {
+				t.Errorf("failed to Create: %v", err)
+			}
+		}
+
+		err = repo.Revoke(ctx, sessions[0].ID)
+		if err != nil {
+			t.Errorf("failed to Revoke: %v", err)
+		}
+		_, err = repo.GetByRefreshToken(ctx, sessionTokens[0])
+		require.ErrorIs(t, err, domain.ErrNotExist)
+		err = repo.Revoke(ctx, sessions[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		found, err := repo.GetByID(ctx, sessions[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.True(t, found.RevokedAt.Valid)
+
+		revoked, err := repo.RevokeAllForUser(ctx, sessions[0].UserID)
+		if err != nil {
+			t.Errorf("failed to RevokeAllForUser: %v", err)
+		}
+		require.Equal(t, int64(1), revoked)
+
+		userSessions, err := repo.GetByUserID(ctx, sessions[0].UserID)
+		if err != nil {
+			t.Errorf("failed to GetByUserID: %v", err)
+		}
+		require.Equal(t, 0, len(userSessions))
+	})
+
+	t.Run("test PurgeExpired", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSessionRepo(db)
+		sessions := newSessions()
+		sessions[1].ExpiresAt = time.Now().UTC().Add(-time.Hour)
+		for i := range sessions {
+			_, err = repo.Create(ctx, sessions[i], sessionTokens[i])
+			if err != nil {
+				t.Errorf("failed to Create: %v", err)
+			}
+		}
+
+		_, err = repo.GetByRefreshToken(ctx, sessionTokens[1])
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		purged, err := repo.PurgeExpired(ctx, time.Now())
+		if err != nil {
+			t.Errorf("failed to PurgeExpired: %v", err)
+		}
+		require.Equal(t, int64(1), purged)
+
+		_, err = repo.GetByID(ctx, sessions[1].ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+		_, err = repo.GetByID(ctx, sessions[0].ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("test user Delete cascades", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		userRepo := repository.NewUserRepo(db)
+		_, err = userRepo.Create(ctx, createdUser)
+		if err != nil {
+			t.Errorf("failed to Create user: %v", err)
+		}
+
+		repo := repository.NewSessionRepo(db)
+		session := newSessions()[0]
+		session.UserID = createdUser.ID
+		_, err = repo.Create(ctx, session, sessionTokens[0])
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		err = userRepo.Delete(ctx, createdUser.ID)
+		if err != nil {
+			t.Errorf("failed to Delete: %v", err)
+		}
+		_, err = repo.GetByID(ctx, session.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+}
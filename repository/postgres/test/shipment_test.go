@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	repository "github.com/EmirShimshir/marketplace-repository/repository/postgres"
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var createdShipment = domain.Shipment{
+	ID:             domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fc01"),
+	OrderShopID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ee1"),
+	Carrier:        "CDEK",
+	TrackingNumber: "1234567890",
+}
+
+var shipmentEvents = []domain.ShipmentEvent{
+	domain.ShipmentEvent{
+		ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fc11"),
+		ShipmentID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fc01"),
+		Type:        domain.ShipmentEventShipped,
+		Location:    "Moscow",
+		Description: "handed over to the carrier",
+		OccurredAt:  time.Date(2024, 10, 1, 10, 0, 0, 0, time.UTC),
+	},
+	domain.ShipmentEvent{
+		ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fc12"),
+		ShipmentID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fc01"),
+		Type:        domain.ShipmentEventInTransit,
+		Location:    "Tver",
+		Description: "arrived at the sorting center",
+		OccurredAt:  time.Date(2024, 10, 2, 10, 0, 0, 0, time.UTC),
+	},
+	domain.ShipmentEvent{
+		ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fc13"),
+		ShipmentID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fc01"),
+		Type:        domain.ShipmentEventDelivered,
+		Location:    "Saint Petersburg",
+		Description: "delivered to the recipient",
+		OccurredAt:  time.Date(2024, 10, 6, 10, 0, 0, 0, time.UTC),
+	},
+}
+
+func TestShipmentRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test Create not ready", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewShipmentRepo(db)
+		_, err = repo.Create(ctx, createdShipment)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		_, err = repo.GetByOrderShopID(ctx, createdShipment.OrderShopID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+
+	t.Run("test Create and Update", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		readyOrderShop := orderShops[0]
+		readyOrderShop.Status = domain.OrderShopStatusReady
+		_, err = repository.NewOrderRepo(db).UpdateOrderShop(ctx, readyOrderShop)
+		if err != nil {
+			t.Errorf("failed to UpdateOrderShop: %v", err)
+		}
+
+		repo := repository.NewShipmentRepo(db)
+		found, err := repo.Create(ctx, createdShipment)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.WithinDuration(t, time.Now(), found.CreatedAt, time.Minute)
+		expected := createdShipment
+		expected.CreatedAt = found.CreatedAt
+		require.Equal(t, expected, found)
+
+		duplicate := createdShipment
+		duplicate.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fc02")
+		_, err = repo.Create(ctx, duplicate)
+		require.ErrorIs(t, err, domain.ErrDuplicate)
+
+		expected.Carrier = "Russian Post"
+		expected.TrackingNumber = "RA123456789RU"
+		found, err = repo.Update(ctx, expected)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		require.Equal(t, expected, found)
+
+		found, err = repo.GetByOrderShopID(ctx, createdShipment.OrderShopID)
+		if err != nil {
+			t.Errorf("failed to GetByOrderShopID: %v", err)
+		}
+		require.Equal(t, expected, found)
+	})
+
+	t.Run("test AppendEvent and GetOverdueShipments", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		readyOrderShop := orderShops[0]
+		readyOrderShop.Status = domain.OrderShopStatusReady
+		_, err = repository.NewOrderRepo(db).UpdateOrderShop(ctx, readyOrderShop)
+		if err != nil {
+			t.Errorf("failed to UpdateOrderShop: %v", err)
+		}
+
+		repo := repository.NewShipmentRepo(db)
+		_, err = repo.Create(ctx, createdShipment)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		for _, event := range shipmentEvents[:2] {
+			_, err = repo.AppendEvent(ctx, event)
+			if err != nil {
+				t.Errorf("failed to AppendEvent: %v", err)
+			}
+		}
+
+		overdue, err := repo.GetOverdueShipments(ctx, time.Date(2024, 10, 5, 10, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Errorf("failed to GetOverdueShipments: %v", err)
+		}
+		require.Equal(t, 1, len(overdue))
+		require.Equal(t, createdShipment.ID, overdue[0].ID)
+		require.Equal(t, null.TimeFrom(shipmentEvents[0].OccurredAt), overdue[0].ShippedAt)
+		require.Equal(t, shipmentEvents[:2], overdue[0].Events)
+
+		overdue, err = repo.GetOverdueShipments(ctx, shipmentEvents[0].OccurredAt)
+		if err != nil {
+			t.Errorf("failed to GetOverdueShipments: %v", err)
+		}
+		require.Equal(t, 0, len(overdue))
+
+		found, err := repo.AppendEvent(ctx, shipmentEvents[2])
+		if err != nil {
+			t.Errorf("failed to AppendEvent: %v", err)
+		}
+		require.Equal(t, null.TimeFrom(shipmentEvents[0].OccurredAt), found.ShippedAt)
+		require.Equal(t, null.TimeFrom(shipmentEvents[2].OccurredAt), found.DeliveredAt)
+		require.Equal(t, shipmentEvents, found.Events)
+
+		overdue, err = repo.GetOverdueShipments(ctx, time.Date(2024, 10, 5, 10, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Errorf("failed to GetOverdueShipments: %v", err)
+		}
+		require.Equal(t, 0, len(overdue))
+
+		late := shipmentEvents[1]
+		late.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70fc14")
+		_, err = repo.AppendEvent(ctx, late)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+}
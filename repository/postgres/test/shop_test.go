@@ -4,7 +4,9 @@ import (
 	"context"
 	"github.com/EmirShimshir/marketplace-core/domain"
 	repository "github.com/EmirShimshir/marketplace-repository/repository/postgres"
+	"github.com/guregu/null"
 	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
 )
 
@@ -17,15 +19,74 @@ var shopItems = []domain.ShopItem{
 	},
 }
 
+var updatedShopItem = domain.ShopItem{
+	ID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ac1"),
+	ShopID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+	ProductID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
+	Quantity:  8,
+}
+
+var lowStockShopItem = domain.ShopItem{
+	ID:               domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ac1"),
+	ShopID:           domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+	ProductID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a1"),
+	Quantity:         0,
+	ReorderThreshold: 2,
+}
+
+var variants = []domain.ProductVariant{
+	domain.ProductVariant{
+		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70d001"),
+		ShopItemID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ac1"),
+		SKU:        "IPH15-128",
+		Attributes: map[string]string{"memory": "128GB", "color": "black"},
+		Quantity:   4,
+	},
+	domain.ProductVariant{
+		ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70d002"),
+		ShopItemID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b702ac1"),
+		SKU:        "IPH15-256",
+		Attributes: map[string]string{"memory": "256GB", "color": "black"},
+		Price:      null.IntFrom(149990),
+		Quantity:   2,
+	},
+}
+
 var shops = []domain.Shop{
 	domain.Shop{
-		ID:          domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
-		SellerID:    domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
-		Name:        "Apple Store",
-		Description: "found 1998",
-		Requisites:  "Alabama",
-		Email:       "Apple@mail.ru",
-		Items:       shopItems,
+		ID:              domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027b1"),
+		SellerID:        domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
+		Name:            "Apple Store",
+		Description:     "found 1998",
+		Requisites:      "Alabama",
+		Email:           "Apple@mail.ru",
+		DefaultCurrency: "RUB",
+		Items:           shopItems,
+	},
+}
+
+const importCSV = `product_id,name,price,category_id,quantity,currency
+30e18bc1-4354-4937-9a3b-03cf0b7027a1,iphone 15,139990,30e18bc1-4354-4937-9a3b-03cf0b70c001,7,
+30e18bc1-4354-4937-9a3b-03cf0b7027a4,ipad,59990,30e18bc1-4354-4937-9a3b-03cf0b70c001,3,
+30e18bc1-4354-4937-9a3b-03cf0b7027a5,broken,abc,30e18bc1-4354-4937-9a3b-03cf0b70c001,1,
+30e18bc1-4354-4937-9a3b-03cf0b7027a6,lost,100,30e18bc1-4354-4937-9a3b-03cf0b70c0ff,1,
+30e18bc1-4354-4937-9a3b-03cf0b7027a7,dollars,100,30e18bc1-4354-4937-9a3b-03cf0b70c001,1,usd
+30e18bc1-4354-4937-9a3b-03cf0b7027a4,ipad again,100,30e18bc1-4354-4937-9a3b-03cf0b70c001,1,
+`
+
+const importNDJSON = `{"product_id": "30e18bc1-4354-4937-9a3b-03cf0b7027a4", "name": "ipad", "price": 59990, "category_id": "30e18bc1-4354-4937-9a3b-03cf0b70c001", "quantity": 1}
+{"product_id": "30e18bc1-4354-4937-9a3b-03cf0b7027a5",
+`
+
+var importReport = domain.ImportReport{
+	Rows:    6,
+	Created: 1,
+	Updated: 1,
+	Errors: []domain.ImportRowError{
+		{Line: 4, Error: "price is not an integer"},
+		{Line: 5, Error: "category does not exist"},
+		{Line: 6, Error: "invalid currency code"},
+		{Line: 7, Error: "product is already listed on line 3"},
 	},
 }
 
@@ -97,6 +158,79 @@ func TestShopRepository(t *testing.T) {
 		require.Equal(t, shops[0], found)
 	})
 
+	t.Run("test SetModerationStatus", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewShopRepo(db)
+		_, err = repo.SetModerationStatus(ctx, domain.ModerationAction{TargetID: shops[0].ID, Action: domain.ModerationActionBlock})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+		_, err = repo.SetModerationStatus(ctx, domain.ModerationAction{TargetID: shops[0].ID, Action: domain.ModerationActionApprove})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		suspended, err := repo.SetModerationStatus(ctx, domain.ModerationAction{
+			TargetID:    shops[0].ID,
+			ModeratorID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+			Action:      domain.ModerationActionSuspend,
+			Reason:      "counterfeit goods",
+		})
+		if err != nil {
+			t.Errorf("failed to SetModerationStatus: %v", err)
+		}
+		require.Equal(t, domain.ShopModerationSuspended, suspended.ModerationStatus)
+
+		found, err := repo.GetShops(ctx, 2, 0)
+		if err != nil {
+			t.Errorf("failed to GetShops: %v", err)
+		}
+		require.Empty(t, found)
+
+		items, err := repo.GetShopItems(ctx, 2, 0)
+		if err != nil {
+			t.Errorf("failed to GetShopItems: %v", err)
+		}
+		require.Empty(t, items)
+
+		found, err = repo.GetShopsByModerationStatus(ctx, domain.ShopModerationSuspended, 2, 0)
+		if err != nil {
+			t.Errorf("failed to GetShopsByModerationStatus: %v", err)
+		}
+		require.Equal(t, []domain.Shop{suspended}, found)
+
+		updated := suspended
+		updated.Name = "Apple Store Moscow"
+		updated.ModerationStatus = domain.ShopModerationApproved
+		updated, err = repo.UpdateShop(ctx, updated)
+		if err != nil {
+			t.Errorf("failed to UpdateShop: %v", err)
+		}
+		require.Equal(t, domain.ShopModerationSuspended, updated.ModerationStatus)
+
+		_, err = repo.SetModerationStatus(ctx, domain.ModerationAction{TargetID: shops[0].ID, Action: domain.ModerationActionApprove})
+		if err != nil {
+			t.Errorf("failed to SetModerationStatus: %v", err)
+		}
+
+		history, err := repo.GetModerationHistory(ctx, shops[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetModerationHistory: %v", err)
+		}
+		require.Equal(t, 2, len(history))
+		require.Equal(t, domain.ModerationActionSuspend, history[0].Action)
+		require.Equal(t, "counterfeit goods", history[0].Reason)
+		require.Equal(t, domain.ModerationActionApprove, history[1].Action)
+	})
+
 	t.Run("test GetShopBySellerID", func(t *testing.T) {
 		t.Cleanup(func() {
 			err = container.Restore(ctx)
@@ -119,4 +253,231 @@ func TestShopRepository(t *testing.T) {
 
 		require.Equal(t, []domain.Shop{shops[0]}, found)
 	})
+	t.Run("test GetStockHistory", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewShopRepo(db)
+		found, err := repo.GetStockHistory(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetStockHistory: %v", err)
+		}
+
+		require.Equal(t, 1, len(found))
+		require.Equal(t, shopItems[0].Quantity, found[0].Delta)
+		require.Equal(t, domain.StockMovementAdjustment, found[0].Reason)
+	})
+
+	t.Run("test UpdateShopItem", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewShopRepo(db)
+		found, err := repo.UpdateShopItem(ctx, updatedShopItem)
+		if err != nil {
+			t.Errorf("failed to UpdateShopItem: %v", err)
+		}
+		require.Equal(t, updatedShopItem, found)
+
+		history, err := repo.GetStockHistory(ctx, updatedShopItem.ID)
+		if err != nil {
+			t.Errorf("failed to GetStockHistory: %v", err)
+		}
+		require.Equal(t, 2, len(history))
+		require.Equal(t, int64(3), history[1].Delta)
+		require.Equal(t, domain.StockMovementRestock, history[1].Reason)
+
+		consistent, err := repo.ReconcileStock(ctx, updatedShopItem.ID)
+		if err != nil {
+			t.Errorf("failed to ReconcileStock: %v", err)
+		}
+		require.True(t, consistent)
+	})
+	t.Run("test GetLowStockItems", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewShopRepo(db)
+		found, err := repo.GetLowStockItems(ctx, shops[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetLowStockItems: %v", err)
+		}
+		require.Equal(t, 0, len(found))
+
+		_, err = repo.UpdateShopItem(ctx, lowStockShopItem)
+		if err != nil {
+			t.Errorf("failed to UpdateShopItem: %v", err)
+		}
+
+		found, err = repo.GetLowStockItems(ctx, shops[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetLowStockItems: %v", err)
+		}
+		require.Equal(t, []domain.ShopItem{lowStockShopItem}, found)
+	})
+
+	t.Run("test GetOutOfStockProducts", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewShopRepo(db)
+		_, err = repo.UpdateShopItem(ctx, lowStockShopItem)
+		if err != nil {
+			t.Errorf("failed to UpdateShopItem: %v", err)
+		}
+
+		found, err := repo.GetOutOfStockProducts(ctx, 10, "")
+		if err != nil {
+			t.Errorf("failed to GetOutOfStockProducts: %v", err)
+		}
+		require.Equal(t, []domain.ShopItem{lowStockShopItem}, found)
+
+		found, err = repo.GetOutOfStockProducts(ctx, 10, lowStockShopItem.ID)
+		if err != nil {
+			t.Errorf("failed to GetOutOfStockProducts: %v", err)
+		}
+		require.Equal(t, 0, len(found))
+	})
+
+	t.Run("test variants", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewShopRepo(db)
+		for _, variant := range variants {
+			found, err := repo.CreateVariant(ctx, variant)
+			if err != nil {
+				t.Errorf("failed to CreateVariant: %v", err)
+			}
+			require.Equal(t, variant, found)
+		}
+		_, err = repo.CreateVariant(ctx, variants[0])
+		require.ErrorIs(t, err, domain.ErrDuplicate)
+
+		updatedVariant := variants[1]
+		updatedVariant.Quantity = 7
+		found, err := repo.UpdateVariant(ctx, updatedVariant)
+		if err != nil {
+			t.Errorf("failed to UpdateVariant: %v", err)
+		}
+		require.Equal(t, updatedVariant, found)
+
+		all, err := repo.GetVariantsByShopItemID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetVariantsByShopItemID: %v", err)
+		}
+		require.Equal(t, []domain.ProductVariant{variants[0], updatedVariant}, all)
+	})
+
+	t.Run("test ImportShopItems", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewShopRepo(db)
+		dryRun := importReport
+		dryRun.DryRun = true
+		report, err := repo.ImportShopItems(ctx, shops[0].ID, domain.ImportFormatCSV, strings.NewReader(importCSV), true)
+		if err != nil {
+			t.Errorf("failed to ImportShopItems: %v", err)
+		}
+		require.Equal(t, dryRun, report)
+		_, err = repo.GetShopItemByProductID(ctx, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a4"))
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		report, err = repo.ImportShopItems(ctx, shops[0].ID, domain.ImportFormatCSV, strings.NewReader(importCSV), false)
+		if err != nil {
+			t.Errorf("failed to ImportShopItems: %v", err)
+		}
+		require.Equal(t, importReport, report)
+		found, err := repo.GetShopItemByID(ctx, shopItems[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetShopItemByID: %v", err)
+		}
+		require.Equal(t, int64(7), found.Quantity)
+
+		report, err = repo.ImportShopItems(ctx, shops[0].ID, domain.ImportFormatNDJSON, strings.NewReader(importNDJSON), false)
+		if err != nil {
+			t.Errorf("failed to ImportShopItems: %v", err)
+		}
+		require.Equal(t, int64(2), report.Rows)
+		require.Equal(t, int64(1), report.Updated)
+		require.Equal(t, 1, len(report.Errors))
+		require.Equal(t, int64(2), report.Errors[0].Line)
+
+		imported, err := repo.GetShopItemByProductID(ctx, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a4"))
+		if err != nil {
+			t.Errorf("failed to GetShopItemByProductID: %v", err)
+		}
+		require.Equal(t, int64(1), imported.Quantity)
+		for _, shopItemID := range []domain.ID{shopItems[0].ID, imported.ID} {
+			consistent, err := repo.ReconcileStock(ctx, shopItemID)
+			if err != nil {
+				t.Errorf("failed to ReconcileStock: %v", err)
+			}
+			require.True(t, consistent)
+		}
+
+		_, err = repo.ImportShopItems(ctx, shops[0].ID, domain.ImportFormatCSV, strings.NewReader("product_id,qty\n"), false)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
 }
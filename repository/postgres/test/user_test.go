@@ -2,11 +2,13 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/EmirShimshir/marketplace-core/domain"
 	repository "github.com/EmirShimshir/marketplace-repository/repository/postgres"
 	"github.com/guregu/null"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 var users = []domain.User{
@@ -54,6 +56,17 @@ var updatedUser = domain.User{
 	Role:     domain.UserCustomer,
 }
 
+var searchedUser = domain.User{
+	ID:       domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027ce"),
+	CartID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b111112"),
+	Name:     "Zinaida",
+	Surname:  "Zakharova",
+	Phone:    null.StringFrom("+79995554433"),
+	Email:    "zina@mail.ru",
+	Password: "password",
+	Role:     domain.UserSeller,
+}
+
 func TestUserRepository(t *testing.T) {
 	ctx := context.Background()
 	container, err := newPostgresContainer(ctx)
@@ -164,6 +177,282 @@ func TestUserRepository(t *testing.T) {
 		require.Equal(t, user, updatedUser)
 	})
 
+	t.Run("test SearchUsers filters", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		_, err = repo.Create(ctx, searchedUser)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		found, next, err := repo.SearchUsers(ctx, domain.UserQuery{NamePrefix: "ti"})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{users[0]}, found)
+		require.Empty(t, next)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{SurnamePrefix: "ZAKH"})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser}, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{NamePrefix: "%"})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Empty(t, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{Phone: users[0].Phone.String})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{users[0]}, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{Email: searchedUser.Email})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser}, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{Roles: []domain.UserRole{domain.UserSeller, domain.UserModerator}})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser}, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{Blocked: null.BoolFrom(true)})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Empty(t, found)
+
+		found, _, err = repo.SearchUsers(ctx, domain.UserQuery{Blocked: null.BoolFrom(false), Descending: true})
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser, users[1], users[0]}, found)
+	})
+
+	t.Run("test SearchUsers pagination", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		_, err = repo.Create(ctx, searchedUser)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		query := domain.UserQuery{SortBy: domain.UserSortBySurname, Limit: 2}
+		found, next, err := repo.SearchUsers(ctx, query)
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{users[0], users[1]}, found)
+		require.NotEmpty(t, next)
+
+		query.Cursor = next
+		found, next, err = repo.SearchUsers(ctx, query)
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser}, found)
+		require.Empty(t, next)
+
+		query = domain.UserQuery{Descending: true, Limit: 1}
+		found, next, err = repo.SearchUsers(ctx, query)
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{searchedUser}, found)
+
+		query.Cursor = next
+		found, _, err = repo.SearchUsers(ctx, query)
+		if err != nil {
+			t.Errorf("failed to SearchUsers: %v", err)
+		}
+		require.Equal(t, []domain.User{users[1]}, found)
+
+		_, _, err = repo.SearchUsers(ctx, domain.UserQuery{Cursor: "not a cursor"})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+	})
+
+	t.Run("test ExportUserData", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		data, err := repo.ExportUserData(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to ExportUserData: %v", err)
+		}
+
+		var export domain.UserDataExport
+		err = json.Unmarshal(data, &export)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, users[0].Email, export.User.Email)
+		require.Empty(t, export.User.Password)
+		require.Equal(t, users[0].CartID, export.Cart.ID)
+		require.Equal(t, 1, len(export.Shops))
+		require.Equal(t, shops[0].ID, export.Shops[0].ID)
+		require.Equal(t, withdraws, export.Withdraws)
+		require.WithinDuration(t, time.Now(), export.ExportedAt, time.Minute)
+	})
+
+	t.Run("test EraseUser", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		err = repo.EraseUser(ctx, users[1].ID)
+		if err != nil {
+			t.Errorf("failed to EraseUser: %v", err)
+		}
+
+		erased, err := repo.GetByID(ctx, users[1].ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.NotEqual(t, users[1].Name, erased.Name)
+		require.NotEqual(t, users[1].Email, erased.Email)
+		require.False(t, erased.Phone.Valid)
+		require.Empty(t, erased.Password)
+
+		_, err = repo.GetByEmail(ctx, users[1].Email)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+
+		orderCustomer, err := repository.NewOrderRepo(db).GetOrderCustomerByID(ctx, orderCustomers[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetOrderCustomerByID: %v", err)
+		}
+		require.Equal(t, users[1].ID, orderCustomer.CustomerID)
+		require.Equal(t, orderCustomers[0].TotalPrice, orderCustomer.TotalPrice)
+		require.Empty(t, orderCustomer.Address)
+	})
+
+	t.Run("test BlockUser and UnblockUser", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		sessions := newSessions()
+		sessionRepo := repository.NewSessionRepo(db)
+		for i := range sessions {
+			_, err = sessionRepo.Create(ctx, sessions[i], sessionTokens[i])
+			if err != nil {
+				t.Errorf("failed to Create session: %v", err)
+			}
+		}
+
+		repo := repository.NewUserRepo(db)
+		block := domain.ModerationAction{
+			TargetID:    users[0].ID,
+			ModeratorID: users[1].ID,
+			Reason:      "fraud",
+		}
+		blocked, err := repo.BlockUser(ctx, block)
+		if err != nil {
+			t.Errorf("failed to BlockUser: %v", err)
+		}
+		require.True(t, blocked.BlockedAt.Valid)
+		require.Equal(t, "fraud", blocked.BlockedReason)
+
+		_, err = repo.BlockUser(ctx, block)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		userSessions, err := sessionRepo.GetByUserID(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetByUserID: %v", err)
+		}
+		require.Equal(t, len(sessions), len(userSessions))
+		for _, session := range userSessions {
+			require.True(t, session.RevokedAt.Valid)
+		}
+
+		updated := users[0]
+		updated.Name = "Timur Blocked"
+		updated, err = repo.Update(ctx, updated)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		require.Equal(t, blocked.BlockedAt, updated.BlockedAt)
+
+		unblocked, err := repo.UnblockUser(ctx, domain.ModerationAction{TargetID: users[0].ID, ModeratorID: users[1].ID})
+		if err != nil {
+			t.Errorf("failed to UnblockUser: %v", err)
+		}
+		require.False(t, unblocked.BlockedAt.Valid)
+		require.Empty(t, unblocked.BlockedReason)
+
+		_, err = repo.UnblockUser(ctx, domain.ModerationAction{TargetID: users[0].ID})
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		history, err := repo.GetModerationHistory(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to GetModerationHistory: %v", err)
+		}
+		require.Equal(t, 2, len(history))
+		require.Equal(t, domain.ModerationActionBlock, history[0].Action)
+		require.Equal(t, users[1].ID, history[0].ModeratorID)
+		require.Equal(t, "fraud", history[0].Reason)
+		require.Equal(t, domain.ModerationActionUnblock, history[1].Action)
+	})
+
 	t.Run("test delete user", func(t *testing.T) {
 		t.Cleanup(func() {
 			err = container.Restore(ctx)
@@ -180,8 +469,17 @@ func TestUserRepository(t *testing.T) {
 
 		repo := repository.NewUserRepo(db)
 		err = repo.Delete(ctx, users[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		_, err = repo.Create(ctx, createdUser)
+		if err != nil {
+			t.Errorf("failed to create user: %v", err)
+		}
+		err = repo.Delete(ctx, createdUser.ID)
 		if err != nil {
 			t.Errorf("failed to delete user: %v", err)
 		}
+		_, err = repo.GetByID(ctx, createdUser.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
 	})
 }
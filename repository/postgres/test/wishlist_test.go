@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"context"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	repository "github.com/EmirShimshir/marketplace-repository/repository/postgres"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var createdWishlist = domain.Wishlist{
+	ID:     domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70f0a1"),
+	UserID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb"),
+	Name:   "birthday",
+	Items:  []domain.WishlistItem{},
+}
+
+var createdWishlistItem = domain.WishlistItem{
+	ID:         domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70f0b1"),
+	WishlistID: domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70f0a1"),
+	ProductID:  domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027a2"),
+}
+
+func TestWishlistRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test Create", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewWishlistRepo(db)
+		found, err := repo.Create(ctx, createdWishlist)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		require.WithinDuration(t, time.Now(), found.CreatedAt, time.Minute)
+		expected := createdWishlist
+		expected.CreatedAt = found.CreatedAt
+		require.Equal(t, expected, found)
+
+		duplicate := createdWishlist
+		duplicate.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70f0a2")
+		_, err = repo.Create(ctx, duplicate)
+		require.ErrorIs(t, err, domain.ErrDuplicate)
+
+		wishlists, err := repo.GetByUserID(ctx, createdWishlist.UserID)
+		if err != nil {
+			t.Errorf("failed to GetByUserID: %v", err)
+		}
+		require.Equal(t, []domain.Wishlist{found}, wishlists)
+	})
+
+	t.Run("test Update and Delete", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewWishlistRepo(db)
+		_, err = repo.Create(ctx, createdWishlist)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		renamed := createdWishlist
+		renamed.Name = "later"
+		found, err := repo.Update(ctx, renamed)
+		if err != nil {
+			t.Errorf("failed to Update: %v", err)
+		}
+		require.Equal(t, renamed.Name, found.Name)
+
+		err = repo.Delete(ctx, createdWishlist.ID)
+		if err != nil {
+			t.Errorf("failed to Delete: %v", err)
+		}
+		_, err = repo.GetByID(ctx, createdWishlist.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+
+	t.Run("test AddItem", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewWishlistRepo(db)
+		_, err = repo.Create(ctx, createdWishlist)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		found, err := repo.AddItem(ctx, createdWishlistItem)
+		if err != nil {
+			t.Errorf("failed to AddItem: %v", err)
+		}
+		require.WithinDuration(t, time.Now(), found.AddedAt, time.Minute)
+		expected := createdWishlistItem
+		expected.AddedAt = found.AddedAt
+		require.Equal(t, expected, found)
+
+		duplicate := createdWishlistItem
+		duplicate.ID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70f0b2")
+		_, err = repo.AddItem(ctx, duplicate)
+		require.ErrorIs(t, err, domain.ErrDuplicate)
+
+		wishlist, err := repo.GetByID(ctx, createdWishlist.ID)
+		if err != nil {
+			t.Errorf("failed to GetByID: %v", err)
+		}
+		require.Equal(t, []domain.WishlistItem{found}, wishlist.Items)
+	})
+
+	t.Run("test MoveToCart", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewWishlistRepo(db)
+		_, err = repo.Create(ctx, createdWishlist)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+		_, err = repo.AddItem(ctx, createdWishlistItem)
+		if err != nil {
+			t.Errorf("failed to AddItem: %v", err)
+		}
+
+		cartItem := domain.CartItem{
+			ID:       domain.ID("30e18bc1-4354-4937-9a3b-03cf0b70f0c1"),
+			CartID:   carts[1].ID,
+			Quantity: 2,
+		}
+		_, err = repo.MoveToCart(ctx, createdWishlistItem.ID, cartItem)
+		require.ErrorIs(t, err, domain.ErrNotAllowed)
+
+		cartItem.CartID = carts[0].ID
+		found, err := repo.MoveToCart(ctx, createdWishlistItem.ID, cartItem)
+		if err != nil {
+			t.Errorf("failed to MoveToCart: %v", err)
+		}
+		require.Equal(t, cartItems[1].ID, found.ID)
+		require.Equal(t, cartItems[1].ProductID, found.ProductID)
+		require.Equal(t, cartItems[1].Quantity+cartItem.Quantity, found.Quantity)
+
+		_, err = repo.GetItemByID(ctx, createdWishlistItem.ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+
+	t.Run("test MoveFromCart", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewWishlistRepo(db)
+		_, err = repo.Create(ctx, createdWishlist)
+		if err != nil {
+			t.Errorf("failed to Create: %v", err)
+		}
+
+		found, err := repo.MoveFromCart(ctx, cartItems[0].ID, createdWishlistItem)
+		if err != nil {
+			t.Errorf("failed to MoveFromCart: %v", err)
+		}
+		require.Equal(t, createdWishlistItem.ID, found.ID)
+		require.Equal(t, cartItems[0].ProductID, found.ProductID)
+
+		_, err = repository.NewCartRepo(db).GetCartItemByID(ctx, cartItems[0].ID)
+		require.ErrorIs(t, err, domain.ErrNotExist)
+	})
+}
@@ -3,11 +3,18 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
 	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/EmirShimshir/marketplace-repository/repository/usersearch"
+	"github.com/guregu/null"
 	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"strings"
+	"time"
 )
 
 type PostgresUserRepo struct {
@@ -23,10 +30,29 @@ func NewUserRepo(db *sqlx.DB) *PostgresUserRepo {
 const (
 	userGetQuery        = "SELECT * FROM public.user LIMIT $1 OFFSET $2"
 	userGetByIDQuery    = "SELECT * FROM public.user WHERE id = $1"
-	userGetByEmailQuery = "SELECT * FROM public.user WHERE email = $1"
+	userGetByEmailQuery = "SELECT * FROM public.user WHERE email_hash = $1"
 	userDeleteQuery     = "DELETE FROM public.user WHERE id = $1"
 )
 
+const (
+	userGetForUpdateQuery = "SELECT * FROM public.user WHERE id = $1 FOR UPDATE"
+	userBlockQuery        = "UPDATE public.user SET blocked_at = $2, blocked_reason = $3 WHERE id = $1"
+	userUnblockQuery      = "UPDATE public.user SET blocked_at = NULL, blocked_reason = '' WHERE id = $1"
+)
+
+const (
+	userDeletePendingTokensQuery = "DELETE FROM public.user_token WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL"
+)
+
+const (
+	userEraseOrdersQuery = "UPDATE public.order_customer SET address = '', shipping_address = shipping_address || " +
+		"'{\"recipient\": \"\", \"phone\": \"\", \"street\": \"\", \"postal_code\": \"\"}'::jsonb WHERE customer_id = $1"
+	userEraseAddressesQuery = "DELETE FROM public.user_address WHERE user_id = $1"
+	userEraseWishlistsQuery = "DELETE FROM public.wishlist WHERE user_id = $1"
+	userEraseSessionsQuery  = "DELETE FROM public.user_session WHERE user_id = $1"
+	userEraseTokensQuery    = "DELETE FROM public.user_token WHERE user_id = $1"
+)
+
 func (u *PostgresUserRepo) Get(ctx context.Context, limit, offset int64) ([]domain.User, error) {
 	var pgUsers []entity.PgUser
 	if err := u.db.SelectContext(ctx, &pgUsers, userGetQuery, limit, offset); err != nil {
@@ -39,7 +65,10 @@ func (u *PostgresUserRepo) Get(ctx context.Context, limit, offset int64) ([]doma
 
 	users := make([]domain.User, len(pgUsers))
 	for i, user := range pgUsers {
-		users[i] = user.ToDomain()
+		var err error
+		if users[i], err = user.ToDomain(); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
 	}
 	return users, nil
 }
@@ -53,12 +82,16 @@ func (u *PostgresUserRepo) GetByID(ctx context.Context, userID domain.ID) (domai
 			return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 		}
 	}
-	return pgUser.ToDomain(), nil
+	user, err := pgUser.ToDomain()
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return user, nil
 }
 
 func (u *PostgresUserRepo) GetByEmail(ctx context.Context, email string) (domain.User, error) {
 	var pgUser entity.PgUser
-	err := u.db.GetContext(ctx, &pgUser, userGetByEmailQuery, email)
+	err := u.db.GetContext(ctx, &pgUser, userGetByEmailQuery, encryption.BlindIndex(email))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return domain.User{}, errors.Wrap(domain.ErrNotExist, err.Error())
@@ -66,7 +99,117 @@ func (u *PostgresUserRepo) GetByEmail(ctx context.Context, email string) (domain
 			return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
 		}
 	}
-	return pgUser.ToDomain(), nil
+	user, err := pgUser.ToDomain()
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return user, nil
+}
+
+// likePrefix turns a prefix into a case-insensitive LIKE pattern.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(prefix)) + "%"
+}
+
+// SearchUsers lists the users matching the query for moderators. Name and
+// surname match by case-insensitive prefix. Email and phone are stored
+// encrypted, so they only match exactly through their blind indexes. The
+// returned cursor fetches the next page and is empty on the last page.
+func (u *PostgresUserRepo) SearchUsers(ctx context.Context, query domain.UserQuery) ([]domain.User, string, error) {
+	var cursor usersearch.Cursor
+	if query.Cursor != "" {
+		var err error
+		if cursor, err = usersearch.DecodeCursor(query.Cursor); err != nil {
+			return nil, "", err
+		}
+	}
+	if query.Limit <= 0 {
+		query.Limit = usersearch.DefaultLimit
+	}
+
+	conditions := []string{"true"}
+	var args []interface{}
+	param := func(arg interface{}) string {
+		args = append(args, arg)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if len(query.Roles) > 0 {
+		roles := make([]string, len(query.Roles))
+		for i, role := range query.Roles {
+			roles[i] = param(entity.NewPgUserRole(role)) + "::user_role"
+		}
+		conditions = append(conditions, "role IN ("+strings.Join(roles, ", ")+")")
+	}
+	if query.NamePrefix != "" {
+		conditions = append(conditions, "lower(name) LIKE "+param(likePrefix(query.NamePrefix)))
+	}
+	if query.SurnamePrefix != "" {
+		conditions = append(conditions, "lower(surname) LIKE "+param(likePrefix(query.SurnamePrefix)))
+	}
+	if query.Email != "" {
+		conditions = append(conditions, "email_hash = "+param(encryption.BlindIndex(query.Email)))
+	}
+	if query.Phone != "" {
+		conditions = append(conditions, "phone_hash = "+param(encryption.BlindIndex(query.Phone)))
+	}
+	if query.Blocked.Valid {
+		if query.Blocked.Bool {
+			conditions = append(conditions, "blocked_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "blocked_at IS NULL")
+		}
+	}
+
+	var column string
+	switch query.SortBy {
+	case domain.UserSortByName:
+		column = "name"
+	case domain.UserSortBySurname:
+		column = "surname"
+	}
+	direction, compare := "ASC", ">"
+	if query.Descending {
+		direction, compare = "DESC", "<"
+	}
+	order := "id " + direction
+	if column != "" {
+		order = column + " " + direction + ", " + order
+	}
+	if query.Cursor != "" {
+		if column == "" {
+			conditions = append(conditions, "id "+compare+" "+param(cursor.ID)+"::uuid")
+		} else {
+			conditions = append(conditions, "("+column+", id) "+compare+
+				" ("+param(cursor.Value)+"::varchar, "+param(cursor.ID)+"::uuid)")
+		}
+	}
+	sqlQuery := "SELECT * FROM public.user WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + order + " LIMIT " + param(query.Limit+1)
+
+	var pgUsers []entity.PgUser
+	if err := u.db.SelectContext(ctx, &pgUsers, sqlQuery, args...); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, "", errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	more := int64(len(pgUsers)) > query.Limit
+	if more {
+		pgUsers = pgUsers[:query.Limit]
+	}
+
+	users := make([]domain.User, len(pgUsers))
+	for i, pgUser := range pgUsers {
+		var err error
+		if users[i], err = pgUser.ToDomain(); err != nil {
+			return nil, "", errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	var next string
+	if more {
+		next = usersearch.NewCursor(users[len(users)-1], query.SortBy).Encode()
+	}
+	return users, next, nil
 }
 
 func (u *PostgresUserRepo) Create(ctx context.Context, user domain.User) (domain.User, error) {
@@ -75,7 +218,8 @@ func (u *PostgresUserRepo) Create(ctx context.Context, user domain.User) (domain
 		return domain.User{}, errors.Wrap(domain.ErrTransactionError, err.Error())
 	}
 
-	var pgCart = entity.NewPgCart(domain.Cart{ID: user.CartID, Price: 0})
+	now := time.Now().UTC()
+	var pgCart = entity.NewPgCart(domain.Cart{ID: user.CartID, Price: 0, CreatedAt: now, UpdatedAt: now})
 	queryString := entity.InsertQueryString(pgCart, "cart")
 	_, err = tx.NamedExecContext(ctx, queryString, pgCart)
 	if err != nil {
@@ -92,7 +236,11 @@ func (u *PostgresUserRepo) Create(ctx context.Context, user domain.User) (domain
 		}
 	}
 
-	var pgUser = entity.NewPgUser(user)
+	pgUser, err := entity.NewPgUser(user)
+	if err != nil {
+		tx.Rollback()
+		return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
 	queryString = entity.InsertQueryString(pgUser, "user")
 	_, err = tx.NamedExecContext(ctx, queryString, pgUser)
 	if err != nil {
@@ -117,21 +265,258 @@ func (u *PostgresUserRepo) Create(ctx context.Context, user domain.User) (domain
 	return u.GetByID(ctx, user.ID)
 }
 
+// Update rewrites the user fields, the block state is only changed by
+// BlockUser and UnblockUser and the email is only verified by consuming a
+// verification token. A changed email is no longer verified and the pending
+// verification tokens of the old email are deleted with the change.
 func (u *PostgresUserRepo) Update(ctx context.Context, user domain.User) (domain.User, error) {
-	var pgUser = entity.NewPgUser(user)
+	current, err := u.GetByID(ctx, user.ID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	user.BlockedAt = current.BlockedAt
+	user.BlockedReason = current.BlockedReason
+	user.EmailVerifiedAt = current.EmailVerifiedAt
+	if user.Email != current.Email {
+		user.EmailVerifiedAt = null.Time{}
+	}
+
+	pgUser, err := entity.NewPgUser(user)
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	// the tokens are deleted before the user is written, a token consumed
+	// meanwhile holds its row until the consume commits and verifies the old
+	// email, which the update below then clears
+	if user.Email != current.Email {
+		_, err = tx.ExecContext(ctx, userDeletePendingTokensQuery, user.ID,
+			entity.NewPgTokenPurpose(domain.TokenPurposeEmailVerification))
+		if err != nil {
+			tx.Rollback()
+			return domain.User{}, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+		}
+	}
 	queryString := entity.UpdateQueryString(pgUser, "user")
-	_, err := u.db.NamedExecContext(ctx, queryString, pgUser)
+	_, err = tx.NamedExecContext(ctx, queryString, pgUser)
 	if err != nil {
+		tx.Rollback()
 		return domain.User{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
 	}
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return domain.User{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
 
 	return u.GetByID(ctx, user.ID)
 }
 
+// Delete removes the user together with the addresses, the wishlists, the
+// sessions and the tokens of the user. A user with orders, shops or reviews
+// is not deleted, EraseUser anonymizes it instead.
 func (u *PostgresUserRepo) Delete(ctx context.Context, userID domain.ID) error {
 	_, err := u.db.ExecContext(ctx, userDeleteQuery, userID)
 	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgForeignKeyViolationCode {
+			return errors.Wrap(domain.ErrNotAllowed, "user has orders, shops or reviews, erase the user instead")
+		}
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// ExportUserData returns the personal data of the user as a JSON encoded
+// domain.UserDataExport. The password hash is left out.
+func (u *PostgresUserRepo) ExportUserData(ctx context.Context, userID domain.ID) ([]byte, error) {
+	user, err := u.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Password = ""
+
+	export := domain.UserDataExport{
+		User:       user,
+		ExportedAt: time.Now().UTC(),
+	}
+	if export.Cart, err = NewCartRepo(u.db).GetCartByID(ctx, user.CartID); err != nil {
+		return nil, err
+	}
+	if export.Addresses, err = NewUserAddressRepo(u.db).GetByUserID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Orders, err = NewOrderRepo(u.db).GetOrderCustomerByCustomerID(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Shops, err = NewShopRepo(u.db).GetShopBySellerID(ctx, userID); err != nil {
+		return nil, err
+	}
+	export.Withdraws = make([]domain.Withdraw, 0)
+	for _, shop := range export.Shops {
+		withdraws, err := NewWithdrawRepo(u.db).GetByShopID(ctx, shop.ID)
+		if err != nil {
+			return nil, err
+		}
+		export.Withdraws = append(export.Withdraws, withdraws...)
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return data, nil
+}
+
+// erasedUser replaces the personal data of the user with placeholders, the
+// email stays unique so the row keeps satisfying its constraints.
+func erasedUser(user domain.User) domain.User {
+	user.Name = "Deleted"
+	user.Surname = "User"
+	user.Phone = null.String{}
+	user.Email = fmt.Sprintf("erased-%s@erased.invalid", user.ID)
+	user.Password = ""
+	user.EmailVerifiedAt = null.Time{}
+	return user
+}
+
+// EraseUser anonymizes the user instead of deleting it, so the orders, shops
+// and withdraws that sellers and accounting rely on are kept. The contact
+// details of the orders are cleared, the country and city of the shipping
+// address stay for tax reporting. Addresses, wishlists, cart items, sessions
+// and tokens of the user are deleted.
+func (u *PostgresUserRepo) EraseUser(ctx context.Context, userID domain.ID) error {
+	user, err := u.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	pgUser, err := entity.NewPgUser(erasedUser(user))
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if _, err = tx.NamedExecContext(ctx, entity.UpdateQueryString(pgUser, "user"), pgUser); err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if _, err = tx.ExecContext(ctx, userEraseOrdersQuery, userID); err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if _, err = tx.ExecContext(ctx, cartItemsDeleteByCartIDQuery, user.CartID); err != nil {
+		tx.Rollback()
 		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
 	}
+	if _, err = tx.ExecContext(ctx, cartUpdatePriceQuery, user.CartID, 0); err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	for _, query := range []string{userEraseAddressesQuery, userEraseWishlistsQuery,
+		userEraseSessionsQuery, userEraseTokensQuery} {
+		if _, err = tx.ExecContext(ctx, query, userID); err != nil {
+			tx.Rollback()
+			return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
 	return nil
 }
+
+// txGetUserForUpdate locks the user row until the end of the transaction.
+func txGetUserForUpdate(ctx context.Context, tx *sqlx.Tx, userID domain.ID) (entity.PgUser, error) {
+	var pgUser entity.PgUser
+	if err := tx.GetContext(ctx, &pgUser, userGetForUpdateQuery, userID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return entity.PgUser{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return entity.PgUser{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgUser, nil
+}
+
+// BlockUser blocks the user given by the TargetID of the action and revokes
+// the active sessions of the user. The action is added to the moderation
+// history of the user, its type and time are set here.
+func (u *PostgresUserRepo) BlockUser(ctx context.Context, action domain.ModerationAction) (domain.User, error) {
+	action.Action = domain.ModerationActionBlock
+	action.CreatedAt = time.Now().UTC()
+
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	pgUser, err := txGetUserForUpdate(ctx, tx, action.TargetID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if pgUser.BlockedAt.Valid {
+		tx.Rollback()
+		return domain.User{}, errors.Wrap(domain.ErrNotAllowed, "user is already blocked")
+	}
+	if _, err = tx.ExecContext(ctx, userBlockQuery, action.TargetID, action.CreatedAt, action.Reason); err != nil {
+		tx.Rollback()
+		return domain.User{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if _, err = tx.ExecContext(ctx, sessionRevokeAllQuery, action.TargetID, action.CreatedAt); err != nil {
+		tx.Rollback()
+		return domain.User{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if err = txAddModerationAction(ctx, tx, entity.PgModerationTargetUser, action); err != nil {
+		return domain.User{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return u.GetByID(ctx, action.TargetID)
+}
+
+// UnblockUser lifts the block of the user given by the TargetID of the
+// action and adds the action to the moderation history of the user.
+func (u *PostgresUserRepo) UnblockUser(ctx context.Context, action domain.ModerationAction) (domain.User, error) {
+	action.Action = domain.ModerationActionUnblock
+	action.CreatedAt = time.Now().UTC()
+
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	pgUser, err := txGetUserForUpdate(ctx, tx, action.TargetID)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if !pgUser.BlockedAt.Valid {
+		tx.Rollback()
+		return domain.User{}, errors.Wrap(domain.ErrNotAllowed, "user is not blocked")
+	}
+	if _, err = tx.ExecContext(ctx, userUnblockQuery, action.TargetID); err != nil {
+		tx.Rollback()
+		return domain.User{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if err = txAddModerationAction(ctx, tx, entity.PgModerationTargetUser, action); err != nil {
+		return domain.User{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.User{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return u.GetByID(ctx, action.TargetID)
+}
+
+// GetModerationHistory returns the moderation actions taken on the user,
+// the oldest first.
+func (u *PostgresUserRepo) GetModerationHistory(ctx context.Context, userID domain.ID) ([]domain.ModerationAction, error) {
+	return getModerationHistory(ctx, u.db, entity.PgModerationTargetUser, userID)
+}
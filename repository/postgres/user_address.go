@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+type PostgresUserAddressRepo struct {
+	db *sqlx.DB
+}
+
+func NewUserAddressRepo(db *sqlx.DB) *PostgresUserAddressRepo {
+	return &PostgresUserAddressRepo{
+		db: db,
+	}
+}
+
+const (
+	userAddressGetByIDQuery      = "SELECT * FROM public.user_address WHERE id = $1"
+	userAddressGetByUserIDQuery  = "SELECT * FROM public.user_address WHERE user_id = $1 ORDER BY is_default DESC, id"
+	userAddressDeleteQuery       = "DELETE FROM public.user_address WHERE id = $1"
+	userAddressClearDefaultQuery = "UPDATE public.user_address SET is_default = false WHERE user_id = $1 AND id <> $2 AND is_default"
+	userAddressSetDefaultQuery   = "UPDATE public.user_address SET is_default = true WHERE id = $1 AND user_id = $2"
+)
+
+func (u *PostgresUserAddressRepo) GetByID(ctx context.Context, addressID domain.ID) (domain.UserAddress, error) {
+	var pgUserAddress entity.PgUserAddress
+	if err := u.db.GetContext(ctx, &pgUserAddress, userAddressGetByIDQuery, addressID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.UserAddress{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	address, err := pgUserAddress.ToDomain()
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return address, nil
+}
+
+// GetByUserID returns the addresses of the user, the default one comes first.
+func (u *PostgresUserAddressRepo) GetByUserID(ctx context.Context, userID domain.ID) ([]domain.UserAddress, error) {
+	var pgUserAddresses []entity.PgUserAddress
+	if err := u.db.SelectContext(ctx, &pgUserAddresses, userAddressGetByUserIDQuery, userID); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	addresses := make([]domain.UserAddress, len(pgUserAddresses))
+	for i, pgUserAddress := range pgUserAddresses {
+		var err error
+		if addresses[i], err = pgUserAddress.ToDomain(); err != nil {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return addresses, nil
+}
+
+// txClearDefault drops the default flag from every other address of the user,
+// so that at most one address stays the default.
+func txClearDefault(ctx context.Context, tx *sqlx.Tx, userID, addressID domain.ID) error {
+	if _, err := tx.ExecContext(ctx, userAddressClearDefaultQuery, userID, addressID); err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	return nil
+}
+
+func (u *PostgresUserAddressRepo) Create(ctx context.Context, address domain.UserAddress) (domain.UserAddress, error) {
+	pgUserAddress, err := entity.NewPgUserAddress(address)
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	if pgUserAddress.IsDefault {
+		if err = txClearDefault(ctx, tx, address.UserID, address.ID); err != nil {
+			return domain.UserAddress{}, err
+		}
+	}
+	queryString := entity.InsertQueryString(pgUserAddress, "user_address")
+	_, err = tx.NamedExecContext(ctx, queryString, pgUserAddress)
+	if err != nil {
+		tx.Rollback()
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == PgUniqueViolationCode {
+				return domain.UserAddress{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else if pgErr.Code == PgForeignKeyViolationCode {
+				return domain.UserAddress{}, errors.Wrap(domain.ErrNotExist, err.Error())
+			} else {
+				return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+		} else {
+			return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return u.GetByID(ctx, address.ID)
+}
+
+// Update rewrites the address fields, the owner of the address can not be changed.
+func (u *PostgresUserAddressRepo) Update(ctx context.Context, address domain.UserAddress) (domain.UserAddress, error) {
+	current, err := u.GetByID(ctx, address.ID)
+	if err != nil {
+		return domain.UserAddress{}, err
+	}
+	address.UserID = current.UserID
+	pgUserAddress, err := entity.NewPgUserAddress(address)
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	if pgUserAddress.IsDefault {
+		if err = txClearDefault(ctx, tx, address.UserID, address.ID); err != nil {
+			return domain.UserAddress{}, err
+		}
+	}
+	queryString := entity.UpdateQueryString(pgUserAddress, "user_address")
+	_, err = tx.NamedExecContext(ctx, queryString, pgUserAddress)
+	if err != nil {
+		tx.Rollback()
+		return domain.UserAddress{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.UserAddress{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return u.GetByID(ctx, address.ID)
+}
+
+func (u *PostgresUserAddressRepo) Delete(ctx context.Context, addressID domain.ID) error {
+	_, err := u.db.ExecContext(ctx, userAddressDeleteQuery, addressID)
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// SetDefault makes the address the default one of the user.
+func (u *PostgresUserAddressRepo) SetDefault(ctx context.Context, userID domain.ID, addressID domain.ID) error {
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	if err = txClearDefault(ctx, tx, userID, addressID); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, userAddressSetDefaultQuery, addressID, userID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrNotExist, "address of the user not found")
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+	return nil
+}
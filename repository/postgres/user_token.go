@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/encryption"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/guregu/null"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type PostgresUserTokenRepo struct {
+	db *sqlx.DB
+}
+
+func NewUserTokenRepo(db *sqlx.DB) *PostgresUserTokenRepo {
+	return &PostgresUserTokenRepo{
+		db: db,
+	}
+}
+
+const (
+	userTokenGetByIDQuery = "SELECT * FROM public.user_token WHERE id = $1"
+	userTokenConsumeQuery = "UPDATE public.user_token SET consumed_at = $3 " +
+		"WHERE token_hash = $1 AND purpose = $2 AND consumed_at IS NULL AND expires_at > $3 RETURNING *"
+	userTokenVerifyEmailQuery  = "UPDATE public.user SET email_verified_at = COALESCE(email_verified_at, $2) WHERE id = $1"
+	userTokenPurgeExpiredQuery = "DELETE FROM public.user_token WHERE expires_at < $1"
+)
+
+func (u *PostgresUserTokenRepo) GetByID(ctx context.Context, tokenID domain.ID) (domain.UserToken, error) {
+	var pgUserToken entity.PgUserToken
+	if err := u.db.GetContext(ctx, &pgUserToken, userTokenGetByIDQuery, tokenID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.UserToken{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.UserToken{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgUserToken.ToDomain(), nil
+}
+
+// Create stores the token with the hash of rawToken, the TokenHash of token
+// is ignored.
+func (u *PostgresUserTokenRepo) Create(ctx context.Context, token domain.UserToken, rawToken string) (domain.UserToken, error) {
+	var pgUserToken = entity.NewPgUserToken(token)
+	pgUserToken.TokenHash = encryption.HashToken(rawToken)
+	pgUserToken.CreatedAt = time.Now().UTC()
+	pgUserToken.ExpiresAt = token.ExpiresAt.UTC()
+	pgUserToken.ConsumedAt = null.Time{}
+	queryString := entity.InsertQueryString(pgUserToken, "user_token")
+	_, err := u.db.NamedExecContext(ctx, queryString, pgUserToken)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == PgUniqueViolationCode {
+				return domain.UserToken{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else if pgErr.Code == PgForeignKeyViolationCode {
+				return domain.UserToken{}, errors.Wrap(domain.ErrNotExist, err.Error())
+			} else {
+				return domain.UserToken{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+		} else {
+			return domain.UserToken{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	return u.GetByID(ctx, token.ID)
+}
+
+// Consume marks the token as used and returns it. The check and the update
+// are a single statement, so of concurrent calls only one succeeds, the
+// others get ErrNotExist like for unknown or expired tokens. Consuming an
+// email verification token also marks the email of the user as verified.
+func (u *PostgresUserTokenRepo) Consume(ctx context.Context, rawToken string, purpose domain.TokenPurpose) (domain.UserToken, error) {
+	tx, err := u.db.Beginx()
+	if err != nil {
+		return domain.UserToken{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	now := time.Now().UTC()
+	var pgUserToken entity.PgUserToken
+	err = tx.GetContext(ctx, &pgUserToken, userTokenConsumeQuery, encryption.HashToken(rawToken), entity.NewPgTokenPurpose(purpose), now)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.UserToken{}, errors.Wrap(domain.ErrNotExist, "token is unknown, expired or already used")
+		} else {
+			return domain.UserToken{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+	}
+	if purpose == domain.TokenPurposeEmailVerification {
+		if _, err = tx.ExecContext(ctx, userTokenVerifyEmailQuery, pgUserToken.UserID, now); err != nil {
+			tx.Rollback()
+			return domain.UserToken{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.UserToken{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return pgUserToken.ToDomain(), nil
+}
+
+// PurgeExpired removes the tokens that expired before olderThan, consumed or
+// not. It is meant to be run on a schedule.
+func (u *PostgresUserTokenRepo) PurgeExpired(ctx context.Context, olderThan time.Time) (int64, error) {
+	res, err := u.db.ExecContext(ctx, userTokenPurgeExpiredQuery, olderThan.UTC())
+	if err != nil {
+		return 0, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	affected, _ := res.RowsAffected()
+	return affected, nil
+}
@@ -0,0 +1,299 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type PostgresWishlistRepo struct {
+	db *sqlx.DB
+}
+
+func NewWishlistRepo(db *sqlx.DB) *PostgresWishlistRepo {
+	return &PostgresWishlistRepo{
+		db: db,
+	}
+}
+
+const (
+	wishlistGetByIDQuery              = "SELECT * FROM public.wishlist WHERE id = $1"
+	wishlistGetByUserIDQuery          = "SELECT * FROM public.wishlist WHERE user_id = $1 ORDER BY created_at, name"
+	wishlistUpdateQuery               = "UPDATE public.wishlist SET name = $2 WHERE id = $1"
+	wishlistDeleteQuery               = "DELETE FROM public.wishlist WHERE id = $1"
+	wishlistItemsGetQuery             = "SELECT * FROM public.wishlist_product WHERE wishlist_id = $1 ORDER BY added_at, id"
+	wishlistItemGetByIDQuery          = "SELECT * FROM public.wishlist_product WHERE id = $1"
+	wishlistItemGetForUpdateQuery     = "SELECT * FROM public.wishlist_product WHERE id = $1 FOR UPDATE"
+	wishlistItemDeleteQuery           = "DELETE FROM public.wishlist_product WHERE id = $1"
+	wishlistCartItemGetForUpdateQuery = "SELECT * FROM public.cart_product WHERE id = $1 FOR UPDATE"
+	wishlistIsCartOwnerQuery          = "SELECT EXISTS (SELECT 1 FROM public.wishlist w " +
+		"JOIN public.user u ON u.id = w.user_id WHERE w.id = $1 AND u.cart_id = $2)"
+	wishlistMoveToCartQuery = "INSERT INTO public.cart_product " +
+		"(id, cart_id, product_id, variant_id, quantity, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $6) " +
+		"ON CONFLICT (cart_id, product_id, coalesce(variant_id, '00000000-0000-0000-0000-000000000000')) " +
+		"DO UPDATE SET quantity = public.cart_product.quantity + EXCLUDED.quantity, updated_at = EXCLUDED.updated_at RETURNING id"
+	wishlistMoveFromCartQuery = "INSERT INTO public.wishlist_product " +
+		"(id, wishlist_id, product_id, variant_id, added_at) VALUES ($1, $2, $3, $4, $5) " +
+		"ON CONFLICT (wishlist_id, product_id, coalesce(variant_id, '00000000-0000-0000-0000-000000000000')) " +
+		"DO UPDATE SET added_at = EXCLUDED.added_at RETURNING id"
+)
+
+func (w *PostgresWishlistRepo) getWishlistItems(ctx context.Context, wishlistID domain.ID) ([]domain.WishlistItem, error) {
+	var pgWishlistItems []entity.PgWishlistItem
+	if err := w.db.SelectContext(ctx, &pgWishlistItems, wishlistItemsGetQuery, wishlistID); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	wishlistItems := make([]domain.WishlistItem, len(pgWishlistItems))
+	for i, wishlistItem := range pgWishlistItems {
+		wishlistItems[i] = wishlistItem.ToDomain()
+	}
+	return wishlistItems, nil
+}
+
+func (w *PostgresWishlistRepo) GetByID(ctx context.Context, wishlistID domain.ID) (domain.Wishlist, error) {
+	var pgWishlist entity.PgWishlist
+	if err := w.db.GetContext(ctx, &pgWishlist, wishlistGetByIDQuery, wishlistID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Wishlist{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.Wishlist{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	wishlistItems, err := w.getWishlistItems(ctx, wishlistID)
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+
+	wishlist := pgWishlist.ToDomain()
+	wishlist.Items = wishlistItems
+	return wishlist, nil
+}
+
+func (w *PostgresWishlistRepo) GetByUserID(ctx context.Context, userID domain.ID) ([]domain.Wishlist, error) {
+	var pgWishlists []entity.PgWishlist
+	if err := w.db.SelectContext(ctx, &pgWishlists, wishlistGetByUserIDQuery, userID); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	wishlists := make([]domain.Wishlist, len(pgWishlists))
+	for i, pgWishlist := range pgWishlists {
+		wishlist := pgWishlist.ToDomain()
+		wishlistItems, err := w.getWishlistItems(ctx, wishlist.ID)
+		if err != nil {
+			return nil, err
+		}
+		wishlist.Items = wishlistItems
+		wishlists[i] = wishlist
+	}
+	return wishlists, nil
+}
+
+func (w *PostgresWishlistRepo) Create(ctx context.Context, wishlist domain.Wishlist) (domain.Wishlist, error) {
+	var pgWishlist = entity.NewPgWishlist(wishlist)
+	pgWishlist.CreatedAt = time.Now().UTC()
+	queryString := entity.InsertQueryString(pgWishlist, "wishlist")
+	_, err := w.db.NamedExecContext(ctx, queryString, pgWishlist)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == PgUniqueViolationCode {
+				return domain.Wishlist{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else if pgErr.Code == PgForeignKeyViolationCode {
+				return domain.Wishlist{}, errors.Wrap(domain.ErrNotExist, err.Error())
+			} else {
+				return domain.Wishlist{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+		} else {
+			return domain.Wishlist{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	return w.GetByID(ctx, wishlist.ID)
+}
+
+// Update renames the wishlist, the owner and the items are left untouched.
+func (w *PostgresWishlistRepo) Update(ctx context.Context, wishlist domain.Wishlist) (domain.Wishlist, error) {
+	_, err := w.db.ExecContext(ctx, wishlistUpdateQuery, wishlist.ID, wishlist.Name)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return domain.Wishlist{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+		}
+		return domain.Wishlist{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+
+	return w.GetByID(ctx, wishlist.ID)
+}
+
+func (w *PostgresWishlistRepo) Delete(ctx context.Context, wishlistID domain.ID) error {
+	_, err := w.db.ExecContext(ctx, wishlistDeleteQuery, wishlistID)
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+func (w *PostgresWishlistRepo) GetItemByID(ctx context.Context, wishlistItemID domain.ID) (domain.WishlistItem, error) {
+	var pgWishlistItem entity.PgWishlistItem
+	if err := w.db.GetContext(ctx, &pgWishlistItem, wishlistItemGetByIDQuery, wishlistItemID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.WishlistItem{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.WishlistItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	return pgWishlistItem.ToDomain(), nil
+}
+
+func (w *PostgresWishlistRepo) AddItem(ctx context.Context, wishlistItem domain.WishlistItem) (domain.WishlistItem, error) {
+	var pgWishlistItem = entity.NewPgWishlistItem(wishlistItem)
+	pgWishlistItem.AddedAt = time.Now().UTC()
+	queryString := entity.InsertQueryString(pgWishlistItem, "wishlist_product")
+	_, err := w.db.NamedExecContext(ctx, queryString, pgWishlistItem)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == PgUniqueViolationCode {
+				return domain.WishlistItem{}, errors.Wrap(domain.ErrDuplicate, err.Error())
+			} else if pgErr.Code == PgForeignKeyViolationCode {
+				return domain.WishlistItem{}, errors.Wrap(domain.ErrNotExist, err.Error())
+			} else {
+				return domain.WishlistItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+			}
+		} else {
+			return domain.WishlistItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+
+	return w.GetItemByID(ctx, wishlistItem.ID)
+}
+
+func (w *PostgresWishlistRepo) DeleteItem(ctx context.Context, wishlistItemID domain.ID) error {
+	_, err := w.db.ExecContext(ctx, wishlistItemDeleteQuery, wishlistItemID)
+	if err != nil {
+		return errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	return nil
+}
+
+// txCheckCartOwner makes sure the cart belongs to the owner of the wishlist.
+func txCheckCartOwner(ctx context.Context, tx *sqlx.Tx, wishlistID, cartID domain.ID) error {
+	var owner bool
+	if err := tx.GetContext(ctx, &owner, wishlistIsCartOwnerQuery, wishlistID, cartID); err != nil {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if !owner {
+		tx.Rollback()
+		return errors.Wrap(domain.ErrNotAllowed, "cart does not belong to the wishlist owner")
+	}
+	return nil
+}
+
+// MoveToCart removes the item from the wishlist and puts it into the cart
+// of the wishlist owner, summing the quantity if the cart already has it.
+// Only the ID, the CartID and the Quantity of cartItem are used.
+func (w *PostgresWishlistRepo) MoveToCart(ctx context.Context, wishlistItemID domain.ID, cartItem domain.CartItem) (domain.CartItem, error) {
+	if cartItem.Quantity < 1 {
+		return domain.CartItem{}, errors.Wrap(domain.ErrNotAllowed, "quantity must be positive")
+	}
+
+	tx, err := w.db.Beginx()
+	if err != nil {
+		return domain.CartItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var pgWishlistItem entity.PgWishlistItem
+	if err = tx.GetContext(ctx, &pgWishlistItem, wishlistItemGetForUpdateQuery, wishlistItemID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.CartItem{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.CartItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	if err = txCheckCartOwner(ctx, tx, domain.ID(pgWishlistItem.WishlistID.String()), cartItem.CartID); err != nil {
+		return domain.CartItem{}, err
+	}
+
+	now := time.Now().UTC()
+	var cartItemID uuid.UUID
+	err = tx.GetContext(ctx, &cartItemID, wishlistMoveToCartQuery, cartItem.ID, cartItem.CartID,
+		pgWishlistItem.ProductID, pgWishlistItem.VariantID, cartItem.Quantity, now)
+	if err != nil {
+		tx.Rollback()
+		return domain.CartItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if _, err = tx.ExecContext(ctx, wishlistItemDeleteQuery, wishlistItemID); err != nil {
+		tx.Rollback()
+		return domain.CartItem{}, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	if err = txTouchCart(ctx, tx, cartItem.CartID, now); err != nil {
+		return domain.CartItem{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.CartItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var pgCartItem entity.PgCartItem
+	if err = w.db.GetContext(ctx, &pgCartItem, cartItemGetByIQuery, cartItemID); err != nil {
+		return domain.CartItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	return pgCartItem.ToDomain(), nil
+}
+
+// MoveFromCart removes the item from the cart and saves it to the wishlist
+// of the cart owner. Only the ID and the WishlistID of wishlistItem are used.
+func (w *PostgresWishlistRepo) MoveFromCart(ctx context.Context, cartItemID domain.ID, wishlistItem domain.WishlistItem) (domain.WishlistItem, error) {
+	tx, err := w.db.Beginx()
+	if err != nil {
+		return domain.WishlistItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	var pgCartItem entity.PgCartItem
+	if err = tx.GetContext(ctx, &pgCartItem, wishlistCartItemGetForUpdateQuery, cartItemID); err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return domain.WishlistItem{}, errors.Wrap(domain.ErrNotExist, err.Error())
+		} else {
+			return domain.WishlistItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+		}
+	}
+	cartID := domain.ID(pgCartItem.CartID.String())
+	if err = txCheckCartOwner(ctx, tx, wishlistItem.WishlistID, cartID); err != nil {
+		return domain.WishlistItem{}, err
+	}
+
+	now := time.Now().UTC()
+	var wishlistItemID uuid.UUID
+	err = tx.GetContext(ctx, &wishlistItemID, wishlistMoveFromCartQuery, wishlistItem.ID, wishlistItem.WishlistID,
+		pgCartItem.ProductID, pgCartItem.VariantID, now)
+	if err != nil {
+		tx.Rollback()
+		return domain.WishlistItem{}, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+	if _, err = tx.ExecContext(ctx, cartItemDeleteQuery, cartItemID); err != nil {
+		tx.Rollback()
+		return domain.WishlistItem{}, errors.Wrap(domain.ErrDeleteFailed, err.Error())
+	}
+	if err = txTouchCart(ctx, tx, cartID, now); err != nil {
+		return domain.WishlistItem{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return domain.WishlistItem{}, errors.Wrap(domain.ErrTransactionError, err.Error())
+	}
+
+	return w.GetItemByID(ctx, domain.ID(wishlistItemID.String()))
+}
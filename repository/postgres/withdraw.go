@@ -4,10 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/EmirShimshir/marketplace-repository/repository/currency"
 	"github.com/EmirShimshir/marketplace-repository/repository/postgres/entity"
+	"github.com/guregu/null"
 	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
+	"time"
 )
 
 type PostgresWithdrawRepo struct {
@@ -25,6 +28,19 @@ const (
 	withdrawGetByIDQuery     = "SELECT * FROM public.withdraw WHERE id = $1"
 	withdrawGetByShopIDQuery = "SELECT * FROM public.withdraw WHERE shop_id = $1"
 	WithdrawDeleteQuery      = "SELECT * FROM public.withdraw WHERE id = $1"
+	withdrawUpdateQuery      = "UPDATE public.withdraw SET comment = $2, sum = $3 WHERE id = $1 AND (status = 'Start' OR sum = $3)"
+)
+
+const (
+	withdrawApproveQuery = "UPDATE public.withdraw SET status = 'Ready', approved_at = $2, reviewer_id = $3 " +
+		"WHERE id = $1 AND status = 'Start'"
+	withdrawPayQuery = "UPDATE public.withdraw SET status = 'Done', paid_at = $2 " +
+		"WHERE id = $1 AND status = 'Ready' AND payout_batch_id IS NULL"
+	withdrawGetByStatusQuery = "SELECT * FROM public.withdraw WHERE status = $1 " +
+		"ORDER BY coalesce(requested_at, '-infinity'), id LIMIT $2"
+	withdrawGetByStatusCursor = "SELECT w.* FROM public.withdraw w JOIN public.withdraw c ON c.id = $3 " +
+		"WHERE w.status = $1 AND (coalesce(w.requested_at, '-infinity'), w.id) > (coalesce(c.requested_at, '-infinity'), c.id) " +
+		"ORDER BY coalesce(w.requested_at, '-infinity'), w.id LIMIT $2"
 )
 
 func (w *PostgresWithdrawRepo) Get(ctx context.Context, limit, offset int64) ([]domain.Withdraw, error) {
@@ -71,7 +87,30 @@ func (w *PostgresWithdrawRepo) GetByShopID(ctx context.Context, shopID domain.ID
 	}
 	return withdraws, nil
 }
+
+// Create stores a new withdraw request, which always starts in the Start
+// status. The request time is set here and a withdraw without a currency is
+// paid in the default currency of the shop.
 func (w *PostgresWithdrawRepo) Create(ctx context.Context, withdraw domain.Withdraw) (domain.Withdraw, error) {
+	if withdraw.Status != domain.WithdrawStatusStart {
+		return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "withdraw must be created in the Start status")
+	}
+	if withdraw.Currency == "" {
+		shop, err := NewShopRepo(w.db).GetShopByID(ctx, withdraw.ShopID)
+		if err != nil {
+			return domain.Withdraw{}, err
+		}
+		withdraw.Currency = shop.DefaultCurrency
+	}
+	if !currency.Valid(withdraw.Currency) {
+		return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "invalid currency code")
+	}
+	withdraw.RequestedAt = time.Now().UTC()
+	withdraw.ApprovedAt = null.Time{}
+	withdraw.PaidAt = null.Time{}
+	withdraw.ReviewerID = ""
+	withdraw.BatchID = ""
+
 	var pgWithdraw = entity.NewPgWithdraw(withdraw)
 	queryString := entity.InsertQueryString(pgWithdraw, "withdraw")
 	_, err := w.db.NamedExecContext(ctx, queryString, pgWithdraw)
@@ -91,16 +130,78 @@ func (w *PostgresWithdrawRepo) Create(ctx context.Context, withdraw domain.Withd
 	return w.GetByID(ctx, withdraw.ID)
 }
 
+// Update changes the comment and the sum of the withdraw, the status is
+// only changed by TransitionWithdraw. The sum can only change in the Start
+// status, an approved withdraw keeps the sum the reviewer approved.
 func (w *PostgresWithdrawRepo) Update(ctx context.Context, withdraw domain.Withdraw) (domain.Withdraw, error) {
-	var pgWithdraw = entity.NewPgWithdraw(withdraw)
-	queryString := entity.UpdateQueryString(pgWithdraw, "withdraw")
-	_, err := w.db.NamedExecContext(ctx, queryString, pgWithdraw)
+	res, err := w.db.ExecContext(ctx, withdrawUpdateQuery, withdraw.ID, withdraw.Comment, withdraw.Sum)
 	if err != nil {
 		return domain.Withdraw{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
 	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		if _, err = w.GetByID(ctx, withdraw.ID); err != nil {
+			return domain.Withdraw{}, err
+		}
+		return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "withdraw sum can only change in the Start status")
+	}
 
 	return w.GetByID(ctx, withdraw.ID)
 }
+
+// TransitionWithdraw moves the withdraw to the next status, Start to Ready
+// when the reviewer approves it and Ready to Done when it is paid. The
+// status is checked and changed by a single statement, so a transition made
+// from a stale status is rejected with ErrNotAllowed. A withdraw attached to
+// a payout batch is paid when the batch is settled.
+func (w *PostgresWithdrawRepo) TransitionWithdraw(ctx context.Context, withdrawID domain.ID, status domain.WithdrawStatus, reviewerID domain.ID) (domain.Withdraw, error) {
+	var res sql.Result
+	var err error
+	switch status {
+	case domain.WithdrawStatusReady:
+		if reviewerID == "" {
+			return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "withdraw approval needs a reviewer")
+		}
+		res, err = w.db.ExecContext(ctx, withdrawApproveQuery, withdrawID, time.Now().UTC(), reviewerID)
+	case domain.WithdrawStatusDone:
+		res, err = w.db.ExecContext(ctx, withdrawPayQuery, withdrawID, time.Now().UTC())
+	default:
+		return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "withdraw can not go back to the Start status")
+	}
+	if err != nil {
+		return domain.Withdraw{}, errors.Wrap(domain.ErrUpdateFailed, err.Error())
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		if _, err = w.GetByID(ctx, withdrawID); err != nil {
+			return domain.Withdraw{}, err
+		}
+		return domain.Withdraw{}, errors.Wrap(domain.ErrNotAllowed, "withdraw is not in the previous status")
+	}
+
+	return w.GetByID(ctx, withdrawID)
+}
+
+// GetByStatus returns the withdraws with the status oldest request first,
+// the ones with an unknown request time before all others, to be worked as a
+// queue. An empty cursor starts from the beginning,
+// otherwise only withdraws requested after the cursor withdraw are returned.
+func (w *PostgresWithdrawRepo) GetByStatus(ctx context.Context, status domain.WithdrawStatus, limit int64, cursor domain.ID) ([]domain.Withdraw, error) {
+	var pgWithdraws []entity.PgWithdraw
+	var err error
+	if cursor == "" {
+		err = w.db.SelectContext(ctx, &pgWithdraws, withdrawGetByStatusQuery, entity.NewPgWithdrawStatus(status), limit)
+	} else {
+		err = w.db.SelectContext(ctx, &pgWithdraws, withdrawGetByStatusCursor, entity.NewPgWithdrawStatus(status), limit, cursor)
+	}
+	if err != nil {
+		return nil, errors.Wrap(domain.ErrPersistenceFailed, err.Error())
+	}
+
+	withdraws := make([]domain.Withdraw, len(pgWithdraws))
+	for i, withdraw := range pgWithdraws {
+		withdraws[i] = withdraw.ToDomain()
+	}
+	return withdraws, nil
+}
 func (w *PostgresWithdrawRepo) Delete(ctx context.Context, withdrawID domain.ID) error {
 	_, err := w.db.ExecContext(ctx, WithdrawDeleteQuery, withdrawID)
 	if err != nil {
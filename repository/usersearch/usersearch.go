@@ -0,0 +1,53 @@
+// Package usersearch holds the parts of the moderator user search that both
+// backends share, the page size and the cursor handed to the caller.
+package usersearch
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/EmirShimshir/marketplace-core/domain"
+	"github.com/pkg/errors"
+)
+
+// DefaultLimit is the page size of a search without a limit.
+const DefaultLimit = 50
+
+// Cursor is the position of the last user of a search page, it is handed to
+// the caller as an opaque string. Value is the sort field of the user and is
+// empty when the users are sorted by id.
+type Cursor struct {
+	Value string    `json:"v"`
+	ID    domain.ID `json:"id"`
+}
+
+// NewCursor returns the position of the user in a search sorted by sortBy.
+func NewCursor(user domain.User, sortBy domain.UserSortField) Cursor {
+	cursor := Cursor{ID: user.ID}
+	switch sortBy {
+	case domain.UserSortByName:
+		cursor.Value = user.Name
+	case domain.UserSortBySurname:
+		cursor.Value = user.Surname
+	}
+	return cursor
+}
+
+// Encode returns the cursor as the opaque string handed to the caller.
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by Encode, a malformed cursor is
+// ErrNotAllowed.
+func DecodeCursor(s string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil {
+		return Cursor{}, errors.Wrap(domain.ErrNotAllowed, "invalid cursor: "+err.Error())
+	}
+	return cursor, nil
+}